@@ -1,92 +1,392 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// billsCacheKey is the single TTLCache key BillsCache stores the listing under
+const billsCacheKey = "bills"
+
 // BillsCache provides thread-safe caching for bills data
+// Hits and misses of Get are counted without taking the cache lock
 type BillsCache struct {
-	mu          sync.RWMutex
-	bills       []Bill
-	lastUpdated time.Time
-	ttl         time.Duration
+	cache  *TTLCache[string, []Bill]
+	hits   atomic.Uint64
+	misses atomic.Uint64
 }
 
 // NewBillsCache creates a new bills cache with the specified TTL
 func NewBillsCache(ttl time.Duration) *BillsCache {
 	return &BillsCache{
-		ttl: ttl,
+		cache: NewSliceTTLCache[string, Bill](ttl),
 	}
 }
 
 // Get retrieves bills from cache if not expired
 // Returns the bills and a boolean indicating if the cache hit was successful
 func (c *BillsCache) Get() ([]Bill, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Check if cache is empty
-	if len(c.bills) == 0 {
-		return nil, false
-	}
-
-	// Check if cache has expired
-	if time.Since(c.lastUpdated) > c.ttl {
+	bills, ok := c.cache.Get(billsCacheKey)
+	if !ok || len(bills) == 0 {
+		c.misses.Add(1)
 		return nil, false
 	}
+	c.hits.Add(1)
+	return bills, true
+}
 
-	// Return cached bills (make a copy to prevent external modifications)
-	billsCopy := make([]Bill, len(c.bills))
-	copy(billsCopy, c.bills)
+// Stats returns how many Get calls have hit and missed the cache
+func (c *BillsCache) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
 
-	return billsCopy, true
+// GetStale retrieves bills from cache even if expired, with when they were fetched
+// Returns false only if the cache holds no bills
+func (c *BillsCache) GetStale() ([]Bill, time.Time, bool) {
+	bills, storedAt, ok := c.cache.Peek(billsCacheKey)
+	if !ok || len(bills) == 0 {
+		return nil, time.Time{}, false
+	}
+	return bills, storedAt, true
 }
 
 // Set updates the cache with new bills data
 func (c *BillsCache) Set(bills []Bill) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Store bills (make a copy to prevent external modifications)
-	c.bills = make([]Bill, len(bills))
-	copy(c.bills, bills)
-	c.lastUpdated = time.Now()
+	c.cache.Set(billsCacheKey, bills)
 }
 
 // Clear removes all bills from the cache
 func (c *BillsCache) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.bills = nil
-	c.lastUpdated = time.Time{}
+	c.cache.Delete(billsCacheKey)
 }
 
 // GetLastUpdated returns when the cache was last updated
 func (c *BillsCache) GetLastUpdated() time.Time {
+	_, storedAt, _ := c.cache.Peek(billsCacheKey)
+	return storedAt
+}
+
+// IsExpired checks if the cache has expired
+func (c *BillsCache) IsExpired() bool {
+	bills, ok := c.cache.Get(billsCacheKey)
+	return !ok || len(bills) == 0
+}
+
+// GetSize returns the number of bills in the cache
+func (c *BillsCache) GetSize() int {
+	bills, _, _ := c.cache.Peek(billsCacheKey)
+	return len(bills)
+}
+
+// billsSnapshot is the on-disk form of a BillsCache
+type billsSnapshot struct {
+	Bills       []Bill    `json:"bills"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// SaveToFile persists the cached bills and when they were fetched to path
+func (c *BillsCache) SaveToFile(path string) error {
+	bills, lastUpdated, _ := c.cache.Peek(billsCacheKey)
+	data, err := json.MarshalIndent(billsSnapshot{Bills: bills, LastUpdated: lastUpdated}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bills: %w", err)
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// LoadFromFile restores bills saved by SaveToFile. The original fetch time is kept,
+// so bills older than the TTL are loaded but still treated as expired.
+func (c *BillsCache) LoadFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var snapshot billsSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse bills cache %s: %w", path, err)
+	}
+
+	c.cache.set(billsCacheKey, snapshot.Bills, snapshot.LastUpdated)
+	return nil
+}
+
+// modelCatalogCacheKey is the single TTLCache key ModelCatalogCache stores the catalog under
+const modelCatalogCacheKey = "models"
+
+// ModelCatalogCache provides thread-safe caching for the OpenRouter model catalog
+type ModelCatalogCache struct {
+	cache *TTLCache[string, []CatalogModel]
+}
+
+// NewModelCatalogCache creates a new model catalog cache with the specified TTL
+func NewModelCatalogCache(ttl time.Duration) *ModelCatalogCache {
+	return &ModelCatalogCache{
+		cache: NewSliceTTLCache[string, CatalogModel](ttl),
+	}
+}
+
+// Get retrieves the catalog from cache if not expired
+// Returns the models and a boolean indicating if the cache hit was successful
+func (c *ModelCatalogCache) Get() ([]CatalogModel, bool) {
+	models, ok := c.cache.Get(modelCatalogCacheKey)
+	if !ok || len(models) == 0 {
+		return nil, false
+	}
+	return models, true
+}
+
+// Set updates the cache with a freshly fetched catalog
+func (c *ModelCatalogCache) Set(models []CatalogModel) {
+	c.cache.Set(modelCatalogCacheKey, models)
+}
+
+// ttlEntry is a single value stored in a TTLCache
+type ttlEntry[V any] struct {
+	value    V
+	storedAt time.Time
+	lastUsed atomic.Uint64 // Cache clock reading at the last Set or Get, for LRU eviction
+}
+
+// TTLCache provides thread-safe keyed caching with a fixed TTL per entry
+// When maxEntries is set, the least recently used entries are evicted beyond it
+type TTLCache[K comparable, V any] struct {
+	mu         sync.RWMutex
+	entries    map[K]*ttlEntry[V]
+	ttl        time.Duration
+	maxEntries int           // 0 for no limit
+	clock      atomic.Uint64 // Ticks on every Set and Get, ordering entries by use
+	clone      func(V) V     // Copies values on the way in and out; nil stores them as-is
+}
+
+// NewTTLCache creates a new keyed cache with the specified TTL
+func NewTTLCache[K comparable, V any](ttl time.Duration) *TTLCache[K, V] {
+	return &TTLCache[K, V]{
+		entries: make(map[K]*ttlEntry[V]),
+		ttl:     ttl,
+	}
+}
+
+// NewBoundedTTLCache creates a keyed cache with the specified TTL holding at most
+// maxEntries entries, evicting the least recently used beyond that (0 for no limit)
+func NewBoundedTTLCache[K comparable, V any](ttl time.Duration, maxEntries int) *TTLCache[K, V] {
+	c := NewTTLCache[K, V](ttl)
+	c.maxEntries = maxEntries
+	return c
+}
+
+// NewSliceTTLCache creates a keyed cache of slices that copies them on Set and on
+// every read, so callers can't modify what is cached
+func NewSliceTTLCache[K comparable, E any](ttl time.Duration) *TTLCache[K, []E] {
+	c := NewTTLCache[K, []E](ttl)
+	c.clone = slices.Clone[[]E]
+	return c
+}
+
+// Get retrieves the value for key if present and not expired
+// Returns the value and a boolean indicating if the cache hit was successful
+func (c *TTLCache[K, V]) Get(key K) (V, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	return c.lastUpdated
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.storedAt) > c.ttl {
+		var zero V
+		return zero, false
+	}
+	entry.lastUsed.Store(c.clock.Add(1))
+
+	return c.copyValue(entry.value), true
 }
 
-// IsExpired checks if the cache has expired
-func (c *BillsCache) IsExpired() bool {
+// Peek retrieves the value for key and when it was stored, even if it has expired
+func (c *TTLCache[K, V]) Peek(key K) (V, time.Time, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if len(c.bills) == 0 {
-		return true
+	entry, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, time.Time{}, false
 	}
 
-	return time.Since(c.lastUpdated) > c.ttl
+	return c.copyValue(entry.value), entry.storedAt, true
 }
 
-// GetSize returns the number of bills in the cache
-func (c *BillsCache) GetSize() int {
+// Set stores value under key, resetting its expiry
+func (c *TTLCache[K, V]) Set(key K, value V) {
+	c.set(key, value, time.Now())
+}
+
+// set stores value under key as if it had been stored at storedAt
+func (c *TTLCache[K, V]) set(key K, value V, storedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries so the map doesn't grow with stale data
+	for k, entry := range c.entries {
+		if time.Since(entry.storedAt) > c.ttl {
+			delete(c.entries, k)
+		}
+	}
+
+	entry := &ttlEntry[V]{value: c.copyValue(value), storedAt: storedAt}
+	entry.lastUsed.Store(c.clock.Add(1))
+	c.entries[key] = entry
+
+	// Evict the least recently used entries beyond the limit
+	for c.maxEntries > 0 && len(c.entries) > c.maxEntries {
+		var oldestKey K
+		var oldest uint64
+		first := true
+		for k, e := range c.entries {
+			if used := e.lastUsed.Load(); first || used < oldest {
+				oldestKey, oldest, first = k, used, false
+			}
+		}
+		delete(c.entries, oldestKey)
+	}
+}
+
+// Delete removes key from the cache
+func (c *TTLCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Len returns the number of entries in the cache, including expired ones not yet pruned
+func (c *TTLCache[K, V]) Len() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	return len(c.bills)
+	return len(c.entries)
+}
+
+// copyValue returns value, or a copy of it if the cache clones its values
+func (c *TTLCache[K, V]) copyValue(value V) V {
+	if c.clone == nil {
+		return value
+	}
+	return c.clone(value)
+}
+
+// PageValidators records the HTTP cache validators and parsed bills for one listing page
+type PageValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Bills        []Bill `json:"bills"`
+	HasNext      bool   `json:"has_next"`
+	TotalPages   int    `json:"total_pages"`
+}
+
+// PageValidatorStore provides thread-safe storage of per-page validators
+// Entries are persisted to disk as JSON so conditional requests survive restarts
+type PageValidatorStore struct {
+	mu    sync.RWMutex
+	pages map[int]PageValidators
+	path  string
+}
+
+// NewPageValidatorStore creates a store backed by the JSON file at path
+// Existing validators are loaded if the file is present and readable
+func NewPageValidatorStore(path string) *PageValidatorStore {
+	s := &PageValidatorStore{
+		pages: make(map[int]PageValidators),
+		path:  path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: failed to read page validators: %v", err)
+		}
+		return s
+	}
+
+	if err := json.Unmarshal(data, &s.pages); err != nil {
+		log.Printf("Warning: ignoring corrupt page validators file %s: %v", path, err)
+		s.pages = make(map[int]PageValidators)
+	}
+
+	return s
+}
+
+// Get returns the validators stored for a page
+func (s *PageValidatorStore) Get(page int) (PageValidators, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.pages[page]
+	if !ok {
+		return PageValidators{}, false
+	}
+
+	// Return a copy of the bills to prevent external modifications
+	v.Bills = append([]Bill(nil), v.Bills...)
+	return v, true
+}
+
+// Set stores the validators for a page and persists the store to disk
+func (s *PageValidatorStore) Set(page int, v PageValidators) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v.Bills = append([]Bill(nil), v.Bills...)
+	s.pages[page] = v
+
+	return s.save()
+}
+
+// save writes the store to disk; the caller must hold the write lock
+func (s *PageValidatorStore) save() error {
+	data, err := json.MarshalIndent(s.pages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal page validators: %w", err)
+	}
+
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic writes data to path, creating its directory if needed
+// It writes to a temp file and renames so a crash never leaves a partial file, and
+// readers see either the old or the new contents. Each write gets its own temp file
+// so concurrent writers to the same path don't trip over each other
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", path, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+
+	return nil
 }
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTTLCache tests keyed caching with TTL expiry
+func TestTTLCache(t *testing.T) {
+	t.Run("cache hit", func(t *testing.T) {
+		cache := NewTTLCache[string, string](time.Hour)
+		cache.Set("a", "alpha")
+
+		value, ok := cache.Get("a")
+		if !ok || value != "alpha" {
+			t.Errorf("Get(a) = %q, %v; want 'alpha', true", value, ok)
+		}
+		if _, ok := cache.Get("b"); ok {
+			t.Error("Expected miss for unknown key")
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		cache := NewTTLCache[string, string](20 * time.Millisecond)
+		cache.Set("a", "alpha")
+
+		time.Sleep(40 * time.Millisecond)
+
+		if value, ok := cache.Get("a"); ok {
+			t.Errorf("Expected expired entry to miss, got %q", value)
+		}
+
+		// Setting a new key prunes the expired one
+		cache.Set("b", "beta")
+		if cache.Len() != 1 {
+			t.Errorf("Len = %d, want 1 after pruning", cache.Len())
+		}
+	})
+
+	t.Run("overwrite and delete", func(t *testing.T) {
+		cache := NewTTLCache[string, int](time.Hour)
+		cache.Set("n", 1)
+		cache.Set("n", 2)
+
+		if value, _ := cache.Get("n"); value != 2 {
+			t.Errorf("Get(n) = %d, want 2", value)
+		}
+
+		cache.Delete("n")
+		if _, ok := cache.Get("n"); ok {
+			t.Error("Expected miss after Delete")
+		}
+		if cache.Len() != 0 {
+			t.Errorf("Len = %d, want 0", cache.Len())
+		}
+	})
+}
+
+// TestBoundedTTLCacheEviction tests evicting the least recently used entries past the limit
+func TestBoundedTTLCacheEviction(t *testing.T) {
+	cache := NewBoundedTTLCache[string, int](time.Hour, 3)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+
+	// Reading a makes b the least recently used
+	cache.Get("a")
+	cache.Set("d", 4)
+
+	if cache.Len() != 3 {
+		t.Errorf("Len = %d, want 3", cache.Len())
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected b to be evicted as least recently used")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+
+	// Overwriting an existing key doesn't evict anything
+	cache.Set("c", 30)
+	if cache.Len() != 3 {
+		t.Errorf("Len = %d after overwrite, want 3", cache.Len())
+	}
+
+	// Inserting several entries evicts the oldest in order
+	cache.Set("e", 5)
+	cache.Set("f", 6)
+	for key, want := range map[string]bool{"a": false, "d": false, "c": true, "e": true, "f": true} {
+		if _, ok := cache.Get(key); ok != want {
+			t.Errorf("Get(%s) present = %v, want %v", key, ok, want)
+		}
+	}
+
+	// Without a limit nothing is evicted
+	unbounded := NewBoundedTTLCache[int, int](time.Hour, 0)
+	for i := range 100 {
+		unbounded.Set(i, i)
+	}
+	if unbounded.Len() != 100 {
+		t.Errorf("Unbounded Len = %d, want 100", unbounded.Len())
+	}
+}
+
+// TestTTLCacheKeysAndPeek tests non-string keys and reading entries past their TTL
+func TestTTLCacheKeysAndPeek(t *testing.T) {
+	cache := NewTTLCache[int, string](20 * time.Millisecond)
+	before := time.Now()
+	cache.Set(7, "seven")
+
+	value, storedAt, ok := cache.Peek(7)
+	if !ok || value != "seven" || storedAt.Before(before) {
+		t.Errorf("Peek(7) = %q, %v, %v", value, storedAt, ok)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok := cache.Get(7); ok {
+		t.Error("Expected expired entry to miss")
+	}
+	if value, _, ok := cache.Peek(7); !ok || value != "seven" {
+		t.Errorf("Peek(7) after expiry = %q, %v; want the expired value", value, ok)
+	}
+	if _, _, ok := cache.Peek(8); ok {
+		t.Error("Expected Peek miss for unknown key")
+	}
+}
+
+// TestSliceTTLCacheCopies tests that cached slices can't be modified through the
+// slices passed to Set or returned by Get and Peek
+func TestSliceTTLCacheCopies(t *testing.T) {
+	cache := NewSliceTTLCache[string, Bill](time.Hour)
+
+	bills := []Bill{{ID: "r1"}, {ID: "s2"}}
+	cache.Set("bills", bills)
+	bills[0].ID = "changed by caller"
+
+	got, _ := cache.Get("bills")
+	if got[0].ID != "r1" {
+		t.Errorf("Cached bill = %q, want r1 despite the caller's change", got[0].ID)
+	}
+	got[1].ID = "changed by reader"
+
+	peeked, _, _ := cache.Peek("bills")
+	if peeked[1].ID != "s2" {
+		t.Errorf("Cached bill = %q, want s2 despite the reader's change", peeked[1].ID)
+	}
+}
+
+// TestTTLCacheConcurrentAccess tests concurrent reads, writes and deletes
+func TestTTLCacheConcurrentAccess(t *testing.T) {
+	cache := NewSliceTTLCache[int, int](time.Hour)
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				key := i % 10
+				cache.Set(key, []int{worker, i})
+				if value, ok := cache.Get(key); ok && len(value) != 2 {
+					t.Errorf("Get(%d) = %v, want a two-element slice", key, value)
+				}
+				cache.Peek(key)
+				if i%7 == 0 {
+					cache.Delete(key)
+				}
+				cache.Len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := cache.Len(); n > 10 {
+		t.Errorf("Len = %d, want at most 10 keys", n)
+	}
+}
+
+// TestPageValidatorStore tests persistence of per-page validators
+func TestPageValidatorStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bills", "page_validators.json")
+
+	store := NewPageValidatorStore(path)
+	if _, ok := store.Get(1); ok {
+		t.Error("Expected empty store when no file exists")
+	}
+
+	validators := PageValidators{ETag: `"abc"`, Bills: []Bill{{ID: "r1"}}, HasNext: true}
+	if err := store.Set(1, validators); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Mutating the returned bills must not affect the store
+	got, _ := store.Get(1)
+	got.Bills[0].ID = "changed"
+
+	reloaded, ok := NewPageValidatorStore(path).Get(1)
+	if !ok || !reflect.DeepEqual(reloaded, validators) {
+		t.Errorf("Reloaded validators = %+v, want %+v", reloaded, validators)
+	}
+
+	// A corrupt file is ignored rather than failing startup
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt file: %v", err)
+	}
+	if _, ok := NewPageValidatorStore(path).Get(1); ok {
+		t.Error("Expected corrupt validators file to be ignored")
+	}
+}
+
+// TestBillsCacheStats tests counting cache hits and misses
+func TestBillsCacheStats(t *testing.T) {
+	cache := NewBillsCache(30 * time.Millisecond)
+
+	cache.Get() // miss: empty
+	cache.Set([]Bill{{ID: "r1"}})
+	cache.Get() // hit
+	cache.Get() // hit
+	cache.Get() // hit
+
+	// Inspecting the cache doesn't count as a lookup
+	cache.IsExpired()
+	cache.GetStale()
+
+	time.Sleep(60 * time.Millisecond)
+	cache.Get() // miss: expired
+
+	if hits, misses := cache.Stats(); hits != 3 || misses != 2 {
+		t.Errorf("Stats() = %d hits, %d misses; want 3 hits, 2 misses", hits, misses)
+	}
+}
+
+// TestBillsCachePersistence tests saving and restoring the bills cache
+func TestBillsCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bills", "bills.json")
+
+	cache := NewBillsCache(time.Hour)
+	cache.Set([]Bill{{ID: "r1", Title: "First Bill"}, {ID: "s2", Title: "Second Bill"}})
+	if err := cache.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	restored := NewBillsCache(time.Hour)
+	if err := restored.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	bills, ok := restored.Get()
+	if !ok || len(bills) != 2 || bills[1].Title != "Second Bill" {
+		t.Errorf("Restored bills = %v, %v", bills, ok)
+	}
+	if !restored.GetLastUpdated().Equal(cache.GetLastUpdated()) {
+		t.Errorf("LastUpdated = %v, want %v", restored.GetLastUpdated(), cache.GetLastUpdated())
+	}
+
+	// Bills older than the TTL load but are still expired
+	stale := NewBillsCache(time.Nanosecond)
+	if err := stale.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if !stale.IsExpired() || stale.GetSize() != 2 {
+		t.Errorf("Expected stale bills to load as expired")
+	}
+	if bills, lastUpdated, ok := stale.GetStale(); !ok || len(bills) != 2 || !lastUpdated.Equal(cache.GetLastUpdated()) {
+		t.Errorf("GetStale() = %v, %v, %v; want the expired bills and their fetch time", bills, lastUpdated, ok)
+	}
+	if _, _, ok := NewBillsCache(time.Hour).GetStale(); ok {
+		t.Error("GetStale() on an empty cache should report no bills")
+	}
+
+	if err := restored.LoadFromFile(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected not-exist error for a missing file, got %v", err)
+	}
+}
+
+// TestModelCatalogCache tests that the catalog is copied in and out and expires
+func TestModelCatalogCache(t *testing.T) {
+	cache := NewModelCatalogCache(30 * time.Millisecond)
+	if _, ok := cache.Get(); ok {
+		t.Error("Expected miss before anything is cached")
+	}
+
+	models := []CatalogModel{{ID: "openai/gpt-5.1"}, {ID: "anthropic/claude-sonnet-4.5"}}
+	cache.Set(models)
+	models[0].ID = "changed/by-caller"
+
+	got, ok := cache.Get()
+	if !ok || len(got) != 2 || got[0].ID != "openai/gpt-5.1" {
+		t.Fatalf("Get() = %v, %v; want the catalog as it was set", got, ok)
+	}
+	got[1].ID = "changed/by-reader"
+	if again, _ := cache.Get(); again[1].ID != "anthropic/claude-sonnet-4.5" {
+		t.Errorf("Modifying a read catalog changed the cache: %v", again)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := cache.Get(); ok {
+		t.Error("Expected expired catalog to miss")
+	}
+}
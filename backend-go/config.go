@@ -1,9 +1,19 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"maps"
+	"net/mail"
+	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,6 +24,15 @@ var (
 	// OpenRouterAPIKey is the API key for OpenRouter
 	OpenRouterAPIKey string
 
+	// APIKey, when set, is required as a bearer token on all /api/* routes
+	// (configurable via API_KEY; empty leaves the API open)
+	APIKey string
+
+	// AdminAPIKey, when set, is required in the X-Admin-Key header on admin routes
+	// such as PUT /api/config/models, on top of APIKey. Empty disables admin routes
+	// (configurable via ADMIN_API_KEY)
+	AdminAPIKey string
+
 	// CouncilModels is the list of models to query in parallel
 	CouncilModels = []string{
 		"openai/gpt-5.1",
@@ -22,19 +41,182 @@ var (
 		"x-ai/grok-4",
 	}
 
+	// ModelWeights sets how much each ranker's Stage 2 ranking counts towards the
+	// aggregate. Unlisted models have weight 1.0; a weight of 0 ignores the ranker.
+	ModelWeights = map[string]float64{}
+
+	// ModelPromptPricing is the prompt price in USD per million tokens, used by the
+	// cost estimate endpoint. Models without an entry are estimated without a cost.
+	ModelPromptPricing = map[string]float64{}
+
+	// RankerModels rank the Stage 1 responses in Stage 2, so cheaper or faster models
+	// can review what CouncilModels answered. Empty means CouncilModels rank their own
+	// answers (configurable via RANKER_MODELS as a comma-separated list)
+	RankerModels = []string{}
+
+	// ExcludeSelfRanking keeps a ranker that also answered in Stage 1 from ranking its
+	// own response: it is left out of that ranker's prompt, and any vote the ranker
+	// still gives itself is dropped from the aggregate (configurable via
+	// EXCLUDE_SELF_RANKING)
+	ExcludeSelfRanking = false
+
+	// WinnerRationaleEnabled collects the sentences each Stage 2 ranker wrote about the
+	// winning response into the council metadata, explaining why it won. The sentences
+	// are extracted from the rankings already collected, so no model is queried
+	// (configurable via WINNER_RATIONALE)
+	WinnerRationaleEnabled = false
+
+	// StructuredRankingModels support JSON schema output, so they are asked for their
+	// Stage 2 ranking as JSON instead of a free-text "FINAL RANKING:" section
+	// (configurable via STRUCTURED_RANKING_MODELS as a comma-separated list)
+	StructuredRankingModels = []string{
+		"openai/gpt-5.1",
+		"google/gemini-3-pro-preview",
+	}
+
+	// ScrubModelIdentity strips self-identification phrases such as "As Claude, ..."
+	// from Stage 1 responses before they are shown anonymized to the Stage 2 rankers
+	// (configurable via SCRUB_MODEL_IDENTITY)
+	ScrubModelIdentity = false
+
+	// MaxCouncilResponses caps how many Stage 1 responses go on to be ranked and
+	// synthesized, keeping Stage 2 prompts within model context limits. Responses
+	// beyond the cap are dropped in CouncilModels order (configurable via
+	// MAX_COUNCIL_RESPONSES; 0 means no cap)
+	MaxCouncilResponses = 0
+
+	// MaxResponseCharsInRanking cuts each Stage 1 response to this many characters in
+	// the Stage 2 ranking prompt, and MaxRankingPromptTokens shortens all responses
+	// proportionally when the whole prompt is estimated to exceed it. Stored responses
+	// keep their full text (configurable via MAX_RESPONSE_CHARS_IN_RANKING and
+	// MAX_RANKING_PROMPT_TOKENS; 0 disables either limit)
+	MaxResponseCharsInRanking = 20000
+	MaxRankingPromptTokens    = 100000
+
+	// DuplicateSimilarityThreshold is the word-overlap (Jaccard) similarity at which two
+	// Stage 1 responses are reported as duplicates in the council metadata
+	// (configurable via DUPLICATE_SIMILARITY_THRESHOLD, between 0 and 1)
+	DuplicateSimilarityThreshold = 0.8
+
 	// ChairmanModel is the model used for final synthesis
 	ChairmanModel = "google/gemini-3-pro-preview"
 
+	// ChairmanFallbacks are tried in order if the chairman model fails
+	// (configurable via CHAIRMAN_FALLBACKS as a comma-separated list)
+	ChairmanFallbacks = []string{}
+
+	// CouncilSystemPrompt, when set, is sent as a system message ahead of the user's
+	// query in Stage 1 (configurable via COUNCIL_SYSTEM_PROMPT)
+	CouncilSystemPrompt = ""
+
+	// ChairmanSystemPrompt, when set, is sent as a system message ahead of the
+	// Stage 3 synthesis prompt (configurable via CHAIRMAN_SYSTEM_PROMPT)
+	ChairmanSystemPrompt = ""
+
+	// Reasoning effort requested from reasoning models in each stage ("minimal", "low",
+	// "medium" or "high"; empty leaves it to the model). Configurable via
+	// COUNCIL_REASONING_EFFORT, RANKING_REASONING_EFFORT and CHAIRMAN_REASONING_EFFORT.
+	CouncilReasoningEffort  = ""
+	RankingReasoningEffort  = ""
+	ChairmanReasoningEffort = ""
+
+	// ReasoningContentFallback uses a reasoning model's reasoning text as its answer
+	// when it returns no content, instead of counting the query as failed
+	// (configurable via REASONING_CONTENT_FALLBACK)
+	ReasoningContentFallback = false
+
+	// CouncilModes are named presets a request can pick with mode instead of tuning
+	// models itself, each a roster with its own timeouts and reasoning effort. A
+	// preset without council models uses the configured roster (configurable via
+	// council_modes in the config file, which adds to or replaces these)
+	CouncilModes = map[string]CouncilConfig{
+		"fast": {
+			CouncilModels:     []string{"google/gemini-2.5-flash", "openai/gpt-5-mini", "anthropic/claude-haiku-4.5"},
+			ChairmanModel:     "google/gemini-2.5-flash",
+			ModelQueryTimeout: 30 * time.Second,
+			CouncilTimeout:    90 * time.Second,
+			ReasoningEffort:   "minimal",
+		},
+		"balanced": {},
+		"thorough": {
+			CouncilModels: []string{
+				"openai/gpt-5.1",
+				"google/gemini-3-pro-preview",
+				"anthropic/claude-opus-4.1",
+				"x-ai/grok-4",
+				"deepseek/deepseek-r1",
+			},
+			ChairmanModel:     "openai/gpt-5.1",
+			ChairmanFallbacks: []string{"google/gemini-3-pro-preview"},
+			ModelQueryTimeout: 5 * time.Minute,
+			CouncilTimeout:    15 * time.Minute,
+			ReasoningEffort:   "high",
+		},
+	}
+
+	// TitleModel is the fast model used to generate conversation titles
+	TitleModel = "google/gemini-2.5-flash"
+
+	// OpenRouterBaseURL is the OpenRouter-compatible API root the endpoints below are
+	// derived from (configurable via OPENROUTER_BASE_URL, e.g. to go through a proxy)
+	OpenRouterBaseURL = "https://openrouter.ai/api/v1"
+
 	// OpenRouterAPIURL is the endpoint for OpenRouter API
 	OpenRouterAPIURL = "https://openrouter.ai/api/v1/chat/completions"
 
+	// OpenRouterModelsURL is the endpoint for OpenRouter's model catalog
+	OpenRouterModelsURL = "https://openrouter.ai/api/v1/models"
+
 	// DataDir is the directory for conversation storage
+	// (configurable via DATA_DIR, e.g. to point at a mounted volume)
 	DataDir = "data/conversations"
 
+	// BillsCacheDir is the directory for the on-disk bills scraper cache
+	BillsCacheDir = "data/bills"
+
+	// QueryCacheEnabled stores every successful non-streaming model response on disk
+	// and answers identical later queries from it without calling OpenRouter. Meant
+	// for development and tests, where it saves credits and makes runs repeatable
+	// (configurable via QUERY_CACHE_ENABLED; entries never expire)
+	QueryCacheEnabled = false
+
+	// QueryCacheDir is the directory for cached model responses
+	// (configurable via QUERY_CACHE_DIR)
+	QueryCacheDir = "data/query_cache"
+
+	// MaxSearchResults caps the number of conversations returned by a search
+	MaxSearchResults = 50
+
 	// Timeout constants
+	// ModelQueryTimeout bounds each individual model query in all three stages
 	ModelQueryTimeout = 120 * time.Second
 	TitleGenTimeout   = 30 * time.Second
 
+	// ModelLaunchJitter spreads the start of parallel model queries over this window
+	// instead of firing them all at once, to stay under provider burst limits
+	// (configurable via MODEL_LAUNCH_JITTER as a Go duration; 0 disables)
+	ModelLaunchJitter time.Duration = 0
+
+	// IdempotencyKeyTTL is how long a message response is kept for replay to
+	// requests retried with the same Idempotency-Key
+	IdempotencyKeyTTL = 10 * time.Minute
+
+	// CircuitBreakerThreshold is how many consecutive failures open a model's circuit,
+	// skipping it until CircuitBreakerCooldown has passed (configurable via
+	// CIRCUIT_BREAKER_THRESHOLD, 0 disables, and CIRCUIT_BREAKER_COOLDOWN)
+	CircuitBreakerThreshold = 5
+	CircuitBreakerCooldown  = 5 * time.Minute
+
+	// CouncilTimeout bounds a full 3-stage council run
+	// (configurable via COUNCIL_TIMEOUT as a Go duration, e.g. "4m")
+	CouncilTimeout = 5 * time.Minute
+
+	// CouncilRunRetries is how many times a council run is retried when every council
+	// model fails in Stage 1, waiting CouncilRetryBackoff (doubling each time) between
+	// attempts (configurable via COUNCIL_RUN_RETRIES and COUNCIL_RETRY_BACKOFF)
+	CouncilRunRetries   = 0
+	CouncilRetryBackoff = 2 * time.Second
+
 	// CORS allowed origins (configurable via environment)
 	// In development (empty/default), allows any localhost port
 	// In production, set CORS_ALLOWED_ORIGINS environment variable
@@ -43,11 +225,125 @@ var (
 	// MaxRequestBodySize is the maximum allowed request body size (1MB)
 	MaxRequestBodySize int64 = 1 << 20
 
+	// RateLimitRPS is the sustained requests per second allowed per client IP
+	// (configurable via RATE_LIMIT_RPS; 0 disables rate limiting)
+	RateLimitRPS = 5.0
+
+	// RateLimitBurst is the number of requests a client IP may make in a burst
+	// (configurable via RATE_LIMIT_BURST)
+	RateLimitBurst = 20
+
+	// TrustedProxies are the proxies whose X-Forwarded-For header identifies the
+	// client, for rate limiting and request logs. Requests from any other peer are
+	// identified by their own address (configurable via TRUSTED_PROXIES as a
+	// comma-separated list of IPs or CIDR ranges; empty trusts no proxy)
+	TrustedProxies = []netip.Prefix{}
+
+	// LogFormat selects structured log output: "text" (default) or "json"
+	// (configurable via LOG_FORMAT)
+	LogFormat = "text"
+
+	// MaxMessageLength is the maximum length of a user message in characters
+	// (configurable via MAX_MESSAGE_LENGTH)
+	MaxMessageLength = 32000
+
+	// MaxMessagesPerConversation caps how many messages a conversation holds, counting
+	// room for the answer to each new question; MessageLimitPolicy decides whether a
+	// message past the cap is rejected or the oldest exchanges are dropped to make
+	// room (configurable via MAX_MESSAGES_PER_CONVERSATION, 0 for no cap, and
+	// MESSAGE_LIMIT_POLICY)
+	MaxMessagesPerConversation = 0
+	MessageLimitPolicy         = MessageLimitReject
+
+	// MaxImagesPerMessage caps the image URLs attached to a user message
+	MaxImagesPerMessage = 4
+
+	// PageRequestDelay is the minimum gap between bills listing requests, to be
+	// respectful of APH (configurable via SCRAPER_PAGE_DELAY as a Go duration; 0
+	// removes the gap)
+	PageRequestDelay = 500 * time.Millisecond
+
+	// BillsFetchConcurrency is the maximum number of listing pages fetched at once
+	// (configurable via SCRAPER_CONCURRENCY)
+	BillsFetchConcurrency = 2
+
+	// ScraperMaxRetries is how many times a scraper request is retried after a
+	// network error, 429 or 5xx (configurable via SCRAPER_MAX_RETRIES)
+	ScraperMaxRetries = 2
+
+	// ScraperRetryBackoff is the delay before the first scraper retry, doubling on each
+	// further retry (configurable via SCRAPER_RETRY_BACKOFF as a Go duration)
+	ScraperRetryBackoff = 2 * time.Second
+
+	// ScraperMaxRetryDelay caps any single retry delay, including one requested by a
+	// server's Retry-After header
+	ScraperMaxRetryDelay = 30 * time.Second
+
 	// BillsCacheTTL is the time-to-live for bills cache (default 5 minutes)
 	BillsCacheTTL = 5 * time.Minute
+
+	// BillsRefreshInterval is how often the bills cache is re-scraped in the background,
+	// kept under BillsCacheTTL so the cache stays warm
+	// (configurable via BILLS_REFRESH_INTERVAL as a Go duration; 0 disables)
+	BillsRefreshInterval = 4 * time.Minute
+
+	// BillsPageSize is how many bills /api/bills returns per page when the request
+	// doesn't give a page_size; 0 returns every bill on one page
+	// (configurable via BILLS_PAGE_SIZE)
+	BillsPageSize = 0
+
+	// BillsIncrementalRefresh makes each background refresh also keep bill details
+	// cached, fetching detail pages only for bills that are new or changed since the
+	// last refresh (configurable via BILLS_INCREMENTAL_REFRESH)
+	BillsIncrementalRefresh = false
+
+	// SSEHeartbeatInterval is how often a keep-alive comment is written to message
+	// streams, so proxies don't drop the connection during a slow stage
+	// (configurable via SSE_HEARTBEAT_INTERVAL as a Go duration; 0 disables)
+	SSEHeartbeatInterval = 15 * time.Second
+
+	// ShutdownTimeout bounds how long in-flight requests get to finish on shutdown
+	ShutdownTimeout = 10 * time.Second
+
+	// ModelCatalogTTL is the time-to-live for the OpenRouter model catalog cache
+	ModelCatalogTTL = 1 * time.Hour
+
+	// ModelCatalogTimeout bounds a fetch of the OpenRouter model catalog
+	ModelCatalogTimeout = 30 * time.Second
+
+	// StrictModelValidation makes startup fail, rather than just warn, when a
+	// configured model ID isn't in OpenRouter's model catalog (configurable via
+	// STRICT_MODEL_VALIDATION)
+	StrictModelValidation = false
+
+	// MaxFetchURLSize bounds the body downloaded for /api/fetch-url, for pages and PDFs
+	// alike (configurable via FETCH_URL_MAX_BYTES)
+	MaxFetchURLSize int64 = 20 << 20
+
+	// FetchURLPolicy restricts the addresses /api/fetch-url may reach to public ones,
+	// and how many redirects it follows to get there (configurable via
+	// FETCH_URL_ALLOW_PRIVATE, FETCH_URL_ALLOW_CIDRS, FETCH_URL_DENY_CIDRS and
+	// FETCH_URL_MAX_REDIRECTS)
+	FetchURLPolicy = URLPolicy{MaxRedirects: 5}
+
+	// URLContentCacheTTL is the time-to-live for fetched URL content (default 1 hour)
+	URLContentCacheTTL = 1 * time.Hour
+
+	// URLCacheMaxEntries caps how many fetched pages are cached, evicting the least
+	// recently used beyond it (configurable via URL_CACHE_MAX_ENTRIES; 0 means no cap)
+	URLCacheMaxEntries = 500
+
+	// CouncilCacheTTL is how long a council result is reused for a repeat of the same
+	// question to the same roster (configurable via COUNCIL_CACHE_TTL as a Go
+	// duration; 0, the default, disables the cache)
+	CouncilCacheTTL time.Duration = 0
+
+	// BillDetailCacheTTL is the time-to-live for per-bill detail pages (default 1 hour)
+	BillDetailCacheTTL = 1 * time.Hour
 )
 
-// LoadConfig loads configuration from environment variables
+// LoadConfig loads configuration from an optional council.config.json file and
+// environment variables, with environment variables taking precedence
 func LoadConfig() {
 	// Load .env file - try multiple locations
 	envLocations := []string{
@@ -76,21 +372,735 @@ func LoadConfig() {
 		log.Printf("Warning: .env file not found in any expected location")
 	}
 
+	// Apply the optional JSON config file before the environment overrides it
+	if path := findConfigFile(); path != "" {
+		cfg, err := loadConfigFile(path)
+		if err != nil {
+			log.Fatalf("Invalid config file %s: %v", path, err)
+		}
+		cfg.apply()
+		log.Printf("Loaded config from %s: %d council models, chairman %s, council timeout %v",
+			path, len(CouncilModels), ChairmanModel, CouncilTimeout)
+	}
+
 	// Get OpenRouter API key
 	OpenRouterAPIKey = os.Getenv("OPENROUTER_API_KEY")
 	if OpenRouterAPIKey == "" {
 		log.Fatal("OPENROUTER_API_KEY environment variable is required")
 	}
 
+	// Load OpenRouter base URL from environment if provided
+	if raw := os.Getenv("OPENROUTER_BASE_URL"); raw != "" {
+		base, err := parseBaseURL(raw)
+		if err != nil {
+			log.Fatalf("OPENROUTER_BASE_URL %q is invalid: %v", raw, err)
+		}
+		OpenRouterBaseURL = base
+		OpenRouterAPIURL = base + "/chat/completions"
+		OpenRouterModelsURL = base + "/models"
+	}
+
+	// Log output format
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		LogFormat = format
+	}
+
+	// Optional API key protecting the backend's own endpoints
+	APIKey = os.Getenv("API_KEY")
+	AdminAPIKey = os.Getenv("ADMIN_API_KEY")
+
 	// Load CORS origins from environment if provided
 	if corsOrigins := os.Getenv("CORS_ALLOWED_ORIGINS"); corsOrigins != "" {
-		CORSAllowedOrigins = []string{}
-		for _, origin := range filepath.SplitList(corsOrigins) {
-			if origin != "" {
-				CORSAllowedOrigins = append(CORSAllowedOrigins, origin)
+		CORSAllowedOrigins = normalizeOrigins(strings.Split(corsOrigins, ","))
+	}
+
+	// Load chairman fallback models from environment if provided
+	if fallbacks := os.Getenv("CHAIRMAN_FALLBACKS"); fallbacks != "" {
+		ChairmanFallbacks = parseModelList(fallbacks)
+	}
+
+	// Load title-generation model from environment if provided
+	if raw, ok := os.LookupEnv("TITLE_MODEL"); ok {
+		model := strings.TrimSpace(raw)
+		if !validModelID(model) {
+			log.Fatalf("TITLE_MODEL must be a provider/model ID, got %q", raw)
+		}
+		TitleModel = model
+	}
+
+	// Load Stage 2 ranker models from environment if provided
+	if models := os.Getenv("RANKER_MODELS"); models != "" {
+		RankerModels = parseModelList(models)
+	}
+
+	// Load structured-output ranking models from environment if provided
+	if models, ok := os.LookupEnv("STRUCTURED_RANKING_MODELS"); ok {
+		StructuredRankingModels = parseModelList(models)
+	}
+
+	// Load feature flags from environment if provided
+	for name, flag := range map[string]*bool{
+		"SCRUB_MODEL_IDENTITY":       &ScrubModelIdentity,
+		"EXCLUDE_SELF_RANKING":       &ExcludeSelfRanking,
+		"WINNER_RATIONALE":           &WinnerRationaleEnabled,
+		"REASONING_CONTENT_FALLBACK": &ReasoningContentFallback,
+		"STRICT_MODEL_VALIDATION":    &StrictModelValidation,
+		"QUERY_CACHE_ENABLED":        &QueryCacheEnabled,
+		"SCRAPER_SPOOF_BROWSER":      &ScraperSpoofBrowser,
+		"BILLS_INCREMENTAL_REFRESH":  &BillsIncrementalRefresh,
+		"FETCH_URL_ALLOW_PRIVATE":    &FetchURLPolicy.AllowPrivate,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			enabled, err := strconv.ParseBool(raw)
+			if err != nil {
+				log.Fatalf("%s must be true or false, got %q", name, raw)
+			}
+			*flag = enabled
+		}
+	}
+
+	// Load duplicate-response threshold from environment if provided
+	if raw := os.Getenv("DUPLICATE_SIMILARITY_THRESHOLD"); raw != "" {
+		threshold, err := strconv.ParseFloat(raw, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			log.Fatalf("DUPLICATE_SIMILARITY_THRESHOLD must be a number in (0, 1], got %q", raw)
+		}
+		DuplicateSimilarityThreshold = threshold
+	}
+
+	// Load system prompts from environment if provided
+	if prompt := os.Getenv("COUNCIL_SYSTEM_PROMPT"); prompt != "" {
+		CouncilSystemPrompt = prompt
+	}
+	if prompt := os.Getenv("CHAIRMAN_SYSTEM_PROMPT"); prompt != "" {
+		ChairmanSystemPrompt = prompt
+	}
+
+	// Load per-stage reasoning effort from environment if provided
+	for name, effort := range map[string]*string{
+		"COUNCIL_REASONING_EFFORT":  &CouncilReasoningEffort,
+		"RANKING_REASONING_EFFORT":  &RankingReasoningEffort,
+		"CHAIRMAN_REASONING_EFFORT": &ChairmanReasoningEffort,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			if !validReasoningEffort(raw) {
+				log.Fatalf("%s must be one of minimal, low, medium or high, got %q", name, raw)
+			}
+			*effort = raw
+		}
+	}
+
+	// Load per-IP rate limits from environment if provided
+	if raw := os.Getenv("RATE_LIMIT_RPS"); raw != "" {
+		rps, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rps < 0 {
+			log.Fatalf("RATE_LIMIT_RPS must be a non-negative number, got %q", raw)
+		}
+		RateLimitRPS = rps
+	}
+	if raw := os.Getenv("RATE_LIMIT_BURST"); raw != "" {
+		burst, err := strconv.Atoi(raw)
+		if err != nil || burst <= 0 {
+			log.Fatalf("RATE_LIMIT_BURST must be a positive integer, got %q", raw)
+		}
+		RateLimitBurst = burst
+	}
+
+	// Load overall council deadline from environment if provided
+	if raw := os.Getenv("COUNCIL_TIMEOUT"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("COUNCIL_TIMEOUT must be a positive duration, got %q", raw)
+		}
+		CouncilTimeout = d
+	}
+
+	// Load whole-run retry policy from environment if provided
+	if raw := os.Getenv("COUNCIL_RUN_RETRIES"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("COUNCIL_RUN_RETRIES must be a non-negative integer, got %q", raw)
+		}
+		CouncilRunRetries = n
+	}
+	if raw := os.Getenv("COUNCIL_RETRY_BACKOFF"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("COUNCIL_RETRY_BACKOFF must be a positive duration, got %q", raw)
+		}
+		CouncilRetryBackoff = d
+	}
+
+	if raw := os.Getenv("MODEL_LAUNCH_JITTER"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			log.Fatalf("MODEL_LAUNCH_JITTER must be a non-negative duration, got %q", raw)
+		}
+		ModelLaunchJitter = d
+	}
+
+	if raw := os.Getenv("COUNCIL_CACHE_TTL"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			log.Fatalf("COUNCIL_CACHE_TTL must be a non-negative duration, got %q", raw)
+		}
+		CouncilCacheTTL = d
+	}
+
+	if raw := os.Getenv("SSE_HEARTBEAT_INTERVAL"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			log.Fatalf("SSE_HEARTBEAT_INTERVAL must be a non-negative duration, got %q", raw)
+		}
+		SSEHeartbeatInterval = d
+	}
+
+	// Load model circuit breaker settings from environment if provided
+	if raw := os.Getenv("CIRCUIT_BREAKER_THRESHOLD"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("CIRCUIT_BREAKER_THRESHOLD must be a non-negative integer, got %q", raw)
+		}
+		CircuitBreakerThreshold = n
+	}
+	if raw := os.Getenv("CIRCUIT_BREAKER_COOLDOWN"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("CIRCUIT_BREAKER_COOLDOWN must be a positive duration, got %q", raw)
+		}
+		CircuitBreakerCooldown = d
+	}
+
+	// Load scraper retry policy from environment if provided
+	if raw := os.Getenv("SCRAPER_MAX_RETRIES"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("SCRAPER_MAX_RETRIES must be a non-negative integer, got %q", raw)
+		}
+		ScraperMaxRetries = n
+	}
+	if raw := os.Getenv("SCRAPER_RETRY_BACKOFF"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("SCRAPER_RETRY_BACKOFF must be a positive duration, got %q", raw)
+		}
+		ScraperRetryBackoff = d
+	}
+
+	if raw := os.Getenv("SCRAPER_PAGE_DELAY"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			log.Fatalf("SCRAPER_PAGE_DELAY must be a non-negative duration, got %q", raw)
+		}
+		PageRequestDelay = d
+	}
+	if raw := os.Getenv("SCRAPER_CONCURRENCY"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			log.Fatalf("SCRAPER_CONCURRENCY must be a positive integer, got %q", raw)
+		}
+		BillsFetchConcurrency = n
+	}
+
+	// Load bills listing location from environment if provided
+	if raw := os.Getenv("BILLS_BASE_URL"); raw != "" {
+		base, err := parseBaseURL(raw)
+		if err != nil {
+			log.Fatalf("BILLS_BASE_URL %q is invalid: %v", raw, err)
+		}
+		BillsBaseURL = base
+	}
+	if query := os.Getenv("BILLS_PAGE_QUERY"); query != "" {
+		if err := validatePageQuery(query); err != nil {
+			log.Fatalf("BILLS_PAGE_QUERY %q is invalid: %v", query, err)
+		}
+		BillsPageQuery = strings.TrimPrefix(query, "?")
+	}
+
+	// Load scraper identity from environment if provided
+	if agent := os.Getenv("SCRAPER_USER_AGENT"); agent != "" {
+		if strings.ContainsAny(agent, "\r\n") {
+			log.Fatalf("SCRAPER_USER_AGENT must be a single line, got %q", agent)
+		}
+		ScraperUserAgent = agent
+	}
+	if contact := os.Getenv("SCRAPER_CONTACT"); contact != "" {
+		if _, err := mail.ParseAddress(contact); err != nil {
+			log.Fatalf("SCRAPER_CONTACT must be an email address, got %q", contact)
+		}
+		ScraperContact = contact
+	}
+
+	// Load background bills refresh interval from environment if provided
+	if raw := os.Getenv("BILLS_REFRESH_INTERVAL"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			log.Fatalf("BILLS_REFRESH_INTERVAL must be a non-negative duration, got %q", raw)
+		}
+		BillsRefreshInterval = d
+	}
+
+	if raw := os.Getenv("BILLS_PAGE_SIZE"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("BILLS_PAGE_SIZE must be a non-negative integer, got %q", raw)
+		}
+		BillsPageSize = n
+	}
+
+	// Load conversation storage directory from environment if provided
+	if dir := os.Getenv("DATA_DIR"); dir != "" {
+		if err := ensureWritableDir(dir); err != nil {
+			log.Fatalf("DATA_DIR %q is not usable: %v", dir, err)
+		}
+		DataDir = dir
+	}
+
+	if dir := os.Getenv("QUERY_CACHE_DIR"); dir != "" {
+		QueryCacheDir = dir
+	}
+	if QueryCacheEnabled {
+		if err := ensureWritableDir(QueryCacheDir); err != nil {
+			log.Fatalf("QUERY_CACHE_DIR %q is not usable: %v", QueryCacheDir, err)
+		}
+		log.Printf("Model response cache enabled in %s; identical queries will not reach OpenRouter", QueryCacheDir)
+	}
+
+	// Load maximum message length from environment if provided
+	if raw := os.Getenv("MAX_MESSAGE_LENGTH"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			log.Fatalf("MAX_MESSAGE_LENGTH must be a positive integer, got %q", raw)
+		}
+		MaxMessageLength = n
+	}
+
+	if raw := os.Getenv("MAX_MESSAGES_PER_CONVERSATION"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n == 1 {
+			log.Fatalf("MAX_MESSAGES_PER_CONVERSATION must be 0 or an integer of at least 2, got %q", raw)
+		}
+		MaxMessagesPerConversation = n
+	}
+	if policy := os.Getenv("MESSAGE_LIMIT_POLICY"); policy != "" {
+		if policy != MessageLimitReject && policy != MessageLimitDropOldest {
+			log.Fatalf("MESSAGE_LIMIT_POLICY must be %q or %q, got %q", MessageLimitReject, MessageLimitDropOldest, policy)
+		}
+		MessageLimitPolicy = policy
+	}
+
+	if raw := os.Getenv("FETCH_URL_MAX_BYTES"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			log.Fatalf("FETCH_URL_MAX_BYTES must be a positive integer, got %q", raw)
+		}
+		MaxFetchURLSize = n
+	}
+	if raw := os.Getenv("FETCH_URL_MAX_REDIRECTS"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("FETCH_URL_MAX_REDIRECTS must be a non-negative integer, got %q", raw)
+		}
+		FetchURLPolicy.MaxRedirects = n
+	}
+
+	for name, prefixes := range map[string]*[]netip.Prefix{
+		"FETCH_URL_ALLOW_CIDRS": &FetchURLPolicy.Allow,
+		"FETCH_URL_DENY_CIDRS":  &FetchURLPolicy.Deny,
+		"TRUSTED_PROXIES":       &TrustedProxies,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			parsed, err := parsePrefixList(raw)
+			if err != nil {
+				log.Fatalf("%s is invalid: %v", name, err)
+			}
+			*prefixes = parsed
+		}
+	}
+
+	if raw := os.Getenv("URL_CACHE_MAX_ENTRIES"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("URL_CACHE_MAX_ENTRIES must be a non-negative integer, got %q", raw)
+		}
+		URLCacheMaxEntries = n
+	}
+
+	if raw := os.Getenv("MAX_COUNCIL_RESPONSES"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("MAX_COUNCIL_RESPONSES must be a non-negative integer, got %q", raw)
+		}
+		MaxCouncilResponses = n
+	}
+
+	for name, limit := range map[string]*int{
+		"MAX_RESPONSE_CHARS_IN_RANKING": &MaxResponseCharsInRanking,
+		"MAX_RANKING_PROMPT_TOKENS":     &MaxRankingPromptTokens,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				log.Fatalf("%s must be a non-negative integer, got %q", name, raw)
 			}
+			*limit = n
 		}
 	}
 
 	log.Println("Configuration loaded successfully")
 }
+
+// ensureWritableDir creates dir if needed and checks that files can be written to it.
+func ensureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	probe, err := os.CreateTemp(dir, ".write-probe-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// configFileLocations are searched in order for a council config file
+// when COUNCIL_CONFIG is not set
+var configFileLocations = []string{
+	"council.config.json",    // Current directory
+	"../council.config.json", // Parent directory
+}
+
+// fileConfig is the JSON shape of council.config.json. Every field is optional;
+// timeouts are Go duration strings such as "90s" or "5m".
+type fileConfig struct {
+	CouncilModels      []string           `json:"council_models"`
+	RankerModels       []string           `json:"ranker_models"`
+	ChairmanModel      string             `json:"chairman_model"`
+	ChairmanFallbacks  []string           `json:"chairman_fallbacks"`
+	TitleModel         string             `json:"title_model"`
+	ModelWeights       map[string]float64 `json:"model_weights"`
+	ModelPromptPricing map[string]float64 `json:"model_prompt_pricing"`
+	ModelQueryTimeout  string             `json:"model_query_timeout"`
+	TitleGenTimeout    string             `json:"title_gen_timeout"`
+	CouncilTimeout     string             `json:"council_timeout"`
+	CORSAllowedOrigins []string           `json:"cors_allowed_origins"`
+	ScraperSelectors   *ScraperSelectors  `json:"scraper_selectors"`
+
+	CouncilModes map[string]fileCouncilMode `json:"council_modes"`
+
+	// Parsed timeouts, zero when not set
+	modelQueryTimeout time.Duration
+	titleGenTimeout   time.Duration
+	councilTimeout    time.Duration
+
+	// Parsed council modes
+	councilModes map[string]CouncilConfig
+}
+
+// fileCouncilMode is the JSON shape of a council mode in council.config.json: a
+// roster, which may be left out to use the configured one, and its run settings
+type fileCouncilMode struct {
+	CouncilConfig
+	ModelQueryTimeout string `json:"model_query_timeout"`
+	CouncilTimeout    string `json:"council_timeout"`
+	ReasoningEffort   string `json:"reasoning_effort"`
+}
+
+// parse validates the mode and returns it as a CouncilConfig
+func (m fileCouncilMode) parse() (CouncilConfig, error) {
+	var errs []error
+	mode := m.CouncilConfig
+	if len(mode.CouncilModels) > 0 {
+		if err := mode.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	} else if mode.ChairmanModel != "" || len(mode.RankerModels) > 0 || len(mode.ChairmanFallbacks) > 0 {
+		errs = append(errs, errors.New("council_models is required to set the rest of the roster"))
+	}
+	for _, timeout := range []struct {
+		name   string
+		raw    string
+		parsed *time.Duration
+	}{
+		{"model_query_timeout", m.ModelQueryTimeout, &mode.ModelQueryTimeout},
+		{"council_timeout", m.CouncilTimeout, &mode.CouncilTimeout},
+	} {
+		if timeout.raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(timeout.raw)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration, got %q", timeout.name, timeout.raw))
+			continue
+		}
+		*timeout.parsed = d
+	}
+	if m.ReasoningEffort != "" && !validReasoningEffort(m.ReasoningEffort) {
+		errs = append(errs, fmt.Errorf("reasoning_effort must be one of minimal, low, medium or high, got %q", m.ReasoningEffort))
+	}
+	mode.ReasoningEffort = m.ReasoningEffort
+	return mode, errors.Join(errs...)
+}
+
+// findConfigFile returns the config file path from COUNCIL_CONFIG, or the first
+// existing file in configFileLocations, or "" if there is none.
+func findConfigFile() string {
+	if path := os.Getenv("COUNCIL_CONFIG"); path != "" {
+		return path
+	}
+	for _, path := range configFileLocations {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadConfigFile reads and validates a JSON config file.
+func loadConfigFile(path string) (*fileConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cfg fileConfig
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// validate checks the parsed config and parses its timeouts.
+func (cfg *fileConfig) validate() error {
+	var errs []error
+
+	if cfg.CouncilModels != nil && len(cfg.CouncilModels) == 0 {
+		errs = append(errs, errors.New("council_models must not be empty"))
+	}
+	for _, model := range slices.Concat(cfg.CouncilModels, cfg.RankerModels, cfg.ChairmanFallbacks) {
+		if strings.TrimSpace(model) == "" {
+			errs = append(errs, errors.New("model IDs must not be blank"))
+			break
+		}
+	}
+	if cfg.TitleModel != "" && !validModelID(cfg.TitleModel) {
+		errs = append(errs, fmt.Errorf("title_model must be a provider/model ID, got %q", cfg.TitleModel))
+	}
+	for model, weight := range cfg.ModelWeights {
+		if weight < 0 {
+			errs = append(errs, fmt.Errorf("model_weights[%q] must not be negative", model))
+		}
+	}
+	for model, price := range cfg.ModelPromptPricing {
+		if price < 0 {
+			errs = append(errs, fmt.Errorf("model_prompt_pricing[%q] must not be negative", model))
+		}
+	}
+
+	if cfg.ScraperSelectors != nil {
+		if err := cfg.ScraperSelectors.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("scraper_selectors: %w", err))
+		}
+	}
+
+	if cfg.CouncilModes != nil {
+		cfg.councilModes = make(map[string]CouncilConfig, len(cfg.CouncilModes))
+	}
+	for name, raw := range cfg.CouncilModes {
+		mode, err := raw.parse()
+		if strings.TrimSpace(name) == "" {
+			err = errors.Join(err, errors.New("mode names must not be blank"))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("council_modes[%q]: %w", name, err))
+			continue
+		}
+		cfg.councilModes[name] = mode
+	}
+
+	timeouts := []struct {
+		name   string
+		raw    string
+		parsed *time.Duration
+	}{
+		{"model_query_timeout", cfg.ModelQueryTimeout, &cfg.modelQueryTimeout},
+		{"title_gen_timeout", cfg.TitleGenTimeout, &cfg.titleGenTimeout},
+		{"council_timeout", cfg.CouncilTimeout, &cfg.councilTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(timeout.raw)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration, got %q", timeout.name, timeout.raw))
+			continue
+		}
+		*timeout.parsed = d
+	}
+
+	return errors.Join(errs...)
+}
+
+// apply copies the fields that were set in the file onto the config globals.
+func (cfg *fileConfig) apply() {
+	if len(cfg.CouncilModels) > 0 {
+		CouncilModels = cfg.CouncilModels
+	}
+	if cfg.RankerModels != nil {
+		RankerModels = cfg.RankerModels
+	}
+	if cfg.ChairmanModel != "" {
+		ChairmanModel = cfg.ChairmanModel
+	}
+	if cfg.ChairmanFallbacks != nil {
+		ChairmanFallbacks = cfg.ChairmanFallbacks
+	}
+	if cfg.TitleModel != "" {
+		TitleModel = cfg.TitleModel
+	}
+	if cfg.ModelWeights != nil {
+		ModelWeights = cfg.ModelWeights
+	}
+	if cfg.ModelPromptPricing != nil {
+		ModelPromptPricing = cfg.ModelPromptPricing
+	}
+	if cfg.modelQueryTimeout > 0 {
+		ModelQueryTimeout = cfg.modelQueryTimeout
+	}
+	if cfg.titleGenTimeout > 0 {
+		TitleGenTimeout = cfg.titleGenTimeout
+	}
+	if cfg.councilTimeout > 0 {
+		CouncilTimeout = cfg.councilTimeout
+	}
+	if cfg.CORSAllowedOrigins != nil {
+		CORSAllowedOrigins = normalizeOrigins(cfg.CORSAllowedOrigins)
+	}
+	if cfg.ScraperSelectors != nil {
+		BillsSelectors = cfg.ScraperSelectors.WithDefaults()
+	}
+	if len(cfg.councilModes) > 0 {
+		modes := maps.Clone(CouncilModes)
+		maps.Copy(modes, cfg.councilModes)
+		CouncilModes = modes
+	}
+}
+
+// normalizeOrigins trims and validates CORS origins, keeping only well-formed
+// http(s) scheme+host[:port] origins. Scheme and host are lowercased and a trailing
+// slash is dropped so entries match the Origin header browsers send. Rejected
+// entries are logged.
+func normalizeOrigins(raw []string) []string {
+	origins := []string{}
+	for _, entry := range raw {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		origin, err := normalizeOrigin(entry)
+		if err != nil {
+			log.Printf("Warning: ignoring CORS origin %q: %v", entry, err)
+			continue
+		}
+		origins = append(origins, origin)
+	}
+	if len(raw) > 0 && len(origins) == 0 {
+		log.Printf("Warning: no valid CORS origins configured; only localhost origins will be allowed")
+	}
+	return origins
+}
+
+// normalizeOrigin validates a single origin and returns its canonical form.
+func normalizeOrigin(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.New("scheme must be http or https")
+	}
+	if u.Hostname() == "" {
+		return "", errors.New("missing host")
+	}
+	if strings.Contains(u.Hostname(), "*") {
+		suffix, ok := strings.CutPrefix(u.Hostname(), "*.")
+		if !ok || strings.Contains(suffix, "*") || !strings.Contains(suffix, ".") {
+			return "", errors.New(`wildcard must be the leftmost label of a domain, as in "https://*.example.com"`)
+		}
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid port %q", port)
+		}
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", errors.New("must be scheme://host[:port] with no path, query or credentials")
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// parseBaseURL validates an absolute http(s) API root such as
+// "https://openrouter.ai/api/v1" and returns it without a trailing slash.
+func parseBaseURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.New("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return "", errors.New("missing host")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", errors.New("must not have a query or fragment")
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
+// validatePageQuery checks that a listing page query template has exactly one %d
+// for the page number and no other formatting verbs
+func validatePageQuery(query string) error {
+	if strings.Count(query, "%d") != 1 || strings.Count(query, "%") != 1 {
+		return errors.New(`must contain exactly one "%d" for the page number and no other "%"`)
+	}
+	if strings.ContainsAny(query, " #") {
+		return errors.New("must not contain spaces or a fragment")
+	}
+	return nil
+}
+
+// validReasoningEffort reports whether effort is a reasoning effort OpenRouter accepts
+func validReasoningEffort(effort string) bool {
+	switch effort {
+	case "minimal", "low", "medium", "high":
+		return true
+	}
+	return false
+}
+
+// validModelID reports whether id looks like an OpenRouter model ID such as
+// "google/gemini-2.5-flash"
+func validModelID(id string) bool {
+	provider, name, ok := strings.Cut(id, "/")
+	return ok && provider != "" && name != "" && !strings.ContainsAny(id, " \t\r\n")
+}
+
+// parseModelList splits a comma-separated list of model IDs,
+// trimming whitespace and dropping empty entries.
+func parseModelList(raw string) []string {
+	models := []string{}
+	for _, model := range strings.Split(raw, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
@@ -2,60 +2,472 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/sync/errgroup"
 )
 
+// buildStage1Messages builds the prompt sent to every council model in Stage 1.
+// Attached images are sent alongside the question as content parts.
+func buildStage1Messages(userQuery string, imageURLs ...string) []OpenRouterMessage {
+	message := OpenRouterMessage{Role: "user", Content: userQuery}
+	if len(imageURLs) > 0 {
+		message.Parts = []ContentPart{{Type: "text", Text: userQuery}}
+		for _, url := range imageURLs {
+			message.Parts = append(message.Parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}})
+		}
+	}
+	return withSystemPrompt(CouncilSystemPrompt, []OpenRouterMessage{message})
+}
+
+// imagesUnsupported reports whether a query failure looks like the model rejecting
+// image input: OpenRouter answers 400, 404 or 415 when no provider for the model
+// accepts images.
+func imagesUnsupported(err error) bool {
+	var orErr *OpenRouterError
+	if !errors.As(err, &orErr) {
+		return false
+	}
+	switch orErr.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusUnsupportedMediaType:
+		return true
+	}
+	return false
+}
+
+// buildBillAnalysisPrompt builds the council question for analyzing a bill,
+// including whatever detail is available.
+func buildBillAnalysisPrompt(detail *BillDetail) string {
+	var b strings.Builder
+	b.WriteString("Analyze this bill currently before the Australian Parliament and its likely impact. ")
+	b.WriteString("Explain what it would change, who would be affected, and the main arguments for and against it.\n\n")
+
+	fields := []struct{ label, value string }{
+		{"Title", detail.Title},
+		{"Type", detail.Type},
+		{"Sponsor", detail.Sponsor},
+		{"Portfolio", detail.PortfolioSponsor},
+		{"Originating house", detail.OriginatingHouse},
+		{"Chamber", detail.Chamber},
+		{"Status", detail.Status},
+		{"Introduced", detail.DateIntroduced},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", field.label, field.value)
+		}
+	}
+
+	summary := detail.FullSummary
+	if summary == "" {
+		summary = detail.Summary
+	}
+	if summary != "" {
+		fmt.Fprintf(&b, "\nSummary:\n%s\n", summary)
+	}
+
+	if len(detail.Progress) > 0 {
+		b.WriteString("\nProgress:\n")
+		for _, event := range detail.Progress {
+			fmt.Fprintf(&b, "- %s: %s (%s)\n", event.Chamber, event.Stage, event.Date)
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// withSystemPrompt prepends a system message to messages, unless prompt is empty.
+func withSystemPrompt(prompt string, messages []OpenRouterMessage) []OpenRouterMessage {
+	if prompt == "" {
+		return messages
+	}
+	return append([]OpenRouterMessage{{Role: "system", Content: prompt}}, messages...)
+}
+
 // Stage1CollectResponses collects individual responses from all council models.
 // This is the first stage of the council process where each model independently
-// answers the user's question. Returns a slice of responses, one per successful model.
-func Stage1CollectResponses(ctx context.Context, userQuery string) ([]Stage1Response, error) {
-	// Create messages slice with user query
-	messages := []OpenRouterMessage{
-		{Role: "user", Content: userQuery},
-	}
+// answers the user's question, along with any attached images. Models that reject the
+// images are asked again with the text alone. Returns a slice of responses, one per
+// successful model in council model order, and a failure record for each model that
+// didn't respond.
+func Stage1CollectResponses(ctx context.Context, userQuery string, imageURLs ...string) ([]Stage1Response, []ModelFailure, error) {
+	messages := buildStage1Messages(userQuery, imageURLs...)
+	cfg := councilConfig(ctx)
+	councilModels := cfg.CouncilModels
 
 	// Query all models in parallel
-	responses, err := QueryModelsParallel(ctx, CouncilModels, messages)
+	opts := cfg.queryOptions(CouncilReasoningEffort)
+	responses, queryErrors, err := QueryModelsParallelWithOptions(ctx, councilModels, messages, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query models: %w", err)
+		return nil, nil, fmt.Errorf("failed to query models: %w", err)
 	}
 
-	// Format results - only include successful responses
+	// Degrade gracefully for text-only models
+	if len(imageURLs) > 0 {
+		var textOnly []string
+		for _, model := range councilModels {
+			if imagesUnsupported(queryErrors[model]) {
+				textOnly = append(textOnly, model)
+			}
+		}
+		if len(textOnly) > 0 {
+			note := fmt.Sprintf("%s\n\n(The user attached %d image(s) that you are unable to view.)", userQuery, len(imageURLs))
+			retried, retryErrors, err := QueryModelsParallelWithOptions(ctx, textOnly, buildStage1Messages(note), opts)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to query models: %w", err)
+			}
+			for _, model := range textOnly {
+				responses[model] = retried[model]
+				if retryErr, ok := retryErrors[model]; ok {
+					queryErrors[model] = retryErr
+				} else {
+					delete(queryErrors, model)
+				}
+			}
+		}
+	}
+
+	// Record failures in configured model order
+	var failures []ModelFailure
+	for _, model := range councilModels {
+		if queryErr, ok := queryErrors[model]; ok {
+			failures = append(failures, ModelFailure{
+				Model:  model,
+				Reason: DescribeQueryError(queryErr),
+				Err:    queryErr,
+			})
+		}
+	}
+
+	// Format results in configured model order so labels are reproducible - only
+	// include successful responses
 	var stage1Results []Stage1Response
-	for model, response := range responses {
-		if response != nil {
+	for _, model := range councilModels {
+		if response := responses[model]; response != nil {
+			warnIfTruncated(ctx, 1, model, response)
 			stage1Results = append(stage1Results, Stage1Response{
-				Model:    model,
-				Response: response.Content,
+				Model:            model,
+				Response:         response.Content,
+				ReasoningDetails: response.ReasoningDetails,
+				FinishReason:     response.FinishReason,
+				Latency:          response.Latency,
 			})
 		}
 	}
 
-	return stage1Results, nil
+	return stage1Results, failures, nil
+}
+
+// CapCouncilResponses keeps at most limit Stage 1 responses, preferring models
+// listed earlier in the council models of the roster the run uses, and returns the
+// kept responses in that order along with the models that were dropped. A limit of
+// 0 or less keeps everything.
+func CapCouncilResponses(ctx context.Context, stage1Results []Stage1Response, limit int) ([]Stage1Response, []string) {
+	if limit <= 0 || len(stage1Results) <= limit {
+		return stage1Results, nil
+	}
+
+	// Models missing from the council models sort last
+	councilModels := councilConfig(ctx).CouncilModels
+	priority := func(model string) int {
+		if i := slices.Index(councilModels, model); i >= 0 {
+			return i
+		}
+		return len(councilModels)
+	}
+	ordered := slices.Clone(stage1Results)
+	slices.SortStableFunc(ordered, func(a, b Stage1Response) int {
+		return priority(a.Model) - priority(b.Model)
+	})
+
+	var omitted []string
+	for _, result := range ordered[limit:] {
+		omitted = append(omitted, result.Model)
+	}
+	return ordered[:limit], omitted
+}
+
+// BuildLabelToModel maps anonymized labels ("Response A", "Response B", ...) to the
+// Stage 1 models in order. Labels are assigned by position, so the mapping for a
+// stored conversation can be rebuilt from its Stage 1 results.
+func BuildLabelToModel(stage1Results []Stage1Response) map[string]string {
+	labelToModel := make(map[string]string)
+	for i, result := range stage1Results {
+		labelToModel["Response "+labelLetters(i)] = result.Model
+	}
+	return labelToModel
 }
 
 // Stage2CollectRankings collects rankings from each model on anonymized responses.
 // This is the second stage where models evaluate each other's responses without
-// knowing which model produced which response. Returns rankings, a label-to-model
-// mapping for de-anonymization, and any error encountered.
+// knowing which model produced which response; the rankers are the council roster's
+// Rankers(), which need not be the models that answered. Returns rankings, a
+// label-to-model mapping (to the Stage 1 answerers) for de-anonymization, and any
+// error encountered.
 func Stage2CollectRankings(ctx context.Context, userQuery string, stage1Results []Stage1Response) ([]Stage2Ranking, map[string]string, error) {
-	// Create anonymized labels (A, B, C...)
-	labelToModel := make(map[string]string)
-	var responsesText strings.Builder
+	return Stage2CollectRankingsStream(ctx, userQuery, stage1Results, nil)
+}
 
-	for i, result := range stage1Results {
-		label := string(rune('A' + i))
-		labelKey := fmt.Sprintf("Response %s", label)
-		labelToModel[labelKey] = result.Model
+// Stage2CollectRankingsStream is the streaming variant of Stage2CollectRankings.
+// Each successful ranking is passed to onRanking as soon as its model responds;
+// calls are serialized. The returned rankings are the complete set.
+func Stage2CollectRankingsStream(ctx context.Context, userQuery string, stage1Results []Stage1Response, onRanking func(Stage2Ranking)) ([]Stage2Ranking, map[string]string, error) {
+	// Create anonymized labels (A, B, C... Z, AA, AB...)
+	labelToModel := BuildLabelToModel(stage1Results)
+	responses := rankingResponses(userQuery, stage1Results)
+
+	// Models that support structured output are asked for a JSON ranking, the rest
+	// answer in free text
+	cfg := councilConfig(ctx)
+	textOpts := cfg.queryOptions(RankingReasoningEffort)
+	optsFor := func(model string, visible map[string]string) QueryOptions {
+		opts := textOpts
+		if slices.Contains(StructuredRankingModels, model) {
+			opts.ResponseFormat = rankingResponseFormat(visible)
+		}
+		return opts
+	}
+
+	// Rankers sharing the full prompt are queried together, one query per output
+	// format. With ExcludeSelfRanking, a ranker that also answered in Stage 1 is
+	// shown every response but its own, so it gets a prompt of its own.
+	type rankingQuery struct {
+		models   []string
+		messages []OpenRouterMessage
+		opts     QueryOptions
+	}
+	messages, _ := rankingMessages(userQuery, stage1Results, responses, "")
+	var structuredModels, textModels []string
+	var queries []rankingQuery
+	for _, model := range cfg.Rankers() {
+		switch {
+		case ExcludeSelfRanking && slices.ContainsFunc(stage1Results, func(r Stage1Response) bool { return r.Model == model }):
+			ownMessages, visible := rankingMessages(userQuery, stage1Results, responses, model)
+			queries = append(queries, rankingQuery{[]string{model}, ownMessages, optsFor(model, visible)})
+		case slices.Contains(StructuredRankingModels, model):
+			structuredModels = append(structuredModels, model)
+		default:
+			textModels = append(textModels, model)
+		}
+	}
+	if len(structuredModels) > 0 {
+		queries = append(queries, rankingQuery{structuredModels, messages, optsFor(structuredModels[0], labelToModel)})
+	}
+	queries = append(queries, rankingQuery{textModels, messages, textOpts})
+
+	// Query all groups in parallel, parsing each ranking as it arrives
+	var mu sync.Mutex
+	var stage2Results []Stage2Ranking
+	collect := func(model string, response *OpenRouterResponse, _ error) {
+		if response == nil {
+			return
+		}
+		// A cut-off ranking usually loses its FINAL RANKING section
+		warnIfTruncated(ctx, 2, model, response)
+		ranking := parseStage2Ranking(model, response.Content)
+		ranking.Latency = response.Latency
 
-		responsesText.WriteString(fmt.Sprintf("Response %s:\n%s\n\n", label, result.Response))
+		mu.Lock()
+		defer mu.Unlock()
+		stage2Results = append(stage2Results, ranking)
+		if onRanking != nil {
+			onRanking(ranking)
+		}
 	}
 
-	// Build ranking prompt
-	rankingPrompt := fmt.Sprintf(`You are evaluating different responses to the following question:
+	g, groupCtx := errgroup.WithContext(ctx)
+	for _, query := range queries {
+		if len(query.models) == 0 {
+			continue
+		}
+		g.Go(func() error {
+			_, _, err := QueryModelsParallelStream(groupCtx, query.models, query.messages, query.opts, collect)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, fmt.Errorf("failed to query models for rankings: %w", err)
+	}
+
+	return stage2Results, labelToModel, nil
+}
+
+// CouncilConfig is a council roster: the models that answer, rank and synthesize.
+// A run uses the configured roster unless another is attached with WithCouncilConfig.
+type CouncilConfig struct {
+	CouncilModels     []string `json:"council_models"`
+	RankerModels      []string `json:"ranker_models,omitempty"`
+	ChairmanModel     string   `json:"chairman_model"`
+	ChairmanFallbacks []string `json:"chairman_fallbacks,omitempty"`
+
+	// Run settings, set by council modes. Zero values use the configured
+	// ModelQueryTimeout, CouncilTimeout and per-stage reasoning efforts.
+	ModelQueryTimeout time.Duration `json:"-"`
+	CouncilTimeout    time.Duration `json:"-"`
+	ReasoningEffort   string        `json:"-"` // Used in every stage
+}
+
+// councilConfigKey is the context key for a CouncilConfig overriding the configured roster
+type councilConfigKey struct{}
+
+// WithCouncilConfig returns a context whose council runs use cfg instead of the
+// configured roster, so different rosters can run side by side
+func WithCouncilConfig(ctx context.Context, cfg CouncilConfig) context.Context {
+	return context.WithValue(ctx, councilConfigKey{}, cfg)
+}
+
+// councilRosterMu guards the configured roster, which SetCouncilRoster can replace
+// while the server is running
+var councilRosterMu sync.RWMutex
+
+// councilConfig returns the roster attached to ctx, or the configured one
+func councilConfig(ctx context.Context) CouncilConfig {
+	if cfg, ok := ctx.Value(councilConfigKey{}).(CouncilConfig); ok {
+		return cfg
+	}
+	councilRosterMu.RLock()
+	defer councilRosterMu.RUnlock()
+	return CouncilConfig{
+		CouncilModels:     CouncilModels,
+		RankerModels:      RankerModels,
+		ChairmanModel:     ChairmanModel,
+		ChairmanFallbacks: ChairmanFallbacks,
+	}
+}
+
+// SetCouncilRoster replaces the configured council models and chairman in one step,
+// leaving either unchanged if empty, and returns the resulting roster. Runs already
+// in flight keep the roster they started with.
+func SetCouncilRoster(councilModels []string, chairman string) CouncilConfig {
+	councilRosterMu.Lock()
+	if len(councilModels) > 0 {
+		CouncilModels = slices.Clone(councilModels)
+	}
+	if chairman != "" {
+		ChairmanModel = chairman
+	}
+	councilRosterMu.Unlock()
+	return councilConfig(context.Background())
+}
+
+// Validate checks that the roster has council models and a chairman, all well-formed
+func (cfg CouncilConfig) Validate() error {
+	var errs []error
+	if len(cfg.CouncilModels) == 0 {
+		errs = append(errs, errors.New("council_models must not be empty"))
+	}
+	if cfg.ChairmanModel == "" {
+		errs = append(errs, errors.New("chairman_model is required"))
+	}
+	for _, model := range slices.Concat(cfg.CouncilModels, cfg.RankerModels, cfg.ChairmanFallbacks, []string{cfg.ChairmanModel}) {
+		if model != "" && !validModelID(model) {
+			errs = append(errs, fmt.Errorf("%q is not a provider/model ID", model))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rankers returns the models that rank responses in Stage 2: RankerModels, or the
+// CouncilModels themselves when no separate rankers are given.
+func (cfg CouncilConfig) Rankers() []string {
+	if len(cfg.RankerModels) > 0 {
+		return cfg.RankerModels
+	}
+	return cfg.CouncilModels
+}
+
+// queryOptions returns the options for a model query in a stage whose configured
+// reasoning effort is stageEffort
+func (cfg CouncilConfig) queryOptions(stageEffort string) QueryOptions {
+	opts := QueryOptions{Timeout: ModelQueryTimeout, ReasoningEffort: stageEffort}
+	if cfg.ModelQueryTimeout > 0 {
+		opts.Timeout = cfg.ModelQueryTimeout
+	}
+	if cfg.ReasoningEffort != "" {
+		opts.ReasoningEffort = cfg.ReasoningEffort
+	}
+	return opts
+}
+
+// councilTimeout returns the deadline for a whole council run
+func (cfg CouncilConfig) councilTimeout() time.Duration {
+	if cfg.CouncilTimeout > 0 {
+		return cfg.CouncilTimeout
+	}
+	return CouncilTimeout
+}
+
+// ErrUnknownCouncilMode is returned for a council mode not in CouncilModes
+var ErrUnknownCouncilMode = errors.New("unknown council mode")
+
+// CouncilMode returns the council preset named name from CouncilModes. A preset
+// without council models runs the configured roster with its own run settings.
+func CouncilMode(name string) (CouncilConfig, error) {
+	mode, ok := CouncilModes[name]
+	if !ok {
+		return CouncilConfig{}, fmt.Errorf("%w %q (available: %s)", ErrUnknownCouncilMode, name, strings.Join(CouncilModeNames(), ", "))
+	}
+	if len(mode.CouncilModels) == 0 {
+		configured := councilConfig(context.Background())
+		mode.CouncilModels = configured.CouncilModels
+		mode.RankerModels = configured.RankerModels
+		mode.ChairmanModel = configured.ChairmanModel
+		mode.ChairmanFallbacks = configured.ChairmanFallbacks
+	}
+	return mode, nil
+}
+
+// CouncilModeNames returns the names of the council modes, sorted
+func CouncilModeNames() []string {
+	return slices.Sorted(maps.Keys(CouncilModes))
+}
+
+// Rankers returns the configured Stage 2 rankers: RankerModels, or the CouncilModels
+// themselves when no separate rankers are configured.
+func Rankers() []string {
+	return councilConfig(context.Background()).Rankers()
+}
+
+// rankingMessages builds the Stage 2 messages showing each Stage 1 response (as
+// prepared by rankingResponses) under its label, leaving out any written by
+// exclude. Returns the messages and the labels shown.
+func rankingMessages(userQuery string, stage1Results []Stage1Response, responses []string, exclude string) ([]OpenRouterMessage, map[string]string) {
+	visible := make(map[string]string)
+	var responsesText strings.Builder
+	for i, result := range stage1Results {
+		if result.Model == exclude {
+			continue
+		}
+		label := labelLetters(i)
+		visible["Response "+label] = result.Model
+		responsesText.WriteString(fmt.Sprintf("Response %s:\n%s\n\n", label, responses[i]))
+	}
+
+	return []OpenRouterMessage{
+		{Role: "user", Content: buildRankingPrompt(userQuery, responsesText.String())},
+	}, visible
+}
+
+// buildRankingPrompt builds the Stage 2 prompt asking a model to evaluate and rank
+// the anonymized responses.
+func buildRankingPrompt(userQuery string, responsesText string) string {
+	return fmt.Sprintf(`You are evaluating different responses to the following question:
 
 Question: %s
 
@@ -84,40 +496,237 @@ FINAL RANKING:
 2. Response A
 3. Response B
 
-Now provide your evaluation and ranking:`, userQuery, responsesText.String())
+Now provide your evaluation and ranking:`, userQuery, responsesText)
+}
 
-	// Create messages
-	messages := []OpenRouterMessage{
-		{Role: "user", Content: rankingPrompt},
+// rankingTruncationMarker is appended to responses cut short in the ranking prompt
+const rankingTruncationMarker = "\n\n[... response truncated for length ...]"
+
+// rankingResponses returns the Stage 1 responses as shown to the Stage 2 rankers.
+// Identities are scrubbed when ScrubModelIdentity is set, each response is cut to
+// MaxResponseCharsInRanking characters, and if the prompt would still exceed
+// MaxRankingPromptTokens (estimated at charsPerToken characters per token) every
+// response is shortened in proportion to its length. The stored Stage 1 responses
+// keep their full text.
+func rankingResponses(userQuery string, stage1Results []Stage1Response) []string {
+	responses := make([]string, len(stage1Results))
+	limits := make([]int, len(stage1Results))
+	total := 0
+	for i, result := range stage1Results {
+		responses[i] = result.Response
+		if ScrubModelIdentity {
+			responses[i] = scrubModelIdentity(responses[i])
+		}
+		limits[i] = utf8.RuneCountInString(responses[i])
+		if MaxResponseCharsInRanking > 0 {
+			limits[i] = min(limits[i], MaxResponseCharsInRanking)
+		}
+		total += limits[i]
 	}
 
-	// Query all models in parallel
-	responses, err := QueryModelsParallel(ctx, CouncilModels, messages)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to query models for rankings: %w", err)
+	if MaxRankingPromptTokens > 0 {
+		// Leave room for the instructions, question, labels and truncation markers
+		budget := (MaxRankingPromptTokens - EstimateTokens(buildRankingPrompt(userQuery, "")) -
+			len(responses)*EstimateTokens("Response AA:"+rankingTruncationMarker)) * charsPerToken
+		if total > budget {
+			ratio := float64(max(budget, 0)) / float64(total)
+			for i := range limits {
+				limits[i] = int(float64(limits[i]) * ratio)
+			}
+		}
 	}
 
-	// Format results
-	var stage2Results []Stage2Ranking
-	for model, response := range responses {
-		if response != nil {
-			fullText := response.Content
-			parsed := ParseRankingFromText(fullText)
-			stage2Results = append(stage2Results, Stage2Ranking{
-				Model:         model,
-				Ranking:       fullText,
-				ParsedRanking: parsed,
-			})
+	for i := range responses {
+		responses[i] = truncateForRanking(responses[i], limits[i])
+	}
+	return responses
+}
+
+// truncateForRanking cuts text to at most limit characters, marking the cut.
+func truncateForRanking(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return strings.TrimRightFunc(string(runes[:limit]), unicode.IsSpace) + rankingTruncationMarker
+}
+
+// Self-identification phrases that would reveal which model wrote a Stage 1
+// response. modelNamePattern matches a model family with an optional version or
+// tier ("Claude 3.5 Sonnet", "GPT-5.1", "Gemini Pro"); modelMakerPattern matches
+// the companies behind them.
+const (
+	modelNamePattern  = `(?:Claude|ChatGPT|GPT|Gemini|Grok|Llama|Mistral|DeepSeek|Qwen|Kimi)(?:[ -](?:\d[\w.]*|Sonnet|Opus|Haiku|Pro|Flash|Ultra|Turbo|mini))*`
+	modelMakerPattern = `(?:Anthropic|OpenAI|Google(?: DeepMind)?|DeepMind|xAI|Meta|Mistral AI|Alibaba|Moonshot AI)`
+	assistantPattern  = `(?:an? )?(?:AI |large )?(?:language )?(?:model|assistant)`
+	madeByPattern     = `(?:made|developed|created|built|trained) by ` + modelMakerPattern
+)
+
+// identityClausePattern matches a self-identification clause such as "As Claude, "
+// or "As an AI assistant developed by OpenAI, ".
+var identityClausePattern = regexp.MustCompile(`(?i)\bas (?:` + modelNamePattern + `|` + assistantPattern + ` ` + madeByPattern + `)\s*,\s*`)
+
+// identityStatementPattern matches a self-identification such as "I am Claude,
+// made by Anthropic" or "I'm GPT-4", which becomes "I am an AI assistant".
+var identityStatementPattern = regexp.MustCompile(`(?i)\bI(?:'m| am) (?:` + modelNamePattern + `(?:,? ` + assistantPattern + `)?(?:,? ` + madeByPattern + `)?|` + assistantPattern + `,? ` + madeByPattern + `)`)
+
+// identityMakerPattern matches "I was trained by Google" and the like, which
+// becomes "I was trained".
+var identityMakerPattern = regexp.MustCompile(`(?i)\bI was ` + madeByPattern)
+
+// scrubModelIdentity strips common self-identification phrases from a Stage 1
+// response so peer reviewers can't tell which model wrote it.
+func scrubModelIdentity(text string) string {
+	// Remove "As Claude, ..." clauses opening a sentence (but not lists such as
+	// "models such as Gemini, Claude and GPT"), capitalizing the word that now
+	// starts the sentence
+	var scrubbed strings.Builder
+	last := 0
+	for _, match := range identityClausePattern.FindAllStringIndex(text, -1) {
+		if !sentenceStart(text[:match[0]]) {
+			continue
+		}
+		scrubbed.WriteString(text[last:match[0]])
+		last = match[1]
+		if last < len(text) {
+			r, size := utf8.DecodeRuneInString(text[last:])
+			scrubbed.WriteRune(unicode.ToUpper(r))
+			last += size
 		}
 	}
+	scrubbed.WriteString(text[last:])
 
-	return stage2Results, labelToModel, nil
+	text = identityStatementPattern.ReplaceAllString(scrubbed.String(), "I am an AI assistant")
+	return identityMakerPattern.ReplaceAllString(text, "I was trained")
+}
+
+// sentenceStart reports whether text following prefix starts a new sentence.
+func sentenceStart(prefix string) bool {
+	prefix = strings.TrimRight(prefix, " \t")
+	return prefix == "" || strings.ContainsAny(prefix[len(prefix)-1:], ".!?\n")
+}
+
+// rankingResponseFormat is the JSON schema structured-output models rank against:
+// their evaluation, then every response label from best to worst.
+func rankingResponseFormat(labelToModel map[string]string) *ResponseFormat {
+	labels := make([]string, 0, len(labelToModel))
+	for label := range labelToModel {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	return &ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &JSONSchema{
+			Name:   "peer_ranking",
+			Strict: true,
+			Schema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"evaluation": map[string]interface{}{
+						"type":        "string",
+						"description": "Your evaluation of each response: what it does well and what it does poorly",
+					},
+					"ranking": map[string]interface{}{
+						"type":        "array",
+						"description": "Every response label, from best to worst",
+						"items":       map[string]interface{}{"type": "string", "enum": labels},
+					},
+				},
+				"required":             []string{"evaluation", "ranking"},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+// structuredRanking is the JSON shape requested by rankingResponseFormat
+type structuredRanking struct {
+	Evaluation string   `json:"evaluation"`
+	Ranking    []string `json:"ranking"`
+}
+
+// parseStage2Ranking builds a Stage 2 ranking from a model's response, reading it as
+// structured JSON when possible and falling back to ParseRankingFromText otherwise.
+// Structured rankings are rewritten as text with a "FINAL RANKING:" section so they
+// display and export like free-text ones.
+func parseStage2Ranking(model, content string) Stage2Ranking {
+	evaluation, labels, ok := parseStructuredRanking(content)
+	if !ok {
+		return Stage2Ranking{
+			Model:         model,
+			Ranking:       content,
+			ParsedRanking: ParseRankingFromText(content),
+		}
+	}
+
+	var text strings.Builder
+	if evaluation != "" {
+		text.WriteString(evaluation)
+		text.WriteString("\n\n")
+	}
+	text.WriteString("FINAL RANKING:")
+	for i, label := range labels {
+		fmt.Fprintf(&text, "\n%d. %s", i+1, label)
+	}
+
+	return Stage2Ranking{
+		Model:         model,
+		Ranking:       text.String(),
+		ParsedRanking: labels,
+	}
+}
+
+// parseStructuredRanking decodes a JSON ranking, tolerating a surrounding markdown code
+// fence. Labels are normalized like ParseRankingFromText's. ok is false if the content
+// isn't a JSON ranking with at least one recognizable label.
+func parseStructuredRanking(content string) (evaluation string, labels []string, ok bool) {
+	content = strings.TrimSpace(content)
+	if fenced, found := strings.CutPrefix(content, "```"); found {
+		fenced = strings.TrimPrefix(fenced, "json")
+		content = strings.TrimSpace(strings.TrimSuffix(fenced, "```"))
+	}
+
+	var parsed structuredRanking
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return "", nil, false
+	}
+	labels = extractLabels(responseLabelPattern, strings.Join(parsed.Ranking, "\n"))
+	if len(labels) == 0 {
+		return "", nil, false
+	}
+	return strings.TrimSpace(parsed.Evaluation), labels, true
 }
 
 // Stage3SynthesizeFinal synthesizes the final response using the chairman model.
 // This is the final stage where the chairman reviews all responses and rankings
-// to produce a comprehensive answer. Returns the synthesized response or an error.
+// to produce a comprehensive answer. If the chairman model fails, each model in
+// ChairmanFallbacks is tried in order. Returns the synthesized response or an error
+// if every chairman fails.
 func Stage3SynthesizeFinal(ctx context.Context, userQuery string, stage1Results []Stage1Response, stage2Results []Stage2Ranking) (*Stage3Response, error) {
+	messages := buildChairmanMessages(userQuery, stage1Results, stage2Results)
+	opts := councilConfig(ctx).queryOptions(ChairmanReasoningEffort)
+
+	return synthesizeWithChairmen(ctx, func(chairman string) (*OpenRouterResponse, error) {
+		return QueryModelWithOptions(ctx, chairman, messages, opts)
+	})
+}
+
+// Stage3SynthesizeFinalStream is the streaming variant of Stage3SynthesizeFinal.
+// Each token of the chairman's answer is passed to onToken as it arrives. If a chairman
+// fails part-way through and a fallback takes over, the fallback's tokens follow the
+// partial output; the returned Stage3Response always holds the complete final answer.
+func Stage3SynthesizeFinalStream(ctx context.Context, userQuery string, stage1Results []Stage1Response, stage2Results []Stage2Ranking, onToken func(string)) (*Stage3Response, error) {
+	messages := buildChairmanMessages(userQuery, stage1Results, stage2Results)
+	opts := councilConfig(ctx).queryOptions(ChairmanReasoningEffort)
+
+	return synthesizeWithChairmen(ctx, func(chairman string) (*OpenRouterResponse, error) {
+		return QueryModelStream(ctx, chairman, messages, opts, onToken)
+	})
+}
+
+// buildChairmanMessages builds the chairman prompt from all Stage 1 and Stage 2 context
+func buildChairmanMessages(userQuery string, stage1Results []Stage1Response, stage2Results []Stage2Ranking) []OpenRouterMessage {
 	// Build comprehensive context with all stage1 results
 	var stage1Text strings.Builder
 	for _, result := range stage1Results {
@@ -149,107 +758,414 @@ Your task as Chairman is to synthesize all of this information into a single, co
 Provide a clear, well-reasoned final answer that represents the council's collective wisdom:`, userQuery, stage1Text.String(), stage2Text.String())
 
 	// Create messages
-	messages := []OpenRouterMessage{
+	return withSystemPrompt(ChairmanSystemPrompt, []OpenRouterMessage{
 		{Role: "user", Content: chairmanPrompt},
+	})
+}
+
+// synthesizeWithChairmen runs query against the chairman model, falling back to each
+// model in ChairmanFallbacks in order until one succeeds.
+func synthesizeWithChairmen(ctx context.Context, query func(chairman string) (*OpenRouterResponse, error)) (*Stage3Response, error) {
+	cfg := councilConfig(ctx)
+	chairmen := append([]string{cfg.ChairmanModel}, cfg.ChairmanFallbacks...)
+	var errs []error
+	for i, chairman := range chairmen {
+		response, err := query(chairman)
+		if err != nil {
+			slog.WarnContext(ctx, "chairman model failed", "model", chairman, "error", err)
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break // No point trying fallbacks once the request is cancelled
+			}
+			continue
+		}
+
+		warnIfTruncated(ctx, 3, chairman, response)
+		return &Stage3Response{
+			Model:            chairman,
+			Response:         response.Content,
+			UsedFallback:     i > 0,
+			ReasoningDetails: response.ReasoningDetails,
+			FinishReason:     response.FinishReason,
+		}, nil
 	}
 
-	// Query chairman model
-	response, err := QueryModel(ctx, ChairmanModel, messages, ModelQueryTimeout)
-	if err != nil {
-		return nil, fmt.Errorf("chairman model query failed: %w", err)
+	return nil, fmt.Errorf("chairman model query failed: %w", errors.Join(errs...))
+}
+
+// warnIfTruncated logs a response the model stopped early because it ran out of
+// output tokens, since the answer or ranking it returned is incomplete
+func warnIfTruncated(ctx context.Context, stage int, model string, response *OpenRouterResponse) {
+	if response.Truncated() {
+		slog.WarnContext(ctx, "model response truncated", "stage", stage, "model", model, "finish_reason", response.FinishReason)
 	}
+}
 
-	return &Stage3Response{
-		Model:    ChairmanModel,
-		Response: response.Content,
-	}, nil
+// responseLabelPattern matches a response label such as "Response A", "**Response B**",
+// "Response C:" or "Response 2", capturing the letter or numeric part of the label.
+var responseLabelPattern = regexp.MustCompile(`Response\s+([A-Z]{1,2}|\d+)(?:[^A-Za-z0-9]|$)`)
+
+// numberedLabelPattern matches a numbered list entry such as "1. Response A",
+// "2) **Response B**" or "**3. Response C:**", capturing the label.
+var numberedLabelPattern = regexp.MustCompile(`\d+[.)]\s*[*_]*\s*Response\s+([A-Z]{1,2}|\d+)(?:[^A-Za-z0-9]|$)`)
+
+// finalRankingPattern matches the "FINAL RANKING:" header, tolerating markdown
+// emphasis between the words and the colon (e.g. "**FINAL RANKING**:").
+var finalRankingPattern = regexp.MustCompile(`FINAL RANKING[*_]*\s*:`)
+
+// labelLetters returns the letter label for a zero-based response index:
+// A, B, ... Z, then AA, AB, ... so any number of responses gets a unique label.
+func labelLetters(index int) string {
+	letters := ""
+	for n := index + 1; n > 0; n = (n - 1) / 26 {
+		letters = string(rune('A'+(n-1)%26)) + letters
+	}
+	return letters
+}
+
+// normalizeResponseLabel converts a captured label to the canonical "Response X" form.
+// Numeric labels are 1-based, so "Response 1" becomes "Response A".
+// Returns an empty string for labels that can't be normalized (e.g. "Response 0").
+func normalizeResponseLabel(label string) string {
+	if n, err := strconv.Atoi(label); err == nil {
+		if n < 1 {
+			return ""
+		}
+		return "Response " + labelLetters(n-1)
+	}
+	return "Response " + label
+}
+
+// extractLabels returns the normalized labels matched by pattern, in order of first
+// appearance, skipping repeated mentions of the same label.
+func extractLabels(pattern *regexp.Regexp, text string) []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		label := normalizeResponseLabel(match[1])
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	return labels
 }
 
 // ParseRankingFromText extracts the ranking from a model's response text.
 // Looks for a "FINAL RANKING:" section and parses numbered responses (e.g., "1. Response A").
-// Falls back to extracting any "Response X" patterns found in the text.
+// Markdown emphasis, trailing colons and numeric labels ("Response 1") are tolerated, and
+// every label is normalized to the canonical "Response X" form. Labels mentioned in the
+// evaluation prose before the ranking section are ignored. Falls back to extracting any
+// "Response X" patterns found in the text if there is no usable ranking section.
 func ParseRankingFromText(rankingText string) []string {
-	// Look for "FINAL RANKING:" section
-	if strings.Contains(rankingText, "FINAL RANKING:") {
-		parts := strings.Split(rankingText, "FINAL RANKING:")
-		if len(parts) >= 2 {
-			rankingSection := parts[1]
-
-			// Try to extract numbered list format (e.g., "1. Response A")
-			numberedPattern := regexp.MustCompile(`\d+\.\s*Response [A-Z]`)
-			numberedMatches := numberedPattern.FindAllString(rankingSection, -1)
-			if len(numberedMatches) > 0 {
-				// Extract just the "Response X" part
-				responsePattern := regexp.MustCompile(`Response [A-Z]`)
-				var results []string
-				for _, match := range numberedMatches {
-					if resp := responsePattern.FindString(match); resp != "" {
-						results = append(results, resp)
-					}
-				}
-				return results
-			}
+	// Look for the last "FINAL RANKING:" header, since the ranking comes at the end
+	if headers := finalRankingPattern.FindAllStringIndex(rankingText, -1); len(headers) > 0 {
+		rankingSection := rankingText[headers[len(headers)-1][1]:]
 
-			// Fallback: Extract all "Response X" patterns in order
-			responsePattern := regexp.MustCompile(`Response [A-Z]`)
-			matches := responsePattern.FindAllString(rankingSection, -1)
-			if len(matches) > 0 {
-				return matches
-			}
+		// Try to extract numbered list format (e.g., "1. Response A")
+		if results := extractLabels(numberedLabelPattern, rankingSection); len(results) > 0 {
+			return results
+		}
+
+		// Fallback: Extract all "Response X" patterns in order
+		if results := extractLabels(responseLabelPattern, rankingSection); len(results) > 0 {
+			return results
 		}
 	}
 
 	// Fallback: try to find any "Response X" patterns in order
-	responsePattern := regexp.MustCompile(`Response [A-Z]`)
-	matches := responsePattern.FindAllString(rankingText, -1)
-	return matches
+	return extractLabels(responseLabelPattern, rankingText)
 }
 
 // CalculateAggregateRankings computes aggregate rankings across all models.
-// Calculates the average rank position for each model based on peer rankings.
+// Calculates the average rank position for each model based on peer rankings,
+// weighting each ranker's contribution by its ModelWeights entry.
 // Returns a slice of aggregate rankings sorted by average rank (lower is better).
+// Ties are broken by the number of rankings received (more first), then by model name,
+// so the order is deterministic.
 func CalculateAggregateRankings(stage2Results []Stage2Ranking, labelToModel map[string]string) []AggregateRanking {
-	// Track positions for each model
-	modelPositions := make(map[string][]int)
+	// Track weighted positions for each model
+	type weightedPositions struct {
+		sum    float64 // sum of weight * position
+		weight float64 // sum of weights
+		count  int
+	}
+	modelPositions := make(map[string]*weightedPositions)
 
 	for _, ranking := range stage2Results {
-		parsed := ranking.ParsedRanking
+		weight := modelWeight(ranking.Model)
+		if weight <= 0 {
+			continue
+		}
 
-		for position, label := range parsed {
+		skipped := 0
+		for i, label := range ranking.ParsedRanking {
 			if modelName, ok := labelToModel[label]; ok {
-				modelPositions[modelName] = append(modelPositions[modelName], position+1) // position+1 because 0-indexed
+				// Drop a ranker's vote for itself, moving the rest up a place
+				if ExcludeSelfRanking && modelName == ranking.Model {
+					skipped++
+					continue
+				}
+				position := i - skipped
+				wp := modelPositions[modelName]
+				if wp == nil {
+					wp = &weightedPositions{}
+					modelPositions[modelName] = wp
+				}
+				wp.sum += weight * float64(position+1) // position+1 because 0-indexed
+				wp.weight += weight
+				wp.count++
 			}
 		}
 	}
 
-	// Calculate average position for each model
+	// Calculate weighted average position for each model
 	var aggregate []AggregateRanking
-	for model, positions := range modelPositions {
-		if len(positions) > 0 {
-			sum := 0
-			for _, pos := range positions {
-				sum += pos
-			}
-			avgRank := float64(sum) / float64(len(positions))
-
-			aggregate = append(aggregate, AggregateRanking{
-				Model:         model,
-				AverageRank:   avgRank,
-				RankingsCount: len(positions),
-			})
-		}
+	for model, wp := range modelPositions {
+		aggregate = append(aggregate, AggregateRanking{
+			Model:         model,
+			AverageRank:   wp.sum / wp.weight,
+			RankingsCount: wp.count,
+		})
 	}
 
-	// Sort by average rank (lower is better)
+	// Sort by average rank (lower is better), breaking ties deterministically
 	sort.Slice(aggregate, func(i, j int) bool {
-		return aggregate[i].AverageRank < aggregate[j].AverageRank
+		a, b := aggregate[i], aggregate[j]
+		if a.AverageRank != b.AverageRank {
+			return a.AverageRank < b.AverageRank
+		}
+		if a.RankingsCount != b.RankingsCount {
+			return a.RankingsCount > b.RankingsCount
+		}
+		return a.Model < b.Model
 	})
 
 	return aggregate
 }
 
+// rankTieTolerance absorbs floating point error when comparing weighted average ranks
+const rankTieTolerance = 1e-9
+
+// SummarizeWinner returns the top-ranked model(s) from sorted aggregate rankings,
+// listing every model tied for first, or nil if there are no rankings.
+func SummarizeWinner(aggregate []AggregateRanking) *CouncilWinner {
+	if len(aggregate) == 0 {
+		return nil
+	}
+
+	best := aggregate[0].AverageRank
+	winner := &CouncilWinner{AverageRank: best}
+	for _, ranking := range aggregate {
+		if ranking.AverageRank-best > rankTieTolerance {
+			winner.Margin = ranking.AverageRank - best
+			break
+		}
+		winner.Models = append(winner.Models, ranking.Model)
+	}
+	return winner
+}
+
+// WinnerRationale collects what the Stage 2 rankers wrote about the winning
+// responses: every sentence of their evaluations, before the FINAL RANKING
+// section, that mentions a winner's label. Sentences are kept in ranker order with
+// repeats dropped. Returns nil when there is no winner or no ranker commented on it.
+func WinnerRationale(stage2Results []Stage2Ranking, labelToModel map[string]string, winner *CouncilWinner) []string {
+	if winner == nil {
+		return nil
+	}
+	winningLabels := make(map[string]bool)
+	for label, model := range labelToModel {
+		if slices.Contains(winner.Models, model) {
+			winningLabels[label] = true
+		}
+	}
+	if len(winningLabels) == 0 {
+		return nil
+	}
+
+	var rationale []string
+	seen := make(map[string]bool)
+	for _, ranking := range stage2Results {
+		evaluation := ranking.Ranking
+		if headers := finalRankingPattern.FindAllStringIndex(evaluation, -1); len(headers) > 0 {
+			evaluation = evaluation[:headers[len(headers)-1][0]]
+		}
+		for _, sentence := range splitSentences(evaluation) {
+			if seen[sentence] {
+				continue
+			}
+			if slices.ContainsFunc(extractLabels(responseLabelPattern, sentence), func(label string) bool { return winningLabels[label] }) {
+				seen[sentence] = true
+				rationale = append(rationale, sentence)
+			}
+		}
+	}
+	return rationale
+}
+
+// listMarkerPattern matches the markdown bullet, heading or numbering at the start of a line
+var listMarkerPattern = regexp.MustCompile(`^(?:[-*+>#]+|\d+[.)])\s+`)
+
+// splitSentences splits text into sentences, one line at a time so list items and
+// headings stand alone. A sentence ends at ".", "!" or "?" followed by whitespace
+// and a capital letter, digit or markdown emphasis, so abbreviations such as "e.g."
+// mid-sentence don't split it.
+func splitSentences(text string) []string {
+	var sentences []string
+	add := func(sentence string) {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = listMarkerPattern.ReplaceAllString(strings.TrimSpace(line), "")
+		runes := []rune(line)
+		start := 0
+		for i := 0; i < len(runes)-2; i++ {
+			if !strings.ContainsRune(".!?", runes[i]) || !unicode.IsSpace(runes[i+1]) {
+				continue
+			}
+			if next := runes[i+2]; unicode.IsUpper(next) || unicode.IsDigit(next) || next == '*' || next == '_' {
+				add(string(runes[start : i+1]))
+				start = i + 2
+			}
+		}
+		add(string(runes[start:]))
+	}
+	return sentences
+}
+
+// CalculateConsensusScore measures how much the Stage 2 rankers agreed, as Kendall's W
+// coefficient of concordance: 1 when every ranker produced the same order, 0 when
+// the rankings cancel out completely. Responses a ranker left out share the remaining
+// positions equally. Returns 0 when fewer than two rankers or responses take part.
+func CalculateConsensusScore(stage2Results []Stage2Ranking, labelToModel map[string]string) float64 {
+	n := len(labelToModel)
+	if n < 2 {
+		return 0
+	}
+
+	// Sum each response's rank across rankers
+	rankSums := make(map[string]float64, n)
+	m := 0
+	for _, ranking := range stage2Results {
+		if modelWeight(ranking.Model) <= 0 {
+			continue
+		}
+
+		ranked := make(map[string]bool)
+		for _, label := range ranking.ParsedRanking {
+			if _, ok := labelToModel[label]; ok && !ranked[label] {
+				ranked[label] = true
+				rankSums[label] += float64(len(ranked))
+			}
+		}
+		if len(ranked) == 0 {
+			continue
+		}
+		m++
+
+		// Unranked responses tie for the positions after the ranked ones
+		tiedRank := float64(len(ranked)+1+n) / 2
+		for label := range labelToModel {
+			if !ranked[label] {
+				rankSums[label] += tiedRank
+			}
+		}
+	}
+	if m < 2 {
+		return 0
+	}
+
+	// W = 12 * S / (m^2 * (n^3 - n)), where S is the squared deviation of the rank sums
+	meanSum := float64(m*(n+1)) / 2
+	var s float64
+	for label := range labelToModel {
+		d := rankSums[label] - meanSum
+		s += d * d
+	}
+	nf, mf := float64(n), float64(m)
+	return 12 * s / (mf * mf * (nf*nf*nf - nf))
+}
+
+// SimilarResponses returns the Jaccard similarity of the word sets of a and b:
+// 1 for responses using exactly the same words, 0 for no words in common. Words
+// are compared case-insensitively, ignoring punctuation.
+func SimilarResponses(a, b string) float64 {
+	wordsA, wordsB := responseWords(a), responseWords(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+// responseWords splits text into its set of lowercased words.
+func responseWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
+
+// FindDuplicateResponses groups Stage 1 responses whose SimilarResponses score is at
+// least DuplicateSimilarityThreshold, directly or through another member of the
+// group. Returns the model names of each group of two or more, in Stage 1 order,
+// or nil when every response is distinct.
+func FindDuplicateResponses(stage1Results []Stage1Response) [][]string {
+	// Union-find over response indices
+	parent := make([]int, len(stage1Results))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range stage1Results {
+		for j := i + 1; j < len(stage1Results); j++ {
+			if SimilarResponses(stage1Results[i].Response, stage1Results[j].Response) >= DuplicateSimilarityThreshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]string)
+	var roots []int
+	for i, result := range stage1Results {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], result.Model)
+	}
+
+	var groups [][]string
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			groups = append(groups, members[root])
+		}
+	}
+	return groups
+}
+
 // GenerateConversationTitle generates a short title for a conversation.
-// Uses a fast model (gemini-2.5-flash) to create a 3-5 word summary of the user's query.
+// Uses a fast model (TitleModel) to create a 3-5 word summary of the user's query.
 // Returns the generated title or an error if generation fails.
 func GenerateConversationTitle(ctx context.Context, userQuery string) (string, error) {
 	titlePrompt := fmt.Sprintf(`Generate a very short title (3-5 words maximum) that summarizes the following question.
@@ -263,8 +1179,8 @@ Title:`, userQuery)
 		{Role: "user", Content: titlePrompt},
 	}
 
-	// Use gemini-2.5-flash for fast title generation
-	response, err := QueryModel(ctx, "google/gemini-2.5-flash", messages, TitleGenTimeout)
+	// Use a fast model for title generation
+	response, err := QueryModel(ctx, TitleModel, messages, TitleGenTimeout)
 	if err != nil {
 		return "", fmt.Errorf("title generation failed: %w", err)
 	}
@@ -274,42 +1190,153 @@ Title:`, userQuery)
 	// Clean up the title - remove quotes
 	title = strings.Trim(title, "\"'")
 
-	// Truncate if too long
-	if len(title) > 50 {
-		title = title[:47] + "..."
+	// Truncate if too long, counting characters rather than bytes
+	if runes := []rune(title); len(runes) > 50 {
+		title = string(runes[:47]) + "..."
 	}
 
 	return title, nil
 }
 
+// CouncilCacheKey identifies a council run for result caching: a hash of the query
+// (with whitespace normalized), its images, and every setting that shapes the
+// answer - the model rosters, chairman, system prompts, prompt templates and the
+// Stage 2 options. Changing any of them yields a different key. The rosters are
+// those of the council in ctx.
+func CouncilCacheKey(ctx context.Context, userQuery string, imageURLs []string) string {
+	query := strings.Join(strings.Fields(userQuery), " ")
+	roster := councilConfig(ctx)
+	key, _ := json.Marshal(struct {
+		Query                   string
+		ImageURLs               []string
+		CouncilModels           []string
+		RankerModels            []string
+		StructuredRankingModels []string
+		ChairmanModel           string
+		ChairmanFallbacks       []string
+		ModelWeights            map[string]float64
+		Stage1Prompt            []OpenRouterMessage
+		RankingPrompt           string
+		ChairmanPrompt          []OpenRouterMessage
+		ReasoningEfforts        [3]string
+		ScrubModelIdentity      bool
+		ExcludeSelfRanking      bool
+		MaxCouncilResponses     int
+		RankingLimits           [2]int
+	}{
+		Query:                   query,
+		ImageURLs:               imageURLs,
+		CouncilModels:           roster.CouncilModels,
+		RankerModels:            roster.Rankers(),
+		StructuredRankingModels: StructuredRankingModels,
+		ChairmanModel:           roster.ChairmanModel,
+		ChairmanFallbacks:       roster.ChairmanFallbacks,
+		ModelWeights:            ModelWeights,
+		Stage1Prompt:            buildStage1Messages(query),
+		RankingPrompt:           buildRankingPrompt(query, ""),
+		ChairmanPrompt:          buildChairmanMessages(query, nil, nil),
+		ReasoningEfforts:        [3]string{CouncilReasoningEffort, RankingReasoningEffort, ChairmanReasoningEffort},
+		ScrubModelIdentity:      ScrubModelIdentity,
+		ExcludeSelfRanking:      ExcludeSelfRanking,
+		MaxCouncilResponses:     MaxCouncilResponses,
+		RankingLimits:           [2]int{MaxResponseCharsInRanking, MaxRankingPromptTokens},
+	})
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// collectStage1WithRetries runs Stage 1, running it again up to CouncilRunRetries
+// times with exponential backoff from CouncilRetryBackoff while every council model
+// fails. Nothing after Stage 1 has happened at that point, so this retries the whole
+// run. Partial success is never retried.
+func collectStage1WithRetries(ctx context.Context, userQuery string, imageURLs ...string) ([]Stage1Response, []ModelFailure, error) {
+	delay := CouncilRetryBackoff
+	for attempt := 0; ; attempt++ {
+		results, failures, err := Stage1CollectResponses(ctx, userQuery, imageURLs...)
+		if err != nil || len(results) > 0 || attempt >= CouncilRunRetries || ctx.Err() != nil {
+			return results, failures, err
+		}
+
+		slog.WarnContext(ctx, "all council models failed, retrying council run", "attempt", attempt+1, "error", joinFailures(failures))
+		select {
+		case <-ctx.Done():
+			return results, failures, nil
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// ErrCouncilTimeout is returned when a council run exceeds its CouncilTimeout.
+var ErrCouncilTimeout = errors.New("council run exceeded its deadline")
+
 // RunFullCouncil runs the complete 3-stage council process.
 // Orchestrates all three stages: parallel model queries, anonymized peer review,
 // and chairman synthesis. Returns results from all stages plus metadata including
 // rankings and label mappings, or an error if any critical stage fails.
-func RunFullCouncil(ctx context.Context, userQuery string) ([]Stage1Response, []Stage2Ranking, Stage3Response, Metadata, error) {
+// Any attached images are shown to the council in Stage 1.
+func RunFullCouncil(ctx context.Context, userQuery string, imageURLs ...string) ([]Stage1Response, []Stage2Ranking, Stage3Response, Metadata, error) {
+	// Pin the roster for the whole run, so a roster update can't change models between stages
+	ctx = WithCouncilConfig(ctx, councilConfig(ctx))
+
+	// Bound the whole run, not just each model query
+	timeout := councilConfig(ctx).councilTimeout()
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrCouncilTimeout)
+	defer cancel()
+	start := time.Now()
+
 	// Stage 1: Collect responses
-	stage1Results, err := Stage1CollectResponses(ctx, userQuery)
+	stage1Results, failures, err := collectStage1WithRetries(ctx, userQuery, imageURLs...)
+	if councilTimedOut(ctx) {
+		return stage1Results, nil, Stage3Response{}, Metadata{FailedModels: failures, DuplicateGroups: FindDuplicateResponses(stage1Results)}, councilTimeoutError(1, timeout)
+	}
 	if err != nil {
 		return nil, nil, Stage3Response{}, Metadata{}, fmt.Errorf("stage 1 failed: %w", err)
 	}
 
 	// If no models responded successfully, return error
 	if len(stage1Results) == 0 {
-		return nil, nil, Stage3Response{}, Metadata{},
-			fmt.Errorf("all council models failed to respond")
+		return nil, nil, Stage3Response{}, Metadata{FailedModels: failures},
+			fmt.Errorf("all council models failed to respond: %w", joinFailures(failures))
 	}
 
+	// Latencies cover every model that answered, including any capped below
+	modelLatencies := ModelLatencies(stage1Results)
+
+	// Only the first MaxCouncilResponses responses go on to Stages 2 and 3
+	stage1Results, omittedModels := CapCouncilResponses(ctx, stage1Results, MaxCouncilResponses)
+
+	// Flag near-identical responses; they are kept, but skew ranking and synthesis
+	duplicateGroups := FindDuplicateResponses(stage1Results)
+	stage2Start := time.Now()
+
 	// Stage 2: Collect rankings
 	stage2Results, labelToModel, err := Stage2CollectRankings(ctx, userQuery, stage1Results)
+	if councilTimedOut(ctx) {
+		return stage1Results, stage2Results, Stage3Response{}, Metadata{LabelToModel: labelToModel, FailedModels: failures, OmittedModels: omittedModels, DuplicateGroups: duplicateGroups}, councilTimeoutError(2, timeout)
+	}
 	if err != nil {
 		return nil, nil, Stage3Response{}, Metadata{}, fmt.Errorf("stage 2 failed: %w", err)
 	}
 
 	// Calculate aggregate rankings
 	aggregateRankings := CalculateAggregateRankings(stage2Results, labelToModel)
+	consensusScore := CalculateConsensusScore(stage2Results, labelToModel)
+	stage3Start := time.Now()
 
 	// Stage 3: Synthesize final answer
 	stage3Result, err := Stage3SynthesizeFinal(ctx, userQuery, stage1Results, stage2Results)
+	if councilTimedOut(ctx) {
+		return stage1Results, stage2Results, Stage3Response{}, Metadata{
+			LabelToModel:      labelToModel,
+			AggregateRankings: aggregateRankings,
+			FailedModels:      failures,
+			ConsensusScore:    consensusScore,
+			Winner:            SummarizeWinner(aggregateRankings),
+			OmittedModels:     omittedModels,
+			DuplicateGroups:   duplicateGroups,
+		}, councilTimeoutError(3, timeout)
+	}
 	if err != nil {
 		return nil, nil, Stage3Response{}, Metadata{}, fmt.Errorf("stage 3 failed: %w", err)
 	}
@@ -318,7 +1345,84 @@ func RunFullCouncil(ctx context.Context, userQuery string) ([]Stage1Response, []
 	metadata := Metadata{
 		LabelToModel:      labelToModel,
 		AggregateRankings: aggregateRankings,
+		FailedModels:      failures,
+		ConsensusScore:    consensusScore,
+		Winner:            SummarizeWinner(aggregateRankings),
+		OmittedModels:     omittedModels,
+		DuplicateGroups:   duplicateGroups,
+		ModelLatencies:    modelLatencies,
+		RankingLatencies:  RankingLatencies(stage2Results),
+	}
+	if WinnerRationaleEnabled {
+		metadata.WinnerRationale = WinnerRationale(stage2Results, labelToModel, metadata.Winner)
 	}
+	end := time.Now()
+	metadata.TotalDuration = end.Sub(start).Seconds()
+	metadata.Stage1Duration = stage2Start.Sub(start).Seconds()
+	metadata.Stage2Duration = stage3Start.Sub(stage2Start).Seconds()
+	metadata.Stage3Duration = end.Sub(stage3Start).Seconds()
 
 	return stage1Results, stage2Results, *stage3Result, metadata, nil
 }
+
+// ModelLatencies returns how long each Stage 1 model took to answer, in seconds.
+// Returns nil if no latencies were recorded.
+func ModelLatencies(stage1Results []Stage1Response) map[string]float64 {
+	var latencies map[string]float64
+	for _, result := range stage1Results {
+		latencies = addLatency(latencies, result.Model, result.Latency)
+	}
+	return latencies
+}
+
+// RankingLatencies returns how long each Stage 2 ranker took, in seconds.
+// Returns nil if no latencies were recorded.
+func RankingLatencies(stage2Results []Stage2Ranking) map[string]float64 {
+	var latencies map[string]float64
+	for _, ranking := range stage2Results {
+		latencies = addLatency(latencies, ranking.Model, ranking.Latency)
+	}
+	return latencies
+}
+
+// addLatency records model's latency in seconds, allocating latencies if needed.
+// Zero latencies (not measured) are skipped.
+func addLatency(latencies map[string]float64, model string, latency time.Duration) map[string]float64 {
+	if latency <= 0 {
+		return latencies
+	}
+	if latencies == nil {
+		latencies = make(map[string]float64)
+	}
+	latencies[model] = latency.Seconds()
+	return latencies
+}
+
+// modelWeight returns the ranking weight for a model, defaulting to 1.0.
+func modelWeight(model string) float64 {
+	if weight, ok := ModelWeights[model]; ok {
+		return weight
+	}
+	return 1.0
+}
+
+// councilTimedOut reports whether ctx was cancelled by the RunFullCouncil deadline,
+// as opposed to the caller going away.
+func councilTimedOut(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrCouncilTimeout)
+}
+
+// councilTimeoutError describes which stage was cut short by the council deadline.
+func councilTimeoutError(stage int, timeout time.Duration) error {
+	return fmt.Errorf("stage %d: %w after %v", stage, ErrCouncilTimeout, timeout)
+}
+
+// joinFailures combines the underlying errors of failed models into a single error,
+// so callers can still inspect them with errors.Is and errors.As.
+func joinFailures(failures []ModelFailure) error {
+	var errs []error
+	for _, failure := range failures {
+		errs = append(errs, fmt.Errorf("%s: %w", failure.Model, failure.Err))
+	}
+	return errors.Join(errs...)
+}
@@ -1,11 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 // TestParseRankingFromText tests the ranking parser with various formats
@@ -88,6 +99,70 @@ FINAL RANKING:
 4. Response C`,
 			expected: []string{"Response D", "Response A", "Response B", "Response C"},
 		},
+		{
+			name: "markdown bold labels",
+			input: `FINAL RANKING:
+1. **Response B**
+2. **Response A**
+3. **Response C**`,
+			expected: []string{"Response B", "Response A", "Response C"},
+		},
+		{
+			name: "labels with trailing colons",
+			input: `FINAL RANKING:
+1. Response C:
+2. Response A:
+3. Response B:`,
+			expected: []string{"Response C", "Response A", "Response B"},
+		},
+		{
+			name: "bold list item with emphasized header",
+			input: `**FINAL RANKING**:
+**1. Response B:**
+**2. _Response A_**`,
+			expected: []string{"Response B", "Response A"},
+		},
+		{
+			name: "numeric labels normalized to letters",
+			input: `FINAL RANKING:
+1. Response 3
+2. Response 1
+3. Response 2`,
+			expected: []string{"Response C", "Response A", "Response B"},
+		},
+		{
+			name: "parenthesized list numbers",
+			input: `FINAL RANKING:
+1) Response B
+2) Response A`,
+			expected: []string{"Response B", "Response A"},
+		},
+		{
+			name: "bold prose mentions before FINAL RANKING are not counted",
+			input: `**Response A:** strong on detail.
+**Response B:** concise but shallow.
+**Response C:** best overall.
+
+FINAL RANKING:
+1. **Response C**
+2. **Response A**
+3. **Response B**`,
+			expected: []string{"Response C", "Response A", "Response B"},
+		},
+		{
+			name: "repeated label in ranking section counted once",
+			input: `FINAL RANKING:
+1. Response B
+2. Response A
+3. Response B`,
+			expected: []string{"Response B", "Response A"},
+		},
+		{
+			name: "words starting with a capital after Response are not labels",
+			input: `Response Also mentioned. FINAL RANKING:
+1. Response A`,
+			expected: []string{"Response A"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -117,7 +192,8 @@ func TestCalculateAggregateRankings(t *testing.T) {
 		stage2Results []Stage2Ranking
 		labelToModel  map[string]string
 		expectedLen   int
-		checkFirst    string // Expected first model in ranking
+		checkFirst    string   // Expected first model in ranking
+		checkOrder    []string // Expected full order, if specified
 	}{
 		{
 			name: "single model ranking all responses",
@@ -172,7 +248,31 @@ func TestCalculateAggregateRankings(t *testing.T) {
 			},
 			expectedLen: 2,
 			// Average: model/a = (1+2)/2 = 1.5, model/b = (2+1)/2 = 1.5
-			// With tie, order may vary, so we don't check first
+			// Tie with equal counts is broken alphabetically
+			checkFirst: "model/a",
+		},
+		{
+			name: "ties broken by rankings count then name",
+			stage2Results: []Stage2Ranking{
+				{
+					Model:         "test/ranker1",
+					ParsedRanking: []string{"Response B", "Response A"},
+				},
+				{
+					Model:         "test/ranker2",
+					ParsedRanking: []string{"Response C", "Response D", "Response B"},
+				},
+			},
+			labelToModel: map[string]string{
+				"Response A": "model/a",
+				"Response B": "model/b",
+				"Response C": "model/c",
+				"Response D": "model/d",
+			},
+			expectedLen: 4,
+			// Average: c = 1, b = (1+3)/2 = 2, a = 2, d = 2
+			// b has more rankings than a and d, which are then ordered by name
+			checkOrder: []string{"model/c", "model/b", "model/a", "model/d"},
 		},
 		{
 			name: "empty rankings",
@@ -231,6 +331,17 @@ func TestCalculateAggregateRankings(t *testing.T) {
 				}
 			}
 
+			// Check full order if specified
+			if tt.checkOrder != nil {
+				var got []string
+				for _, r := range result {
+					got = append(got, r.Model)
+				}
+				if !reflect.DeepEqual(got, tt.checkOrder) {
+					t.Errorf("Order: got %v, want %v", got, tt.checkOrder)
+				}
+			}
+
 			// Verify all rankings have positive count
 			for _, ranking := range result {
 				if ranking.RankingsCount <= 0 {
@@ -275,7 +386,12 @@ func TestCalculateAggregateRankingsAverages(t *testing.T) {
 		t.Fatalf("Expected 3 results, got %d", len(result))
 	}
 
-	for _, r := range result {
+	// All tied on average and count, so the order is alphabetical
+	wantOrder := []string{"model/a", "model/b", "model/c"}
+	for i, r := range result {
+		if r.Model != wantOrder[i] {
+			t.Errorf("Position %d: got %q, want %q", i, r.Model, wantOrder[i])
+		}
 		if r.AverageRank != 2.0 {
 			t.Errorf("Model %s: expected average rank 2.0, got %.2f", r.Model, r.AverageRank)
 		}
@@ -285,6 +401,369 @@ func TestCalculateAggregateRankingsAverages(t *testing.T) {
 	}
 }
 
+// TestCalculateAggregateRankingsWeighted tests that ranker weights shape the aggregate
+func TestCalculateAggregateRankingsWeighted(t *testing.T) {
+	oldWeights := ModelWeights
+	defer func() { ModelWeights = oldWeights }()
+
+	// Two rankers prefer A, one heavily-weighted ranker prefers B
+	stage2Results := []Stage2Ranking{
+		{Model: "ranker1", ParsedRanking: []string{"Response A", "Response B"}},
+		{Model: "ranker2", ParsedRanking: []string{"Response A", "Response B"}},
+		{Model: "ranker3", ParsedRanking: []string{"Response B", "Response A"}},
+	}
+	labelToModel := map[string]string{
+		"Response A": "model/a",
+		"Response B": "model/b",
+	}
+
+	t.Run("unweighted majority wins", func(t *testing.T) {
+		ModelWeights = map[string]float64{}
+		result := CalculateAggregateRankings(stage2Results, labelToModel)
+		if result[0].Model != "model/a" {
+			t.Errorf("First model: got %q, want model/a", result[0].Model)
+		}
+	})
+
+	t.Run("heavily-weighted ranker dominates", func(t *testing.T) {
+		ModelWeights = map[string]float64{"ranker3": 8}
+		result := CalculateAggregateRankings(stage2Results, labelToModel)
+		if result[0].Model != "model/b" {
+			t.Errorf("First model: got %q, want model/b", result[0].Model)
+		}
+		// model/b: (1*2 + 1*2 + 8*1) / 10 = 1.2
+		if result[0].AverageRank != 1.2 {
+			t.Errorf("model/b average rank: got %.2f, want 1.20", result[0].AverageRank)
+		}
+		if result[0].RankingsCount != 3 {
+			t.Errorf("model/b rankings count: got %d, want 3", result[0].RankingsCount)
+		}
+	})
+
+	t.Run("zero weight excludes a ranker", func(t *testing.T) {
+		ModelWeights = map[string]float64{"ranker1": 0, "ranker2": 0}
+		result := CalculateAggregateRankings(stage2Results, labelToModel)
+		if result[0].Model != "model/b" || result[0].AverageRank != 1 {
+			t.Errorf("First: got %q at %.2f, want model/b at 1.00", result[0].Model, result[0].AverageRank)
+		}
+		for _, r := range result {
+			if r.RankingsCount != 1 {
+				t.Errorf("Model %s: expected 1 counted ranking, got %d", r.Model, r.RankingsCount)
+			}
+		}
+	})
+
+	t.Run("all rankers excluded", func(t *testing.T) {
+		ModelWeights = map[string]float64{"ranker1": 0, "ranker2": 0, "ranker3": 0}
+		if result := CalculateAggregateRankings(stage2Results, labelToModel); len(result) != 0 {
+			t.Errorf("Expected no aggregate rankings, got %v", result)
+		}
+	})
+}
+
+// TestSummarizeWinner tests picking the winner and margin from aggregate rankings
+func TestSummarizeWinner(t *testing.T) {
+	tests := []struct {
+		name      string
+		aggregate []AggregateRanking
+		want      *CouncilWinner
+	}{
+		{
+			name: "clear winner",
+			aggregate: []AggregateRanking{
+				{Model: "model/a", AverageRank: 1.25},
+				{Model: "model/b", AverageRank: 2},
+				{Model: "model/c", AverageRank: 2.75},
+			},
+			want: &CouncilWinner{Models: []string{"model/a"}, AverageRank: 1.25, Margin: 0.75},
+		},
+		{
+			name: "tie for first",
+			aggregate: []AggregateRanking{
+				{Model: "model/a", AverageRank: 1.5},
+				{Model: "model/b", AverageRank: 1.5},
+				{Model: "model/c", AverageRank: 3},
+			},
+			want: &CouncilWinner{Models: []string{"model/a", "model/b"}, AverageRank: 1.5, Margin: 1.5},
+		},
+		{
+			name: "tie within floating point error",
+			aggregate: []AggregateRanking{
+				{Model: "model/a", AverageRank: 0.1 + 0.2},
+				{Model: "model/b", AverageRank: 0.3},
+			},
+			want: &CouncilWinner{Models: []string{"model/a", "model/b"}, AverageRank: 0.1 + 0.2},
+		},
+		{
+			name:      "single model",
+			aggregate: []AggregateRanking{{Model: "model/a", AverageRank: 1}},
+			want:      &CouncilWinner{Models: []string{"model/a"}, AverageRank: 1},
+		},
+		{
+			name:      "no rankings",
+			aggregate: nil,
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SummarizeWinner(tt.aggregate); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SummarizeWinner = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWinnerRationale tests collecting the rankers' commentary on the winning response
+func TestWinnerRationale(t *testing.T) {
+	labelToModel := map[string]string{
+		"Response A": "model/a",
+		"Response B": "model/b",
+		"Response C": "model/c",
+	}
+	stage2Results := []Stage2Ranking{
+		{
+			Model: "ranker1",
+			Ranking: `Response A is accurate but thin on examples. Response B gives the clearest explanation, e.g. with worked code. It also covers edge cases.
+Response C repeats Response B in places.
+
+FINAL RANKING:
+1. Response B
+2. Response A
+3. Response C`,
+		},
+		{
+			Model: "ranker2",
+			Ranking: `### Evaluation
+- **Response B:** Thorough and well structured! Nothing important is missing.
+- **Response A:** Too brief.
+- Response 2 cites its sources, unlike the others.
+
+**FINAL RANKING**:
+1. Response B
+2. Response C
+3. Response A`,
+		},
+		{
+			// Repeats are dropped; a ranking without commentary adds nothing
+			Model:   "ranker3",
+			Ranking: "Response B gives the clearest explanation, e.g. with worked code.\n\nFINAL RANKING:\n1. Response B\n2. Response A",
+		},
+		{Model: "ranker4", Ranking: "FINAL RANKING:\n1. Response B\n2. Response C"},
+	}
+
+	got := WinnerRationale(stage2Results, labelToModel, &CouncilWinner{Models: []string{"model/b"}})
+	want := []string{
+		"Response B gives the clearest explanation, e.g. with worked code.",
+		"Response C repeats Response B in places.",
+		"**Response B:** Thorough and well structured!",
+		"Response 2 cites its sources, unlike the others.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WinnerRationale =\n%q\nwant\n%q", got, want)
+	}
+
+	// Every model tied for first counts as a winner
+	got = WinnerRationale(stage2Results, labelToModel, &CouncilWinner{Models: []string{"model/a", "model/c"}})
+	want = []string{
+		"Response A is accurate but thin on examples.",
+		"Response C repeats Response B in places.",
+		"**Response A:** Too brief.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WinnerRationale for a tie =\n%q\nwant\n%q", got, want)
+	}
+
+	if got := WinnerRationale(stage2Results, labelToModel, nil); got != nil {
+		t.Errorf("WinnerRationale without a winner = %q, want nil", got)
+	}
+	if got := WinnerRationale(stage2Results[3:], labelToModel, &CouncilWinner{Models: []string{"model/b"}}); got != nil {
+		t.Errorf("WinnerRationale without commentary = %q, want nil", got)
+	}
+}
+
+// TestRunFullCouncilWinnerRationale tests that the rationale is only collected when enabled
+func TestRunFullCouncilWinnerRationale(t *testing.T) {
+	oldAPIURL, oldAPIKey := OpenRouterAPIURL, OpenRouterAPIKey
+	oldModels, oldRankers, oldChairman := CouncilModels, RankerModels, ChairmanModel
+	oldStructured, oldEnabled := StructuredRankingModels, WinnerRationaleEnabled
+	defer func() {
+		OpenRouterAPIURL, OpenRouterAPIKey = oldAPIURL, oldAPIKey
+		CouncilModels, RankerModels, ChairmanModel = oldModels, oldRankers, oldChairman
+		StructuredRankingModels, WinnerRationaleEnabled = oldStructured, oldEnabled
+	}()
+
+	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t,
+		"Response A is the most complete answer. Response B misses the point.\n\nFINAL RANKING:\n1. Response A\n2. Response B"))
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a", "model/b"}
+	RankerModels = nil
+	ChairmanModel = "model/chairman"
+	StructuredRankingModels = nil
+
+	WinnerRationaleEnabled = false
+	_, _, _, metadata, err := RunFullCouncil(context.Background(), "What is Go?")
+	if err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", err)
+	}
+	if metadata.WinnerRationale != nil {
+		t.Errorf("Expected no rationale while disabled, got %q", metadata.WinnerRationale)
+	}
+
+	WinnerRationaleEnabled = true
+	_, _, _, metadata, err = RunFullCouncil(context.Background(), "What is Go?")
+	if err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", err)
+	}
+	if want := []string{"Response A is the most complete answer."}; !reflect.DeepEqual(metadata.WinnerRationale, want) {
+		t.Errorf("WinnerRationale = %q, want %q", metadata.WinnerRationale, want)
+	}
+}
+
+// TestCalculateConsensusScore tests Kendall's W over Stage 2 rankings
+func TestCalculateConsensusScore(t *testing.T) {
+	three := map[string]string{
+		"Response A": "model/a",
+		"Response B": "model/b",
+		"Response C": "model/c",
+	}
+	rankings := func(orders ...[]string) []Stage2Ranking {
+		var results []Stage2Ranking
+		for i, order := range orders {
+			results = append(results, Stage2Ranking{Model: fmt.Sprintf("ranker%d", i), ParsedRanking: order})
+		}
+		return results
+	}
+	a, b, c := "Response A", "Response B", "Response C"
+
+	tests := []struct {
+		name         string
+		stage2       []Stage2Ranking
+		labelToModel map[string]string
+		want         float64
+	}{
+		{
+			name:         "full agreement",
+			stage2:       rankings([]string{a, b, c}, []string{a, b, c}, []string{a, b, c}),
+			labelToModel: three,
+			want:         1,
+		},
+		{
+			name:         "reversed rankings",
+			stage2:       rankings([]string{a, b, c}, []string{c, b, a}),
+			labelToModel: three,
+			want:         0,
+		},
+		{
+			name:         "cyclic rankings cancel out",
+			stage2:       rankings([]string{a, b, c}, []string{b, c, a}, []string{c, a, b}),
+			labelToModel: three,
+			want:         0,
+		},
+		{
+			name:         "agree on the winner only",
+			stage2:       rankings([]string{a, b, c}, []string{a, c, b}),
+			labelToModel: three,
+			want:         0.75,
+		},
+		{
+			name:         "omitted responses share the remaining ranks",
+			stage2:       rankings([]string{a}, []string{a, b, c}),
+			labelToModel: three,
+			want:         0.8125,
+		},
+		{
+			name:         "unparseable rankings are ignored",
+			stage2:       rankings([]string{a, b, c}, nil, []string{a, b, c}),
+			labelToModel: three,
+			want:         1,
+		},
+		{
+			name:         "single ranker",
+			stage2:       rankings([]string{a, b, c}),
+			labelToModel: three,
+			want:         0,
+		},
+		{
+			name:         "single response",
+			stage2:       rankings([]string{a}, []string{a}),
+			labelToModel: map[string]string{a: "model/a"},
+			want:         0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateConsensusScore(tt.stage2, tt.labelToModel)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CalculateConsensusScore = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSimilarResponses tests word-overlap similarity between responses
+func TestSimilarResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"identical", "Go is fast.", "Go is fast.", 1},
+		{"case and punctuation ignored", "Go is FAST!", "go, is fast", 1},
+		{"repeated words counted once", "go go go", "Go", 1},
+		{"disjoint", "Go is fast", "Rust has lifetimes", 0},
+		{"partial overlap", "go is fast", "go is simple", 0.5},
+		{"both empty", "", "  ", 1},
+		{"one empty", "Go", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SimilarResponses(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("SimilarResponses(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := SimilarResponses(tt.b, tt.a); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("SimilarResponses is not symmetric for %q, %q: %v", tt.b, tt.a, got)
+			}
+		})
+	}
+}
+
+// TestFindDuplicateResponses tests grouping near-duplicate Stage 1 responses
+func TestFindDuplicateResponses(t *testing.T) {
+	oldThreshold := DuplicateSimilarityThreshold
+	defer func() { DuplicateSimilarityThreshold = oldThreshold }()
+	DuplicateSimilarityThreshold = 0.8
+
+	answer := "Go is a statically typed compiled language designed at Google with goroutines channels and a garbage collector"
+	stage1 := []Stage1Response{
+		{Model: "model/a", Response: answer},
+		{Model: "model/b", Response: "Rust guarantees memory safety through ownership and borrowing without a garbage collector"},
+		{Model: "model/c", Response: answer + " for simple concurrency."},
+		{Model: "model/d", Response: "Python is a dynamically typed interpreted language"},
+	}
+
+	groups := FindDuplicateResponses(stage1)
+	if want := [][]string{{"model/a", "model/c"}}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("DuplicateGroups = %v, want %v", groups, want)
+	}
+
+	// Groups join transitively through a shared member
+	stage1 = append(stage1, Stage1Response{Model: "model/e", Response: answer + " for simple concurrency and fast builds."})
+	groups = FindDuplicateResponses(stage1)
+	if want := [][]string{{"model/a", "model/c", "model/e"}}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("DuplicateGroups = %v, want %v", groups, want)
+	}
+
+	if groups := FindDuplicateResponses(stage1[1:2]); groups != nil {
+		t.Errorf("Single response DuplicateGroups = %v, want nil", groups)
+	}
+}
+
 // TestStage1CollectResponses tests Stage 1 with mocked API
 func TestStage1CollectResponses(t *testing.T) {
 	// Save original config
@@ -308,7 +787,7 @@ func TestStage1CollectResponses(t *testing.T) {
 
 	// Run Stage 1
 	ctx := context.Background()
-	results, err := Stage1CollectResponses(ctx, "What is Go?")
+	results, failures, err := Stage1CollectResponses(ctx, "What is Go?")
 
 	if err != nil {
 		t.Fatalf("Stage1CollectResponses failed: %v", err)
@@ -317,6 +796,9 @@ func TestStage1CollectResponses(t *testing.T) {
 	if len(results) != 2 {
 		t.Errorf("Expected 2 results, got %d", len(results))
 	}
+	if len(failures) != 0 {
+		t.Errorf("Expected no failures, got %v", failures)
+	}
 
 	// Verify all results have content
 	for _, result := range results {
@@ -326,9 +808,9 @@ func TestStage1CollectResponses(t *testing.T) {
 	}
 }
 
-// TestStage2CollectRankings tests Stage 2 ranking collection
-func TestStage2CollectRankings(t *testing.T) {
-	// Save original config
+// TestStage1CollectResponsesOrder tests that results follow CouncilModels order
+// regardless of which model answers first
+func TestStage1CollectResponsesOrder(t *testing.T) {
 	oldAPIURL := OpenRouterAPIURL
 	oldAPIKey := OpenRouterAPIKey
 	oldModels := CouncilModels
@@ -338,38 +820,196 @@ func TestStage2CollectRankings(t *testing.T) {
 		CouncilModels = oldModels
 	}()
 
-	// Create mock server that returns a ranking
-	mockRankingResponse := `Response A provides good detail.
-Response B is comprehensive.
-
-FINAL RANKING:
-1. Response B
-2. Response A`
-
-	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, mockRankingResponse))
+	// Later models answer sooner; model/d fails
+	delays := map[string]time.Duration{"model/a": 60 * time.Millisecond, "model/b": 40 * time.Millisecond, "model/c": 20 * time.Millisecond, "model/e": 0}
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "model/d" {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(delays[req.Model])
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "Answer from " + req.Model}},
+			},
+		})
+	})
 	defer mockServer.Close()
 
-	// Configure for testing
 	OpenRouterAPIURL = mockServer.URL
 	OpenRouterAPIKey = "test-key"
-	CouncilModels = []string{"test/ranker"}
+	CouncilModels = []string{"model/a", "model/b", "model/c", "model/d", "model/e"}
 
-	// Create stage1 results
-	stage1 := []Stage1Response{
-		{Model: "model/a", Response: "Response from model A"},
-		{Model: "model/b", Response: "Response from model B"},
+	for run := 0; run < 3; run++ {
+		results, _, err := Stage1CollectResponses(context.Background(), "What is Go?")
+		if err != nil {
+			t.Fatalf("Stage1CollectResponses failed: %v", err)
+		}
+
+		var order []string
+		for _, result := range results {
+			order = append(order, result.Model)
+		}
+		if want := []string{"model/a", "model/b", "model/c", "model/e"}; !reflect.DeepEqual(order, want) {
+			t.Fatalf("Run %d: order = %v, want %v", run, order, want)
+		}
+		if labels := BuildLabelToModel(results); labels["Response A"] != "model/a" || labels["Response D"] != "model/e" {
+			t.Errorf("Run %d: labels = %v", run, labels)
+		}
 	}
+}
 
-	// Run Stage 2
-	ctx := context.Background()
-	results, labelToModel, err := Stage2CollectRankings(ctx, "What is Go?", stage1)
+// TestStage1FailureReporting tests that failed models are reported with reasons
+func TestStage1FailureReporting(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldTimeout := ModelQueryTimeout
+	oldChairman := ChairmanModel
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ModelQueryTimeout = oldTimeout
+		ChairmanModel = oldChairman
+	}()
 
-	if err != nil {
-		t.Fatalf("Stage2CollectRankings failed: %v", err)
+	successHandler := CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A")
+	mockHandler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+
+		switch req.Model {
+		case "model/unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("invalid key"))
+		case "model/slow":
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+		case "model/garbled":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("not json"))
+		case "model/thinking":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"choices":[{"message":{"content":"","reasoning_details":[{"type":"reasoning.text","text":"Go is..."}]}}]}`))
+		default:
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			successHandler(w, r)
+		}
 	}
 
-	if len(results) != 1 {
-		t.Errorf("Expected 1 result, got %d", len(results))
+	mockServer := MockOpenRouterServer(t, mockHandler)
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/ok", "model/unauthorized", "model/slow", "model/garbled", "model/thinking"}
+	ChairmanModel = "model/ok"
+	ModelQueryTimeout = 200 * time.Millisecond
+
+	expected := []ModelFailure{
+		{Model: "model/unauthorized", Reason: "http status 401 (Unauthorized)"},
+		{Model: "model/slow", Reason: "timeout"},
+		{Model: "model/garbled", Reason: "parse error: could not decode model response"},
+		{Model: "model/thinking", Reason: "empty response: no content returned"},
+	}
+
+	checkFailures := func(t *testing.T, failures []ModelFailure) {
+		if len(failures) != len(expected) {
+			t.Fatalf("Expected %d failures, got %d: %v", len(expected), len(failures), failures)
+		}
+		for i, want := range expected {
+			if failures[i].Model != want.Model || failures[i].Reason != want.Reason {
+				t.Errorf("Failure[%d] = {%s, %s}, want {%s, %s}",
+					i, failures[i].Model, failures[i].Reason, want.Model, want.Reason)
+			}
+		}
+	}
+
+	t.Run("Stage1CollectResponses reports failures", func(t *testing.T) {
+		results, failures, err := Stage1CollectResponses(context.Background(), "What is Go?")
+		if err != nil {
+			t.Fatalf("Stage1CollectResponses failed: %v", err)
+		}
+		if len(results) != 1 || results[0].Model != "model/ok" {
+			t.Errorf("Expected only model/ok to succeed, got %v", results)
+		}
+		checkFailures(t, failures)
+	})
+
+	t.Run("RunFullCouncil surfaces failures in metadata", func(t *testing.T) {
+		_, _, _, metadata, err := RunFullCouncil(context.Background(), "What is Go?")
+		if err != nil {
+			t.Fatalf("RunFullCouncil failed: %v", err)
+		}
+		checkFailures(t, metadata.FailedModels)
+	})
+
+	t.Run("all models failing wraps upstream errors", func(t *testing.T) {
+		CouncilModels = []string{"model/unauthorized"}
+
+		_, _, _, metadata, err := RunFullCouncil(context.Background(), "What is Go?")
+		if err == nil {
+			t.Fatal("Expected error when all models fail")
+		}
+		if len(metadata.FailedModels) != 1 {
+			t.Errorf("Expected 1 failed model in metadata, got %d", len(metadata.FailedModels))
+		}
+		if councilErrorStatus(err) != http.StatusBadGateway {
+			t.Errorf("Expected auth failure to map to 502, got %d", councilErrorStatus(err))
+		}
+	})
+}
+
+// TestStage2CollectRankings tests Stage 2 ranking collection
+func TestStage2CollectRankings(t *testing.T) {
+	// Save original config
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+	}()
+
+	// Create mock server that returns a ranking
+	mockRankingResponse := `Response A provides good detail.
+Response B is comprehensive.
+
+FINAL RANKING:
+1. Response B
+2. Response A`
+
+	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, mockRankingResponse))
+	defer mockServer.Close()
+
+	// Configure for testing
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"test/ranker"}
+
+	// Create stage1 results
+	stage1 := []Stage1Response{
+		{Model: "model/a", Response: "Response from model A"},
+		{Model: "model/b", Response: "Response from model B"},
+	}
+
+	// Run Stage 2
+	ctx := context.Background()
+	results, labelToModel, err := Stage2CollectRankings(ctx, "What is Go?", stage1)
+
+	if err != nil {
+		t.Fatalf("Stage2CollectRankings failed: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Errorf("Expected 1 result, got %d", len(results))
 	}
 
 	// Check label mapping
@@ -395,192 +1035,1518 @@ FINAL RANKING:
 	}
 }
 
-// TestStage3SynthesizeFinal tests Stage 3 synthesis
-func TestStage3SynthesizeFinal(t *testing.T) {
-	// Save original config
+// TestScrubModelIdentity tests stripping self-identification from Stage 1 responses
+func TestScrubModelIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"leading clause", "As Claude, I think Go is great.", "I think Go is great."},
+		{"clause mid-text", "Go is fast. As GPT-5.1, i would add that it compiles quickly.", "Go is fast. I would add that it compiles quickly."},
+		{"clause with maker", "As an AI assistant developed by OpenAI, I can help.", "I can help."},
+		{"versioned model", "As Claude 3.5 Sonnet, my view is simple.", "My view is simple."},
+		{"statement", "I am Gemini, a large language model trained by Google. Go uses goroutines.", "I am an AI assistant. Go uses goroutines."},
+		{"contraction", "I'm Grok, built by xAI, and here is my answer.", "I am an AI assistant, and here is my answer."},
+		{"maker only", "I was trained by Anthropic to be helpful.", "I was trained to be helpful."},
+		{"models as subject matter", "Models such as Gemini, Claude and GPT differ. OpenAI built GPT.", "Models such as Gemini, Claude and GPT differ. OpenAI built GPT."},
+		{"no self-reference", "Go was designed at Google in 2007.", "Go was designed at Google in 2007."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scrubModelIdentity(tt.in); got != tt.want {
+				t.Errorf("scrubModelIdentity(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStage2CollectRankingsScrubModelIdentity tests that self-references are scrubbed
+// from the ranking prompt only, and only when ScrubModelIdentity is set
+func TestStage2CollectRankingsScrubModelIdentity(t *testing.T) {
 	oldAPIURL := OpenRouterAPIURL
 	oldAPIKey := OpenRouterAPIKey
-	oldChairman := ChairmanModel
+	oldModels := CouncilModels
+	oldScrub := ScrubModelIdentity
 	defer func() {
 		OpenRouterAPIURL = oldAPIURL
 		OpenRouterAPIKey = oldAPIKey
-		ChairmanModel = oldChairman
+		CouncilModels = oldModels
+		ScrubModelIdentity = oldScrub
 	}()
 
-	// Create mock server
-	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, "Go is a statically typed, compiled programming language designed at Google."))
+	var prompt string
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		prompt = req.Messages[0].Content
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A\n2. Response B")(w, r)
+	})
 	defer mockServer.Close()
 
-	// Configure for testing
 	OpenRouterAPIURL = mockServer.URL
 	OpenRouterAPIKey = "test-key"
-	ChairmanModel = "test/chairman"
+	CouncilModels = []string{"test/ranker"}
 
-	// Create stage1 and stage2 data
 	stage1 := []Stage1Response{
-		{Model: "model/a", Response: "Go is a programming language."},
-		{Model: "model/b", Response: "Go was created by Google."},
+		{Model: "anthropic/claude-sonnet-4.5", Response: "As Claude, I think Go is simple."},
+		{Model: "google/gemini-3-pro-preview", Response: "I am Gemini, made by Google. Go has goroutines."},
 	}
 
-	stage2 := []Stage2Ranking{
-		{
-			Model:         "model/a",
-			Ranking:       "FINAL RANKING:\n1. Response B\n2. Response A",
-			ParsedRanking: []string{"Response B", "Response A"},
-		},
+	for _, scrub := range []bool{false, true} {
+		ScrubModelIdentity = scrub
+		if _, _, err := Stage2CollectRankings(context.Background(), "What is Go?", stage1); err != nil {
+			t.Fatalf("Stage2CollectRankings failed: %v", err)
+		}
+
+		leaked := strings.Contains(prompt, "Claude") || strings.Contains(prompt, "Gemini") || strings.Contains(prompt, "Google")
+		if leaked == scrub {
+			t.Errorf("ScrubModelIdentity = %v, ranking prompt:\n%s", scrub, prompt)
+		}
+		if scrub && (!strings.Contains(prompt, "Response A:\nI think Go is simple.") || !strings.Contains(prompt, "Response B:\nI am an AI assistant. Go has goroutines.")) {
+			t.Errorf("Ranking prompt missing scrubbed responses:\n%s", prompt)
+		}
 	}
 
-	// Run Stage 3
-	ctx := context.Background()
-	result, err := Stage3SynthesizeFinal(ctx, "What is Go?", stage1, stage2)
+	// The Stage 1 results themselves are left untouched
+	if stage1[0].Response != "As Claude, I think Go is simple." || stage1[1].Response != "I am Gemini, made by Google. Go has goroutines." {
+		t.Errorf("Stage 1 responses were modified: %+v", stage1)
+	}
+}
+
+// TestRankingResponsesTruncation tests cutting oversized responses for the ranking prompt
+func TestRankingResponsesTruncation(t *testing.T) {
+	oldMaxChars := MaxResponseCharsInRanking
+	oldMaxTokens := MaxRankingPromptTokens
+	defer func() {
+		MaxResponseCharsInRanking = oldMaxChars
+		MaxRankingPromptTokens = oldMaxTokens
+	}()
+
+	stage1 := []Stage1Response{
+		{Model: "model/short", Response: "Go is simple."},
+		{Model: "model/long", Response: strings.Repeat("é", 5000)},
+		{Model: "model/longer", Response: strings.Repeat("x", 20000)},
+	}
+
+	t.Run("per-response limit", func(t *testing.T) {
+		MaxResponseCharsInRanking = 1000
+		MaxRankingPromptTokens = 0
+
+		responses := rankingResponses("What is Go?", stage1)
+		if responses[0] != "Go is simple." {
+			t.Errorf("Short response changed: %q", responses[0])
+		}
+		for i, want := range []string{strings.Repeat("é", 1000), strings.Repeat("x", 1000)} {
+			if responses[i+1] != want+rankingTruncationMarker {
+				t.Errorf("Response %d = %d characters, want %d plus the truncation marker", i+1, utf8.RuneCountInString(responses[i+1]), 1000)
+			}
+		}
+	})
+
+	t.Run("prompt budget shortens proportionally", func(t *testing.T) {
+		MaxResponseCharsInRanking = 0
+		MaxRankingPromptTokens = 2000
+
+		responses := rankingResponses("What is Go?", stage1)
+		long := utf8.RuneCountInString(strings.TrimSuffix(responses[1], rankingTruncationMarker))
+		longer := utf8.RuneCountInString(strings.TrimSuffix(responses[2], rankingTruncationMarker))
+		if !strings.HasSuffix(responses[1], rankingTruncationMarker) || !strings.HasSuffix(responses[2], rankingTruncationMarker) {
+			t.Fatalf("Expected both long responses to be truncated")
+		}
+		if ratio := float64(longer) / float64(long); ratio < 3.9 || ratio > 4.1 {
+			t.Errorf("Truncated lengths %d and %d are not in the original 1:4 proportion", long, longer)
+		}
+
+		var text strings.Builder
+		for i, response := range responses {
+			text.WriteString(fmt.Sprintf("Response %s:\n%s\n\n", labelLetters(i), response))
+		}
+		if tokens := EstimateTokens(buildRankingPrompt("What is Go?", text.String())); tokens > MaxRankingPromptTokens {
+			t.Errorf("Ranking prompt is ~%d tokens, want at most %d", tokens, MaxRankingPromptTokens)
+		}
+	})
+
+	// The Stage 1 results themselves are left untouched
+	if len(stage1[2].Response) != 20000 {
+		t.Errorf("Stage 1 response was modified: %d characters", len(stage1[2].Response))
+	}
+}
+
+// TestStage2CollectRankingsTruncatesResponses tests that oversized responses are cut
+// in the ranking prompt sent to the models
+func TestStage2CollectRankingsTruncatesResponses(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldMaxChars := MaxResponseCharsInRanking
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		MaxResponseCharsInRanking = oldMaxChars
+	}()
+
+	var prompt string
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		prompt = req.Messages[0].Content
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A\n2. Response B")(w, r)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"test/ranker"}
+	MaxResponseCharsInRanking = 100
+
+	stage1 := []Stage1Response{
+		{Model: "model/a", Response: "Short answer."},
+		{Model: "model/b", Response: strings.Repeat("verbose ", 500)},
+	}
+	if _, _, err := Stage2CollectRankings(context.Background(), "What is Go?", stage1); err != nil {
+		t.Fatalf("Stage2CollectRankings failed: %v", err)
+	}
+
+	want := "Response B:\n" + strings.TrimSpace(strings.Repeat("verbose ", 13)[:100]) + rankingTruncationMarker + "\n\n"
+	if !strings.Contains(prompt, "Response A:\nShort answer.\n\n") || !strings.Contains(prompt, want) {
+		t.Errorf("Ranking prompt missing truncated responses:\n%s", prompt)
+	}
+	if len(stage1[1].Response) != 4000 {
+		t.Errorf("Stage 1 response was modified: %d characters", len(stage1[1].Response))
+	}
+}
+
+// TestStage2CollectRankingsRankerModels tests ranking with a different roster from
+// the models that answered
+func TestStage2CollectRankingsRankerModels(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldRankers := RankerModels
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		RankerModels = oldRankers
+	}()
+
+	var mu sync.Mutex
+	var queried []string
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		mu.Lock()
+		queried = append(queried, req.Model)
+		mu.Unlock()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response B\n2. Response A")(w, r)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"strong/a", "strong/b"}
+	RankerModels = []string{"cheap/x", "cheap/y", "cheap/z"}
 
+	stage1 := []Stage1Response{
+		{Model: "strong/a", Response: "Answer A"},
+		{Model: "strong/b", Response: "Answer B"},
+	}
+	rankings, labelToModel, err := Stage2CollectRankings(context.Background(), "What is Go?", stage1)
 	if err != nil {
-		t.Fatalf("Stage3SynthesizeFinal failed: %v", err)
+		t.Fatalf("Stage2CollectRankings failed: %v", err)
 	}
 
-	if result == nil {
-		t.Fatal("Result should not be nil")
+	slices.Sort(queried)
+	if !reflect.DeepEqual(queried, RankerModels) {
+		t.Errorf("Queried %v, want only the ranker models %v", queried, RankerModels)
+	}
+	var rankers []string
+	for _, ranking := range rankings {
+		rankers = append(rankers, ranking.Model)
+	}
+	slices.Sort(rankers)
+	if !reflect.DeepEqual(rankers, RankerModels) {
+		t.Errorf("Rankings from %v, want %v", rankers, RankerModels)
 	}
 
-	if result.Model != ChairmanModel {
-		t.Errorf("Model = %q, want %q", result.Model, ChairmanModel)
+	// Labels still point at the answerers
+	if want := map[string]string{"Response A": "strong/a", "Response B": "strong/b"}; !reflect.DeepEqual(labelToModel, want) {
+		t.Errorf("LabelToModel = %v, want %v", labelToModel, want)
+	}
+	aggregate := CalculateAggregateRankings(rankings, labelToModel)
+	if len(aggregate) != 2 || aggregate[0].Model != "strong/b" || aggregate[0].RankingsCount != 3 {
+		t.Errorf("Aggregate = %+v, want strong/b first with 3 votes", aggregate)
+	}
+
+	// Without separate rankers the council ranks itself
+	RankerModels = nil
+	if got := Rankers(); !reflect.DeepEqual(got, CouncilModels) {
+		t.Errorf("Rankers() = %v, want CouncilModels %v", got, CouncilModels)
+	}
+}
+
+// TestStage2CollectRankingsExcludeSelf tests that with ExcludeSelfRanking no model
+// is asked to rank, or counted as ranking, its own response
+func TestStage2CollectRankingsExcludeSelf(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldRankers := RankerModels
+	oldStructured := StructuredRankingModels
+	oldExclude := ExcludeSelfRanking
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		RankerModels = oldRankers
+		StructuredRankingModels = oldStructured
+		ExcludeSelfRanking = oldExclude
+	}()
+
+	// Every ranker votes for all three labels, including its own if it sees it
+	var mu sync.Mutex
+	requests := make(map[string]OpenRouterRequest)
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		mu.Lock()
+		requests[req.Model] = req
+		mu.Unlock()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A\n2. Response B\n3. Response C")(w, r)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a", "model/b", "model/c"}
+	RankerModels = []string{"model/a", "model/b", "model/c", "judge/x"}
+	StructuredRankingModels = []string{"model/b"}
+	ExcludeSelfRanking = true
+
+	stage1 := []Stage1Response{
+		{Model: "model/a", Response: "Answer from model/a"},
+		{Model: "model/b", Response: "Answer from model/b"},
+		{Model: "model/c", Response: "Answer from model/c"},
+	}
+	rankings, labelToModel, err := Stage2CollectRankings(context.Background(), "What is Go?", stage1)
+	if err != nil {
+		t.Fatalf("Stage2CollectRankings failed: %v", err)
+	}
+	if len(rankings) != 4 {
+		t.Fatalf("Expected 4 rankings, got %d", len(rankings))
+	}
+
+	for ranker, req := range requests {
+		prompt := req.Messages[0].Content
+		for label, model := range labelToModel {
+			shown := strings.Contains(prompt, label+":\nAnswer from "+model)
+			if want := model != ranker; shown != want {
+				t.Errorf("Prompt for %s shows %s = %v, want %v", ranker, label, shown, want)
+			}
+		}
+	}
+
+	// The structured schema only offers the labels the ranker can see
+	format := requests["model/b"].ResponseFormat
+	if format == nil {
+		t.Fatal("Expected model/b to be asked for a structured ranking")
+	}
+	items := format.JSONSchema.Schema["properties"].(map[string]interface{})["ranking"].(map[string]interface{})["items"].(map[string]interface{})
+	if enum := fmt.Sprint(items["enum"]); enum != "[Response A Response C]" {
+		t.Errorf("model/b schema enum = %s, want [Response A Response C]", enum)
+	}
+
+	// Self votes are dropped: each answerer gets the two votes of the other answerers
+	// plus the judge's, with later labels moving up a place
+	aggregate := CalculateAggregateRankings(rankings, labelToModel)
+	got := make(map[string]AggregateRanking)
+	for _, entry := range aggregate {
+		got[entry.Model] = entry
+	}
+	for model, want := range map[string]float64{
+		"model/a": 1,       // 1st from model/b, model/c and the judge
+		"model/b": 5.0 / 3, // 1st from model/a, 2nd from model/c and the judge
+		"model/c": 7.0 / 3, // 2nd from model/a and model/b, 3rd from the judge
+	} {
+		if got[model].RankingsCount != 3 || math.Abs(got[model].AverageRank-want) > 1e-9 {
+			t.Errorf("%s aggregate = %+v, want average %.3f over 3 votes", model, got[model], want)
+		}
+	}
+}
+
+// TestLabelLetters tests label generation for arbitrary response counts
+func TestLabelLetters(t *testing.T) {
+	tests := []struct {
+		index    int
+		expected string
+	}{
+		{0, "A"},
+		{1, "B"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+	}
+
+	for _, tt := range tests {
+		if got := labelLetters(tt.index); got != tt.expected {
+			t.Errorf("labelLetters(%d) = %q, want %q", tt.index, got, tt.expected)
+		}
+	}
+}
+
+// TestStage2CollectRankingsStructured tests requesting JSON rankings from models that
+// support structured output, and falling back to the text parser for malformed JSON
+func TestStage2CollectRankingsStructured(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldStructured := StructuredRankingModels
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		StructuredRankingModels = oldStructured
+	}()
+
+	// json/ranker answers with valid JSON, broken/ranker with malformed JSON that still
+	// has a FINAL RANKING section, and text/ranker isn't asked for JSON at all
+	var mu sync.Mutex
+	formats := make(map[string]*ResponseFormat)
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		formats[req.Model] = req.ResponseFormat
+		mu.Unlock()
+
+		content := map[string]string{
+			"json/ranker":   `{"evaluation": "B is more thorough.", "ranking": ["Response B", "Response A"]}`,
+			"broken/ranker": "{\"ranking\": [\"Response B\"\nFINAL RANKING:\n1. Response A\n2. Response B",
+			"text/ranker":   "FINAL RANKING:\n1. Response A\n2. Response B",
+		}[req.Model]
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": content}},
+			},
+		})
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"json/ranker", "broken/ranker", "text/ranker"}
+	StructuredRankingModels = []string{"json/ranker", "broken/ranker"}
+
+	stage1 := []Stage1Response{
+		{Model: "model/a", Response: "Response from model A"},
+		{Model: "model/b", Response: "Response from model B"},
+	}
+	results, _, err := Stage2CollectRankings(context.Background(), "What is Go?", stage1)
+	if err != nil {
+		t.Fatalf("Stage2CollectRankings failed: %v", err)
+	}
+
+	byModel := make(map[string]Stage2Ranking)
+	for _, result := range results {
+		byModel[result.Model] = result
+	}
+	want := map[string][]string{
+		"json/ranker":   {"Response B", "Response A"},
+		"broken/ranker": {"Response A", "Response B"},
+		"text/ranker":   {"Response A", "Response B"},
+	}
+	for model, ranking := range want {
+		if got := byModel[model].ParsedRanking; !reflect.DeepEqual(got, ranking) {
+			t.Errorf("%s: parsed ranking = %v, want %v", model, got, ranking)
+		}
+	}
+	if got := byModel["json/ranker"].Ranking; got != "B is more thorough.\n\nFINAL RANKING:\n1. Response B\n2. Response A" {
+		t.Errorf("Structured ranking text = %q", got)
+	}
+
+	// Only the structured-output models are sent a JSON schema, listing every label
+	for _, model := range []string{"json/ranker", "broken/ranker"} {
+		format := formats[model]
+		if format == nil || format.Type != "json_schema" || format.JSONSchema == nil {
+			t.Fatalf("%s: response_format = %+v, want a json_schema", model, format)
+		}
+		items := format.JSONSchema.Schema["properties"].(map[string]interface{})["ranking"].(map[string]interface{})["items"].(map[string]interface{})
+		if enum := fmt.Sprint(items["enum"]); enum != "[Response A Response B]" {
+			t.Errorf("%s: ranking label enum = %s", model, enum)
+		}
+	}
+	if formats["text/ranker"] != nil {
+		t.Errorf("text/ranker: expected no response_format, got %+v", formats["text/ranker"])
+	}
+}
+
+// TestParseStructuredRanking tests decoding JSON rankings
+func TestParseStructuredRanking(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		wantEvaluation string
+		wantLabels     []string
+		wantOK         bool
+	}{
+		{
+			name:           "plain JSON",
+			content:        `{"evaluation": "A is best.", "ranking": ["Response A", "Response C", "Response B"]}`,
+			wantEvaluation: "A is best.",
+			wantLabels:     []string{"Response A", "Response C", "Response B"},
+			wantOK:         true,
+		},
+		{
+			name:       "code fence and numeric labels",
+			content:    "```json\n{\"ranking\": [\"Response 2\", \"Response 1\", \"Response 2\"]}\n```",
+			wantLabels: []string{"Response B", "Response A"},
+			wantOK:     true,
+		},
+		{name: "malformed JSON", content: `{"ranking": ["Response A"`},
+		{name: "free text", content: "FINAL RANKING:\n1. Response A"},
+		{name: "no recognizable labels", content: `{"ranking": ["the first one", "the second"]}`},
+		{name: "empty ranking", content: `{"evaluation": "Both fine.", "ranking": []}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluation, labels, ok := parseStructuredRanking(tt.content)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if evaluation != tt.wantEvaluation || !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("parseStructuredRanking = %q, %v; want %q, %v", evaluation, labels, tt.wantEvaluation, tt.wantLabels)
+			}
+		})
+	}
+}
+
+// TestStage2CollectRankingsManyModels tests labeling with more than 26 responses
+func TestStage2CollectRankingsManyModels(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+	}()
+
+	// Ranker puts the labels past Z first
+	mockRankingResponse := `FINAL RANKING:
+1. Response AD
+2. Response AA
+3. Response Z
+4. Response A`
+
+	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, mockRankingResponse))
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"test/ranker"}
+
+	// 30 Stage 1 responses
+	var stage1 []Stage1Response
+	for i := 0; i < 30; i++ {
+		stage1 = append(stage1, Stage1Response{
+			Model:    fmt.Sprintf("model/%02d", i),
+			Response: fmt.Sprintf("Response from model %d", i),
+		})
+	}
+
+	results, labelToModel, err := Stage2CollectRankings(context.Background(), "What is Go?", stage1)
+	if err != nil {
+		t.Fatalf("Stage2CollectRankings failed: %v", err)
+	}
+
+	// Every response gets a unique, well-formed label
+	if len(labelToModel) != 30 {
+		t.Fatalf("Expected 30 label mappings, got %d", len(labelToModel))
+	}
+	labelPattern := regexp.MustCompile(`^Response [A-Z]+$`)
+	models := make(map[string]bool)
+	for label, model := range labelToModel {
+		if !labelPattern.MatchString(label) {
+			t.Errorf("Malformed label %q", label)
+		}
+		models[model] = true
+	}
+	if len(models) != 30 {
+		t.Errorf("Expected 30 distinct models in mapping, got %d", len(models))
+	}
+
+	// Labels past Z parse and map back to the right models
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 ranking, got %d", len(results))
+	}
+	expectedParsed := []string{"Response AD", "Response AA", "Response Z", "Response A"}
+	if !reflect.DeepEqual(results[0].ParsedRanking, expectedParsed) {
+		t.Errorf("ParsedRanking = %v, want %v", results[0].ParsedRanking, expectedParsed)
+	}
+
+	aggregate := CalculateAggregateRankings(results, labelToModel)
+	expectedOrder := []string{"model/29", "model/26", "model/25", "model/00"}
+	if len(aggregate) != len(expectedOrder) {
+		t.Fatalf("Expected %d aggregate rankings, got %d", len(expectedOrder), len(aggregate))
+	}
+	for i, model := range expectedOrder {
+		if aggregate[i].Model != model {
+			t.Errorf("Aggregate[%d] = %q, want %q", i, aggregate[i].Model, model)
+		}
+	}
+}
+
+// TestStage3SynthesizeFinal tests Stage 3 synthesis
+func TestStage3SynthesizeFinal(t *testing.T) {
+	// Save original config
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldChairman := ChairmanModel
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		ChairmanModel = oldChairman
+	}()
+
+	// Create mock server
+	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, "Go is a statically typed, compiled programming language designed at Google."))
+	defer mockServer.Close()
+
+	// Configure for testing
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	ChairmanModel = "test/chairman"
+
+	// Create stage1 and stage2 data
+	stage1 := []Stage1Response{
+		{Model: "model/a", Response: "Go is a programming language."},
+		{Model: "model/b", Response: "Go was created by Google."},
+	}
+
+	stage2 := []Stage2Ranking{
+		{
+			Model:         "model/a",
+			Ranking:       "FINAL RANKING:\n1. Response B\n2. Response A",
+			ParsedRanking: []string{"Response B", "Response A"},
+		},
+	}
+
+	// Run Stage 3
+	ctx := context.Background()
+	result, err := Stage3SynthesizeFinal(ctx, "What is Go?", stage1, stage2)
+
+	if err != nil {
+		t.Fatalf("Stage3SynthesizeFinal failed: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Result should not be nil")
+	}
+
+	if result.Model != ChairmanModel {
+		t.Errorf("Model = %q, want %q", result.Model, ChairmanModel)
+	}
+
+	if result.Response == "" {
+		t.Error("Response should not be empty")
+	}
+}
+
+// TestCouncilTruncatedResponses tests that responses cut off at the output token
+// limit are flagged in Stage 1 and Stage 3 results
+func TestCouncilTruncatedResponses(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+	}()
+
+	// model/b and the chairman run out of tokens mid-answer
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		finishReason := "stop"
+		if req.Model == "model/b" || req.Model == "test/chairman" {
+			finishReason = FinishReasonLength
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":"Go is a"},"finish_reason":%q}]}`, finishReason)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a", "model/b"}
+	ChairmanModel = "test/chairman"
+
+	stage1, _, err := Stage1CollectResponses(context.Background(), "What is Go?")
+	if err != nil {
+		t.Fatalf("Stage1CollectResponses failed: %v", err)
+	}
+	if len(stage1) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(stage1))
+	}
+	if stage1[0].FinishReason != "stop" || stage1[1].FinishReason != FinishReasonLength {
+		t.Errorf("FinishReasons = %q, %q, want %q, %q", stage1[0].FinishReason, stage1[1].FinishReason, "stop", FinishReasonLength)
+	}
+
+	stage3, err := Stage3SynthesizeFinal(context.Background(), "What is Go?", stage1, nil)
+	if err != nil {
+		t.Fatalf("Stage3SynthesizeFinal failed: %v", err)
+	}
+	if stage3.FinishReason != FinishReasonLength {
+		t.Errorf("Stage 3 FinishReason = %q, want %q", stage3.FinishReason, FinishReasonLength)
+	}
+
+	data, _ := json.Marshal(stage1[1])
+	if !strings.Contains(string(data), `"finish_reason":"length"`) {
+		t.Errorf("Expected finish_reason in Stage 1 JSON, got %s", data)
+	}
+}
+
+// TestSetCouncilRosterInFlight tests that a roster update doesn't affect a council
+// run already in progress, and applies to the next run
+func TestSetCouncilRosterInFlight(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+	}()
+
+	started := make(chan struct{})
+	updated := make(chan struct{})
+	var once sync.Once
+	var mu sync.Mutex
+	queried := map[string]bool{}
+	successHandler := CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		mu.Lock()
+		queried[req.Model] = true
+		mu.Unlock()
+
+		// Hold the first run in Stage 1 until the roster has been replaced
+		if req.Model == "model/a" {
+			once.Do(func() { close(started) })
+			<-updated
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		successHandler(w, r)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a", "model/b"}
+	ChairmanModel = "model/chairman"
+
+	type result struct {
+		stage3 Stage3Response
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		_, _, stage3, _, err := RunFullCouncil(context.Background(), "What is Go?")
+		done <- result{stage3, err}
+	}()
+
+	<-started
+	roster := SetCouncilRoster([]string{"model/c"}, "model/new-chairman")
+	close(updated)
+	if !reflect.DeepEqual(roster.CouncilModels, []string{"model/c"}) || roster.ChairmanModel != "model/new-chairman" {
+		t.Errorf("SetCouncilRoster = %+v", roster)
+	}
+
+	first := <-done
+	if first.err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", first.err)
+	}
+	if first.stage3.Model != "model/chairman" {
+		t.Errorf("In-flight run synthesized with %q, want the old chairman", first.stage3.Model)
+	}
+	if queried["model/c"] || queried["model/new-chairman"] {
+		t.Errorf("In-flight run queried models from the new roster: %v", queried)
+	}
+
+	_, _, second, _, err := RunFullCouncil(context.Background(), "What is Go?")
+	if err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", err)
+	}
+	if second.Model != "model/new-chairman" || !queried["model/c"] {
+		t.Errorf("Next run used chairman %q and queried %v, want the new roster", second.Model, queried)
+	}
+}
+
+// TestGenerateConversationTitle tests title generation
+func TestGenerateConversationTitle(t *testing.T) {
+	// Save original config
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+	}()
+
+	// Create mock server
+	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, "Go Programming Language"))
+	defer mockServer.Close()
+
+	// Configure for testing
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	// Generate title
+	ctx := context.Background()
+	title, err := GenerateConversationTitle(ctx, "What is the Go programming language and how does it work?")
+
+	if err != nil {
+		t.Fatalf("GenerateConversationTitle failed: %v", err)
+	}
+
+	if title == "" {
+		t.Error("Title should not be empty")
+	}
+
+	if len(title) > 50 {
+		t.Errorf("Title too long: %d characters (max 50)", len(title))
+	}
+}
+
+// TestGenerateConversationTitleModel tests that titles are requested from TitleModel
+func TestGenerateConversationTitleModel(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldTitleModel := TitleModel
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		TitleModel = oldTitleModel
+	}()
+
+	var requested string
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		requested = req.Model
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		CreateMockOpenRouterHandler(t, "Go Basics")(w, r)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	TitleModel = "openai/gpt-4o-mini"
+
+	if _, err := GenerateConversationTitle(context.Background(), "What is Go?"); err != nil {
+		t.Fatalf("GenerateConversationTitle failed: %v", err)
+	}
+	if requested != "openai/gpt-4o-mini" {
+		t.Errorf("Title requested from %q, want openai/gpt-4o-mini", requested)
+	}
+}
+
+// TestRunFullCouncil tests the complete 3-stage workflow
+func TestRunFullCouncil(t *testing.T) {
+	// This is an integration test covering all stages
+
+	// Save original config
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+	}()
+
+	// Track which stage we're in based on the request
+	requestCount := 0
+	mockHandler := func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+
+		var response string
+		if requestCount <= 2 {
+			// Stage 1 responses
+			response = "This is response " + string(rune('A'+requestCount-1))
+		} else if requestCount <= 4 {
+			// Stage 2 rankings
+			response = "FINAL RANKING:\n1. Response B\n2. Response A"
+		} else {
+			// Stage 3 synthesis
+			response = "Go is a programming language created by Google."
+		}
+
+		apiResponse := OpenRouterAPIResponse{
+			Choices: []struct {
+				Message struct {
+					Content          string      `json:"content"`
+					ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
+				} `json:"message"`
+				FinishReason string `json:"finish_reason"`
+			}{
+				{
+					Message: struct {
+						Content          string      `json:"content"`
+						ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
+					}{
+						Content: response,
+					},
+				},
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(apiResponse)
+	}
+
+	mockServer := MockOpenRouterServer(t, mockHandler)
+	defer mockServer.Close()
+
+	// Configure for testing
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a", "model/b"}
+	ChairmanModel = "model/chairman"
+
+	// Run full council
+	ctx := context.Background()
+	stage1, stage2, stage3, metadata, err := RunFullCouncil(ctx, "What is Go?")
+
+	if err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", err)
+	}
+
+	// Verify Stage 1
+	if len(stage1) != 2 {
+		t.Errorf("Stage1: expected 2 responses, got %d", len(stage1))
+	}
+
+	// Verify Stage 2
+	if len(stage2) != 2 {
+		t.Errorf("Stage2: expected 2 rankings, got %d", len(stage2))
+	}
+
+	// Verify Stage 3
+	if stage3.Response == "" {
+		t.Error("Stage3: response should not be empty")
+	}
+
+	// Verify metadata
+	if len(metadata.LabelToModel) == 0 {
+		t.Error("Metadata: labelToModel should not be empty")
+	}
+	if len(metadata.AggregateRankings) == 0 {
+		t.Error("Metadata: aggregateRankings should not be empty")
+	}
+
+	// Both rankers put Response B first
+	if metadata.ConsensusScore != 1 {
+		t.Errorf("Metadata: consensus score = %v, want 1", metadata.ConsensusScore)
+	}
+	want := &CouncilWinner{Models: []string{metadata.LabelToModel["Response B"]}, AverageRank: 1, Margin: 1}
+	if !reflect.DeepEqual(metadata.Winner, want) {
+		t.Errorf("Metadata: winner = %+v, want %+v", metadata.Winner, want)
+	}
+}
+
+// TestRunFullCouncilMaxResponses tests that only MaxCouncilResponses Stage 1 responses
+// reach the ranking and synthesis prompts
+func TestRunFullCouncilMaxResponses(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	oldMax := MaxCouncilResponses
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+		MaxCouncilResponses = oldMax
+	}()
+
+	var mu sync.Mutex
+	var rankingPrompts []string
+	var chairmanPrompt string
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[len(req.Messages)-1].Content
+
+		var response string
+		mu.Lock()
+		switch {
+		case req.Model == "model/chairman":
+			chairmanPrompt = prompt
+			response = "Synthesis"
+		case strings.HasPrefix(prompt, "You are evaluating"):
+			rankingPrompts = append(rankingPrompts, prompt)
+			response = "FINAL RANKING:\n1. Response A\n2. Response B"
+		default:
+			response = "Answer from " + req.Model
+		}
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": response}},
+			},
+		})
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a", "model/b", "model/c", "model/d"}
+	ChairmanModel = "model/chairman"
+	MaxCouncilResponses = 2
+
+	stage1, stage2, _, metadata, err := RunFullCouncil(context.Background(), "What is Go?")
+	if err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", err)
+	}
+
+	if len(stage1) != 2 || stage1[0].Model != "model/a" || stage1[1].Model != "model/b" {
+		t.Errorf("Stage 1 = %+v, want the first two council models", stage1)
+	}
+	if want := []string{"model/c", "model/d"}; !reflect.DeepEqual(metadata.OmittedModels, want) {
+		t.Errorf("OmittedModels = %v, want %v", metadata.OmittedModels, want)
+	}
+	if want := map[string]string{"Response A": "model/a", "Response B": "model/b"}; !reflect.DeepEqual(metadata.LabelToModel, want) {
+		t.Errorf("LabelToModel = %v, want %v", metadata.LabelToModel, want)
+	}
+
+	// Every council model still ranks, but only the capped responses
+	if len(stage2) != 4 || len(rankingPrompts) != 4 {
+		t.Fatalf("Expected 4 rankings, got %d (%d prompts)", len(stage2), len(rankingPrompts))
+	}
+	for _, prompt := range append(rankingPrompts, chairmanPrompt) {
+		if !strings.Contains(prompt, "Answer from model/a") || !strings.Contains(prompt, "Answer from model/b") {
+			t.Errorf("Prompt missing a kept response:\n%s", prompt)
+		}
+		if strings.Contains(prompt, "Answer from model/c") || strings.Contains(prompt, "Answer from model/d") || strings.Contains(prompt, "Response C:") {
+			t.Errorf("Prompt includes an omitted response:\n%s", prompt)
+		}
+	}
+
+	// No cap by default
+	MaxCouncilResponses = 0
+	stage1, _, _, metadata, err = RunFullCouncil(context.Background(), "What is Go?")
+	if err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", err)
+	}
+	if len(stage1) != 4 || metadata.OmittedModels != nil {
+		t.Errorf("Uncapped run kept %d responses, omitted %v", len(stage1), metadata.OmittedModels)
+	}
+
+	// A roster attached to the run, such as a mode preset, sets the priority order
+	MaxCouncilResponses = 2
+	ctx := WithCouncilConfig(context.Background(), CouncilConfig{
+		CouncilModels: []string{"model/d", "model/c", "model/b"},
+		ChairmanModel: "model/chairman",
+	})
+	stage1, _, _, metadata, err = RunFullCouncil(ctx, "What is Go?")
+	if err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", err)
+	}
+	if len(stage1) != 2 || stage1[0].Model != "model/d" || stage1[1].Model != "model/c" {
+		t.Errorf("Stage 1 = %+v, want the first two models of the attached roster", stage1)
+	}
+	if want := []string{"model/b"}; !reflect.DeepEqual(metadata.OmittedModels, want) {
+		t.Errorf("OmittedModels = %v, want %v", metadata.OmittedModels, want)
+	}
+}
+
+// TestRunFullCouncilRetries tests re-running the council when every model fails
+// in Stage 1, and that partial failures aren't retried
+func TestRunFullCouncilRetries(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	oldRetries := CouncilRunRetries
+	oldBackoff := CouncilRetryBackoff
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+		CouncilRunRetries = oldRetries
+		CouncilRetryBackoff = oldBackoff
+	}()
+
+	// Stage 1 requests fail while failing reports true for the model
+	var mu sync.Mutex
+	var stage1Requests int
+	var failing func(model string) bool
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[len(req.Messages)-1].Content
+
+		response := "Answer from " + req.Model
+		switch {
+		case req.Model == "model/chairman":
+			response = "Synthesis"
+		case strings.HasPrefix(prompt, "You are evaluating"):
+			response = "FINAL RANKING:\n1. Response A"
+		default:
+			mu.Lock()
+			stage1Requests++
+			fail := failing(req.Model)
+			mu.Unlock()
+			if fail {
+				http.Error(w, `{"error":{"message":"upstream unavailable"}}`, http.StatusBadRequest)
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": response}},
+			},
+		})
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/retry-a", "model/retry-b"}
+	ChairmanModel = "model/chairman"
+	CouncilRetryBackoff = 10 * time.Millisecond
+
+	// Every model fails on the first attempt only
+	outage := func() func(string) bool {
+		return func(string) bool { return stage1Requests <= len(CouncilModels) }
+	}
+
+	t.Run("recovers after total failure", func(t *testing.T) {
+		stage1Requests = 0
+		failing = outage()
+		CouncilRunRetries = 2
+
+		stage1, _, stage3, metadata, err := RunFullCouncil(context.Background(), "What is Go?")
+		if err != nil {
+			t.Fatalf("RunFullCouncil failed: %v", err)
+		}
+		if len(stage1) != 2 || stage3.Response != "Synthesis" {
+			t.Errorf("Unexpected result: stage1 %+v, stage3 %+v", stage1, stage3)
+		}
+		if len(metadata.FailedModels) != 0 {
+			t.Errorf("FailedModels = %+v, want only the successful attempt's failures", metadata.FailedModels)
+		}
+		if stage1Requests != 4 {
+			t.Errorf("Stage 1 requests = %d, want 4 (one failed round, one retry)", stage1Requests)
+		}
+	})
+
+	t.Run("no retries by default", func(t *testing.T) {
+		stage1Requests = 0
+		failing = outage()
+		CouncilRunRetries = 0
+
+		if _, _, _, _, err := RunFullCouncil(context.Background(), "What is Go?"); err == nil || !strings.Contains(err.Error(), "all council models failed") {
+			t.Errorf("Expected all models to fail without retries, got %v", err)
+		}
+		if stage1Requests != 2 {
+			t.Errorf("Stage 1 requests = %d, want 2", stage1Requests)
+		}
+	})
+
+	t.Run("partial failure is not retried", func(t *testing.T) {
+		stage1Requests = 0
+		failing = func(model string) bool { return model == "model/retry-b" }
+		CouncilRunRetries = 2
+
+		stage1, _, _, metadata, err := RunFullCouncil(context.Background(), "What is Go?")
+		if err != nil {
+			t.Fatalf("RunFullCouncil failed: %v", err)
+		}
+		if len(stage1) != 1 || len(metadata.FailedModels) != 1 {
+			t.Errorf("Expected one response and one failure, got %+v and %+v", stage1, metadata.FailedModels)
+		}
+		if stage1Requests != 2 {
+			t.Errorf("Stage 1 requests = %d, want 2", stage1Requests)
+		}
+	})
+}
+
+// TestRunFullCouncilLatencies tests that each model's Stage 1 and Stage 2 query
+// time, and each stage's wall-clock duration, is reported in the metadata
+func TestRunFullCouncilLatencies(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+	}()
+
+	// Answers take 50ms from the fast model and 200ms from the slow one; rankings 100ms
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[len(req.Messages)-1].Content
+
+		response := "Answer from " + req.Model
+		switch {
+		case req.Model == "model/chairman":
+			response = "Synthesis"
+		case strings.HasPrefix(prompt, "You are evaluating"):
+			time.Sleep(100 * time.Millisecond)
+			response = "FINAL RANKING:\n1. Response A\n2. Response B"
+		case req.Model == "model/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			time.Sleep(50 * time.Millisecond)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": response}},
+			},
+		})
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/fast", "model/slow"}
+	ChairmanModel = "model/chairman"
+
+	stage1, _, _, metadata, err := RunFullCouncil(context.Background(), "What is Go?")
+	if err != nil {
+		t.Fatalf("RunFullCouncil failed: %v", err)
+	}
+
+	within := func(got, want float64) bool { return got >= want && got < want+0.5 }
+	if got := metadata.ModelLatencies["model/fast"]; !within(got, 0.05) {
+		t.Errorf("model/fast latency = %.3fs, want about 0.05s", got)
+	}
+	if got := metadata.ModelLatencies["model/slow"]; !within(got, 0.2) {
+		t.Errorf("model/slow latency = %.3fs, want about 0.2s", got)
+	}
+	if len(metadata.RankingLatencies) != 2 {
+		t.Fatalf("RankingLatencies = %v, want one per ranker", metadata.RankingLatencies)
+	}
+	for model, got := range metadata.RankingLatencies {
+		if !within(got, 0.1) {
+			t.Errorf("%s ranking latency = %.3fs, want about 0.1s", model, got)
+		}
+	}
+
+	// Each stage lasts as long as its slowest model, and the run as long as its stages
+	if !within(metadata.Stage1Duration, 0.2) {
+		t.Errorf("Stage 1 duration = %.3fs, want about 0.2s", metadata.Stage1Duration)
+	}
+	if !within(metadata.Stage2Duration, 0.1) {
+		t.Errorf("Stage 2 duration = %.3fs, want about 0.1s", metadata.Stage2Duration)
+	}
+	if metadata.Stage3Duration <= 0 || metadata.Stage3Duration >= metadata.Stage1Duration {
+		t.Errorf("Stage 3 duration = %.3fs, want a quick synthesis", metadata.Stage3Duration)
+	}
+	stages := metadata.Stage1Duration + metadata.Stage2Duration + metadata.Stage3Duration
+	if metadata.TotalDuration < stages-1e-6 || metadata.TotalDuration > stages+0.05 {
+		t.Errorf("Total duration = %.3fs, want the sum of the stages (%.3fs)", metadata.TotalDuration, stages)
+	}
+
+	// Latencies are reported in the metadata, not stored with each response
+	data, _ := json.Marshal(stage1)
+	if strings.Contains(string(data), "latency") {
+		t.Errorf("Stage 1 JSON includes latency: %s", data)
+	}
+	data, _ = json.Marshal(metadata)
+	for _, field := range []string{`"model_latencies"`, `"ranking_latencies"`, `"total_duration"`, `"stage3_duration"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("Metadata JSON missing %s: %s", field, data)
+		}
+	}
+}
+
+// TestRunFullCouncilTimeout tests that the overall deadline aborts a slow council run
+func TestRunFullCouncilTimeout(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldTimeout := CouncilTimeout
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		CouncilTimeout = oldTimeout
+	}()
+
+	// Stage 1 answers immediately; every later request hangs until abandoned
+	var mu sync.Mutex
+	requestCount := 0
+	fast := CreateMockOpenRouterHandler(t, "A quick answer")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestCount++
+		n := requestCount
+		mu.Unlock()
+		if n <= 2 {
+			fast(w, r)
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a", "model/b"}
+	CouncilTimeout = 200 * time.Millisecond
+
+	start := time.Now()
+	stage1, stage2, stage3, _, err := RunFullCouncil(context.Background(), "What is Go?")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrCouncilTimeout) {
+		t.Fatalf("Expected ErrCouncilTimeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "stage 2") {
+		t.Errorf("Error %q should name the stage that timed out", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("RunFullCouncil took %v, want it to stop near the %v deadline", elapsed, CouncilTimeout)
+	}
+
+	// Completed stages are still returned
+	if len(stage1) != 2 {
+		t.Errorf("Stage1: expected 2 completed responses, got %d", len(stage1))
+	}
+	if len(stage2) != 0 || stage3.Response != "" {
+		t.Errorf("Expected no Stage 2/3 results, got %d rankings and %q", len(stage2), stage3.Response)
+	}
+
+	// A caller cancelling is not reported as a council timeout
+	CouncilTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, _, _, err := RunFullCouncil(ctx, "What is Go?"); errors.Is(err, ErrCouncilTimeout) {
+		t.Errorf("Caller cancellation should not be reported as ErrCouncilTimeout: %v", err)
+	}
+}
+
+// TestCouncilMode tests looking up council presets and applying their run settings
+func TestCouncilMode(t *testing.T) {
+	oldModes, oldModels, oldChairman := CouncilModes, CouncilModels, ChairmanModel
+	oldQueryTimeout, oldCouncilTimeout := ModelQueryTimeout, CouncilTimeout
+	defer func() {
+		CouncilModes, CouncilModels, ChairmanModel = oldModes, oldModels, oldChairman
+		ModelQueryTimeout, CouncilTimeout = oldQueryTimeout, oldCouncilTimeout
+	}()
+	CouncilModels = []string{"configured/one"}
+	ChairmanModel = "configured/chair"
+	ModelQueryTimeout = time.Minute
+	CouncilTimeout = 5 * time.Minute
+
+	for _, name := range CouncilModeNames() {
+		mode, err := CouncilMode(name)
+		if err != nil {
+			t.Fatalf("CouncilMode(%q) failed: %v", name, err)
+		}
+		if err := mode.Validate(); err != nil {
+			t.Errorf("Preset %q is invalid: %v", name, err)
+		}
+		if mode.ReasoningEffort != "" && !validReasoningEffort(mode.ReasoningEffort) {
+			t.Errorf("Preset %q has reasoning effort %q", name, mode.ReasoningEffort)
+		}
+	}
+	if !reflect.DeepEqual(CouncilModeNames(), []string{"balanced", "fast", "thorough"}) {
+		t.Errorf("CouncilModeNames() = %v", CouncilModeNames())
+	}
+
+	CouncilModes = map[string]CouncilConfig{
+		"quick":   {CouncilModels: []string{"quick/one"}, ChairmanModel: "quick/chair", ModelQueryTimeout: 10 * time.Second, CouncilTimeout: 30 * time.Second, ReasoningEffort: "low"},
+		"default": {},
+	}
+
+	// A preset without a roster runs the configured one with the configured settings
+	mode, err := CouncilMode("default")
+	if err != nil {
+		t.Fatalf("CouncilMode(default) failed: %v", err)
+	}
+	if !reflect.DeepEqual(mode.CouncilModels, CouncilModels) || mode.ChairmanModel != ChairmanModel {
+		t.Errorf("default mode roster = %+v, want the configured roster", mode)
+	}
+	if opts := mode.queryOptions("high"); opts.Timeout != time.Minute || opts.ReasoningEffort != "high" {
+		t.Errorf("default mode query options = %+v", opts)
+	}
+	if mode.councilTimeout() != 5*time.Minute {
+		t.Errorf("default mode council timeout = %v", mode.councilTimeout())
+	}
+
+	mode, _ = CouncilMode("quick")
+	if opts := mode.queryOptions("high"); opts.Timeout != 10*time.Second || opts.ReasoningEffort != "low" {
+		t.Errorf("quick mode query options = %+v", opts)
+	}
+	if mode.councilTimeout() != 30*time.Second {
+		t.Errorf("quick mode council timeout = %v", mode.councilTimeout())
 	}
 
-	if result.Response == "" {
-		t.Error("Response should not be empty")
+	if _, err := CouncilMode("turbo"); !errors.Is(err, ErrUnknownCouncilMode) {
+		t.Errorf("CouncilMode(turbo) error = %v, want ErrUnknownCouncilMode", err)
 	}
 }
 
-// TestGenerateConversationTitle tests title generation
-func TestGenerateConversationTitle(t *testing.T) {
-	// Save original config
-	oldAPIURL := OpenRouterAPIURL
-	oldAPIKey := OpenRouterAPIKey
+// TestRunFullCouncilModeTimeout tests that a council mode's deadline replaces CouncilTimeout
+func TestRunFullCouncilModeTimeout(t *testing.T) {
+	oldAPIURL, oldAPIKey, oldTimeout := OpenRouterAPIURL, OpenRouterAPIKey, CouncilTimeout
 	defer func() {
-		OpenRouterAPIURL = oldAPIURL
-		OpenRouterAPIKey = oldAPIKey
+		OpenRouterAPIURL, OpenRouterAPIKey, CouncilTimeout = oldAPIURL, oldAPIKey, oldTimeout
 	}()
 
-	// Create mock server
-	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, "Go Programming Language"))
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
 	defer mockServer.Close()
-
-	// Configure for testing
 	OpenRouterAPIURL = mockServer.URL
 	OpenRouterAPIKey = "test-key"
-
-	// Generate title
-	ctx := context.Background()
-	title, err := GenerateConversationTitle(ctx, "What is the Go programming language and how does it work?")
-
-	if err != nil {
-		t.Fatalf("GenerateConversationTitle failed: %v", err)
-	}
-
-	if title == "" {
-		t.Error("Title should not be empty")
+	CouncilTimeout = time.Minute
+
+	ctx := WithCouncilConfig(context.Background(), CouncilConfig{
+		CouncilModels:  []string{"model/a"},
+		ChairmanModel:  "model/chair",
+		CouncilTimeout: 100 * time.Millisecond,
+	})
+	start := time.Now()
+	_, _, _, _, err := RunFullCouncil(ctx, "What is Go?")
+	if !errors.Is(err, ErrCouncilTimeout) || !strings.Contains(err.Error(), "after 100ms") {
+		t.Errorf("Expected the mode's 100ms deadline to expire, got %v", err)
 	}
-
-	if len(title) > 50 {
-		t.Errorf("Title too long: %d characters (max 50)", len(title))
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("RunFullCouncil took %v, want it to stop near the mode's deadline", elapsed)
 	}
 }
 
-// TestRunFullCouncil tests the complete 3-stage workflow
-func TestRunFullCouncil(t *testing.T) {
-	// This is an integration test covering all stages
-
-	// Save original config
+// TestSystemPrompts tests that configured system prompts are sent ahead of the user message
+func TestSystemPrompts(t *testing.T) {
 	oldAPIURL := OpenRouterAPIURL
 	oldAPIKey := OpenRouterAPIKey
 	oldModels := CouncilModels
 	oldChairman := ChairmanModel
+	oldCouncilPrompt := CouncilSystemPrompt
+	oldChairmanPrompt := ChairmanSystemPrompt
 	defer func() {
 		OpenRouterAPIURL = oldAPIURL
 		OpenRouterAPIKey = oldAPIKey
 		CouncilModels = oldModels
 		ChairmanModel = oldChairman
+		CouncilSystemPrompt = oldCouncilPrompt
+		ChairmanSystemPrompt = oldChairmanPrompt
 	}()
 
-	// Track which stage we're in based on the request
-	requestCount := 0
-	mockHandler := func(w http.ResponseWriter, r *http.Request) {
-		requestCount++
-
-		var response string
-		if requestCount <= 2 {
-			// Stage 1 responses
-			response = "This is response " + string(rune('A'+requestCount-1))
-		} else if requestCount <= 4 {
-			// Stage 2 rankings
-			response = "FINAL RANKING:\n1. Response B\n2. Response A"
-		} else {
-			// Stage 3 synthesis
-			response = "Go is a programming language created by Google."
-		}
-
-		apiResponse := OpenRouterAPIResponse{
-			Choices: []struct {
-				Message struct {
-					Content          string      `json:"content"`
-					ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
-				} `json:"message"`
-			}{
-				{
-					Message: struct {
-						Content          string      `json:"content"`
-						ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
-					}{
-						Content: response,
-					},
-				},
-			},
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(apiResponse)
-	}
-
-	mockServer := MockOpenRouterServer(t, mockHandler)
+	// Record every request payload, keyed by model
+	var mu sync.Mutex
+	payloads := make(map[string]OpenRouterRequest)
+	respond := CreateMockOpenRouterHandler(t, "An answer")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		mu.Lock()
+		payloads[req.Model] = req
+		mu.Unlock()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		respond(w, r)
+	})
 	defer mockServer.Close()
 
-	// Configure for testing
 	OpenRouterAPIURL = mockServer.URL
 	OpenRouterAPIKey = "test-key"
-	CouncilModels = []string{"model/a", "model/b"}
-	ChairmanModel = "model/chairman"
-
-	// Run full council
+	CouncilModels = []string{"model/a"}
+	ChairmanModel = "test/chairman"
 	ctx := context.Background()
-	stage1, stage2, stage3, metadata, err := RunFullCouncil(ctx, "What is Go?")
 
-	if err != nil {
-		t.Fatalf("RunFullCouncil failed: %v", err)
-	}
+	t.Run("prompts set", func(t *testing.T) {
+		CouncilSystemPrompt = "You are a careful, citation-focused assistant."
+		ChairmanSystemPrompt = "You are a neutral chairman."
 
-	// Verify Stage 1
-	if len(stage1) != 2 {
-		t.Errorf("Stage1: expected 2 responses, got %d", len(stage1))
-	}
+		if _, _, err := Stage1CollectResponses(ctx, "What is Go?"); err != nil {
+			t.Fatalf("Stage1CollectResponses failed: %v", err)
+		}
+		if _, err := Stage3SynthesizeFinal(ctx, "What is Go?", []Stage1Response{{Model: "model/a", Response: "A language."}}, nil); err != nil {
+			t.Fatalf("Stage3SynthesizeFinal failed: %v", err)
+		}
 
-	// Verify Stage 2
-	if len(stage2) != 2 {
-		t.Errorf("Stage2: expected 2 rankings, got %d", len(stage2))
-	}
+		want := map[string]string{
+			"model/a":       CouncilSystemPrompt,
+			"test/chairman": ChairmanSystemPrompt,
+		}
+		for model, prompt := range want {
+			messages := payloads[model].Messages
+			if len(messages) != 2 {
+				t.Fatalf("%s: expected 2 messages, got %d", model, len(messages))
+			}
+			if messages[0].Role != "system" || messages[0].Content != prompt {
+				t.Errorf("%s: first message = %+v, want system %q", model, messages[0], prompt)
+			}
+			if messages[1].Role != "user" {
+				t.Errorf("%s: second message role = %q, want user", model, messages[1].Role)
+			}
+		}
+	})
 
-	// Verify Stage 3
-	if stage3.Response == "" {
-		t.Error("Stage3: response should not be empty")
-	}
+	t.Run("prompts unset", func(t *testing.T) {
+		CouncilSystemPrompt = ""
+		ChairmanSystemPrompt = ""
 
-	// Verify metadata
-	if len(metadata.LabelToModel) == 0 {
-		t.Error("Metadata: labelToModel should not be empty")
-	}
-	if len(metadata.AggregateRankings) == 0 {
-		t.Error("Metadata: aggregateRankings should not be empty")
-	}
+		if _, _, err := Stage1CollectResponses(ctx, "What is Go?"); err != nil {
+			t.Fatalf("Stage1CollectResponses failed: %v", err)
+		}
+		if _, err := Stage3SynthesizeFinal(ctx, "What is Go?", []Stage1Response{{Model: "model/a", Response: "A language."}}, nil); err != nil {
+			t.Fatalf("Stage3SynthesizeFinal failed: %v", err)
+		}
+
+		for _, model := range []string{"model/a", "test/chairman"} {
+			messages := payloads[model].Messages
+			if len(messages) != 1 || messages[0].Role != "user" {
+				t.Errorf("%s: expected only a user message, got %+v", model, messages)
+			}
+		}
+	})
 }
 
 // TestStage3WithChairmanError tests error handling in stage 3
@@ -617,6 +2583,105 @@ func TestStage3WithChairmanError(t *testing.T) {
 	}
 }
 
+// TestStage3ChairmanFallback tests falling back to alternate chairmen
+func TestStage3ChairmanFallback(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldChairman := ChairmanModel
+	oldFallbacks := ChairmanFallbacks
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		ChairmanModel = oldChairman
+		ChairmanFallbacks = oldFallbacks
+	}()
+
+	// Fail the primary chairman, succeed for everything else
+	var requestedModels []string
+	successHandler := CreateMockOpenRouterHandler(t, "Fallback synthesis")
+	mockHandler := func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		requestedModels = append(requestedModels, req.Model)
+
+		if req.Model == "test/primary" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		successHandler(w, r)
+	}
+
+	mockServer := MockOpenRouterServer(t, mockHandler)
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	stage1 := []Stage1Response{{Model: "model/a", Response: "Test"}}
+	stage2 := []Stage2Ranking{{Model: "model/a", Ranking: "FINAL RANKING:\n1. Response A", ParsedRanking: []string{"Response A"}}}
+
+	t.Run("fallback chairman used when primary fails", func(t *testing.T) {
+		requestedModels = nil
+		ChairmanModel = "test/primary"
+		ChairmanFallbacks = []string{"test/fallback1", "test/fallback2"}
+
+		result, err := Stage3SynthesizeFinal(context.Background(), "Test", stage1, stage2)
+		if err != nil {
+			t.Fatalf("Stage3SynthesizeFinal failed: %v", err)
+		}
+
+		if result.Model != "test/fallback1" {
+			t.Errorf("Model = %q, want 'test/fallback1'", result.Model)
+		}
+		if !result.UsedFallback {
+			t.Error("UsedFallback should be true")
+		}
+		if result.Response != "Fallback synthesis" {
+			t.Errorf("Response = %q, want 'Fallback synthesis'", result.Response)
+		}
+
+		// Second fallback should never be tried
+		expected := []string{"test/primary", "test/fallback1"}
+		if !reflect.DeepEqual(requestedModels, expected) {
+			t.Errorf("Requested models = %v, want %v", requestedModels, expected)
+		}
+	})
+
+	t.Run("primary chairman succeeds without fallback", func(t *testing.T) {
+		requestedModels = nil
+		ChairmanModel = "test/healthy"
+		ChairmanFallbacks = []string{"test/fallback1"}
+
+		result, err := Stage3SynthesizeFinal(context.Background(), "Test", stage1, stage2)
+		if err != nil {
+			t.Fatalf("Stage3SynthesizeFinal failed: %v", err)
+		}
+
+		if result.Model != "test/healthy" {
+			t.Errorf("Model = %q, want 'test/healthy'", result.Model)
+		}
+		if result.UsedFallback {
+			t.Error("UsedFallback should be false")
+		}
+	})
+
+	t.Run("all chairmen fail", func(t *testing.T) {
+		requestedModels = nil
+		ChairmanModel = "test/primary"
+		ChairmanFallbacks = []string{}
+
+		result, err := Stage3SynthesizeFinal(context.Background(), "Test", stage1, stage2)
+		if err == nil {
+			t.Error("Expected error when all chairmen fail, got nil")
+		}
+		if result != nil {
+			t.Errorf("Expected nil result on error, got: %v", result)
+		}
+	})
+}
+
 // TestGenerateConversationTitleError tests error handling in title generation
 func TestGenerateConversationTitleError(t *testing.T) {
 	oldAPIURL := OpenRouterAPIURL
@@ -679,6 +2744,50 @@ func TestGenerateConversationTitleTruncation(t *testing.T) {
 	}
 }
 
+// TestGenerateConversationTitleTruncationUTF8 tests that truncation never splits a multibyte character
+func TestGenerateConversationTitleTruncationUTF8(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+	}()
+
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"short CJK title kept whole", strings.Repeat("議", 50), strings.Repeat("議", 50)},
+		{"long CJK title", strings.Repeat("議会", 30), strings.Repeat("議会", 23) + "議..."},
+		{"long emoji title", "Climate 🌍 " + strings.Repeat("🔥", 60), "Climate 🌍 " + strings.Repeat("🔥", 37) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, tt.title))
+			defer mockServer.Close()
+
+			OpenRouterAPIURL = mockServer.URL
+			OpenRouterAPIKey = "test-key"
+
+			title, err := GenerateConversationTitle(context.Background(), "Test")
+			if err != nil {
+				t.Fatalf("GenerateConversationTitle failed: %v", err)
+			}
+			if !utf8.ValidString(title) {
+				t.Errorf("Title is not valid UTF-8: %q", title)
+			}
+			if title != tt.want {
+				t.Errorf("Title = %q, want %q", title, tt.want)
+			}
+			if n := utf8.RuneCountInString(title); n > 50 {
+				t.Errorf("Title has %d characters, want at most 50", n)
+			}
+		})
+	}
+}
+
 // TestGenerateConversationTitleQuoteRemoval tests quote removal from title
 func TestGenerateConversationTitleQuoteRemoval(t *testing.T) {
 	oldAPIURL := OpenRouterAPIURL
@@ -706,3 +2815,181 @@ func TestGenerateConversationTitleQuoteRemoval(t *testing.T) {
 		t.Errorf("Quotes not removed: %s", title)
 	}
 }
+
+// TestReasoningEffort tests per-stage reasoning effort and that returned reasoning
+// details are surfaced in the Stage 1 and Stage 3 results
+func TestReasoningEffort(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	oldEfforts := []string{CouncilReasoningEffort, RankingReasoningEffort, ChairmanReasoningEffort}
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+		CouncilReasoningEffort, RankingReasoningEffort, ChairmanReasoningEffort = oldEfforts[0], oldEfforts[1], oldEfforts[2]
+	}()
+
+	// Record the requested effort per call and echo it back as the reasoning details
+	var mu sync.Mutex
+	var efforts []string
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		effort := ""
+		if req.Reasoning != nil {
+			effort = req.Reasoning.Effort
+		}
+		mu.Lock()
+		efforts = append(efforts, effort)
+		mu.Unlock()
+
+		details := []map[string]string{{"type": "reasoning.text", "text": "thought at " + effort}}
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			chunk, _ := json.Marshal(map[string]interface{}{
+				"choices": []map[string]interface{}{
+					{"delta": map[string]interface{}{"content": "Answer", "reasoning_details": details}},
+				},
+			})
+			fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", chunk)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{"content": "FINAL RANKING:\n1. Response A", "reasoning_details": details}},
+			},
+		})
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a"}
+	ChairmanModel = "test/chairman"
+	CouncilReasoningEffort = "medium"
+	RankingReasoningEffort = "low"
+	ChairmanReasoningEffort = "high"
+	ctx := context.Background()
+
+	takeEffort := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(efforts) != 1 {
+			t.Fatalf("Expected 1 request, got %d", len(efforts))
+		}
+		effort := efforts[0]
+		efforts = nil
+		return effort
+	}
+
+	stage1, _, err := Stage1CollectResponses(ctx, "What is Go?")
+	if err != nil || len(stage1) != 1 {
+		t.Fatalf("Stage1CollectResponses = %v, %v", stage1, err)
+	}
+	if effort := takeEffort(); effort != "medium" {
+		t.Errorf("Stage 1 effort = %q, want medium", effort)
+	}
+	if stage1[0].ReasoningDetails == nil {
+		t.Error("Expected Stage 1 reasoning details to be surfaced")
+	}
+
+	if _, _, err := Stage2CollectRankings(ctx, "What is Go?", stage1); err != nil {
+		t.Fatalf("Stage2CollectRankings failed: %v", err)
+	}
+	if effort := takeEffort(); effort != "low" {
+		t.Errorf("Stage 2 effort = %q, want low", effort)
+	}
+
+	stage3, err := Stage3SynthesizeFinal(ctx, "What is Go?", stage1, nil)
+	if err != nil {
+		t.Fatalf("Stage3SynthesizeFinal failed: %v", err)
+	}
+	if effort := takeEffort(); effort != "high" {
+		t.Errorf("Stage 3 effort = %q, want high", effort)
+	}
+	if stage3.ReasoningDetails == nil {
+		t.Error("Expected Stage 3 reasoning details to be surfaced")
+	}
+
+	stage3, err = Stage3SynthesizeFinalStream(ctx, "What is Go?", stage1, nil, nil)
+	if err != nil {
+		t.Fatalf("Stage3SynthesizeFinalStream failed: %v", err)
+	}
+	if effort := takeEffort(); effort != "high" {
+		t.Errorf("Streamed Stage 3 effort = %q, want high", effort)
+	}
+	if stage3.ReasoningDetails == nil {
+		t.Error("Expected streamed Stage 3 reasoning details to be surfaced")
+	}
+
+	// No effort configured leaves the reasoning field out of the request entirely
+	var raw map[string]interface{}
+	payload, _ := json.Marshal(OpenRouterRequest{Model: "model/a", Reasoning: QueryOptions{}.reasoningConfig()})
+	json.Unmarshal(payload, &raw)
+	if _, ok := raw["reasoning"]; ok {
+		t.Errorf("Expected no reasoning field without an effort, got %s", payload)
+	}
+}
+
+// TestStage1CollectResponsesImages tests sending images to the council, and retrying
+// models that reject images with the text alone
+func TestStage1CollectResponsesImages(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+	}()
+
+	// text/model has no provider that accepts images
+	var mu sync.Mutex
+	received := make(map[string][]OpenRouterMessage)
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		received[req.Model] = append(received[req.Model], req.Messages[0])
+		mu.Unlock()
+
+		if req.Model == "text/model" && len(req.Messages[0].Parts) > 0 {
+			http.Error(w, `{"error":{"message":"No endpoints found that support image input"}}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "A rising line"}},
+			},
+		})
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"vision/model", "text/model"}
+
+	results, failures, err := Stage1CollectResponses(context.Background(), "What does this chart show?", "https://example.com/chart.png")
+	if err != nil {
+		t.Fatalf("Stage1CollectResponses failed: %v", err)
+	}
+	if len(results) != 2 || len(failures) != 0 {
+		t.Fatalf("Expected both models to answer, got %d results and failures %+v", len(results), failures)
+	}
+
+	vision := received["vision/model"]
+	if len(vision) != 1 || len(vision[0].Parts) != 2 || vision[0].Parts[1].ImageURL.URL != "https://example.com/chart.png" {
+		t.Errorf("vision/model received %+v, want the question and image as content parts", vision)
+	}
+
+	text := received["text/model"]
+	if len(text) != 2 {
+		t.Fatalf("Expected text/model to be retried once, got %d requests", len(text))
+	}
+	if text[1].Parts != nil || !strings.Contains(text[1].Content, "1 image(s) that you are unable to view") {
+		t.Errorf("Retry message = %+v, want text only with a note about the image", text[1])
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken approximates how many characters a typical BPE tokenizer packs
+// into one token for English text.
+const charsPerToken = 4
+
+// tokensPerMessage approximates the per-message overhead (role markers and
+// separators) added by chat completion APIs.
+const tokensPerMessage = 4
+
+// EstimateTokens approximates the number of tokens in text without calling a
+// tokenizer: roughly one token per four characters, but never fewer than one
+// per word.
+func EstimateTokens(text string) int {
+	if strings.TrimSpace(text) == "" {
+		return 0
+	}
+	byChars := (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+	byWords := len(strings.Fields(text))
+	return max(byChars, byWords)
+}
+
+// estimateMessagesTokens approximates the prompt tokens for a chat request.
+func estimateMessagesTokens(messages []OpenRouterMessage) int {
+	total := 0
+	for _, msg := range messages {
+		total += tokensPerMessage + EstimateTokens(msg.Content)
+	}
+	return total
+}
+
+// EstimateStage1 projects the Stage 1 prompt size and cost for each model of the
+// council in ctx without querying any of them. Costs are only set for models listed
+// in ModelPromptPricing.
+func EstimateStage1(ctx context.Context, userQuery string) EstimateResponse {
+	promptTokens := estimateMessagesTokens(buildStage1Messages(userQuery))
+
+	var response EstimateResponse
+	for _, model := range councilConfig(ctx).CouncilModels {
+		estimate := TokenEstimate{Model: model, PromptTokens: promptTokens}
+		if price, ok := ModelPromptPricing[model]; ok {
+			cost := float64(promptTokens) * price / 1_000_000
+			estimate.EstimatedCostUSD = &cost
+			if response.TotalCostUSD == nil {
+				response.TotalCostUSD = new(float64)
+			}
+			*response.TotalCostUSD += cost
+		}
+		response.Models = append(response.Models, estimate)
+		response.TotalPromptTokens += promptTokens
+	}
+	return response
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestEstimateTokens tests the tokenizer approximation
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"whitespace only", "  \n\t", 0},
+		{"single short word", "Go", 1},
+		{"characters dominate", "internationalization", 5},
+		{"words dominate", "a b c d e f", 6},
+		{"sentence", "What is the Go programming language?", 9},
+		{"multibyte counted by rune", strings.Repeat("é", 8), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEstimateStage1 tests per-model projections and optional pricing
+func TestEstimateStage1(t *testing.T) {
+	oldModels := CouncilModels
+	oldPricing := ModelPromptPricing
+	defer func() {
+		CouncilModels = oldModels
+		ModelPromptPricing = oldPricing
+	}()
+
+	CouncilModels = []string{"model/a", "model/b"}
+	query := strings.Repeat("word ", 100) // 500 chars -> 125 tokens
+	wantTokens := 125 + tokensPerMessage
+
+	t.Run("without pricing", func(t *testing.T) {
+		ModelPromptPricing = map[string]float64{}
+		estimate := EstimateStage1(context.Background(), query)
+
+		if len(estimate.Models) != 2 {
+			t.Fatalf("Expected 2 model estimates, got %d", len(estimate.Models))
+		}
+		for i, model := range CouncilModels {
+			if estimate.Models[i].Model != model || estimate.Models[i].PromptTokens != wantTokens {
+				t.Errorf("Models[%d] = %+v, want %s with %d tokens", i, estimate.Models[i], model, wantTokens)
+			}
+			if estimate.Models[i].EstimatedCostUSD != nil {
+				t.Errorf("Models[%d] should have no cost without pricing", i)
+			}
+		}
+		if estimate.TotalPromptTokens != 2*wantTokens {
+			t.Errorf("TotalPromptTokens = %d, want %d", estimate.TotalPromptTokens, 2*wantTokens)
+		}
+		if estimate.TotalCostUSD != nil {
+			t.Error("TotalCostUSD should be omitted without pricing")
+		}
+	})
+
+	t.Run("with partial pricing", func(t *testing.T) {
+		ModelPromptPricing = map[string]float64{"model/a": 2.0}
+		estimate := EstimateStage1(context.Background(), query)
+
+		wantCost := float64(wantTokens) * 2.0 / 1_000_000
+		cost := estimate.Models[0].EstimatedCostUSD
+		if cost == nil || math.Abs(*cost-wantCost) > 1e-12 {
+			t.Errorf("model/a cost = %v, want %v", cost, wantCost)
+		}
+		if estimate.Models[1].EstimatedCostUSD != nil {
+			t.Error("model/b should have no cost")
+		}
+		if estimate.TotalCostUSD == nil || math.Abs(*estimate.TotalCostUSD-wantCost) > 1e-12 {
+			t.Errorf("TotalCostUSD = %v, want %v", estimate.TotalCostUSD, wantCost)
+		}
+	})
+}
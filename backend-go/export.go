@@ -0,0 +1,131 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RenderConversationMarkdown renders a conversation as a readable Markdown document.
+// Each user question becomes a section header, followed by the Stage 1 responses
+// under their model names, a rankings table built from the Stage 2 peer reviews,
+// and the chairman's Stage 3 synthesis.
+func RenderConversationMarkdown(conv *Conversation) string {
+	var md strings.Builder
+
+	md.WriteString(fmt.Sprintf("# %s\n\n", conv.Title))
+	md.WriteString(fmt.Sprintf("_Created %s_\n\n", conv.CreatedAt.Format("2 January 2006 15:04 MST")))
+
+	for _, msg := range conv.Messages {
+		switch msg.Role {
+		case "user":
+			md.WriteString(fmt.Sprintf("## %s\n\n", singleLine(msg.Content)))
+		case "assistant":
+			renderAssistantMarkdown(&md, msg)
+		}
+	}
+
+	return md.String()
+}
+
+// renderAssistantMarkdown writes the three council stages of an assistant message
+func renderAssistantMarkdown(md *strings.Builder, msg Message) {
+	// Stage 1: Individual responses
+	if len(msg.Stage1) > 0 {
+		md.WriteString("### Stage 1: Individual Responses\n\n")
+		for _, result := range msg.Stage1 {
+			md.WriteString(fmt.Sprintf("#### %s\n\n%s\n\n", result.Model, strings.TrimSpace(result.Response)))
+		}
+	}
+
+	// Stage 2: Rankings (labels are rebuilt from Stage 1 order, since metadata isn't stored)
+	if len(msg.Stage2) > 0 {
+		labelToModel := BuildLabelToModel(msg.Stage1)
+
+		md.WriteString("### Stage 2: Peer Rankings\n\n")
+		md.WriteString("| Rank | Model | Average Rank | Votes |\n")
+		md.WriteString("|------|-------|--------------|-------|\n")
+		for i, ranking := range CalculateAggregateRankings(msg.Stage2, labelToModel) {
+			md.WriteString(fmt.Sprintf("| %d | %s | %.2f | %d |\n",
+				i+1, escapeTableCell(ranking.Model), ranking.AverageRank, ranking.RankingsCount))
+		}
+		md.WriteString("\n")
+
+		md.WriteString("| Ranker | Ranking (best to worst) |\n")
+		md.WriteString("|--------|-------------------------|\n")
+		for _, ranking := range msg.Stage2 {
+			var models []string
+			for _, label := range ranking.ParsedRanking {
+				if model, ok := labelToModel[label]; ok {
+					models = append(models, model)
+				} else {
+					models = append(models, label)
+				}
+			}
+			md.WriteString(fmt.Sprintf("| %s | %s |\n",
+				escapeTableCell(ranking.Model), escapeTableCell(strings.Join(models, " > "))))
+		}
+		md.WriteString("\n")
+	}
+
+	// Stage 3: Chairman synthesis
+	if msg.Stage3 != nil {
+		md.WriteString("### Stage 3: Final Synthesis\n\n")
+		md.WriteString(fmt.Sprintf("_Chairman: %s_\n\n%s\n\n", msg.Stage3.Model, strings.TrimSpace(msg.Stage3.Response)))
+	}
+}
+
+// singleLine collapses whitespace so text can be used in a Markdown header
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// escapeTableCell escapes characters that would break a Markdown table cell
+func escapeTableCell(text string) string {
+	return strings.ReplaceAll(singleLine(text), "|", "\\|")
+}
+
+// WriteConversationsZip streams every stored conversation into a zip archive on w,
+// one file per conversation named after its ID. format is "json" (the stored
+// conversation) or "markdown" (RenderConversationMarkdown). Conversations are
+// read and compressed one at a time, so memory use does not grow with the data set.
+func WriteConversationsZip(w io.Writer, format string) error {
+	zw := zip.NewWriter(w)
+
+	err := forEachConversation(func(conv Conversation) error {
+		var name string
+		var data []byte
+		switch format {
+		case "markdown":
+			name = conv.ID + ".md"
+			data = []byte(RenderConversationMarkdown(&conv))
+		default:
+			name = conv.ID + ".json"
+			var err error
+			data, err = json.MarshalIndent(conv, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal conversation %s: %w", conv.ID, err)
+			}
+		}
+
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: conv.CreatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+		if _, err := f.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
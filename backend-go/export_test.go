@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRenderConversationMarkdown tests rendering a conversation with all three stages
+func TestRenderConversationMarkdown(t *testing.T) {
+	conv := SampleConversation("export-test")
+	conv.Messages[1].Stage2 = append(conv.Messages[1].Stage2, Stage2Ranking{
+		Model:         "test/model2",
+		Ranking:       "FINAL RANKING:\n1. Response B\n2. Response A",
+		ParsedRanking: []string{"Response B", "Response A"},
+	})
+
+	md := RenderConversationMarkdown(conv)
+
+	// Sections must appear in this order
+	expectedInOrder := []string{
+		"# Test Conversation",
+		"## What is Go?",
+		"### Stage 1: Individual Responses",
+		"#### test/model1\n\nGo is a programming language.",
+		"#### test/model2\n\nGo is developed by Google.",
+		"### Stage 2: Peer Rankings",
+		"| Rank | Model | Average Rank | Votes |",
+		"| 1 | test/model2 | 1.00 | 2 |",
+		"| 2 | test/model1 | 2.00 | 2 |",
+		"| Ranker | Ranking (best to worst) |",
+		"| test/model1 | test/model2 > test/model1 |",
+		"### Stage 3: Final Synthesis",
+		"_Chairman: test/chairman_",
+		"Go is a programming language developed by Google.",
+	}
+
+	pos := 0
+	for _, expected := range expectedInOrder {
+		idx := strings.Index(md[pos:], expected)
+		if idx == -1 {
+			t.Fatalf("Expected %q after position %d in:\n%s", expected, pos, md)
+		}
+		pos += idx + len(expected)
+	}
+}
+
+// TestRenderConversationMarkdownEmpty tests rendering a conversation with no messages
+func TestRenderConversationMarkdownEmpty(t *testing.T) {
+	conv := &Conversation{ID: "empty", CreatedAt: testTime(), Title: "New Conversation", Messages: []Message{}}
+
+	md := RenderConversationMarkdown(conv)
+
+	if !strings.HasPrefix(md, "# New Conversation\n") {
+		t.Errorf("Expected title header, got:\n%s", md)
+	}
+	if strings.Contains(md, "### Stage") {
+		t.Errorf("Empty conversation should have no stage sections, got:\n%s", md)
+	}
+}
+
+// TestRenderConversationMarkdownMultilineQuestion tests that questions render as one header line
+func TestRenderConversationMarkdownMultilineQuestion(t *testing.T) {
+	conv := &Conversation{
+		ID:        "multiline",
+		CreatedAt: testTime(),
+		Title:     "Multiline",
+		Messages:  []Message{{Role: "user", Content: "First line\nsecond   line"}},
+	}
+
+	md := RenderConversationMarkdown(conv)
+
+	if !strings.Contains(md, "## First line second line\n") {
+		t.Errorf("Expected collapsed question header, got:\n%s", md)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrIdempotencyInProgress is returned when a request with the same idempotency key
+// is still being processed
+var ErrIdempotencyInProgress = errors.New("a request with this Idempotency-Key is still in progress")
+
+// MaxIdempotencyKeyLength caps the length of an Idempotency-Key header
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyStore remembers the responses to completed requests by idempotency key
+// for a short time, so a retried request can be answered without repeating its work.
+// Keys whose requests are still running are tracked so concurrent duplicates are rejected.
+type IdempotencyStore struct {
+	results  *TTLCache[string, *SendMessageResponse]
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// NewIdempotencyStore creates a store that remembers responses for ttl
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		results:  NewTTLCache[string, *SendMessageResponse](ttl),
+		inFlight: make(map[string]bool),
+	}
+}
+
+// Begin claims key for a new request. Returns the stored response if the key
+// has already completed, ErrIdempotencyInProgress if it is still running, or
+// (nil, nil) when the caller should process the request and then call Finish.
+func (s *IdempotencyStore) Begin(key string) (*SendMessageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if response, ok := s.results.Get(key); ok {
+		return response, nil
+	}
+	if s.inFlight[key] {
+		return nil, ErrIdempotencyInProgress
+	}
+	s.inFlight[key] = true
+	return nil, nil
+}
+
+// Finish releases key, storing response for replay. A nil response (the request
+// failed) isn't stored, so the client can retry with the same key.
+func (s *IdempotencyStore) Finish(key string, response *SendMessageResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if response != nil {
+		s.results.Set(key, response)
+	}
+	delete(s.inFlight, key)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestIdempotencyStore tests claiming, replaying and releasing idempotency keys
+func TestIdempotencyStore(t *testing.T) {
+	store := NewIdempotencyStore(time.Minute)
+
+	if stored, err := store.Begin("conv:key"); stored != nil || err != nil {
+		t.Fatalf("First Begin = %v, %v; want nil, nil", stored, err)
+	}
+	if _, err := store.Begin("conv:key"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("Concurrent Begin error = %v, want ErrIdempotencyInProgress", err)
+	}
+
+	response := &SendMessageResponse{Stage3: Stage3Response{Response: "Answer"}}
+	store.Finish("conv:key", response)
+	if stored, err := store.Begin("conv:key"); stored != response || err != nil {
+		t.Errorf("Begin after Finish = %v, %v; want the stored response", stored, err)
+	}
+
+	// A failed request releases the key without storing anything
+	store.Begin("conv:failed")
+	store.Finish("conv:failed", nil)
+	if stored, err := store.Begin("conv:failed"); stored != nil || err != nil {
+		t.Errorf("Begin after a failure = %v, %v; want the key to be claimable again", stored, err)
+	}
+
+	// Stored responses expire with the TTL
+	expiring := NewIdempotencyStore(10 * time.Millisecond)
+	expiring.Begin("conv:key")
+	expiring.Finish("conv:key", response)
+	time.Sleep(20 * time.Millisecond)
+	if stored, _ := expiring.Begin("conv:key"); stored != nil {
+		t.Error("Expected the stored response to expire")
+	}
+}
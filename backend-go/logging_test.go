@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRequestLoggerMiddleware tests JSON request logging with request IDs
+func TestRequestLoggerMiddleware(t *testing.T) {
+	oldLogger := slog.Default()
+	defer slog.SetDefault(oldLogger)
+
+	var buf bytes.Buffer
+	SetupLogger(&buf, "json")
+
+	router := gin.New()
+	router.Use(RequestLoggerMiddleware())
+	router.GET("/api/things", func(c *gin.Context) {
+		slog.InfoContext(c.Request.Context(), "handling things")
+		c.JSON(http.StatusTeapot, gin.H{})
+	})
+
+	parseLines := func() []map[string]interface{} {
+		var records []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("Log line is not JSON: %q", line)
+			}
+			records = append(records, record)
+		}
+		buf.Reset()
+		return records
+	}
+
+	t.Run("generates request ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/things", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		requestID := w.Header().Get(RequestIDHeader)
+		if requestID == "" {
+			t.Fatal("Expected X-Request-ID response header")
+		}
+
+		records := parseLines()
+		if len(records) != 2 {
+			t.Fatalf("Expected 2 log records, got %d", len(records))
+		}
+
+		if records[0]["msg"] != "handling things" || records[0]["request_id"] != requestID {
+			t.Errorf("Handler log record = %v, want request_id %q", records[0], requestID)
+		}
+
+		access := records[1]
+		if access["msg"] != "request completed" || access["method"] != "GET" || access["path"] != "/api/things" {
+			t.Errorf("Unexpected access log record: %v", access)
+		}
+		if access["status"] != float64(http.StatusTeapot) || access["level"] != "WARN" {
+			t.Errorf("Expected status 418 logged at WARN, got %v", access)
+		}
+		if access["request_id"] != requestID {
+			t.Errorf("Access log request_id = %v, want %q", access["request_id"], requestID)
+		}
+		if _, ok := access["latency"]; !ok {
+			t.Error("Expected latency in access log")
+		}
+	})
+
+	t.Run("reuses incoming request ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/things", nil)
+		req.Header.Set(RequestIDHeader, "upstream-id-123")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get(RequestIDHeader); got != "upstream-id-123" {
+			t.Errorf("X-Request-ID = %q, want 'upstream-id-123'", got)
+		}
+		for _, record := range parseLines() {
+			if record["request_id"] != "upstream-id-123" {
+				t.Errorf("Record request_id = %v, want 'upstream-id-123'", record["request_id"])
+			}
+		}
+	})
+}
+
+// TestSetupLoggerText tests the human-readable text format
+func TestSetupLoggerText(t *testing.T) {
+	oldLogger := slog.Default()
+	defer slog.SetDefault(oldLogger)
+
+	var buf bytes.Buffer
+	SetupLogger(&buf, "text")
+
+	slog.Info("hello", "count", 3)
+
+	if out := buf.String(); !strings.Contains(out, "msg=hello") || !strings.Contains(out, "count=3") {
+		t.Errorf("Unexpected text log output: %q", out)
+	}
+}
@@ -3,28 +3,100 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
 )
 
 // Global bills cache instance
 var billsCache *BillsCache
 
+// Global model catalog cache instance
+var modelCatalogCache *ModelCatalogCache
+
+// Global fetched URL content cache instance, keyed by normalized URL
+var urlContentCache *TTLCache[string, *FetchURLResult]
+
+// Global bill detail cache instance, keyed by bill ID
+var billDetailCache *TTLCache[string, *BillDetail]
+
+// Global cache of council results by CouncilCacheKey; nil when disabled
+var councilResultCache *TTLCache[string, *SendMessageResponse]
+
+// Global store of message responses by Idempotency-Key
+var idempotencyStore *IdempotencyStore
+
+// billsCacheFile is where fetched bills are persisted; empty disables persistence
+var billsCacheFile string
+
 func main() {
 	// Load configuration
 	LoadConfig()
 
-	// Initialize bills cache
+	// Switch to structured logging; the standard log package is routed through it too
+	SetupLogger(os.Stdout, LogFormat)
+
+	// Initialize bills cache, restoring bills persisted by a previous run
 	billsCache = NewBillsCache(BillsCacheTTL)
+	billsCacheFile = filepath.Join(BillsCacheDir, "bills.json")
+	if err := billsCache.LoadFromFile(billsCacheFile); err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to load persisted bills", "error", err)
+	}
+
+	// Initialize model catalog cache
+	modelCatalogCache = NewModelCatalogCache(ModelCatalogTTL)
+
+	// Catch misconfigured model IDs before the first council run
+	if err := ValidateConfiguredModels(context.Background()); err != nil {
+		fatal("invalid model configuration", "error", err)
+	}
+
+	// Initialize fetched URL content cache
+	urlContentCache = NewBoundedTTLCache[string, *FetchURLResult](URLContentCacheTTL, URLCacheMaxEntries)
+
+	// Initialize bill detail cache
+	billDetailCache = NewTTLCache[string, *BillDetail](BillDetailCacheTTL)
+
+	// Skip council models that keep failing
+	modelCircuits = NewCircuitBreaker(CircuitBreakerThreshold, CircuitBreakerCooldown)
+
+	// Initialize council result cache for repeated questions
+	if CouncilCacheTTL > 0 {
+		councilResultCache = NewTTLCache[string, *SendMessageResponse](CouncilCacheTTL)
+	}
+
+	// Initialize idempotency key store for message sending
+	idempotencyStore = NewIdempotencyStore(IdempotencyKeyTTL)
+
+	// Load persisted page validators for conditional bills scraping
+	pageValidatorStore = NewPageValidatorStore(filepath.Join(BillsCacheDir, "page_validators.json"))
 
 	// Create Gin router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery())
+	if err := trustProxies(router, TrustedProxies); err != nil {
+		fatal("invalid TRUSTED_PROXIES", "error", err)
+	}
+
+	// Structured request logging with request IDs
+	router.Use(RequestLoggerMiddleware())
 
 	// Request size limit middleware
 	router.Use(func(c *gin.Context) {
@@ -38,7 +110,7 @@ func main() {
 			// In production, use environment-configured origins
 			if len(CORSAllowedOrigins) > 0 && CORSAllowedOrigins[0] != "" {
 				for _, allowedOrigin := range CORSAllowedOrigins {
-					if origin == allowedOrigin {
+					if matchOrigin(allowedOrigin, origin) {
 						return true
 					}
 				}
@@ -49,26 +121,85 @@ func main() {
 				len(origin) >= 16 && origin[:16] == "http://localhost" ||
 				len(origin) >= 14 && origin[:14] == "http://127.0.0")
 		},
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "Idempotency-Key", "X-Admin-Key"},
 		AllowCredentials: true,
 	}))
 
+	// Per-IP rate limiting (health check exempt)
+	router.Use(RateLimitMiddleware(RateLimitRPS, RateLimitBurst))
+
+	// Bearer-token authentication (no-op unless API_KEY is set)
+	router.Use(APIKeyAuthMiddleware(APIKey))
+	if APIKey == "" {
+		slog.Warn("API_KEY not set; /api endpoints are unauthenticated")
+	}
+
 	// Routes
 	router.GET("/", healthCheck)
 	router.GET("/api/conversations", listConversationsHandler)
 	router.POST("/api/conversations", createConversationHandler)
+	router.GET("/api/conversations/search", searchConversationsHandler)
+	router.GET("/api/conversations/export/all", exportAllConversationsHandler)
 	router.GET("/api/conversations/:id", getConversationHandler)
+	router.GET("/api/conversations/:id/export", exportConversationHandler)
 	router.POST("/api/conversations/:id/message", sendMessageHandler)
 	router.POST("/api/conversations/:id/message/stream", sendMessageStreamHandler)
+	router.POST("/api/conversations/:id/regenerate", regenerateHandler)
+	router.POST("/api/conversations/:id/fork", forkConversationHandler)
+	router.PUT("/api/conversations/:id/messages/:index", editMessageHandler)
+	router.POST("/api/conversations/:id/estimate", estimateHandler)
+	router.POST("/api/conversations/:id/archive", archiveConversationHandler(true))
+	router.POST("/api/conversations/:id/unarchive", archiveConversationHandler(false))
+	router.POST("/api/conversations/:id/tags", addTagsHandler)
+	router.DELETE("/api/conversations/:id/tags/:tag", removeTagHandler)
+	router.GET("/api/models", listModelsHandler)
+	router.GET("/api/config/models", AdminAuthMiddleware(AdminAPIKey), getModelConfigHandler)
+	router.PUT("/api/config/models", AdminAuthMiddleware(AdminAPIKey), updateModelConfigHandler)
+	router.GET("/api/metrics", metricsHandler)
 	router.GET("/api/bills", getBillsHandler)
+	router.GET("/api/bills/:id", getBillDetailHandler)
+	router.POST("/api/bills/:id/analyze", analyzeBillHandler)
 	router.POST("/api/fetch-url", fetchURLHandler)
+	router.POST("/api/query", queryHandler)
+	router.POST("/api/compare", compareHandler)
+
+	// Stop background work and the server on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Keep the bills cache warm in the background
+	refresherDone := make(chan struct{})
+	if BillsRefreshInterval > 0 {
+		refresher := NewBillsRefresher(billsCache, BillsRefreshInterval, FetchAllBills, billsCacheFile)
+		if BillsIncrementalRefresh {
+			refresher.SyncDetails(billDetailCache, FetchBillDetail)
+		}
+		go func() {
+			defer close(refresherDone)
+			refresher.Run(ctx)
+		}()
+	} else {
+		close(refresherDone)
+	}
 
 	// Start server
-	log.Println("Starting LLM Council backend on port 8001...")
-	if err := router.Run(":8001"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{Addr: ":8001", Handler: router}
+	go func() {
+		slog.Info("starting LLM Council backend", "port", 8001)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("failed to start server", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("server shutdown failed", "error", err)
 	}
+	<-refresherDone
 }
 
 // healthCheck returns a simple health check response.
@@ -82,18 +213,46 @@ func healthCheck(c *gin.Context) {
 
 // listConversationsHandler lists all conversations with metadata only.
 // GET /api/conversations - Returns array of conversation metadata sorted by date.
+// Archived conversations are omitted unless ?include_archived=true; ?tag=<tag> keeps
+// only conversations with that tag. ?sort=updated orders by most recent activity.
 func listConversationsHandler(c *gin.Context) {
-	conversations, err := ListConversations()
+	sortBy := c.DefaultQuery("sort", "created")
+	if sortBy != "created" && sortBy != "updated" {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Unsupported sort: %s", sortBy), gin.H{"field": "sort"})
+		return
+	}
+	filter := ConversationFilter{
+		IncludeArchived: c.Query("include_archived") == "true",
+		Tag:             c.Query("tag"),
+		SortByUpdated:   sortBy == "updated",
+	}
+	conversations, err := ListConversations(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to list conversations: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to list conversations: %v", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, conversations)
 }
 
+// searchConversationsHandler searches conversation titles and message content.
+// GET /api/conversations/search?q=<term> - Returns matching metadata with a snippet of each match.
+func searchConversationsHandler(c *gin.Context) {
+	term := strings.TrimSpace(c.Query("q"))
+	if term == "" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "Query parameter 'q' is required")
+		return
+	}
+
+	results, err := SearchConversations(term)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to search conversations: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
 // createConversationHandler creates a new conversation.
 // POST /api/conversations - Generates a new UUID and creates an empty conversation.
 func createConversationHandler(c *gin.Context) {
@@ -103,9 +262,7 @@ func createConversationHandler(c *gin.Context) {
 	// Create conversation
 	conversation, err := CreateConversation(conversationID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to create conversation: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to create conversation: %v", err))
 		return
 	}
 
@@ -119,49 +276,178 @@ func getConversationHandler(c *gin.Context) {
 
 	conversation, err := GetConversation(conversationID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to get conversation: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
 		return
 	}
 
 	if conversation == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Conversation not found",
-		})
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, conversation)
 }
 
+// exportConversationHandler exports a conversation as a downloadable document.
+// GET /api/conversations/:id/export - Query params: ?format=markdown (default and only format)
+func exportConversationHandler(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	format := c.DefaultQuery("format", "markdown")
+	if format != "markdown" {
+		respondError(c, http.StatusBadRequest, ErrCodeUnsupportedFormat, fmt.Sprintf("Unsupported export format: %s", format))
+		return
+	}
+
+	conversation, err := GetConversation(conversationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+	if conversation == nil {
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", conversationID+".md"))
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(RenderConversationMarkdown(conversation)))
+}
+
+// exportAllConversationsHandler streams every conversation as a zip archive for backups.
+// GET /api/conversations/export/all - Query params: ?format=json (default) or ?format=markdown
+func exportAllConversationsHandler(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		respondError(c, http.StatusBadRequest, ErrCodeUnsupportedFormat, fmt.Sprintf("Unsupported export format: %s", format))
+		return
+	}
+
+	filename := fmt.Sprintf("conversations-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if err := WriteConversationsZip(c.Writer, format); err != nil {
+		// Once the archive has started streaming the status is already sent
+		if c.Writer.Written() {
+			slog.Error("conversation export failed mid-stream", "error", err)
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to export conversations: %v", err))
+	}
+}
+
+// validateMessageContent rejects empty or oversized user messages before a council run.
+func validateMessageContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return errors.New("message content must not be empty")
+	}
+	if length := utf8.RuneCountInString(content); length > MaxMessageLength {
+		return fmt.Errorf("message content is %d characters, exceeding the limit of %d", length, MaxMessageLength)
+	}
+	return nil
+}
+
+// validateImageURLs rejects too many images, or image references that aren't
+// http(s) URLs or base64 image data URLs.
+func validateImageURLs(imageURLs []string) error {
+	if len(imageURLs) > MaxImagesPerMessage {
+		return fmt.Errorf("%d images attached, exceeding the limit of %d", len(imageURLs), MaxImagesPerMessage)
+	}
+	for i, raw := range imageURLs {
+		if strings.HasPrefix(raw, "data:image/") && strings.Contains(raw, ";base64,") {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("image %d must be an http(s) URL or a base64 image data URL", i+1)
+		}
+	}
+	return nil
+}
+
+// councilModeContext returns the request context with the council preset a message
+// asks for, by its mode field or ?mode=, attached. The mode is empty when none was
+// asked for. An unknown mode is reported to the client and ok is false.
+func councilModeContext(c *gin.Context, mode string) (_ context.Context, _ string, ok bool) {
+	ctx := c.Request.Context()
+	if mode == "" {
+		mode = c.Query("mode")
+	}
+	if mode == "" {
+		// Pin the configured roster so a roster update mid-run can't mix rosters
+		return WithCouncilConfig(ctx, councilConfig(ctx)), "", true
+	}
+	cfg, err := CouncilMode(mode)
+	if err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{
+			"field": "mode",
+			"modes": CouncilModeNames(),
+		})
+		return nil, "", false
+	}
+	return WithCouncilConfig(ctx, cfg), mode, true
+}
+
 // sendMessageHandler sends a message and runs the 3-stage council process.
 // POST /api/conversations/:id/message - Runs full council and returns all stages at once.
+// An optional Idempotency-Key header makes retries of the same request replay the
+// stored response instead of running the council again.
 // Use sendMessageStreamHandler for SSE streaming version.
 func sendMessageHandler(c *gin.Context) {
 	conversationID := c.Param("id")
 
+	// Replay the response to an earlier request with the same key. The response is
+	// only stored once the council has succeeded, so failed requests can be retried
+	var response *SendMessageResponse
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if len(idempotencyKey) > MaxIdempotencyKeyLength {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: Idempotency-Key exceeds %d characters", MaxIdempotencyKeyLength))
+		return
+	}
+	if idempotencyKey != "" {
+		storeKey := conversationID + ":" + idempotencyKey
+		stored, err := idempotencyStore.Begin(storeKey)
+		if err != nil {
+			respondError(c, http.StatusConflict, ErrCodeIdempotencyConflict, err.Error())
+			return
+		}
+		if stored != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.JSON(http.StatusOK, stored)
+			return
+		}
+		defer func() { idempotencyStore.Finish(storeKey, response) }()
+	}
+
 	// Parse request
 	var request SendMessageRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Invalid request: %v", err),
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if err := validateMessageContent(request.Content); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "content"})
+		return
+	}
+	if err := validateImageURLs(request.ImageURLs); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "image_urls"})
+		return
+	}
+	ctx, mode, ok := councilModeContext(c, request.Mode)
+	if !ok {
 		return
 	}
 
 	// Check if conversation exists
 	conversation, err := GetConversation(conversationID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to get conversation: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
 		return
 	}
 	if conversation == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Conversation not found",
-		})
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
 		return
 	}
 
@@ -169,20 +455,25 @@ func sendMessageHandler(c *gin.Context) {
 	isFirstMessage := len(conversation.Messages) == 0
 
 	// Add user message
-	if err := AddUserMessage(conversationID, request.Content); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to add user message: %v", err),
-		})
+	if err := AddUserMessage(conversationID, request.Content, request.ImageURLs...); err != nil {
+		if errors.Is(err, ErrConversationFull) {
+			respondErrorDetails(c, http.StatusConflict, ErrCodeConversationFull, fmt.Sprintf("Failed to add user message: %v", err), gin.H{
+				"max_messages": MaxMessagesPerConversation,
+			})
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to add user message: %v", err))
 		return
 	}
 
-	// Generate title if first message (run in background)
+	// Generate title if first message (run in background). The title outlives the
+	// request, so it gets a detached context that keeps the request's values
 	if isFirstMessage {
+		titleCtx := context.WithoutCancel(c.Request.Context())
 		go func() {
-			ctx := context.Background()
-			title, err := GenerateConversationTitle(ctx, request.Content)
+			title, err := GenerateConversationTitle(titleCtx, request.Content)
 			if err != nil {
-				log.Printf("Failed to generate title: %v", err)
+				slog.WarnContext(titleCtx, "failed to generate title", "conversation_id", conversationID, "error", err)
 				// Use default title on error
 				UpdateConversationTitle(conversationID, "New Conversation")
 			} else {
@@ -191,243 +482,1143 @@ func sendMessageHandler(c *gin.Context) {
 		}()
 	}
 
-	// Run the 3-stage council process
-	ctx := context.Background()
-	stage1, stage2, stage3, metadata, err := RunFullCouncil(ctx, request.Content)
+	// Reuse the result of an identical earlier question unless ?no_cache=true. Modes
+	// run different councils, so each has its own results
+	cacheKey := CouncilCacheKey(ctx, request.Content, request.ImageURLs)
+	if mode != "" {
+		cacheKey = mode + ":" + cacheKey
+	}
+	if councilResultCache != nil && c.Query("no_cache") != "true" {
+		if cached, ok := councilResultCache.Get(cacheKey); ok {
+			if err := AddAssistantMessage(conversationID, cached.Stage1, cached.Stage2, cached.Stage3); err != nil {
+				respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to add assistant message: %v", err))
+				return
+			}
+			hit := *cached
+			hit.Cached = true
+			response = &hit
+			c.JSON(http.StatusOK, response)
+			return
+		}
+	}
+
+	// Run the 3-stage council process, bounded by the lifetime of the HTTP request
+	stage1, stage2, stage3, metadata, err := RunFullCouncil(ctx, request.Content, request.ImageURLs...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Council process failed: %v", err),
-		})
+		respondError(c, councilErrorStatus(err), ErrCodeCouncilFailed, fmt.Sprintf("Council process failed: %v", err))
 		return
 	}
+	metadata.Mode = mode
 
 	// Add assistant message
 	if err := AddAssistantMessage(conversationID, stage1, stage2, stage3); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to add assistant message: %v", err),
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to add assistant message: %v", err))
 		return
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, SendMessageResponse{
+	response = &SendMessageResponse{
 		Stage1:   stage1,
 		Stage2:   stage2,
 		Stage3:   stage3,
 		Metadata: metadata,
-	})
+	}
+	if councilResultCache != nil {
+		councilResultCache.Set(cacheKey, response)
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// sendMessageStreamHandler sends a message and streams the 3-stage council process via SSE.
-// POST /api/conversations/:id/message/stream - Streams progress events as each stage completes.
-// Events: stage1_start, stage1_complete, stage2_start, stage2_complete, stage3_start, stage3_complete, complete.
-func sendMessageStreamHandler(c *gin.Context) {
-	conversationID := c.Param("id")
-
-	// Parse request
+// queryHandler runs the council on a one-off question without a conversation.
+// POST /api/query - Body: {"content": "...", "image_urls": [...], "mode": "..."},
+// validated like a message. Returns the same shape as sendMessageHandler; nothing is saved.
+func queryHandler(c *gin.Context) {
 	var request SendMessageRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Invalid request: %v", err),
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
 		return
 	}
-
-	// Check if conversation exists
-	conversation, err := GetConversation(conversationID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to get conversation: %v", err),
-		})
+	if err := validateMessageContent(request.Content); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "content"})
 		return
 	}
-	if conversation == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Conversation not found",
-		})
+	if err := validateImageURLs(request.ImageURLs); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "image_urls"})
 		return
 	}
 
-	// Set SSE headers
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-
-	// Check if this is the first message
-	isFirstMessage := len(conversation.Messages) == 0
-
-	// Add user message
-	if err := AddUserMessage(conversationID, request.Content); err != nil {
-		sendSSEError(c, fmt.Sprintf("Failed to add user message: %v", err))
+	ctx, mode, ok := councilModeContext(c, request.Mode)
+	if !ok {
 		return
 	}
 
-	ctx := context.Background()
-
-	// Start title generation in background if first message
-	var titleChan chan string
-	if isFirstMessage {
-		titleChan = make(chan string, 1)
-		go func() {
-			title, err := GenerateConversationTitle(ctx, request.Content)
-			if err != nil {
-				log.Printf("Failed to generate title: %v", err)
-				UpdateConversationTitle(conversationID, "New Conversation")
-			} else {
-				UpdateConversationTitle(conversationID, title)
-				titleChan <- title
-			}
-			close(titleChan)
-		}()
-	}
-
-	// Stage 1
-	sendSSEEvent(c, gin.H{"type": "stage1_start"})
-	stage1, err := Stage1CollectResponses(ctx, request.Content)
+	stage1, stage2, stage3, metadata, err := RunFullCouncil(ctx, request.Content, request.ImageURLs...)
 	if err != nil {
-		sendSSEError(c, fmt.Sprintf("Stage 1 failed: %v", err))
+		respondError(c, councilErrorStatus(err), ErrCodeCouncilFailed, fmt.Sprintf("Council process failed: %v", err))
 		return
 	}
-	sendSSEEvent(c, gin.H{"type": "stage1_complete", "data": stage1})
+	metadata.Mode = mode
 
-	// Stage 2
-	sendSSEEvent(c, gin.H{"type": "stage2_start"})
-	stage2, labelToModel, err := Stage2CollectRankings(ctx, request.Content, stage1)
-	if err != nil {
-		sendSSEError(c, fmt.Sprintf("Stage 2 failed: %v", err))
-		return
-	}
-	aggregateRankings := CalculateAggregateRankings(stage2, labelToModel)
-	sendSSEEvent(c, gin.H{
-		"type": "stage2_complete",
-		"data": stage2,
-		"metadata": gin.H{
-			"label_to_model":      labelToModel,
-			"aggregate_rankings":  aggregateRankings,
-		},
+	c.JSON(http.StatusOK, SendMessageResponse{
+		Stage1:   stage1,
+		Stage2:   stage2,
+		Stage3:   stage3,
+		Metadata: metadata,
 	})
+}
 
-	// Stage 3
-	sendSSEEvent(c, gin.H{"type": "stage3_start"})
-	stage3, err := Stage3SynthesizeFinal(ctx, request.Content, stage1, stage2)
-	if err != nil {
-		sendSSEError(c, fmt.Sprintf("Stage 3 failed: %v", err))
+// compareHandler runs the same question past two council rosters concurrently.
+// POST /api/compare - Body: {"content": "...", "a": {...}, "b": {...}} with each roster's
+// council_models, chairman_model and optional ranker_models and chairman_fallbacks.
+// Returns both results; nothing is saved to a conversation.
+func compareHandler(c *gin.Context) {
+	var request CompareRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if err := validateMessageContent(request.Content); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "content"})
+		return
+	}
+	if err := validateImageURLs(request.ImageURLs); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "image_urls"})
 		return
 	}
-	sendSSEEvent(c, gin.H{"type": "stage3_complete", "data": stage3})
 
-	// Wait for title if it was being generated
-	if titleChan != nil {
-		if title := <-titleChan; title != "" {
-			sendSSEEvent(c, gin.H{"type": "title_complete", "data": gin.H{"title": title}})
+	rosters := []struct {
+		name   string
+		config CouncilConfig
+		result *SendMessageResponse
+	}{
+		{name: "a", config: request.A, result: new(SendMessageResponse)},
+		{name: "b", config: request.B, result: new(SendMessageResponse)},
+	}
+	for _, roster := range rosters {
+		if err := roster.config.Validate(); err != nil {
+			respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: council %s: %v", roster.name, err), gin.H{"field": roster.name})
+			return
 		}
 	}
 
-	// Save complete assistant message (check for nil first)
-	if stage3 == nil {
-		sendSSEError(c, "Stage 3 returned no result")
-		return
+	// A failure in one council cancels the other, since the comparison is incomplete
+	g, ctx := errgroup.WithContext(c.Request.Context())
+	for _, roster := range rosters {
+		g.Go(func() error {
+			stage1, stage2, stage3, metadata, err := RunFullCouncil(WithCouncilConfig(ctx, roster.config), request.Content, request.ImageURLs...)
+			if err != nil {
+				return &compareError{council: roster.name, err: err}
+			}
+			*roster.result = SendMessageResponse{Stage1: stage1, Stage2: stage2, Stage3: stage3, Metadata: metadata}
+			return nil
+		})
 	}
-	if err := AddAssistantMessage(conversationID, stage1, stage2, *stage3); err != nil {
-		sendSSEError(c, fmt.Sprintf("Failed to save message: %v", err))
+	if err := g.Wait(); err != nil {
+		var compareErr *compareError
+		errors.As(err, &compareErr)
+		respondErrorDetails(c, councilErrorStatus(compareErr.err), ErrCodeCouncilFailed, fmt.Sprintf("Council %s failed: %v", compareErr.council, compareErr.err), gin.H{
+			"council": compareErr.council,
+		})
 		return
 	}
 
-	// Send completion event
-	sendSSEEvent(c, gin.H{"type": "complete"})
+	c.JSON(http.StatusOK, CompareResponse{A: *rosters[0].result, B: *rosters[1].result})
 }
 
-// sendSSEEvent sends a Server-Sent Event.
-// Marshals data to JSON and writes as SSE format with "data: " prefix.
-func sendSSEEvent(c *gin.Context, data interface{}) {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		log.Printf("Failed to marshal SSE event: %v", err)
-		return
-	}
-	c.Writer.WriteString(fmt.Sprintf("data: %s\n\n", string(jsonData)))
-	c.Writer.Flush()
+// compareError records which council of a comparison failed
+type compareError struct {
+	council string
+	err     error
 }
 
-// sendSSEError sends an error event via SSE.
-// Convenience wrapper for sending error-type SSE events.
-func sendSSEError(c *gin.Context, message string) {
-	sendSSEEvent(c, gin.H{"type": "error", "message": message})
-}
+func (e *compareError) Error() string { return fmt.Sprintf("council %s: %v", e.council, e.err) }
+func (e *compareError) Unwrap() error { return e.err }
 
-// getBillsHandler fetches and returns all bills before parliament
-// GET /api/bills - Returns all bills with caching
-// Query params: ?refresh=true (force cache refresh)
-func getBillsHandler(c *gin.Context) {
-	// Check for refresh parameter
-	forceRefresh := c.Query("refresh") == "true"
+// archiveConversationHandler returns a handler that archives or unarchives a conversation.
+// POST /api/conversations/:id/archive - Hides the conversation from the default list.
+// POST /api/conversations/:id/unarchive - Restores it.
+func archiveConversationHandler(archived bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conversationID := c.Param("id")
 
-	// Try to get from cache first (unless refresh requested)
-	if !forceRefresh {
-		if cachedBills, ok := billsCache.Get(); ok {
-			log.Printf("Returning %d bills from cache", len(cachedBills))
-			c.JSON(http.StatusOK, BillsResponse{
-				Bills:       cachedBills,
-				CurrentPage: 1,
-				TotalPages:  CalculateTotalPages(len(cachedBills)),
-				HasNextPage: false,
-				LastUpdated: billsCache.GetLastUpdated(),
-			})
+		// Check if conversation exists
+		conversation, err := GetConversation(conversationID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+			return
+		}
+		if conversation == nil {
+			respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
 			return
 		}
-	}
-
-	// Fetch fresh data
-	log.Println("Fetching fresh bills data from APH website...")
-	ctx := context.Background()
-	bills, err := FetchAllBills(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to fetch bills: %v", err),
-		})
-		return
-	}
 
-	// Update cache
-	billsCache.Set(bills)
-	log.Printf("Cached %d bills", len(bills))
+		if err := SetConversationArchived(conversationID, archived); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to update conversation: %v", err))
+			return
+		}
 
-	// Return response
-	c.JSON(http.StatusOK, BillsResponse{
-		Bills:       bills,
-		CurrentPage: 1,
-		TotalPages:  CalculateTotalPages(len(bills)),
-		HasNextPage: false,
-		LastUpdated: time.Now(),
-	})
+		conversation.Archived = archived
+		c.JSON(http.StatusOK, conversationMetadata(*conversation))
+	}
 }
 
-// fetchURLHandler fetches and extracts content from a given URL
-// POST /api/fetch-url - Body: {"url": "https://..."}
-func fetchURLHandler(c *gin.Context) {
+// addTagsHandler adds tags to a conversation.
+// POST /api/conversations/:id/tags - Body: {"tags": ["..."]}; tags are lowercased and deduplicated.
+func addTagsHandler(c *gin.Context) {
+	conversationID := c.Param("id")
+
 	// Parse request
-	var request struct {
-		URL string `json:"url" binding:"required"`
-	}
+	var request AddTagsRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Invalid request: %v", err),
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if len(normalizeTags(request.Tags)) == 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid request: at least one non-empty tag is required")
 		return
 	}
 
-	// Fetch content
-	ctx := context.Background()
-	content, err := FetchURLContent(ctx, request.URL)
+	// Check if conversation exists
+	conversation, err := GetConversation(conversationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+	if conversation == nil {
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
+		return
+	}
+
+	tags, err := AddConversationTags(conversationID, request.Tags)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to add tags: %v", err))
+		return
+	}
+
+	conversation.Tags = tags
+	c.JSON(http.StatusOK, conversationMetadata(*conversation))
+}
+
+// removeTagHandler removes a tag from a conversation.
+// DELETE /api/conversations/:id/tags/:tag - Succeeds even if the conversation didn't have the tag.
+func removeTagHandler(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	// Check if conversation exists
+	conversation, err := GetConversation(conversationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+	if conversation == nil {
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
+		return
+	}
+
+	tags, err := RemoveConversationTag(conversationID, c.Param("tag"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to remove tag: %v", err))
+		return
+	}
+
+	conversation.Tags = tags
+	c.JSON(http.StatusOK, conversationMetadata(*conversation))
+}
+
+// estimateHandler projects the token usage and cost of sending a message, without
+// calling OpenRouter.
+// POST /api/conversations/:id/estimate - Body: {"content": "...", "mode": "..."} as for
+// /message; the estimate covers the mode's council.
+func estimateHandler(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	// Parse request
+	var request SendMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if err := validateMessageContent(request.Content); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "content"})
+		return
+	}
+	ctx, _, ok := councilModeContext(c, request.Mode)
+	if !ok {
+		return
+	}
+
+	// Check if conversation exists
+	conversation, err := GetConversation(conversationID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to fetch URL content: %v", err),
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+	if conversation == nil {
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, EstimateStage1(ctx, request.Content))
+}
+
+// forkConversationHandler creates a new conversation from a prefix of an existing one.
+// POST /api/conversations/:id/fork - Body: {"up_to_message": N} copies messages 0..N.
+func forkConversationHandler(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	// Parse request
+	var request ForkConversationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	// Check if conversation exists
+	conversation, err := GetConversation(conversationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+	if conversation == nil {
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
+		return
+	}
+
+	fork, err := ForkConversation(conversationID, *request.UpToMessage)
+	if err != nil {
+		message := fmt.Sprintf("Failed to fork conversation: %v", err)
+		if errors.Is(err, ErrInvalidMessageIndex) {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, message)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, fork)
+}
+
+// editMessageHandler corrects a user message and discards everything after it.
+// PUT /api/conversations/:id/messages/:index - Body: {"content": "..."}; returns the
+// updated conversation, ending with the edited message ready to be answered again.
+func editMessageHandler(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: message index %q is not a number", c.Param("index")), gin.H{"field": "index"})
+		return
+	}
+
+	// Parse request
+	var request EditMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if err := validateMessageContent(request.Content); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "content"})
+		return
+	}
+
+	// Check if conversation exists
+	conversation, err := GetConversation(conversationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+	if conversation == nil {
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
+		return
+	}
+
+	if err := EditUserMessage(conversationID, index, request.Content); err != nil {
+		if errors.Is(err, ErrInvalidMessageIndex) || errors.Is(err, ErrNotUserMessage) {
+			respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "index"})
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to edit message: %v", err))
+		return
+	}
+
+	updated, err := GetConversation(conversationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// regenerateHandler re-runs the council on the last user message.
+// POST /api/conversations/:id/regenerate - Replaces the last assistant message with a fresh result.
+// The new result is saved only after the council succeeds, so a failed run keeps the old answer.
+func regenerateHandler(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	// Check if conversation exists
+	conversation, err := GetConversation(conversationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+	if conversation == nil {
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
+		return
+	}
+
+	// The conversation must end with an assistant response to a user message
+	messages := conversation.Messages
+	n := len(messages)
+	if n < 2 || messages[n-1].Role != "assistant" || messages[n-2].Role != "user" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "Last message is not an assistant response to a user message")
+		return
+	}
+	userIndex := n - 2
+	userQuery := messages[userIndex].Content
+	imageURLs := messages[userIndex].ImageURLs
+
+	// Re-run the 3-stage council process, bounded by the lifetime of the HTTP request
+	ctx := c.Request.Context()
+	stage1, stage2, stage3, metadata, err := RunFullCouncil(ctx, userQuery, imageURLs...)
+	if err != nil {
+		respondError(c, councilErrorStatus(err), ErrCodeCouncilFailed, fmt.Sprintf("Council process failed: %v", err))
+		return
+	}
+
+	// Replace the previous assistant message, unless the conversation moved on during the run
+	if err := ReplaceLastAssistantMessage(conversationID, userIndex, userQuery, stage1, stage2, stage3); err != nil {
+		if errors.Is(err, ErrConversationChanged) {
+			respondError(c, http.StatusConflict, ErrCodeConversationChanged, fmt.Sprintf("Failed to replace assistant message: %v", err))
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to replace assistant message: %v", err))
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, SendMessageResponse{
+		Stage1:   stage1,
+		Stage2:   stage2,
+		Stage3:   stage3,
+		Metadata: metadata,
+	})
+}
+
+// Error codes for the "code" field of API error responses. Clients should switch on
+// these rather than on messages, which are meant for people and may change.
+const (
+	ErrCodeInvalidRequest       = "invalid_request"
+	ErrCodeConversationNotFound = "conversation_not_found"
+	ErrCodeConversationFull     = "conversation_full"
+	ErrCodeConversationChanged  = "conversation_changed"
+	ErrCodeBillNotFound         = "bill_not_found"
+	ErrCodeUnsupportedFormat    = "unsupported_format"
+	ErrCodeIdempotencyConflict  = "idempotency_conflict"
+	ErrCodeCouncilFailed        = "council_failed"
+	ErrCodeUpstreamFailed       = "upstream_failed"
+	ErrCodeStorageFailed        = "storage_failed"
+	ErrCodeUnauthorized         = "unauthorized"
+	ErrCodeForbidden            = "forbidden"
+	ErrCodeRateLimited          = "rate_limited"
+)
+
+// respondError aborts the request with an error envelope:
+// {"error": {"code": "...", "message": "..."}}.
+func respondError(c *gin.Context, status int, code, message string) {
+	respondErrorDetails(c, status, code, message, nil)
+}
+
+// respondErrorDetails is respondError with extra machine-readable context, such as
+// the offending field or the ID of a conversation created before the failure.
+func respondErrorDetails(c *gin.Context, status int, code, message string, details gin.H) {
+	c.AbortWithStatusJSON(status, ErrorResponse{
+		Error: APIError{Code: code, Message: message, Details: details},
+	})
+}
+
+// councilErrorStatus maps a council failure to an HTTP status code.
+// Upstream authentication failures are reported as 502 Bad Gateway since they
+// indicate a misconfigured OpenRouter key rather than a server bug, and runs
+// cut short by CouncilTimeout as 504 Gateway Timeout.
+func councilErrorStatus(err error) int {
+	var orErr *OpenRouterError
+	if errors.As(err, &orErr) && orErr.IsAuthError() {
+		return http.StatusBadGateway
+	}
+	if errors.Is(err, ErrCouncilTimeout) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// sendMessageStreamHandler sends a message and streams the 3-stage council process via SSE.
+// POST /api/conversations/:id/message/stream - Streams progress events as each stage completes.
+// Events: stage1_start, stage1_complete, stage2_start, stage2_model_complete (one per
+// ranking as it arrives), stage2_complete, stage3_start, stage3_token (one per chairman
+// token delta), stage3_complete, complete (with the run's timing).
+func sendMessageStreamHandler(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	// Parse request
+	var request SendMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if err := validateMessageContent(request.Content); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "content"})
+		return
+	}
+	if err := validateImageURLs(request.ImageURLs); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err), gin.H{"field": "image_urls"})
+		return
+	}
+	// Council stages are cancelled if the client disconnects mid-stream
+	ctx, mode, ok := councilModeContext(c, request.Mode)
+	if !ok {
+		return
+	}
+
+	// Check if conversation exists
+	conversation, err := GetConversation(conversationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to get conversation: %v", err))
+		return
+	}
+	if conversation == nil {
+		respondError(c, http.StatusNotFound, ErrCodeConversationNotFound, "Conversation not found")
+		return
+	}
+
+	// Set SSE headers
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	// Check if this is the first message
+	isFirstMessage := len(conversation.Messages) == 0
+
+	// Add user message
+	if err := AddUserMessage(conversationID, request.Content, request.ImageURLs...); err != nil {
+		sendSSEError(c, fmt.Sprintf("Failed to add user message: %v", err))
+		return
+	}
+
+	// Keep the connection alive through slow stages
+	stopHeartbeat := startSSEHeartbeat(ctx, c, SSEHeartbeatInterval)
+	defer stopHeartbeat()
+
+	// Start title generation in background if first message. The title is saved even
+	// if the client goes away, so it gets a detached context
+	var titleChan chan string
+	if isFirstMessage {
+		titleChan = make(chan string, 1)
+		titleCtx := context.WithoutCancel(ctx)
+		go func() {
+			title, err := GenerateConversationTitle(titleCtx, request.Content)
+			if err != nil {
+				slog.WarnContext(titleCtx, "failed to generate title", "conversation_id", conversationID, "error", err)
+				UpdateConversationTitle(conversationID, "New Conversation")
+			} else {
+				UpdateConversationTitle(conversationID, title)
+				titleChan <- title
+			}
+			close(titleChan)
+		}()
+	}
+
+	// Bound the whole run, not just each model query, as RunFullCouncil does
+	timeout := councilConfig(ctx).councilTimeout()
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrCouncilTimeout)
+	defer cancel()
+
+	// Stage 1
+	stage1Start := gin.H{"type": "stage1_start"}
+	if mode != "" {
+		stage1Start["mode"] = mode
+	}
+	sendSSEEvent(c, stage1Start)
+	start := time.Now()
+	stage1, failedModels, err := collectStage1WithRetries(ctx, request.Content, request.ImageURLs...)
+	if streamTimedOut(ctx, c, 1, timeout) || clientDisconnected(ctx, conversationID, 1) {
+		return
+	}
+	if err != nil {
+		sendSSEError(c, fmt.Sprintf("Stage 1 failed: %v", err))
+		return
+	}
+	if len(stage1) == 0 {
+		sendSSEError(c, fmt.Sprintf("all council models failed to respond: %v", joinFailures(failedModels)))
+		return
+	}
+	modelLatencies := ModelLatencies(stage1)
+	stage1, omittedModels := CapCouncilResponses(ctx, stage1, MaxCouncilResponses)
+	sendSSEEvent(c, gin.H{
+		"type": "stage1_complete",
+		"data": stage1,
+		"metadata": gin.H{
+			"failed_models":    failedModels,
+			"omitted_models":   omittedModels,
+			"duplicate_groups": FindDuplicateResponses(stage1),
+			"model_latencies":  modelLatencies,
+		},
+	})
+
+	// Stage 2
+	sendSSEEvent(c, gin.H{"type": "stage2_start"})
+	stage2Start := time.Now()
+	stage2, labelToModel, err := Stage2CollectRankingsStream(ctx, request.Content, stage1, func(ranking Stage2Ranking) {
+		sendSSEEvent(c, gin.H{"type": "stage2_model_complete", "data": ranking})
+	})
+	if streamTimedOut(ctx, c, 2, timeout) || clientDisconnected(ctx, conversationID, 2) {
+		return
+	}
+	if err != nil {
+		sendSSEError(c, fmt.Sprintf("Stage 2 failed: %v", err))
+		return
+	}
+	aggregateRankings := CalculateAggregateRankings(stage2, labelToModel)
+	winner := SummarizeWinner(aggregateRankings)
+	stage2Metadata := gin.H{
+		"label_to_model":     labelToModel,
+		"aggregate_rankings": aggregateRankings,
+		"consensus_score":    CalculateConsensusScore(stage2, labelToModel),
+		"winner":             winner,
+		"ranking_latencies":  RankingLatencies(stage2),
+	}
+	if WinnerRationaleEnabled {
+		stage2Metadata["winner_rationale"] = WinnerRationale(stage2, labelToModel, winner)
+	}
+	sendSSEEvent(c, gin.H{
+		"type":     "stage2_complete",
+		"data":     stage2,
+		"metadata": stage2Metadata,
+	})
+
+	// Stage 3
+	sendSSEEvent(c, gin.H{"type": "stage3_start"})
+	stage3Start := time.Now()
+	stage3, err := Stage3SynthesizeFinalStream(ctx, request.Content, stage1, stage2, func(token string) {
+		sendSSEEvent(c, gin.H{"type": "stage3_token", "data": token})
+	})
+	if streamTimedOut(ctx, c, 3, timeout) {
+		return
+	}
+	if err != nil {
+		sendSSEError(c, fmt.Sprintf("Stage 3 failed: %v", err))
+		return
+	}
+	end := time.Now()
+	sendSSEEvent(c, gin.H{"type": "stage3_complete", "data": stage3})
+
+	// Wait for title if it was being generated
+	if titleChan != nil {
+		if title := <-titleChan; title != "" {
+			sendSSEEvent(c, gin.H{"type": "title_complete", "data": gin.H{"title": title}})
+		}
+	}
+
+	// Save complete assistant message (check for nil first)
+	if stage3 == nil {
+		sendSSEError(c, "Stage 3 returned no result")
+		return
+	}
+	if err := AddAssistantMessage(conversationID, stage1, stage2, *stage3); err != nil {
+		sendSSEError(c, fmt.Sprintf("Failed to save message: %v", err))
+		return
+	}
+
+	// Send completion event with the run's wall-clock timing, in seconds
+	sendSSEEvent(c, gin.H{
+		"type": "complete",
+		"metadata": gin.H{
+			"total_duration":  end.Sub(start).Seconds(),
+			"stage1_duration": stage2Start.Sub(start).Seconds(),
+			"stage2_duration": stage3Start.Sub(stage2Start).Seconds(),
+			"stage3_duration": end.Sub(stage3Start).Seconds(),
+		},
+	})
+}
+
+// clientDisconnected reports whether the streaming client has gone away during the
+// given stage, in which case the remaining stages are skipped rather than paid for.
+func clientDisconnected(ctx context.Context, conversationID string, stage int) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	slog.InfoContext(ctx, "client disconnected, abandoning council run", "conversation_id", conversationID, "stage", stage)
+	return true
+}
+
+// streamTimedOut reports whether the council run streaming to the client has passed
+// its deadline during the given stage, in which case an error event saying so is
+// sent and the remaining stages are skipped.
+func streamTimedOut(ctx context.Context, c *gin.Context, stage int, timeout time.Duration) bool {
+	if !councilTimedOut(ctx) {
+		return false
+	}
+	sendSSEError(c, fmt.Sprintf("Council process failed: %v", councilTimeoutError(stage, timeout)))
+	return true
+}
+
+// sseWriteLockKey is the gin context key for the mutex serializing writes to an SSE
+// stream that has a heartbeat running alongside the handler.
+const sseWriteLockKey = "sse_write_lock"
+
+// startSSEHeartbeat writes a ": keepalive" comment to the stream every interval until
+// the returned stop function is called or ctx is done. stop waits for the heartbeat
+// goroutine to exit, so nothing is written after the handler returns. A non-positive
+// interval disables the heartbeat.
+func startSSEHeartbeat(ctx context.Context, c *gin.Context, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	mu := &sync.Mutex{}
+	c.Set(sseWriteLockKey, mu)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mu.Lock()
+				c.Writer.WriteString(": keepalive\n\n")
+				c.Writer.Flush()
+				mu.Unlock()
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// sendSSEEvent sends a Server-Sent Event.
+// Marshals data to JSON and writes as SSE format with "data: " prefix.
+func sendSSEEvent(c *gin.Context, data interface{}) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to marshal SSE event", "error", err)
+		return
+	}
+	if mu, ok := c.Get(sseWriteLockKey); ok {
+		mu.(*sync.Mutex).Lock()
+		defer mu.(*sync.Mutex).Unlock()
+	}
+	c.Writer.WriteString(fmt.Sprintf("data: %s\n\n", string(jsonData)))
+	c.Writer.Flush()
+}
+
+// sendSSEError sends an error event via SSE.
+// Convenience wrapper for sending error-type SSE events.
+func sendSSEError(c *gin.Context, message string) {
+	sendSSEEvent(c, gin.H{"type": "error", "message": message})
+}
+
+// listModelsHandler returns the configured council models and OpenRouter's model catalog.
+// GET /api/models - Returns council, chairman and title models plus the cached catalog.
+// Query params: ?refresh=true (force catalog refresh)
+// If the catalog can't be fetched, the configured models are still returned along
+// with a catalog_error describing the failure.
+func listModelsHandler(c *gin.Context) {
+	roster := councilConfig(context.Background())
+	response := ModelsResponse{
+		CouncilModels:     roster.CouncilModels,
+		RankerModels:      roster.Rankers(),
+		ChairmanModel:     roster.ChairmanModel,
+		ChairmanFallbacks: roster.ChairmanFallbacks,
+		TitleModel:        TitleModel,
+	}
+
+	catalog, err := loadModelCatalog(c.Request.Context(), c.Query("refresh") == "true")
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to fetch model catalog", "error", err)
+		response.CatalogError = fmt.Sprintf("Failed to fetch model catalog: %v", err)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	response.Catalog = catalog
+	c.JSON(http.StatusOK, response)
+}
+
+// getModelConfigHandler returns the council models and chairman new runs use.
+// GET /api/config/models - Admin only (X-Admin-Key).
+func getModelConfigHandler(c *gin.Context) {
+	roster := councilConfig(context.Background())
+	c.JSON(http.StatusOK, ModelConfig{CouncilModels: roster.CouncilModels, ChairmanModel: roster.ChairmanModel})
+}
+
+// updateModelConfigHandler changes the council models and/or chairman without a
+// restart. Omitted fields are left unchanged. Every model must be listed in
+// OpenRouter's model catalog; runs already in flight finish with the old roster.
+// PUT /api/config/models - Admin only (X-Admin-Key).
+func updateModelConfigHandler(c *gin.Context) {
+	var request ModelConfig
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if len(request.CouncilModels) == 0 && request.ChairmanModel == "" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid request: council_models or chairman_model is required")
+		return
+	}
+
+	roster := councilConfig(context.Background())
+	if len(request.CouncilModels) > 0 {
+		roster.CouncilModels = request.CouncilModels
+	}
+	if request.ChairmanModel != "" {
+		roster.ChairmanModel = request.ChairmanModel
+	}
+	if err := roster.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	catalog, err := loadModelCatalog(c.Request.Context(), false)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, ErrCodeUpstreamFailed, fmt.Sprintf("Failed to fetch model catalog to validate models: %v", err))
+		return
+	}
+	if unknown := unknownModelIDs(catalog, append([]string{request.ChairmanModel}, request.CouncilModels...)); len(unknown) > 0 {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: models not in OpenRouter's catalog: %s", strings.Join(unknown, ", ")), gin.H{
+			"unknown_models": unknown,
 		})
 		return
 	}
 
-	// Return content
-	c.JSON(http.StatusOK, gin.H{
-		"content": content,
+	roster = SetCouncilRoster(request.CouncilModels, request.ChairmanModel)
+	slog.InfoContext(c.Request.Context(), "council roster updated", "council_models", roster.CouncilModels, "chairman_model", roster.ChairmanModel)
+	c.JSON(http.StatusOK, ModelConfig{CouncilModels: roster.CouncilModels, ChairmanModel: roster.ChairmanModel})
+}
+
+// metricsHandler reports runtime health of the backend
+// GET /api/metrics - Returns the circuit breaker state of every model with recent failures
+// and the bills cache hit/miss counts.
+func metricsHandler(c *gin.Context) {
+	var billsStats CacheStats
+	if billsCache != nil {
+		billsStats.Hits, billsStats.Misses = billsCache.Stats()
+	}
+
+	c.JSON(http.StatusOK, MetricsResponse{
+		ModelCircuits: modelCircuits.Snapshot(),
+		BillsCache:    billsStats,
+	})
+}
+
+// getBillsHandler fetches and returns all bills before parliament
+// GET /api/bills - Returns all bills with caching, in scrape order by default
+// Query params: ?refresh=true (force cache refresh), ?sort=date|title|chamber and
+// ?order=asc|desc (default asc), ?from=YYYY-MM-DD and ?to=YYYY-MM-DD (inclusive),
+// ?page=N and ?page_size=N (default BillsPageSize)
+func getBillsHandler(c *gin.Context) {
+	// Check for refresh parameter
+	forceRefresh := c.Query("refresh") == "true"
+
+	sortBy := c.Query("sort")
+	if sortBy != "" && sortBy != BillSortDate && sortBy != BillSortTitle && sortBy != BillSortChamber {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Unsupported sort: %s", sortBy), gin.H{"field": "sort"})
+		return
+	}
+	order := c.DefaultQuery("order", "asc")
+	if order != "asc" && order != "desc" {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Unsupported order: %s", order), gin.H{"field": "order"})
+		return
+	}
+	var filter BillFilter
+	for field, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(field)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid %s date %q, expected YYYY-MM-DD", field, value), gin.H{"field": field})
+			return
+		}
+		*bound = date
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, "'to' date is before 'from' date", gin.H{"field": "to"})
+		return
+	}
+	page, pageSize := 1, BillsPageSize
+	for field, value := range map[string]*int{"page": &page, "page_size": &pageSize} {
+		raw := c.Query(field)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid %s %q, expected a positive integer", field, raw), gin.H{"field": field})
+			return
+		}
+		*value = n
+	}
+
+	// respond filters, sorts and paginates bills, so the pagination fields describe
+	// the bills matching the request rather than everything scraped
+	respond := func(bills []Bill, lastUpdated time.Time, stale bool) {
+		bills = FilterBills(bills, filter)
+		if sortBy != "" {
+			bills = SortBills(bills, sortBy, order == "desc")
+		}
+		pageBills, totalPages := PaginateBills(bills, page, pageSize)
+		c.JSON(http.StatusOK, BillsResponse{
+			Bills:       pageBills,
+			CurrentPage: page,
+			TotalPages:  totalPages,
+			PageSize:    pageSize,
+			TotalBills:  len(bills),
+			HasNextPage: page < totalPages,
+			LastUpdated: lastUpdated,
+			Stale:       stale,
+		})
+	}
+
+	// Try to get from cache first (unless refresh requested)
+	if !forceRefresh {
+		if cachedBills, ok := billsCache.Get(); ok {
+			slog.InfoContext(c.Request.Context(), "returning bills from cache", "count", len(cachedBills))
+			respond(cachedBills, billsCache.GetLastUpdated(), false)
+			return
+		}
+	}
+
+	// Fetch fresh data
+	slog.InfoContext(c.Request.Context(), "fetching fresh bills data from APH website")
+	ctx := c.Request.Context()
+	bills, err := FetchAllBills(ctx)
+	if errors.Is(err, ErrPartialBills) {
+		// A prefix of the listing is still worth serving
+		slog.WarnContext(ctx, "bills listing is incomplete", "error", err, "count", len(bills))
+		err = nil
+	}
+	if err != nil {
+		// Serve whatever was fetched last rather than nothing while APH is unavailable
+		if staleBills, lastUpdated, ok := billsCache.GetStale(); ok {
+			slog.WarnContext(ctx, "failed to fetch bills, returning stale cache", "error", err, "count", len(staleBills), "last_updated", lastUpdated)
+			respond(staleBills, lastUpdated, true)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeUpstreamFailed, fmt.Sprintf("Failed to fetch bills: %v", err))
+		return
+	}
+
+	// Update cache
+	billsCache.Set(bills)
+	slog.InfoContext(c.Request.Context(), "cached bills", "count", len(bills))
+	if billsCacheFile != "" {
+		if err := billsCache.SaveToFile(billsCacheFile); err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to persist bills cache", "error", err)
+		}
+	}
+
+	// Return response
+	respond(bills, time.Now(), false)
+}
+
+// billIDPattern matches APH bill IDs such as "r7365" or "s1254"
+var billIDPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// getBillDetailHandler fetches the full detail page for a single bill
+// GET /api/bills/:id - Returns summary, sponsor, progress history and document links
+// Query params: ?refresh=true (force cache refresh)
+func getBillDetailHandler(c *gin.Context) {
+	billID := c.Param("id")
+	if !billIDPattern.MatchString(billID) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid bill ID: %q", billID))
+		return
+	}
+
+	ctx := c.Request.Context()
+	detail, err := loadBillDetail(ctx, billID, c.Query("refresh") == "true")
+	if err != nil {
+		respondBillDetailError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// respondBillDetailError reports a failed bill detail fetch, as 404 bill_not_found
+// when the bill doesn't exist and 500 upstream_failed otherwise.
+func respondBillDetailError(c *gin.Context, err error) {
+	message := fmt.Sprintf("Failed to fetch bill detail: %v", err)
+	if errors.Is(err, ErrBillNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeBillNotFound, message)
+		return
+	}
+	respondError(c, http.StatusInternalServerError, ErrCodeUpstreamFailed, message)
+}
+
+// cachedBill returns the bill with the given ID from the cached bills list, if present.
+func cachedBill(billID string) (Bill, bool) {
+	if cachedBills, ok := billsCache.Get(); ok {
+		for _, cached := range cachedBills {
+			if cached.ID == billID {
+				return cached, true
+			}
+		}
+	}
+	return Bill{}, false
+}
+
+// loadBillDetail returns a bill's detail from billDetailCache, fetching and caching
+// it on a miss or when refresh is set.
+func loadBillDetail(ctx context.Context, billID string, refresh bool) (*BillDetail, error) {
+	if !refresh {
+		if detail, ok := billDetailCache.Get(billID); ok {
+			return detail, nil
+		}
+	}
+
+	// Start from the cached list entry when available so its BillURL is used
+	bill, ok := cachedBill(billID)
+	if !ok {
+		bill = Bill{ID: billID}
+	}
+
+	detail, err := FetchBillDetail(ctx, bill)
+	if err != nil {
+		return nil, err
+	}
+
+	billDetailCache.Set(billID, detail)
+	return detail, nil
+}
+
+// analyzeBillHandler runs the council on a bill and saves the result as a new conversation.
+// POST /api/bills/:id/analyze - Returns the new conversation ID plus all three stages.
+// Uses the bill's detail page when available, falling back to the summary from the
+// bills list if the detail page can't be fetched.
+func analyzeBillHandler(c *gin.Context) {
+	billID := c.Param("id")
+	if !billIDPattern.MatchString(billID) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid bill ID: %q", billID))
+		return
+	}
+
+	ctx := c.Request.Context()
+	detail, err := loadBillDetail(ctx, billID, false)
+	if err != nil {
+		bill, ok := cachedBill(billID)
+		if errors.Is(err, ErrBillNotFound) || !ok {
+			respondBillDetailError(c, err)
+			return
+		}
+		slog.WarnContext(ctx, "bill detail unavailable, analyzing list summary", "bill_id", billID, "error", err)
+		detail = &BillDetail{Bill: bill}
+	}
+
+	// Create the conversation up front so the question is kept even if the council fails
+	conversationID := uuid.New().String()
+	if _, err := CreateConversation(conversationID); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to create conversation: %v", err))
+		return
+	}
+	title := detail.Title
+	if title == "" {
+		title = "Bill " + billID
+	}
+	if err := UpdateConversationTitle(conversationID, title); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to set conversation title: %v", err))
+		return
+	}
+
+	prompt := buildBillAnalysisPrompt(detail)
+	if err := AddUserMessage(conversationID, prompt); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to add user message: %v", err))
+		return
+	}
+
+	stage1, stage2, stage3, metadata, err := RunFullCouncil(ctx, prompt)
+	if err != nil {
+		respondErrorDetails(c, councilErrorStatus(err), ErrCodeCouncilFailed, fmt.Sprintf("Council process failed: %v", err), gin.H{
+			"conversation_id": conversationID,
+		})
+		return
+	}
+
+	if err := AddAssistantMessage(conversationID, stage1, stage2, stage3); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageFailed, fmt.Sprintf("Failed to add assistant message: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, AnalyzeBillResponse{
+		ConversationID: conversationID,
+		SendMessageResponse: SendMessageResponse{
+			Stage1:   stage1,
+			Stage2:   stage2,
+			Stage3:   stage3,
+			Metadata: metadata,
+		},
 	})
 }
+
+// fetchURLResponse is the fetch-url payload: the extracted page plus whether it came from cache
+type fetchURLResponse struct {
+	FetchURLResult
+	Cached bool `json:"cached"`
+}
+
+// fetchURLHandler fetches and extracts content from a given URL
+// POST /api/fetch-url - Body: {"url": "https://..."}
+// Query params: ?refresh=true (bypass the URL content cache)
+func fetchURLHandler(c *gin.Context) {
+	// Parse request
+	var request struct {
+		URL string `json:"url" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	// Serve from cache unless a refresh was requested
+	cacheKey := NormalizeContentURL(request.URL)
+	if c.Query("refresh") != "true" {
+		if result, ok := urlContentCache.Get(cacheKey); ok {
+			c.JSON(http.StatusOK, fetchURLResponse{FetchURLResult: *result, Cached: true})
+			return
+		}
+	}
+
+	// Fetch content
+	ctx := c.Request.Context()
+	result, err := FetchURLContent(ctx, request.URL)
+	if err != nil {
+		message := fmt.Sprintf("Failed to fetch URL content: %v", err)
+		switch {
+		case errors.Is(err, ErrUnsupportedContentType):
+			respondError(c, http.StatusUnsupportedMediaType, ErrCodeUnsupportedFormat, message)
+		case errors.Is(err, ErrContentTooLarge):
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, message)
+		case errors.Is(err, ErrURLNotAllowed):
+			respondErrorDetails(c, http.StatusBadRequest, ErrCodeInvalidRequest, message, gin.H{"field": "url"})
+		default:
+			respondError(c, http.StatusInternalServerError, ErrCodeUpstreamFailed, message)
+		}
+		return
+	}
+
+	urlContentCache.Set(cacheKey, result)
+
+	// Return content
+	c.JSON(http.StatusOK, fetchURLResponse{FetchURLResult: *result})
+}
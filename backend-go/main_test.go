@@ -1,13 +1,24 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -80,6 +91,48 @@ func TestListConversationsHandler(t *testing.T) {
 	}
 }
 
+// TestListConversationsHandlerSort tests ?sort=updated and rejecting unknown sorts
+func TestListConversationsHandlerSort(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	CreateConversation("older")
+	time.Sleep(2 * time.Millisecond)
+	CreateConversation("newer")
+	time.Sleep(2 * time.Millisecond)
+	UpdateConversationTitle("older", "Renamed")
+
+	router := gin.New()
+	router.GET("/api/conversations", listConversationsHandler)
+
+	list := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/conversations"+query, nil))
+		return w
+	}
+
+	for query, first := range map[string]string{"": "newer", "?sort=created": "newer", "?sort=updated": "older"} {
+		w := list(query)
+		var conversations []ConversationMetadata
+		if err := json.Unmarshal(w.Body.Bytes(), &conversations); err != nil {
+			t.Fatalf("%q: failed to parse response: %v", query, err)
+		}
+		if len(conversations) != 2 || conversations[0].ID != first {
+			t.Errorf("%q: got %+v, want %s first", query, conversations, first)
+		}
+	}
+
+	apiErr := AssertAPIError(t, list("?sort=title"), http.StatusBadRequest, ErrCodeInvalidRequest)
+	if apiErr.Details["field"] != "sort" {
+		t.Errorf("Details = %v, want field sort", apiErr.Details)
+	}
+}
+
 // TestCreateConversationHandler tests conversation creation
 func TestCreateConversationHandler(t *testing.T) {
 	helper := NewTestHelper(t)
@@ -157,9 +210,7 @@ func TestGetConversationHandler(t *testing.T) {
 
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusNotFound {
-			t.Errorf("Status = %d, want %d", w.Code, http.StatusNotFound)
-		}
+		AssertAPIError(t, w, http.StatusNotFound, ErrCodeConversationNotFound)
 	})
 }
 
@@ -205,6 +256,7 @@ func TestSendMessageHandler(t *testing.T) {
 					Content          string      `json:"content"`
 					ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
 				} `json:"message"`
+				FinishReason string `json:"finish_reason"`
 			}{
 				{Message: struct {
 					Content          string      `json:"content"`
@@ -268,9 +320,7 @@ func TestSendMessageHandler(t *testing.T) {
 
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("Status = %d, want %d", w.Code, http.StatusBadRequest)
-		}
+		AssertAPIError(t, w, http.StatusBadRequest, ErrCodeInvalidRequest)
 	})
 
 	t.Run("non-existent conversation", func(t *testing.T) {
@@ -285,9 +335,7 @@ func TestSendMessageHandler(t *testing.T) {
 
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusNotFound {
-			t.Errorf("Status = %d, want %d", w.Code, http.StatusNotFound)
-		}
+		AssertAPIError(t, w, http.StatusNotFound, ErrCodeConversationNotFound)
 	})
 }
 
@@ -402,6 +450,48 @@ func TestSendMessageStreamHandler(t *testing.T) {
 		if body == "" {
 			t.Error("Expected SSE stream data")
 		}
+
+		// Stage 3 should stream token-by-token before completing
+		var tokens []string
+		var stage3 Stage3Response
+		var timing *Metadata
+		for _, line := range strings.Split(body, "\n") {
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var event struct {
+				Type     string          `json:"type"`
+				Data     json.RawMessage `json:"data"`
+				Metadata json.RawMessage `json:"metadata"`
+			}
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event)
+			switch event.Type {
+			case "stage3_token":
+				var token string
+				json.Unmarshal(event.Data, &token)
+				tokens = append(tokens, token)
+			case "stage3_complete":
+				json.Unmarshal(event.Data, &stage3)
+			case "complete":
+				timing = &Metadata{}
+				json.Unmarshal(event.Metadata, timing)
+			}
+		}
+		if len(tokens) != 2 {
+			t.Errorf("Expected 2 stage3_token events, got %d: %v", len(tokens), tokens)
+		}
+		if stage3.Response != "Test response" || strings.Join(tokens, "") != stage3.Response {
+			t.Errorf("stage3_complete response = %q, tokens = %v", stage3.Response, tokens)
+		}
+
+		// The complete event carries the run's timing
+		if timing == nil {
+			t.Fatal("Expected a complete event")
+		}
+		stages := timing.Stage1Duration + timing.Stage2Duration + timing.Stage3Duration
+		if timing.Stage1Duration <= 0 || timing.Stage3Duration <= 0 || timing.TotalDuration < stages-1e-6 {
+			t.Errorf("complete timing = total %v, stages %v/%v/%v", timing.TotalDuration, timing.Stage1Duration, timing.Stage2Duration, timing.Stage3Duration)
+		}
 	})
 
 	t.Run("stream with invalid request", func(t *testing.T) {
@@ -411,9 +501,7 @@ func TestSendMessageStreamHandler(t *testing.T) {
 
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("Status = %d, want %d", w.Code, http.StatusBadRequest)
-		}
+		AssertAPIError(t, w, http.StatusBadRequest, ErrCodeInvalidRequest)
 	})
 
 	t.Run("stream with non-existent conversation", func(t *testing.T) {
@@ -428,9 +516,7 @@ func TestSendMessageStreamHandler(t *testing.T) {
 
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusNotFound {
-			t.Errorf("Status = %d, want %d", w.Code, http.StatusNotFound)
-		}
+		AssertAPIError(t, w, http.StatusNotFound, ErrCodeConversationNotFound)
 	})
 }
 
@@ -489,9 +575,7 @@ func TestListConversationsHandlerError(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("Status = %d, want %d", w.Code, http.StatusInternalServerError)
-	}
+	AssertAPIError(t, w, http.StatusInternalServerError, ErrCodeStorageFailed)
 }
 
 // TestCreateConversationHandlerError tests error handling in create conversation
@@ -509,9 +593,7 @@ func TestCreateConversationHandlerError(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("Status = %d, want %d", w.Code, http.StatusInternalServerError)
-	}
+	AssertAPIError(t, w, http.StatusInternalServerError, ErrCodeStorageFailed)
 }
 
 // TestGetConversationHandlerError tests error handling in get conversation
@@ -535,9 +617,7 @@ func TestGetConversationHandlerError(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("Status = %d, want %d", w.Code, http.StatusInternalServerError)
-	}
+	AssertAPIError(t, w, http.StatusInternalServerError, ErrCodeStorageFailed)
 }
 
 // TestSendMessageHandlerGetConversationError tests error when getting conversation fails
@@ -565,9 +645,7 @@ func TestSendMessageHandlerGetConversationError(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("Status = %d, want %d", w.Code, http.StatusInternalServerError)
-	}
+	AssertAPIError(t, w, http.StatusInternalServerError, ErrCodeStorageFailed)
 }
 
 // TestSendMessageStreamHandlerGetConversationError tests stream error handling
@@ -595,9 +673,7 @@ func TestSendMessageStreamHandlerGetConversationError(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("Status = %d, want %d", w.Code, http.StatusInternalServerError)
-	}
+	AssertAPIError(t, w, http.StatusInternalServerError, ErrCodeStorageFailed)
 }
 
 // TestSendMessageHandlerAddUserMessageError tests error when adding user message fails
@@ -610,10 +686,11 @@ func TestSendMessageHandlerAddUserMessageError(t *testing.T) {
 	DataDir = tempDir
 	defer func() { DataDir = oldDataDir }()
 
-	// Create conversation then make directory read-only
+	// Create conversation then make directory read-only. Saves replace the file via a
+	// temp file in the same directory, so a read-only file alone wouldn't stop them
 	CreateConversation("readonly-test")
-	// Make the conversation file read-only
-	os.Chmod(GetConversationPath("readonly-test"), 0444)
+	os.Chmod(tempDir, 0555)
+	defer os.Chmod(tempDir, 0755)
 
 	router := gin.New()
 	router.POST("/api/conversations/:id/message", sendMessageHandler)
@@ -627,7 +704,2720 @@ func TestSendMessageHandlerAddUserMessageError(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("Status = %d, want %d", w.Code, http.StatusInternalServerError)
+	AssertAPIError(t, w, http.StatusInternalServerError, ErrCodeStorageFailed)
+}
+
+// TestSendMessageHandlerConversationFull tests rejecting a message past the conversation limit
+func TestSendMessageHandlerConversationFull(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir, oldMax, oldPolicy := DataDir, MaxMessagesPerConversation, MessageLimitPolicy
+	DataDir = tempDir
+	defer func() {
+		DataDir, MaxMessagesPerConversation, MessageLimitPolicy = oldDataDir, oldMax, oldPolicy
+	}()
+	MaxMessagesPerConversation = 2
+	MessageLimitPolicy = MessageLimitReject
+
+	// The sample conversation already holds a question and its answer
+	SaveConversation(SampleConversation("full-test"))
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message", sendMessageHandler)
+
+	req := httptest.NewRequest("POST", "/api/conversations/full-test/message", strings.NewReader(`{"content": "One more?"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	apiErr := AssertAPIError(t, w, http.StatusConflict, ErrCodeConversationFull)
+	if apiErr.Details["max_messages"] != float64(2) {
+		t.Errorf("Details = %v, want max_messages 2", apiErr.Details)
+	}
+}
+
+// TestQueryHandler tests running the council on a one-off question without saving it
+func TestQueryHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL, oldAPIKey := OpenRouterAPIURL, OpenRouterAPIKey
+	oldModels, oldRankers, oldChairman := CouncilModels, RankerModels, ChairmanModel
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL, OpenRouterAPIKey = oldAPIURL, oldAPIKey
+		CouncilModels, RankerModels, ChairmanModel = oldModels, oldRankers, oldChairman
+	}()
+	DataDir = tempDir
+	CouncilModels = []string{"test/one", "test/two"}
+	RankerModels = nil
+	ChairmanModel = "test/chair"
+
+	mockServer := MockOpenRouterServer(t, CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A\n2. Response B"))
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	router := gin.New()
+	router.POST("/api/query", queryHandler)
+
+	query := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/query", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := query(`{"content": "What is Go?"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var response SendMessageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Stage1) != 2 || len(response.Stage2) != 2 || response.Stage3.Model != "test/chair" {
+		t.Errorf("Response = %+v", response)
+	}
+
+	AssertAPIError(t, query(`{"content": "   "}`), http.StatusBadRequest, ErrCodeInvalidRequest)
+	AssertAPIError(t, query(`{}`), http.StatusBadRequest, ErrCodeInvalidRequest)
+
+	// Nothing is written to storage
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read data directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no files in the data directory, got %d", len(entries))
+	}
+	conversations, err := ListConversations(ConversationFilter{})
+	if err != nil {
+		t.Fatalf("ListConversations failed: %v", err)
+	}
+	if len(conversations) != 0 {
+		t.Errorf("Expected no conversations, got %d", len(conversations))
+	}
+}
+
+// TestQueryHandlerModes tests running each council preset and rejecting unknown modes
+func TestQueryHandlerModes(t *testing.T) {
+	oldAPIURL, oldAPIKey := OpenRouterAPIURL, OpenRouterAPIKey
+	oldModels, oldRankers, oldChairman, oldFallbacks := CouncilModels, RankerModels, ChairmanModel, ChairmanFallbacks
+	oldStructured, oldEffort := StructuredRankingModels, CouncilReasoningEffort
+	defer func() {
+		OpenRouterAPIURL, OpenRouterAPIKey = oldAPIURL, oldAPIKey
+		CouncilModels, RankerModels, ChairmanModel, ChairmanFallbacks = oldModels, oldRankers, oldChairman, oldFallbacks
+		StructuredRankingModels, CouncilReasoningEffort = oldStructured, oldEffort
+	}()
+	CouncilModels = []string{"configured/one", "configured/two"}
+	RankerModels = nil
+	ChairmanModel = "configured/chair"
+	ChairmanFallbacks = nil
+	StructuredRankingModels = nil
+	CouncilReasoningEffort = "medium"
+
+	var mu sync.Mutex
+	efforts := map[string]string{}
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[len(req.Messages)-1].Content
+		if prompt == "What is Go?" { // Stage 1
+			mu.Lock()
+			efforts[req.Model] = ""
+			if req.Reasoning != nil {
+				efforts[req.Model] = req.Reasoning.Effort
+			}
+			mu.Unlock()
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "FINAL RANKING:\n1. Response A"}},
+			},
+		})
+	})
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	router := gin.New()
+	router.POST("/api/query", queryHandler)
+
+	query := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, name := range []string{"fast", "balanced", "thorough"} {
+		want, err := CouncilMode(name)
+		if err != nil {
+			t.Fatalf("CouncilMode(%q) failed: %v", name, err)
+		}
+		wantEffort := want.ReasoningEffort
+		if wantEffort == "" {
+			wantEffort = CouncilReasoningEffort
+		}
+
+		// The mode may be given in the body or the query string
+		for _, w := range []*httptest.ResponseRecorder{
+			query("/api/query", `{"content": "What is Go?", "mode": "`+name+`"}`),
+			query("/api/query?mode="+name, `{"content": "What is Go?"}`),
+		} {
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d: %s", name, w.Code, w.Body.String())
+			}
+			var response SendMessageResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("%s: failed to parse response: %v", name, err)
+			}
+			if response.Metadata.Mode != name {
+				t.Errorf("%s: metadata mode = %q", name, response.Metadata.Mode)
+			}
+			if response.Stage3.Model != want.ChairmanModel {
+				t.Errorf("%s: chairman = %q, want %q", name, response.Stage3.Model, want.ChairmanModel)
+			}
+			var answered []string
+			for _, result := range response.Stage1 {
+				answered = append(answered, result.Model)
+			}
+			if !reflect.DeepEqual(answered, want.CouncilModels) {
+				t.Errorf("%s: council = %v, want %v", name, answered, want.CouncilModels)
+			}
+		}
+		mu.Lock()
+		for _, model := range want.CouncilModels {
+			if efforts[model] != wantEffort {
+				t.Errorf("%s: %s reasoning effort = %q, want %q", name, model, efforts[model], wantEffort)
+			}
+		}
+		mu.Unlock()
+	}
+
+	w := query("/api/query?mode=turbo", `{"content": "What is Go?"}`)
+	AssertAPIError(t, w, http.StatusBadRequest, ErrCodeInvalidRequest)
+	var errResponse struct {
+		Error struct {
+			Details map[string]interface{} `json:"details"`
+		} `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &errResponse)
+	if errResponse.Error.Details["field"] != "mode" || !reflect.DeepEqual(errResponse.Error.Details["modes"], []interface{}{"balanced", "fast", "thorough"}) {
+		t.Errorf("Unknown mode details = %v", errResponse.Error.Details)
+	}
+	AssertAPIError(t, query("/api/query", `{"content": "What is Go?", "mode": "turbo"}`), http.StatusBadRequest, ErrCodeInvalidRequest)
+}
+
+// TestCompareHandler tests running two council rosters side by side
+func TestCompareHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL, oldAPIKey := OpenRouterAPIURL, OpenRouterAPIKey
+	oldModels, oldRankers, oldChairman, oldFallbacks := CouncilModels, RankerModels, ChairmanModel, ChairmanFallbacks
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL, OpenRouterAPIKey = oldAPIURL, oldAPIKey
+		CouncilModels, RankerModels, ChairmanModel, ChairmanFallbacks = oldModels, oldRankers, oldChairman, oldFallbacks
+	}()
+	DataDir = tempDir
+
+	// The configured roster must not be used by either side of the comparison
+	CouncilModels = []string{"configured/model"}
+	RankerModels = nil
+	ChairmanModel = "configured/chairman"
+	ChairmanFallbacks = nil
+
+	var mu sync.Mutex
+	queried := map[string]int{}
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[len(req.Messages)-1].Content
+
+		mu.Lock()
+		queried[req.Model]++
+		mu.Unlock()
+
+		response := "Answer from " + req.Model
+		switch {
+		case strings.HasSuffix(req.Model, "/chair"):
+			response = "Synthesis by " + req.Model
+		case strings.HasPrefix(prompt, "You are evaluating"):
+			response = "FINAL RANKING:\n1. Response A"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": response}},
+			},
+		})
+	})
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	router := gin.New()
+	router.POST("/api/compare", compareHandler)
+
+	compare := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/compare", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := compare(`{
+		"content": "What is Go?",
+		"a": {"council_models": ["a/one", "a/two"], "chairman_model": "a/chair"},
+		"b": {"council_models": ["b/one"], "ranker_models": ["b/ranker"], "chairman_model": "b/chair"}
+	}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var response CompareResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.A.Stage1) != 2 || response.A.Stage1[0].Model != "a/one" || response.A.Stage3.Model != "a/chair" {
+		t.Errorf("Council a result = %+v", response.A)
+	}
+	if len(response.B.Stage1) != 1 || response.B.Stage1[0].Model != "b/one" || response.B.Stage3.Model != "b/chair" {
+		t.Errorf("Council b result = %+v", response.B)
+	}
+	if len(response.B.Stage2) != 1 || response.B.Stage2[0].Model != "b/ranker" {
+		t.Errorf("Council b rankings = %+v, want one from b/ranker", response.B.Stage2)
+	}
+
+	// a/one and a/two answer and rank; b/one only answers, b/ranker only ranks
+	want := map[string]int{"a/one": 2, "a/two": 2, "a/chair": 1, "b/one": 1, "b/ranker": 1, "b/chair": 1}
+	if !reflect.DeepEqual(queried, want) {
+		t.Errorf("Queried models = %v, want %v", queried, want)
+	}
+
+	// Nothing is saved as a conversation
+	if conversations, _ := ListConversations(ConversationFilter{}); len(conversations) != 0 {
+		t.Errorf("Expected no conversations, got %d", len(conversations))
+	}
+
+	for name, tt := range map[string]struct{ body, field string }{
+		"empty content":     {`{"content": " ", "a": {"council_models": ["a/one"], "chairman_model": "a/chair"}, "b": {"council_models": ["b/one"], "chairman_model": "b/chair"}}`, "content"},
+		"bad image url":     {`{"content": "Hi", "image_urls": ["ftp://example.com/a.png"], "a": {"council_models": ["a/one"], "chairman_model": "a/chair"}, "b": {"council_models": ["b/one"], "chairman_model": "b/chair"}}`, "image_urls"},
+		"missing council b": {`{"content": "Hi", "a": {"council_models": ["a/one"], "chairman_model": "a/chair"}}`, "b"},
+		"bad model id":      {`{"content": "Hi", "a": {"council_models": ["gpt five"], "chairman_model": "a/chair"}, "b": {"council_models": ["b/one"], "chairman_model": "b/chair"}}`, "a"},
+		"missing chairman":  {`{"content": "Hi", "a": {"council_models": ["a/one"]}, "b": {"council_models": ["b/one"], "chairman_model": "b/chair"}}`, "a"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			apiErr := AssertAPIError(t, compare(tt.body), http.StatusBadRequest, ErrCodeInvalidRequest)
+			if !strings.HasPrefix(apiErr.Message, "Invalid request: ") || apiErr.Details["field"] != tt.field {
+				t.Errorf("Error = %q with details %v, want an \"Invalid request: \" message for field %s", apiErr.Message, apiErr.Details, tt.field)
+			}
+		})
+	}
+}
+
+// TestCompareHandlerCouncilFailure tests reporting which council of a comparison failed
+func TestCompareHandlerCouncilFailure(t *testing.T) {
+	oldAPIURL, oldAPIKey := OpenRouterAPIURL, OpenRouterAPIKey
+	oldRetries := CouncilRunRetries
+	defer func() {
+		OpenRouterAPIURL, OpenRouterAPIKey = oldAPIURL, oldAPIKey
+		CouncilRunRetries = oldRetries
+	}()
+	CouncilRunRetries = 0
+
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if strings.HasPrefix(req.Model, "b/") {
+			http.Error(w, `{"error": {"message": "bad request"}}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "FINAL RANKING:\n1. Response A"}},
+			},
+		})
+	})
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	router := gin.New()
+	router.POST("/api/compare", compareHandler)
+
+	body := `{"content": "What is Go?", "a": {"council_models": ["a/one"], "chairman_model": "a/chair"}, "b": {"council_models": ["b/one"], "chairman_model": "b/chair"}}`
+	req := httptest.NewRequest("POST", "/api/compare", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code < 400 {
+		t.Fatalf("Status = %d, want an error", w.Code)
+	}
+	var response ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Error.Code != ErrCodeCouncilFailed || response.Error.Details["council"] != "b" {
+		t.Errorf("Error = %+v, want council_failed for council b", response.Error)
+	}
+}
+
+// TestCouncilErrorStatus tests mapping council errors to HTTP status codes
+func TestCouncilErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "upstream auth error",
+			err:  fmt.Errorf("stage 3 failed: %w", &OpenRouterError{StatusCode: 401}),
+			want: http.StatusBadGateway,
+		},
+		{
+			name: "upstream forbidden",
+			err:  fmt.Errorf("stage 3 failed: %w", &OpenRouterError{StatusCode: 403}),
+			want: http.StatusBadGateway,
+		},
+		{
+			name: "upstream server error",
+			err:  fmt.Errorf("stage 3 failed: %w", &OpenRouterError{StatusCode: 500, Retryable: true}),
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "council timeout",
+			err:  fmt.Errorf("stage 2: %w after 5m0s", ErrCouncilTimeout),
+			want: http.StatusGatewayTimeout,
+		},
+		{
+			name: "plain error",
+			err:  fmt.Errorf("all council models failed to respond"),
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := councilErrorStatus(tt.err); got != tt.want {
+				t.Errorf("councilErrorStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestListModelsHandler tests listing configured models and the OpenRouter catalog
+func TestListModelsHandler(t *testing.T) {
+	oldModelsURL := OpenRouterModelsURL
+	oldCache := modelCatalogCache
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	defer func() {
+		OpenRouterModelsURL = oldModelsURL
+		modelCatalogCache = oldCache
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+	}()
+
+	CouncilModels = []string{"model/a", "model/b"}
+	ChairmanModel = "model/chairman"
+
+	router := gin.New()
+	router.GET("/api/models", listModelsHandler)
+
+	t.Run("includes configured models and cached catalog", func(t *testing.T) {
+		catalogRequests := 0
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			catalogRequests++
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data": [
+				{"id": "model/a", "name": "Model A", "context_length": 128000, "pricing": {"prompt": "0.000001", "completion": "0.000002"}},
+				{"id": "model/b", "name": "Model B", "context_length": 32000, "pricing": {"prompt": "0", "completion": "0"}}
+			]}`))
+		})
+		defer mockServer.Close()
+
+		OpenRouterModelsURL = mockServer.URL
+		modelCatalogCache = NewModelCatalogCache(time.Hour)
+
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest("GET", "/api/models", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			var response ModelsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+
+			if !reflect.DeepEqual(response.CouncilModels, CouncilModels) {
+				t.Errorf("CouncilModels = %v, want %v", response.CouncilModels, CouncilModels)
+			}
+			if response.ChairmanModel != "model/chairman" {
+				t.Errorf("ChairmanModel = %q, want 'model/chairman'", response.ChairmanModel)
+			}
+			if response.TitleModel != TitleModel {
+				t.Errorf("TitleModel = %q, want %q", response.TitleModel, TitleModel)
+			}
+			if len(response.Catalog) != 2 {
+				t.Fatalf("Catalog length = %d, want 2", len(response.Catalog))
+			}
+			if response.Catalog[0].ID != "model/a" || response.Catalog[0].ContextLength != 128000 {
+				t.Errorf("Unexpected catalog entry: %+v", response.Catalog[0])
+			}
+			if response.CatalogError != "" {
+				t.Errorf("Unexpected catalog error: %s", response.CatalogError)
+			}
+		}
+
+		// Second request should be served from cache
+		if catalogRequests != 1 {
+			t.Errorf("Catalog fetched %d times, want 1", catalogRequests)
+		}
+	})
+
+	t.Run("catalog failure still returns configured models", func(t *testing.T) {
+		mockServer := MockOpenRouterServer(t, CreateMockOpenRouterErrorHandler(503, "unavailable"))
+		defer mockServer.Close()
+
+		OpenRouterModelsURL = mockServer.URL
+		modelCatalogCache = NewModelCatalogCache(time.Hour)
+
+		req := httptest.NewRequest("GET", "/api/models", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var response ModelsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+
+		if !reflect.DeepEqual(response.CouncilModels, CouncilModels) {
+			t.Errorf("CouncilModels = %v, want %v", response.CouncilModels, CouncilModels)
+		}
+		if response.CatalogError == "" {
+			t.Error("Expected catalog_error to be set")
+		}
+		if len(response.Catalog) != 0 {
+			t.Errorf("Expected empty catalog, got %d entries", len(response.Catalog))
+		}
+	})
+}
+
+// TestModelConfigHandlers tests reading and updating the council roster at runtime
+func TestModelConfigHandlers(t *testing.T) {
+	oldModelsURL := OpenRouterModelsURL
+	oldCache := modelCatalogCache
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	defer func() {
+		OpenRouterModelsURL = oldModelsURL
+		modelCatalogCache = oldCache
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+	}()
+
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": [{"id": "model/a"}, {"id": "model/b"}, {"id": "model/c"}, {"id": "model/chairman"}]}`))
+	})
+	defer mockServer.Close()
+
+	OpenRouterModelsURL = mockServer.URL
+	modelCatalogCache = NewModelCatalogCache(time.Hour)
+	CouncilModels = []string{"model/a", "model/b"}
+	ChairmanModel = "model/chairman"
+
+	router := gin.New()
+	router.GET("/api/config/models", AdminAuthMiddleware("admin-secret"), getModelConfigHandler)
+	router.PUT("/api/config/models", AdminAuthMiddleware("admin-secret"), updateModelConfigHandler)
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/config/models", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Admin-Key", "admin-secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) ModelConfig {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var config ModelConfig
+		if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return config
+	}
+
+	t.Run("read", func(t *testing.T) {
+		config := decode(do("GET", ""))
+		want := ModelConfig{CouncilModels: []string{"model/a", "model/b"}, ChairmanModel: "model/chairman"}
+		if !reflect.DeepEqual(config, want) {
+			t.Errorf("Config = %+v, want %+v", config, want)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		config := decode(do("PUT", `{"council_models": ["model/b", "model/c"], "chairman_model": "model/a"}`))
+		want := ModelConfig{CouncilModels: []string{"model/b", "model/c"}, ChairmanModel: "model/a"}
+		if !reflect.DeepEqual(config, want) {
+			t.Errorf("Config = %+v, want %+v", config, want)
+		}
+		if !reflect.DeepEqual(CouncilModels, want.CouncilModels) || ChairmanModel != want.ChairmanModel {
+			t.Errorf("Roster = %v / %q, want %v / %q", CouncilModels, ChairmanModel, want.CouncilModels, want.ChairmanModel)
+		}
+		if read := decode(do("GET", "")); !reflect.DeepEqual(read, want) {
+			t.Errorf("GET after update = %+v, want %+v", read, want)
+		}
+	})
+
+	t.Run("partial update keeps other fields", func(t *testing.T) {
+		config := decode(do("PUT", `{"chairman_model": "model/chairman"}`))
+		want := ModelConfig{CouncilModels: []string{"model/b", "model/c"}, ChairmanModel: "model/chairman"}
+		if !reflect.DeepEqual(config, want) {
+			t.Errorf("Config = %+v, want %+v", config, want)
+		}
+	})
+
+	t.Run("validation rejection", func(t *testing.T) {
+		before := slices.Clone(CouncilModels)
+
+		tests := []struct {
+			name        string
+			body        string
+			wantUnknown []interface{}
+		}{
+			{name: "unknown models", body: `{"council_models": ["model/a", "model/typo"], "chairman_model": "model/missing"}`, wantUnknown: []interface{}{"model/missing", "model/typo"}},
+			{name: "malformed model ID", body: `{"council_models": ["not-a-model"]}`},
+			{name: "empty request", body: `{}`},
+			{name: "invalid JSON", body: `{"council_models": "model/a"}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				apiErr := AssertAPIError(t, do("PUT", tt.body), http.StatusBadRequest, ErrCodeInvalidRequest)
+				if tt.wantUnknown != nil && !reflect.DeepEqual(apiErr.Details["unknown_models"], tt.wantUnknown) {
+					t.Errorf("Details = %v, want unknown_models %v", apiErr.Details, tt.wantUnknown)
+				}
+			})
+		}
+
+		if !reflect.DeepEqual(CouncilModels, before) || ChairmanModel != "model/chairman" {
+			t.Errorf("Rejected updates changed the roster to %v / %q", CouncilModels, ChairmanModel)
+		}
+	})
+
+	t.Run("requires admin key", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/api/config/models", strings.NewReader(`{"chairman_model": "model/a"}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		AssertAPIError(t, w, http.StatusUnauthorized, ErrCodeUnauthorized)
+		if ChairmanModel != "model/chairman" {
+			t.Errorf("ChairmanModel = %q, want it unchanged", ChairmanModel)
+		}
+	})
+}
+
+// TestExportConversationHandler tests the export endpoint
+func TestExportConversationHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	SaveConversation(SampleConversation("export-handler"))
+
+	router := gin.New()
+	router.GET("/api/conversations/:id/export", exportConversationHandler)
+
+	t.Run("markdown export", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/conversations/export-handler/export?format=markdown", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/markdown") {
+			t.Errorf("Content-Type = %q, want text/markdown", w.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(w.Header().Get("Content-Disposition"), "export-handler.md") {
+			t.Errorf("Content-Disposition = %q, want filename export-handler.md", w.Header().Get("Content-Disposition"))
+		}
+		if !strings.Contains(w.Body.String(), "# Test Conversation") {
+			t.Errorf("Body missing title: %s", w.Body.String())
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/conversations/export-handler/export?format=pdf", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		AssertAPIError(t, w, http.StatusBadRequest, ErrCodeUnsupportedFormat)
+	})
+
+	t.Run("non-existent conversation", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/conversations/missing/export", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		AssertAPIError(t, w, http.StatusNotFound, ErrCodeConversationNotFound)
+	})
+}
+
+// TestExportAllConversationsHandler tests streaming every conversation as a zip archive
+func TestExportAllConversationsHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	SaveConversation(SampleConversation("backup-a"))
+	SaveConversation(SampleConversation("backup-b"))
+	os.WriteFile(filepath.Join(tempDir, "corrupt.json"), []byte("{not json"), 0644)
+
+	router := gin.New()
+	router.GET("/api/conversations/export/all", exportAllConversationsHandler)
+	router.GET("/api/conversations/:id/export", exportConversationHandler)
+
+	export := func(t *testing.T, query string) map[string]string {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/conversations/export/all"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		if w.Header().Get("Content-Type") != "application/zip" {
+			t.Errorf("Content-Type = %q, want application/zip", w.Header().Get("Content-Type"))
+		}
+		if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="conversations-`) || !strings.HasSuffix(disposition, `.zip"`) {
+			t.Errorf("Content-Disposition = %q, want a conversations-*.zip attachment", disposition)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if err != nil {
+			t.Fatalf("Failed to read zip: %v", err)
+		}
+		files := make(map[string]string)
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("Failed to open %s: %v", f.Name, err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			files[f.Name] = string(data)
+		}
+		return files
+	}
+
+	t.Run("json by default", func(t *testing.T) {
+		files := export(t, "")
+
+		if len(files) != 2 {
+			t.Fatalf("Zip entries = %v, want backup-a.json and backup-b.json", slices.Collect(maps.Keys(files)))
+		}
+		for _, id := range []string{"backup-a", "backup-b"} {
+			var conv Conversation
+			if err := json.Unmarshal([]byte(files[id+".json"]), &conv); err != nil {
+				t.Fatalf("%s.json is not a conversation: %v", id, err)
+			}
+			if conv.ID != id || len(conv.Messages) != 2 {
+				t.Errorf("%s.json = %+v, want the stored conversation", id, conv)
+			}
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		files := export(t, "?format=markdown")
+
+		if len(files) != 2 {
+			t.Fatalf("Zip entries = %v, want backup-a.md and backup-b.md", slices.Collect(maps.Keys(files)))
+		}
+		if !strings.HasPrefix(files["backup-a.md"], "# Test Conversation") {
+			t.Errorf("backup-a.md = %q, want rendered Markdown", files["backup-a.md"])
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/conversations/export/all?format=pdf", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		AssertAPIError(t, w, http.StatusBadRequest, ErrCodeUnsupportedFormat)
+	})
+}
+
+// TestFetchURLHandlerCache tests URL content caching and forced refresh
+func TestFetchURLHandlerCache(t *testing.T) {
+	AllowLoopbackFetches(t)
+
+	oldCache := urlContentCache
+	defer func() { urlContentCache = oldCache }()
+	urlContentCache = NewTTLCache[string, *FetchURLResult](time.Hour)
+
+	fetches := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		fmt.Fprintf(w, "<html><body><p>Fetch number %d</p></body></html>", fetches)
+	}))
+	defer mockServer.Close()
+
+	router := gin.New()
+	router.POST("/api/fetch-url", fetchURLHandler)
+
+	fetch := func(query, url string) (string, bool) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"url": url})
+		req := httptest.NewRequest("POST", "/api/fetch-url"+query, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var response struct {
+			Text   string `json:"text"`
+			Cached bool   `json:"cached"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Text, response.Cached
+	}
+
+	content, cached := fetch("", mockServer.URL+"/page")
+	if cached || content != "Fetch number 1" {
+		t.Errorf("First fetch = %q (cached=%v), want fresh 'Fetch number 1'", content, cached)
+	}
+
+	// Same page with a fragment should hit the cache
+	content, cached = fetch("", mockServer.URL+"/page#top")
+	if !cached || content != "Fetch number 1" || fetches != 1 {
+		t.Errorf("Second fetch = %q (cached=%v, fetches=%d), want cache hit", content, cached, fetches)
+	}
+
+	content, cached = fetch("?refresh=true", mockServer.URL+"/page")
+	if cached || content != "Fetch number 2" || fetches != 2 {
+		t.Errorf("Refresh fetch = %q (cached=%v, fetches=%d), want fresh 'Fetch number 2'", content, cached, fetches)
+	}
+
+	// The refreshed content replaces the cached entry
+	content, cached = fetch("", mockServer.URL+"/page")
+	if !cached || content != "Fetch number 2" {
+		t.Errorf("Post-refresh fetch = %q (cached=%v), want cached 'Fetch number 2'", content, cached)
+	}
+}
+
+// TestFetchURLHandlerRejectsContent tests the error responses for URLs that can't be
+// extracted
+func TestFetchURLHandlerRejectsContent(t *testing.T) {
+	AllowLoopbackFetches(t)
+
+	oldCache, oldMaxSize := urlContentCache, MaxFetchURLSize
+	defer func() { urlContentCache, MaxFetchURLSize = oldCache, oldMaxSize }()
+	urlContentCache = NewTTLCache[string, *FetchURLResult](time.Hour)
+	MaxFetchURLSize = 64
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/photo.jpg" {
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte{0xff, 0xd8, 0xff})
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>"+strings.Repeat("<p>Long page</p>", 20)+"</body></html>")
+	}))
+	defer mockServer.Close()
+
+	router := gin.New()
+	router.POST("/api/fetch-url", fetchURLHandler)
+
+	fetch := func(url string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"url": url})
+		req := httptest.NewRequest("POST", "/api/fetch-url", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	AssertAPIError(t, fetch(mockServer.URL+"/photo.jpg"), http.StatusUnsupportedMediaType, ErrCodeUnsupportedFormat)
+	AssertAPIError(t, fetch(mockServer.URL+"/long"), http.StatusBadRequest, ErrCodeInvalidRequest)
+	if urlContentCache.Len() != 0 {
+		t.Error("Expected rejected content not to be cached")
+	}
+}
+
+// TestFetchURLHandlerRejectsInternalURL tests that URLs resolving to internal
+// addresses are refused as invalid requests
+func TestFetchURLHandlerRejectsInternalURL(t *testing.T) {
+	oldCache := urlContentCache
+	defer func() { urlContentCache = oldCache }()
+	urlContentCache = NewTTLCache[string, *FetchURLResult](time.Hour)
+
+	router := gin.New()
+	router.POST("/api/fetch-url", fetchURLHandler)
+
+	for _, url := range []string{"http://127.0.0.1:8001/api/conversations", "http://169.254.169.254/latest/meta-data/", "file:///etc/passwd"} {
+		body, _ := json.Marshal(map[string]string{"url": url})
+		req := httptest.NewRequest("POST", "/api/fetch-url", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		AssertAPIError(t, w, http.StatusBadRequest, ErrCodeInvalidRequest)
+	}
+}
+
+// TestGetBillsHandlerSort tests sorting the bills list and rejecting unknown sorts
+func TestGetBillsHandlerSort(t *testing.T) {
+	oldBillsCache := billsCache
+	defer func() { billsCache = oldBillsCache }()
+
+	billsCache = NewBillsCache(time.Hour)
+	billsCache.Set([]Bill{
+		{ID: "r1", Title: "Water Bill", Chamber: "House of Representatives", DateIntroduced: "03 Sep 2025"},
+		{ID: "s2", Title: "Aged Care Bill", Chamber: "Senate", DateIntroduced: ""},
+		{ID: "r3", Title: "Energy Bill", Chamber: "House of Representatives", DateIntroduced: "12 Feb 2024"},
+	})
+
+	router := gin.New()
+	router.GET("/api/bills", getBillsHandler)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/bills"+query, nil))
+		return w
+	}
+
+	for query, want := range map[string][]string{
+		"":                         {"r1", "s2", "r3"},
+		"?sort=date":               {"r3", "r1", "s2"},
+		"?sort=date&order=desc":    {"r1", "r3", "s2"},
+		"?sort=title":              {"s2", "r3", "r1"},
+		"?sort=chamber&order=desc": {"s2", "r1", "r3"},
+	} {
+		w := get(query)
+		var response BillsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%q: failed to parse response: %v", query, err)
+		}
+		var ids []string
+		for _, bill := range response.Bills {
+			ids = append(ids, bill.ID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("%q: got %v, want %v", query, ids, want)
+		}
+	}
+
+	AssertAPIError(t, get("?sort=status"), http.StatusBadRequest, ErrCodeInvalidRequest)
+	AssertAPIError(t, get("?sort=date&order=up"), http.StatusBadRequest, ErrCodeInvalidRequest)
+}
+
+// TestGetBillsHandlerDateRange tests filtering the bills list by introduction date
+func TestGetBillsHandlerDateRange(t *testing.T) {
+	oldBillsCache := billsCache
+	defer func() { billsCache = oldBillsCache }()
+
+	billsCache = NewBillsCache(time.Hour)
+	billsCache.Set([]Bill{
+		{ID: "r1", Title: "Water Bill", DateIntroduced: "03 Sep 2025"},
+		{ID: "s2", Title: "Aged Care Bill", DateIntroduced: ""},
+		{ID: "r3", Title: "Energy Bill", DateIntroduced: "12 Feb 2024"},
+		{ID: "s4", Title: "Housing Bill", DateIntroduced: "01 Jan 2025"},
+	})
+
+	router := gin.New()
+	router.GET("/api/bills", getBillsHandler)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/bills"+query, nil))
+		return w
+	}
+
+	for query, want := range map[string][]string{
+		"?from=2025-01-01":                      {"r1", "s4"},
+		"?to=2025-01-01":                        {"r3", "s4"},
+		"?from=2024-02-12&to=2025-09-03":        {"r1", "r3", "s4"},
+		"?from=2024-01-01&sort=date&order=desc": {"r1", "s4", "r3"},
+		"?from=2026-01-01":                      nil,
+	} {
+		w := get(query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, body %s", query, w.Code, w.Body.String())
+		}
+		var response BillsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%q: failed to parse response: %v", query, err)
+		}
+		var ids []string
+		for _, bill := range response.Bills {
+			ids = append(ids, bill.ID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("%q: got %v, want %v", query, ids, want)
+		}
+	}
+
+	for query, field := range map[string]string{
+		"?from=03%20Sep%202025":          "from",
+		"?to=2025-13-01":                 "to",
+		"?from=2025-06-01&to=2025-01-01": "to",
+	} {
+		apiErr := AssertAPIError(t, get(query), http.StatusBadRequest, ErrCodeInvalidRequest)
+		if apiErr.Details["field"] != field {
+			t.Errorf("%q: details = %v, want field %q", query, apiErr.Details, field)
+		}
+	}
+}
+
+// TestGetBillsHandlerPagination tests that pagination describes the filtered bills
+// and the requested page size
+func TestGetBillsHandlerPagination(t *testing.T) {
+	oldBillsCache, oldPageSize := billsCache, BillsPageSize
+	defer func() { billsCache, BillsPageSize = oldBillsCache, oldPageSize }()
+
+	var bills []Bill
+	for i := 1; i <= 25; i++ {
+		year := 2024
+		if i > 20 {
+			year = 2025
+		}
+		bills = append(bills, Bill{ID: fmt.Sprintf("r%d", i), Title: "Bill", DateIntroduced: fmt.Sprintf("%02d Mar %d", i, year)})
+	}
+	billsCache = NewBillsCache(time.Hour)
+	billsCache.Set(bills)
+
+	router := gin.New()
+	router.GET("/api/bills", getBillsHandler)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/bills"+query, nil))
+		return w
+	}
+
+	tests := []struct {
+		query      string
+		pageSize   int
+		firstID    string
+		count      int
+		totalPages int
+		totalBills int
+		hasNext    bool
+	}{
+		{"", 0, "r1", 25, 1, 25, false},
+		{"?page_size=10", 0, "r1", 10, 3, 25, true},
+		{"?page_size=10&page=3", 0, "r21", 5, 3, 25, false},
+		{"?page_size=10&page=4", 0, "", 0, 3, 25, false},
+		{"?from=2025-01-01&page_size=2", 0, "r21", 2, 3, 5, true},
+		{"?from=2025-01-01&page_size=2&page=3", 0, "r25", 1, 3, 5, false},
+		{"?from=2030-01-01&page_size=10", 0, "", 0, 1, 0, false},
+		{"?page=2", 20, "r21", 5, 2, 25, false},
+		{"?page=2&page_size=5", 20, "r6", 5, 5, 25, true},
+	}
+	for _, tt := range tests {
+		BillsPageSize = tt.pageSize
+		w := get(tt.query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d: %s", tt.query, w.Code, w.Body.String())
+		}
+		var response BillsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%q: failed to parse response: %v", tt.query, err)
+		}
+		if len(response.Bills) != tt.count || (tt.count > 0 && response.Bills[0].ID != tt.firstID) {
+			t.Errorf("%q: got %d bills starting %+v, want %d starting %s", tt.query, len(response.Bills), response.Bills, tt.count, tt.firstID)
+		}
+		if response.TotalPages != tt.totalPages || response.TotalBills != tt.totalBills || response.HasNextPage != tt.hasNext {
+			t.Errorf("%q: total_pages = %d, total_bills = %d, has_next_page = %v, want %d, %d, %v",
+				tt.query, response.TotalPages, response.TotalBills, response.HasNextPage, tt.totalPages, tt.totalBills, tt.hasNext)
+		}
+	}
+
+	AssertAPIError(t, get("?page=0"), http.StatusBadRequest, ErrCodeInvalidRequest)
+	AssertAPIError(t, get("?page_size=-5"), http.StatusBadRequest, ErrCodeInvalidRequest)
+	AssertAPIError(t, get("?page_size=ten"), http.StatusBadRequest, ErrCodeInvalidRequest)
+}
+
+// TestGetBillsHandlerStaleFallback tests serving expired bills when a fresh fetch fails
+func TestGetBillsHandlerStaleFallback(t *testing.T) {
+	oldBillsCache, oldBaseURL, oldRetries := billsCache, BillsBaseURL, ScraperMaxRetries
+	oldCacheFile := billsCacheFile
+	defer func() {
+		billsCache, BillsBaseURL, ScraperMaxRetries = oldBillsCache, oldBaseURL, oldRetries
+		billsCacheFile = oldCacheFile
+	}()
+	ScraperMaxRetries = 0
+	billsCacheFile = ""
+
+	aph := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer aph.Close()
+	BillsBaseURL = aph.URL
+
+	router := gin.New()
+	router.GET("/api/bills", getBillsHandler)
+
+	t.Run("expired cache is served as stale", func(t *testing.T) {
+		billsCache = NewBillsCache(time.Nanosecond)
+		billsCache.Set([]Bill{{ID: "r1", Title: "Water Bill"}, {ID: "s2", Title: "Aged Care Bill"}})
+		lastUpdated := billsCache.GetLastUpdated()
+		time.Sleep(time.Millisecond)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/bills?sort=title", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var response BillsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if !response.Stale {
+			t.Error("Expected stale: true")
+		}
+		if !response.LastUpdated.Equal(lastUpdated) {
+			t.Errorf("LastUpdated = %v, want the cached %v", response.LastUpdated, lastUpdated)
+		}
+		if len(response.Bills) != 2 || response.Bills[0].ID != "s2" {
+			t.Errorf("Bills = %+v, want both cached bills sorted by title", response.Bills)
+		}
+	})
+
+	t.Run("no cached bills", func(t *testing.T) {
+		billsCache = NewBillsCache(time.Hour)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/bills", nil))
+		AssertAPIError(t, w, http.StatusInternalServerError, ErrCodeUpstreamFailed)
+	})
+}
+
+// TestGetBillDetailHandler tests bill detail lookup, caching and error statuses
+func TestGetBillDetailHandler(t *testing.T) {
+	oldBillsCache := billsCache
+	oldDetailCache := billDetailCache
+	defer func() {
+		billsCache = oldBillsCache
+		billDetailCache = oldDetailCache
+	}()
+
+	fetches := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.URL.Query().Get("bId") != "r7365" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, "testdata/bill_detail.html")
+	}))
+	defer mockServer.Close()
+
+	billsCache = NewBillsCache(time.Hour)
+	billsCache.Set([]Bill{
+		{ID: "r7365", Title: "Clean Energy Amendment Bill 2025", BillURL: mockServer.URL + "/Result?bId=r7365"},
+		{ID: "s1", Title: "Withdrawn Bill", BillURL: mockServer.URL + "/Result?bId=s1"},
+	})
+	billDetailCache = NewTTLCache[string, *BillDetail](time.Hour)
+
+	router := gin.New()
+	router.GET("/api/bills/:id", getBillDetailHandler)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		w := get("/api/bills/r7365")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		var detail BillDetail
+		if err := json.Unmarshal(w.Body.Bytes(), &detail); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if detail.ID != "r7365" || detail.Sponsor != "Bowen, Chris, MP" || len(detail.Progress) != 3 {
+			t.Errorf("Unexpected detail: %+v", detail)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expected second request to be served from cache, got %d fetches", fetches)
+	}
+
+	if w := get("/api/bills/r7365?refresh=true"); w.Code != http.StatusOK || fetches != 2 {
+		t.Errorf("Expected refresh to refetch, got status %d and %d fetches", w.Code, fetches)
+	}
+
+	AssertAPIError(t, get("/api/bills/s1"), http.StatusNotFound, ErrCodeBillNotFound)
+
+	AssertAPIError(t, get("/api/bills/bad%20id"), http.StatusBadRequest, ErrCodeInvalidRequest)
+}
+
+// TestSearchConversationsHandler tests the conversation search endpoint
+func TestSearchConversationsHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	SaveConversation(SampleConversation("search-1"))
+
+	router := gin.New()
+	router.GET("/api/conversations/search", searchConversationsHandler)
+	router.GET("/api/conversations/:id", getConversationHandler)
+
+	req := httptest.NewRequest("GET", "/api/conversations/search?q=google", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var results []ConversationSearchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "search-1" || results[0].Snippet == "" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	req = httptest.NewRequest("GET", "/api/conversations/search", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	AssertAPIError(t, w, http.StatusBadRequest, ErrCodeInvalidRequest)
+}
+
+// TestRegenerateHandler tests re-running the council on the last user message
+func TestRegenerateHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a", "model/b"}
+	ChairmanModel = "model/chairman"
+	OpenRouterAPIKey = "test-key"
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/regenerate", regenerateHandler)
+
+	regenerate := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/conversations/"+id+"/regenerate", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("replaces last assistant message", func(t *testing.T) {
+		var queries []string
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			var req OpenRouterRequest
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &req)
+			queries = append(queries, req.Messages[len(req.Messages)-1].Content)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A\n2. Response B")(w, r)
+		})
+		defer mockServer.Close()
+		OpenRouterAPIURL = mockServer.URL
+
+		SaveConversation(SampleConversation("regen-ok"))
+
+		w := regenerate("regen-ok")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		var response SendMessageResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if len(response.Stage1) != 2 || response.Stage3.Model != "model/chairman" {
+			t.Errorf("Unexpected response: %+v", response)
+		}
+		if len(queries) == 0 || queries[0] != "What is Go?" {
+			t.Errorf("Expected council to be re-run on the last user message, got %v", queries)
+		}
+
+		conv, _ := GetConversation("regen-ok")
+		if len(conv.Messages) != 2 {
+			t.Fatalf("Expected 2 messages after regenerate, got %d", len(conv.Messages))
+		}
+		if conv.Messages[1].Stage3.Model != "model/chairman" {
+			t.Errorf("Expected fresh assistant message, got stage3 %+v", conv.Messages[1].Stage3)
+		}
+	})
+
+	t.Run("council failure keeps previous answer", func(t *testing.T) {
+		mockServer := MockOpenRouterServer(t, CreateMockOpenRouterErrorHandler(500, "Internal error"))
+		defer mockServer.Close()
+		OpenRouterAPIURL = mockServer.URL
+
+		SaveConversation(SampleConversation("regen-fail"))
+
+		AssertAPIError(t, regenerate("regen-fail"), http.StatusInternalServerError, ErrCodeCouncilFailed)
+
+		conv, _ := GetConversation("regen-fail")
+		if len(conv.Messages) != 2 || conv.Messages[1].Stage3.Model != "test/chairman" {
+			t.Errorf("Expected original assistant message to be kept, got %+v", conv.Messages)
+		}
+	})
+
+	t.Run("conversation changed during the run", func(t *testing.T) {
+		// Another exchange lands while the council is regenerating the first answer
+		var once sync.Once
+		successHandler := CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A\n2. Response B")
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			once.Do(func() {
+				AddUserMessage("regen-race", "A newer question")
+				AddAssistantMessage("regen-race", nil, nil, Stage3Response{Model: "model/newer"})
+			})
+			successHandler(w, r)
+		})
+		defer mockServer.Close()
+		OpenRouterAPIURL = mockServer.URL
+
+		SaveConversation(SampleConversation("regen-race"))
+
+		AssertAPIError(t, regenerate("regen-race"), http.StatusConflict, ErrCodeConversationChanged)
+
+		conv, _ := GetConversation("regen-race")
+		if len(conv.Messages) != 4 || conv.Messages[1].Stage3.Model != "test/chairman" || conv.Messages[3].Stage3.Model != "model/newer" {
+			t.Errorf("Expected the conversation to be left as the other request saved it, got %+v", conv.Messages)
+		}
+	})
+
+	t.Run("last message not assistant", func(t *testing.T) {
+		conv := SampleConversation("regen-user")
+		conv.Messages = conv.Messages[:1]
+		SaveConversation(conv)
+
+		AssertAPIError(t, regenerate("regen-user"), http.StatusBadRequest, ErrCodeInvalidRequest)
+	})
+
+	t.Run("conversation not found", func(t *testing.T) {
+		AssertAPIError(t, regenerate("missing"), http.StatusNotFound, ErrCodeConversationNotFound)
+	})
+}
+
+// TestForkConversationHandler tests the conversation fork endpoint
+func TestForkConversationHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	SaveConversation(SampleConversation("fork-me"))
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/fork", forkConversationHandler)
+
+	fork := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/conversations/"+id+"/fork", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := fork("fork-me", `{"up_to_message": 0}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var conv Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &conv); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if conv.ForkedFrom != "fork-me" || len(conv.Messages) != 1 || conv.Messages[0].Role != "user" {
+		t.Errorf("Unexpected fork: %+v", conv)
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+	}{
+		{"index out of range", "fork-me", `{"up_to_message": 2}`, http.StatusBadRequest},
+		{"missing index", "fork-me", `{}`, http.StatusBadRequest},
+		{"conversation not found", "missing", `{"up_to_message": 0}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := fork(tt.id, tt.body); w.Code != tt.status {
+				t.Errorf("Status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+// TestEditMessageHandler tests editing a user message over HTTP
+func TestEditMessageHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	SaveConversation(SampleConversation("edit-me"))
+
+	router := gin.New()
+	router.PUT("/api/conversations/:id/messages/:index", editMessageHandler)
+
+	edit := func(id, index, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/conversations/"+id+"/messages/"+index, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		index  string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{"assistant message", "edit-me", "1", `{"content": "Edited"}`, http.StatusBadRequest, ErrCodeInvalidRequest, "index"},
+		{"index out of range", "edit-me", "2", `{"content": "Edited"}`, http.StatusBadRequest, ErrCodeInvalidRequest, "index"},
+		{"non-numeric index", "edit-me", "first", `{"content": "Edited"}`, http.StatusBadRequest, ErrCodeInvalidRequest, "index"},
+		{"empty content", "edit-me", "0", `{"content": "  "}`, http.StatusBadRequest, ErrCodeInvalidRequest, "content"},
+		{"malformed body", "edit-me", "0", `{"content": `, http.StatusBadRequest, ErrCodeInvalidRequest, ""},
+		{"conversation not found", "missing", "0", `{"content": "Edited"}`, http.StatusNotFound, ErrCodeConversationNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := AssertAPIError(t, edit(tt.id, tt.index, tt.body), tt.status, tt.code)
+			if tt.code == ErrCodeInvalidRequest && !strings.HasPrefix(apiErr.Message, "Invalid request: ") {
+				t.Errorf("Message = %q, want an \"Invalid request: \" prefix", apiErr.Message)
+			}
+			if tt.field != "" && apiErr.Details["field"] != tt.field {
+				t.Errorf("Details = %v, want field %s", apiErr.Details, tt.field)
+			}
+		})
+	}
+
+	w := edit("edit-me", "0", `{"content": "What is Go used for?"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var conv Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &conv); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(conv.Messages) != 1 || conv.Messages[0].Content != "What is Go used for?" {
+		t.Errorf("Updated conversation messages = %+v", conv.Messages)
+	}
+}
+
+// TestSendMessageValidation tests content validation in both message handlers
+func TestSendMessageValidation(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldMax := MaxMessageLength
+	defer func() {
+		DataDir = oldDataDir
+		MaxMessageLength = oldMax
+	}()
+
+	DataDir = tempDir
+	MaxMessageLength = 10
+	CreateConversation("validate")
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message", sendMessageHandler)
+	router.POST("/api/conversations/:id/message/stream", sendMessageStreamHandler)
+
+	tests := []struct {
+		name    string
+		content string
+		images  []string
+		errText string
+		field   string
+	}{
+		{"empty content", "", nil, "must not be empty", "content"},
+		{"whitespace only", "  \n\t ", nil, "must not be empty", "content"},
+		{"oversized content", "eleven char", nil, "exceeding the limit of 10", "content"},
+		{"oversized multibyte content", strings.Repeat("é", 11), nil, "11 characters", "content"},
+		{"too many images", "Chart?", strings.Split("https://a.test/1.png,https://a.test/2.png,https://a.test/3.png,https://a.test/4.png,https://a.test/5.png", ","), "5 images attached", "image_urls"},
+		{"image not a URL", "Chart?", []string{"https://a.test/1.png", "file:///etc/passwd"}, "image 2 must be", "image_urls"},
+		{"data URL not an image", "Chart?", []string{"data:text/html;base64,PGgxPg=="}, "image 1 must be", "image_urls"},
+	}
+
+	for _, path := range []string{"/api/conversations/validate/message", "/api/conversations/validate/message/stream"} {
+		for _, tt := range tests {
+			t.Run(path+" "+tt.name, func(t *testing.T) {
+				body, _ := json.Marshal(SendMessageRequest{Content: tt.content, ImageURLs: tt.images})
+				req := httptest.NewRequest("POST", path, bytes.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				apiErr := AssertAPIError(t, w, http.StatusBadRequest, ErrCodeInvalidRequest)
+				if !strings.Contains(apiErr.Message, tt.errText) {
+					t.Errorf("Message = %q, want error containing %q", apiErr.Message, tt.errText)
+				}
+				if apiErr.Details["field"] != tt.field {
+					t.Errorf("Details = %v, want field %q", apiErr.Details, tt.field)
+				}
+			})
+		}
+	}
+
+	// Rejected messages are never stored
+	conv, _ := GetConversation("validate")
+	if len(conv.Messages) != 0 {
+		t.Errorf("Expected no messages to be stored, got %d", len(conv.Messages))
+	}
+
+	// Exactly at the limit is accepted
+	if err := validateMessageContent(strings.Repeat("é", 10)); err != nil {
+		t.Errorf("Expected content at the limit to be valid, got %v", err)
+	}
+	if err := validateImageURLs([]string{"https://example.com/chart.png", "data:image/png;base64,iVBORw0KGgo="}); err != nil {
+		t.Errorf("Expected http(s) and image data URLs to be valid, got %v", err)
+	}
+}
+
+// TestSendMessageHandlerClientCancel verifies that a client disconnect aborts in-flight model queries
+func TestSendMessageHandlerClientCancel(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a", "model/b"}
+
+	// Every model query hangs until the caller gives up
+	started := make(chan struct{}, len(CouncilModels))
+	aborted := make(chan struct{}, len(CouncilModels))
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a dropped connection once the body is consumed
+		io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+			aborted <- struct{}{}
+		case <-time.After(5 * time.Second):
+		}
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	// An existing exchange means no title generation is started
+	conv := SampleConversation("cancel")
+	if err := SaveConversation(conv); err != nil {
+		t.Fatalf("Failed to save conversation: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message", sendMessageHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for range CouncilModels {
+			<-started
+		}
+		cancel()
+	}()
+
+	body, _ := json.Marshal(SendMessageRequest{Content: "Will this be cancelled?"})
+	req := httptest.NewRequest("POST", "/api/conversations/cancel/message", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	router.ServeHTTP(w, req)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Handler took %v after client cancelled, want prompt return", elapsed)
+	}
+
+	for range CouncilModels {
+		select {
+		case <-aborted:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected upstream model queries to be aborted")
+		}
+	}
+
+	if w.Code == http.StatusOK {
+		t.Errorf("Status = %d, want an error status for a cancelled request", w.Code)
+	}
+}
+
+// TestSendMessageStreamHandlerCouncilTimeout tests that the streamed council run is
+// bounded by CouncilTimeout and reports the timeout as an error event
+func TestSendMessageStreamHandlerCouncilTimeout(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldTimeout := CouncilTimeout
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		CouncilTimeout = oldTimeout
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a", "model/b"}
+	CouncilTimeout = 200 * time.Millisecond
+
+	// Stage 1 answers immediately; the rankings hang until abandoned
+	var requests atomic.Int32
+	fast := CreateMockOpenRouterHandler(t, "A quick answer")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			fast(w, r)
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	// An existing exchange means no title generation is started
+	if err := SaveConversation(SampleConversation("stream-timeout")); err != nil {
+		t.Fatalf("Failed to save conversation: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message/stream", sendMessageStreamHandler)
+
+	body, _ := json.Marshal(SendMessageRequest{Content: "What is Go?"})
+	req := httptest.NewRequest("POST", "/api/conversations/stream-timeout/message/stream", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	router.ServeHTTP(w, req)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Handler took %v, want it to stop near the %v deadline", elapsed, CouncilTimeout)
+	}
+
+	events := w.Body.String()
+	if !strings.Contains(events, `"type":"stage1_complete"`) {
+		t.Errorf("Expected Stage 1 to complete before the deadline:\n%s", events)
+	}
+	if !strings.Contains(events, `"type":"error"`) || !strings.Contains(events, "stage 2: "+ErrCouncilTimeout.Error()) {
+		t.Errorf("Expected a Stage 2 timeout error event:\n%s", events)
+	}
+	for _, event := range []string{"stage2_complete", "stage3_start", "complete"} {
+		if strings.Contains(events, `"type":"`+event+`"`) {
+			t.Errorf("Unexpected %s event after the timeout:\n%s", event, events)
+		}
+	}
+
+	conv, _ := GetConversation("stream-timeout")
+	if last := conv.Messages[len(conv.Messages)-1]; last.Role != "user" {
+		t.Errorf("Last message role = %q, want no assistant message saved", last.Role)
+	}
+}
+
+// TestSendMessageStreamHandlerAllModelsFail tests that a Stage 1 with no responses
+// ends the stream with an error naming the failures instead of running Stage 2
+func TestSendMessageStreamHandlerAllModelsFail(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldRetries := CouncilRunRetries
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		CouncilRunRetries = oldRetries
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a", "model/b"}
+	CouncilRunRetries = 0
+
+	var requests atomic.Int32
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, `{"error": {"message": "bad request"}}`, http.StatusBadRequest)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	// An existing exchange means no title generation is started
+	if err := SaveConversation(SampleConversation("stream-all-fail")); err != nil {
+		t.Fatalf("Failed to save conversation: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message/stream", sendMessageStreamHandler)
+
+	body, _ := json.Marshal(SendMessageRequest{Content: "What is Go?"})
+	req := httptest.NewRequest("POST", "/api/conversations/stream-all-fail/message/stream", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	events := w.Body.String()
+	if !strings.Contains(events, `"type":"error"`) || !strings.Contains(events, "all council models failed to respond") {
+		t.Errorf("Expected an all-models-failed error event:\n%s", events)
+	}
+	for _, model := range CouncilModels {
+		if !strings.Contains(events, model) {
+			t.Errorf("Error event should name failed model %s:\n%s", model, events)
+		}
+	}
+	for _, event := range []string{"stage1_complete", "stage2_start", "complete"} {
+		if strings.Contains(events, `"type":"`+event+`"`) {
+			t.Errorf("Unexpected %s event after Stage 1 failed:\n%s", event, events)
+		}
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Requests = %d, want only the two Stage 1 queries", got)
+	}
+
+	conv, _ := GetConversation("stream-all-fail")
+	if last := conv.Messages[len(conv.Messages)-1]; last.Role != "user" {
+		t.Errorf("Last message role = %q, want no assistant message saved", last.Role)
+	}
+}
+
+// TestSendMessageStreamHandlerClientDisconnect verifies that a client dropping the
+// stream mid-run cancels the council without making any further model calls
+func TestSendMessageStreamHandlerClientDisconnect(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a", "model/b"}
+
+	// Stage 1 queries hang until the client goes away
+	var requests atomic.Int32
+	started := make(chan struct{}, len(CouncilModels))
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		requests.Add(1)
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	// An existing exchange means no title generation is started
+	if err := SaveConversation(SampleConversation("disconnect")); err != nil {
+		t.Fatalf("Failed to save conversation: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message/stream", sendMessageStreamHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for range CouncilModels {
+			<-started
+		}
+		cancel()
+	}()
+
+	body, _ := json.Marshal(SendMessageRequest{Content: "Anyone there?"})
+	req := httptest.NewRequest("POST", "/api/conversations/disconnect/message/stream", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	router.ServeHTTP(w, req)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Handler took %v after client disconnected, want prompt return", elapsed)
+	}
+
+	// Give any stray Stage 2 queries a chance to arrive
+	time.Sleep(100 * time.Millisecond)
+	if n := requests.Load(); n != int32(len(CouncilModels)) {
+		t.Errorf("Model requests = %d, want only the %d Stage 1 queries", n, len(CouncilModels))
+	}
+	for _, event := range []string{"stage1_complete", "stage2_start", "complete"} {
+		if strings.Contains(w.Body.String(), `"type":"`+event+`"`) {
+			t.Errorf("Unexpected %s event after disconnect:\n%s", event, w.Body.String())
+		}
+	}
+
+	conv, _ := GetConversation("disconnect")
+	if last := conv.Messages[len(conv.Messages)-1]; last.Role != "user" {
+		t.Errorf("Last message role = %q, want no assistant message saved", last.Role)
+	}
+}
+
+// TestEstimateHandler tests the dry-run estimate endpoint
+func TestEstimateHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldModels := CouncilModels
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		CouncilModels = oldModels
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a", "model/b"}
+	CreateConversation("estimate")
+
+	// Any OpenRouter call would fail the test
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Estimate should not call OpenRouter")
+	})
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/estimate", estimateHandler)
+
+	t.Run("returns per-model estimates", func(t *testing.T) {
+		body, _ := json.Marshal(SendMessageRequest{Content: "What is Go?"})
+		req := httptest.NewRequest("POST", "/api/conversations/estimate/estimate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d, body: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var response EstimateResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if len(response.Models) != 2 || response.Models[0].PromptTokens == 0 {
+			t.Errorf("Unexpected estimate: %+v", response)
+		}
+
+		// Nothing is stored
+		conv, _ := GetConversation("estimate")
+		if len(conv.Messages) != 0 {
+			t.Errorf("Expected no messages to be stored, got %d", len(conv.Messages))
+		}
+	})
+
+	t.Run("estimates the mode's council", func(t *testing.T) {
+		body, _ := json.Marshal(SendMessageRequest{Content: "What is Go?", Mode: "fast"})
+		req := httptest.NewRequest("POST", "/api/conversations/estimate/estimate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response EstimateResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		var models []string
+		for _, estimate := range response.Models {
+			models = append(models, estimate.Model)
+		}
+		if want := CouncilModes["fast"].CouncilModels; !reflect.DeepEqual(models, want) {
+			t.Errorf("Estimated models = %v, want the fast preset %v", models, want)
+		}
+
+		body, _ = json.Marshal(SendMessageRequest{Content: "What is Go?", Mode: "turbo"})
+		req = httptest.NewRequest("POST", "/api/conversations/estimate/estimate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		AssertAPIError(t, w, http.StatusBadRequest, ErrCodeInvalidRequest)
+	})
+
+	t.Run("non-existent conversation", func(t *testing.T) {
+		body, _ := json.Marshal(SendMessageRequest{Content: "What is Go?"})
+		req := httptest.NewRequest("POST", "/api/conversations/missing/estimate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("empty content", func(t *testing.T) {
+		body, _ := json.Marshal(SendMessageRequest{Content: " "})
+		req := httptest.NewRequest("POST", "/api/conversations/estimate/estimate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+// TestArchiveConversationHandler tests archiving and listing archived conversations
+func TestArchiveConversationHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	CreateConversation("archivable")
+
+	router := gin.New()
+	router.GET("/api/conversations", listConversationsHandler)
+	router.POST("/api/conversations/:id/archive", archiveConversationHandler(true))
+	router.POST("/api/conversations/:id/unarchive", archiveConversationHandler(false))
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	listCount := func(path string) int {
+		w := do("GET", path)
+		var conversations []ConversationMetadata
+		if err := json.Unmarshal(w.Body.Bytes(), &conversations); err != nil {
+			t.Fatalf("Failed to parse list: %v", err)
+		}
+		return len(conversations)
+	}
+
+	w := do("POST", "/api/conversations/archivable/archive")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Archive status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var metadata ConversationMetadata
+	json.Unmarshal(w.Body.Bytes(), &metadata)
+	if !metadata.Archived || metadata.ID != "archivable" {
+		t.Errorf("Archive response = %+v, want archived metadata", metadata)
+	}
+
+	if n := listCount("/api/conversations"); n != 0 {
+		t.Errorf("Default list has %d conversations, want 0", n)
+	}
+	if n := listCount("/api/conversations?include_archived=true"); n != 1 {
+		t.Errorf("List with archived has %d conversations, want 1", n)
+	}
+
+	if w := do("POST", "/api/conversations/archivable/unarchive"); w.Code != http.StatusOK {
+		t.Fatalf("Unarchive status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if n := listCount("/api/conversations"); n != 1 {
+		t.Errorf("Default list has %d conversations after unarchive, want 1", n)
+	}
+
+	if w := do("POST", "/api/conversations/missing/archive"); w.Code != http.StatusNotFound {
+		t.Errorf("Archive missing status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+// TestConversationTagHandlers tests the tag endpoints and list filter
+func TestConversationTagHandlers(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	CreateConversation("tag-me")
+	CreateConversation("other")
+
+	router := gin.New()
+	router.GET("/api/conversations", listConversationsHandler)
+	router.POST("/api/conversations/:id/tags", addTagsHandler)
+	router.DELETE("/api/conversations/:id/tags/:tag", removeTagHandler)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/conversations/tag-me/tags", `{"tags": ["Research", "research", "bills"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Add tags status = %d, want %d, body: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var metadata ConversationMetadata
+	json.Unmarshal(w.Body.Bytes(), &metadata)
+	if !reflect.DeepEqual(metadata.Tags, []string{"bills", "research"}) {
+		t.Errorf("Tags = %v, want [bills research]", metadata.Tags)
+	}
+
+	w = do("GET", "/api/conversations?tag=research", "")
+	var conversations []ConversationMetadata
+	json.Unmarshal(w.Body.Bytes(), &conversations)
+	if len(conversations) != 1 || conversations[0].ID != "tag-me" {
+		t.Errorf("Filtered list = %+v, want only 'tag-me'", conversations)
+	}
+
+	w = do("DELETE", "/api/conversations/tag-me/tags/Research", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Remove tag status = %d, want %d", w.Code, http.StatusOK)
+	}
+	json.Unmarshal(w.Body.Bytes(), &metadata)
+	if !reflect.DeepEqual(metadata.Tags, []string{"bills"}) {
+		t.Errorf("Tags after removal = %v, want [bills]", metadata.Tags)
+	}
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"missing tags field", "POST", "/api/conversations/tag-me/tags", `{}`, http.StatusBadRequest},
+		{"only blank tags", "POST", "/api/conversations/tag-me/tags", `{"tags": [" "]}`, http.StatusBadRequest},
+		{"add to missing conversation", "POST", "/api/conversations/missing/tags", `{"tags": ["x"]}`, http.StatusNotFound},
+		{"remove from missing conversation", "DELETE", "/api/conversations/missing/tags/x", "", http.StatusNotFound},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(tt.method, tt.path, tt.body); w.Code != tt.want {
+				t.Errorf("Status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestAnalyzeBillHandler tests running the council on a bill and saving it as a conversation
+func TestAnalyzeBillHandler(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	oldBillsCache := billsCache
+	oldDetailCache := billDetailCache
+	oldBackoff := ScraperRetryBackoff
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+		billsCache = oldBillsCache
+		billDetailCache = oldDetailCache
+		ScraperRetryBackoff = oldBackoff
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a"}
+	ChairmanModel = "model/chairman"
+	ScraperRetryBackoff = time.Millisecond
+
+	// Mock APH: r7365 has a detail page, s9 fails with a server error, s1 doesn't exist
+	billServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("bId") {
+		case "r7365":
+			http.ServeFile(w, r, "testdata/bill_detail.html")
+		case "s9":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer billServer.Close()
+
+	billsCache = NewBillsCache(time.Hour)
+	billsCache.Set([]Bill{
+		{ID: "r7365", Title: "Clean Energy Amendment Bill 2025", BillURL: billServer.URL + "/Result?bId=r7365"},
+		{ID: "s9", Title: "Fallback Bill 2025", Summary: "Amends the fallback rules.", BillURL: billServer.URL + "/Result?bId=s9"},
+		{ID: "s1", Title: "Withdrawn Bill", BillURL: billServer.URL + "/Result?bId=s1"},
+	})
+	billDetailCache = NewTTLCache[string, *BillDetail](time.Hour)
+
+	// Mock OpenRouter, recording the Stage 1 question
+	var mu sync.Mutex
+	var questions []string
+	respond := CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A")
+	openRouter := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		if req.Model == "model/a" && len(req.Messages) == 1 && !strings.Contains(req.Messages[0].Content, "Response A") {
+			mu.Lock()
+			questions = append(questions, req.Messages[0].Content)
+			mu.Unlock()
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		respond(w, r)
+	})
+	defer openRouter.Close()
+	OpenRouterAPIURL = openRouter.URL
+	OpenRouterAPIKey = "test-key"
+
+	router := gin.New()
+	router.POST("/api/bills/:id/analyze", analyzeBillHandler)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+		return w
+	}
+
+	t.Run("analyzes bill detail", func(t *testing.T) {
+		w := post("/api/bills/r7365/analyze")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		var response AnalyzeBillResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if response.ConversationID == "" || len(response.Stage1) != 1 || response.Stage3.Response == "" {
+			t.Errorf("Unexpected response: %+v", response)
+		}
+
+		conv, _ := GetConversation(response.ConversationID)
+		if conv == nil {
+			t.Fatal("Expected the analysis to be saved as a conversation")
+		}
+		if conv.Title != "Clean Energy Amendment Bill 2025" {
+			t.Errorf("Title = %q, want the bill title", conv.Title)
+		}
+		if len(conv.Messages) != 2 || conv.Messages[1].Stage3 == nil {
+			t.Fatalf("Expected a user question and assistant answer, got %+v", conv.Messages)
+		}
+
+		question := conv.Messages[0].Content
+		for _, want := range []string{"Analyze this bill", "Clean Energy Amendment Bill 2025", "Sponsor: Bowen, Chris, MP", "Progress:"} {
+			if !strings.Contains(question, want) {
+				t.Errorf("Question missing %q:\n%s", want, question)
+			}
+		}
+		if len(questions) == 0 || questions[0] != question {
+			t.Errorf("Council was not asked the stored question")
+		}
+	})
+
+	t.Run("falls back to list summary", func(t *testing.T) {
+		w := post("/api/bills/s9/analyze")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var response AnalyzeBillResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		conv, _ := GetConversation(response.ConversationID)
+		if !strings.Contains(conv.Messages[0].Content, "Amends the fallback rules.") {
+			t.Errorf("Expected the list summary in the question:\n%s", conv.Messages[0].Content)
+		}
+	})
+
+	t.Run("unknown bill", func(t *testing.T) {
+		AssertAPIError(t, post("/api/bills/s1/analyze"), http.StatusNotFound, ErrCodeBillNotFound)
+	})
+
+	t.Run("invalid bill ID", func(t *testing.T) {
+		AssertAPIError(t, post("/api/bills/bad%20id/analyze"), http.StatusBadRequest, ErrCodeInvalidRequest)
+	})
+
+	t.Run("council failure reports the saved conversation", func(t *testing.T) {
+		failing := MockOpenRouterServer(t, CreateMockOpenRouterErrorHandler(500, "Internal error"))
+		defer failing.Close()
+		OpenRouterAPIURL = failing.URL
+		defer func() { OpenRouterAPIURL = openRouter.URL }()
+
+		apiErr := AssertAPIError(t, post("/api/bills/r7365/analyze"), http.StatusInternalServerError, ErrCodeCouncilFailed)
+		conversationID, _ := apiErr.Details["conversation_id"].(string)
+		if conv, _ := GetConversation(conversationID); conv == nil || len(conv.Messages) != 1 {
+			t.Errorf("Expected details to name the conversation holding the question, got %v", apiErr.Details)
+		}
+	})
+}
+
+// TestSendMessageHandlerCouncilCache tests reusing council results for repeated questions
+func TestSendMessageHandlerCouncilCache(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	oldCache := councilResultCache
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+		councilResultCache = oldCache
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a"}
+	ChairmanModel = "model/chairman"
+	councilResultCache = NewTTLCache[string, *SendMessageResponse](time.Minute)
+
+	// Count chairman queries: one per council run
+	var councilRuns atomic.Int32
+	respond := CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		if req.Model == ChairmanModel {
+			councilRuns.Add(1)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		respond(w, r)
+	})
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	CreateConversation("cache-1")
+	CreateConversation("cache-2")
+	AddUserMessage("cache-1", "Earlier question") // skip background title generation
+	AddUserMessage("cache-2", "Earlier question")
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message", sendMessageHandler)
+
+	send := func(t *testing.T, conversationID, query, content string) SendMessageResponse {
+		t.Helper()
+		body, _ := json.Marshal(SendMessageRequest{Content: content})
+		req := httptest.NewRequest("POST", "/api/conversations/"+conversationID+"/message"+query, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var response SendMessageResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	t.Run("miss then hit", func(t *testing.T) {
+		first := send(t, "cache-1", "", "What is Go?")
+		if first.Cached || councilRuns.Load() != 1 {
+			t.Fatalf("First request: cached = %v after %d runs, want a fresh run", first.Cached, councilRuns.Load())
+		}
+
+		// Same question with different spacing, from another conversation
+		second := send(t, "cache-2", "", "  What is\nGo? ")
+		if !second.Cached || councilRuns.Load() != 1 {
+			t.Errorf("Repeat: cached = %v after %d runs, want a cache hit", second.Cached, councilRuns.Load())
+		}
+		if second.Stage3.Response != first.Stage3.Response {
+			t.Errorf("Cached Stage 3 = %q, want %q", second.Stage3.Response, first.Stage3.Response)
+		}
+
+		// The hit is still recorded in the conversation
+		conv, _ := GetConversation("cache-2")
+		if last := conv.Messages[len(conv.Messages)-1]; last.Role != "assistant" || last.Stage3 == nil {
+			t.Errorf("Last message = %+v, want the cached assistant response", last)
+		}
+	})
+
+	t.Run("no_cache override", func(t *testing.T) {
+		response := send(t, "cache-1", "?no_cache=true", "What is Go?")
+		if response.Cached || councilRuns.Load() != 2 {
+			t.Errorf("no_cache: cached = %v after %d runs, want a fresh run", response.Cached, councilRuns.Load())
+		}
+	})
+
+	t.Run("different question", func(t *testing.T) {
+		response := send(t, "cache-1", "", "What is Rust?")
+		if response.Cached || councilRuns.Load() != 3 {
+			t.Errorf("New question: cached = %v after %d runs, want a fresh run", response.Cached, councilRuns.Load())
+		}
+	})
+
+	t.Run("roster change invalidates", func(t *testing.T) {
+		CouncilModels = []string{"model/a", "model/b"}
+		response := send(t, "cache-1", "", "What is Go?")
+		if response.Cached || councilRuns.Load() != 4 {
+			t.Errorf("New roster: cached = %v after %d runs, want a fresh run", response.Cached, councilRuns.Load())
+		}
+
+		key := CouncilCacheKey(context.Background(), "What is Go?", nil)
+		ChairmanModel = "model/other-chairman"
+		if CouncilCacheKey(context.Background(), "What is Go?", nil) == key {
+			t.Error("Changing the chairman should change the cache key")
+		}
+
+		// A roster pinned in the context is keyed, not the configured one
+		pinned := WithCouncilConfig(context.Background(), CouncilConfig{CouncilModels: []string{"model/c"}, ChairmanModel: "model/c"})
+		if CouncilCacheKey(pinned, "What is Go?", nil) == CouncilCacheKey(context.Background(), "What is Go?", nil) {
+			t.Error("A pinned roster should change the cache key")
+		}
+	})
+}
+
+// TestSendMessageHandlerIdempotencyKey tests that retries with the same Idempotency-Key
+// replay the stored response instead of running the council again
+func TestSendMessageHandlerIdempotencyKey(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	oldStore := idempotencyStore
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+		idempotencyStore = oldStore
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a"}
+	ChairmanModel = "model/chairman"
+	idempotencyStore = NewIdempotencyStore(time.Minute)
+
+	// Count chairman queries: one per council run
+	var mu sync.Mutex
+	councilRuns := 0
+	respond := CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		if req.Model == ChairmanModel {
+			mu.Lock()
+			councilRuns++
+			mu.Unlock()
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		respond(w, r)
+	})
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	CreateConversation("idem-1")
+	CreateConversation("idem-2")
+	AddUserMessage("idem-1", "Earlier question") // skip background title generation
+	AddUserMessage("idem-2", "Earlier question")
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message", sendMessageHandler)
+
+	send := func(conversationID, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/conversations/"+conversationID+"/message",
+			strings.NewReader(`{"content": "What is Go?"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	runs := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return councilRuns
+	}
+
+	first := send("idem-1", "retry-key")
+	if first.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d: %s", first.Code, http.StatusOK, first.Body.String())
+	}
+	retry := send("idem-1", "retry-key")
+	if retry.Code != http.StatusOK {
+		t.Fatalf("Retry status = %d, want %d: %s", retry.Code, http.StatusOK, retry.Body.String())
+	}
+
+	if got := runs(); got != 1 {
+		t.Errorf("Council ran %d times for a duplicate key, want 1", got)
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("Retry body differs from the original:\n%s\nvs\n%s", retry.Body.String(), first.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected the retry to be marked as replayed")
+	}
+	conv, _ := GetConversation("idem-1")
+	if len(conv.Messages) != 3 {
+		t.Errorf("Expected one user and one assistant message to be added, got %d messages", len(conv.Messages))
+	}
+
+	// A different key, or the same key on another conversation, runs the council again
+	if w := send("idem-1", "other-key"); w.Code != http.StatusOK {
+		t.Fatalf("Status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := send("idem-2", "retry-key"); w.Code != http.StatusOK {
+		t.Fatalf("Status = %d: %s", w.Code, w.Body.String())
+	}
+	if got := runs(); got != 3 {
+		t.Errorf("Council runs = %d, want 3", got)
+	}
+
+	t.Run("key still in progress", func(t *testing.T) {
+		if _, err := idempotencyStore.Begin("idem-2:busy"); err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		defer idempotencyStore.Finish("idem-2:busy", nil)
+
+		if w := send("idem-2", "busy"); w.Code != http.StatusConflict {
+			t.Errorf("Status = %d, want %d", w.Code, http.StatusConflict)
+		}
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		if w := send("idem-2", strings.Repeat("k", MaxIdempotencyKeyLength+1)); w.Code != http.StatusBadRequest {
+			t.Errorf("Status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+// TestSendMessageStreamHandlerHeartbeat verifies keep-alive comments are written while a
+// slow stage runs, and stop once the stream is finished
+func TestSendMessageStreamHandlerHeartbeat(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	oldInterval := SSEHeartbeatInterval
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+		SSEHeartbeatInterval = oldInterval
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a"}
+	ChairmanModel = "model/chairman"
+
+	// Stage 1 is slow; the ranking and synthesis are quick
+	respond := CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response A")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !bytes.Contains(body, []byte("evaluating different responses")) && !bytes.Contains(body, []byte("Chairman")) {
+			time.Sleep(200 * time.Millisecond)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		respond(w, r)
+	})
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message/stream", sendMessageStreamHandler)
+
+	stream := func(t *testing.T, id string) *httptest.ResponseRecorder {
+		t.Helper()
+		CreateConversation(id)
+		AddUserMessage(id, "Earlier question") // skip background title generation
+
+		req := httptest.NewRequest("POST", "/api/conversations/"+id+"/message/stream", strings.NewReader(`{"content": "Test question"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if !strings.Contains(w.Body.String(), `"type":"complete"`) {
+			t.Fatalf("Stream did not complete:\n%s", w.Body.String())
+		}
+		return w
+	}
+
+	t.Run("heartbeats during slow stage", func(t *testing.T) {
+		SSEHeartbeatInterval = 20 * time.Millisecond
+		w := stream(t, "heartbeat")
+
+		body := w.Body.String()
+		start := strings.Index(body, `"type":"stage1_start"`)
+		end := strings.Index(body, `"type":"stage1_complete"`)
+		if n := strings.Count(body[start:end], ": keepalive\n\n"); n < 3 {
+			t.Errorf("Got %d heartbeats during Stage 1, want at least 3:\n%s", n, body)
+		}
+
+		// Nothing is written once the handler has returned
+		length := w.Body.Len()
+		time.Sleep(60 * time.Millisecond)
+		if w.Body.Len() != length {
+			t.Errorf("Heartbeat kept writing after the stream completed")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		SSEHeartbeatInterval = 0
+		w := stream(t, "no-heartbeat")
+
+		if strings.Contains(w.Body.String(), "keepalive") {
+			t.Errorf("Unexpected heartbeat with SSEHeartbeatInterval = 0:\n%s", w.Body.String())
+		}
+	})
+}
+
+// TestSendMessageStreamHandlerStage2Progress tests that each ranking is streamed as a
+// stage2_model_complete event before the aggregate stage2_complete event
+func TestSendMessageStreamHandlerStage2Progress(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldChairman := ChairmanModel
+	defer func() {
+		DataDir = oldDataDir
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		ChairmanModel = oldChairman
+	}()
+
+	DataDir = tempDir
+	CouncilModels = []string{"model/a", "model/b", "model/c"}
+	ChairmanModel = "model/chairman"
+
+	// model/c answers in Stage 1 but fails to rank in Stage 2
+	respond := CreateMockOpenRouterHandler(t, "FINAL RANKING:\n1. Response B\n2. Response A\n3. Response C")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		if req.Model == "model/c" && strings.Contains(req.Messages[0].Content, "evaluating different responses") {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		respond(w, r)
+	})
+	defer mockServer.Close()
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	CreateConversation("test-stage2-stream")
+	AddUserMessage("test-stage2-stream", "Earlier question") // skip background title generation
+
+	router := gin.New()
+	router.POST("/api/conversations/:id/message/stream", sendMessageStreamHandler)
+
+	req := httptest.NewRequest("POST", "/api/conversations/test-stage2-stream/message/stream",
+		strings.NewReader(`{"content": "Test question"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var eventTypes []string
+	rankedModels := make(map[string]bool)
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event)
+		eventTypes = append(eventTypes, event.Type)
+
+		if event.Type == "stage2_model_complete" {
+			var ranking Stage2Ranking
+			json.Unmarshal(event.Data, &ranking)
+			if !reflect.DeepEqual(ranking.ParsedRanking, []string{"Response B", "Response A", "Response C"}) {
+				t.Errorf("%s: parsed ranking = %v", ranking.Model, ranking.ParsedRanking)
+			}
+			rankedModels[ranking.Model] = true
+		}
+	}
+
+	if !reflect.DeepEqual(rankedModels, map[string]bool{"model/a": true, "model/b": true}) {
+		t.Errorf("stage2_model_complete models = %v, want model/a and model/b", rankedModels)
+	}
+
+	// Per-ranker events arrive between stage2_start and stage2_complete
+	start := slices.Index(eventTypes, "stage2_start")
+	complete := slices.Index(eventTypes, "stage2_complete")
+	if start < 0 || complete < 0 {
+		t.Fatalf("Missing stage 2 events: %v", eventTypes)
+	}
+	if got := eventTypes[start+1 : complete]; !reflect.DeepEqual(got, []string{"stage2_model_complete", "stage2_model_complete"}) {
+		t.Errorf("Events between stage2_start and stage2_complete = %v", got)
+	}
+}
+
+// TestMetricsHandler tests reporting model circuit breaker state
+func TestMetricsHandler(t *testing.T) {
+	oldCircuits, oldBillsCache := modelCircuits, billsCache
+	defer func() { modelCircuits, billsCache = oldCircuits, oldBillsCache }()
+
+	modelCircuits = NewCircuitBreaker(1, time.Minute)
+	modelCircuits.Record("model/b", &OpenRouterError{Model: "model/b", Err: ErrTimeout})
+
+	billsCache = NewBillsCache(time.Hour)
+	billsCache.Get()
+	billsCache.Set([]Bill{{ID: "r1"}})
+	billsCache.Get()
+	billsCache.Get()
+
+	router := gin.New()
+	router.GET("/api/metrics", metricsHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var response MetricsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.ModelCircuits) != 1 {
+		t.Fatalf("Expected 1 circuit, got %+v", response.ModelCircuits)
+	}
+	circuit := response.ModelCircuits[0]
+	if circuit.Model != "model/b" || circuit.State != CircuitOpen || circuit.LastError != "timeout" || circuit.OpenUntil == nil {
+		t.Errorf("Unexpected circuit: %+v", circuit)
+	}
+	if response.BillsCache != (CacheStats{Hits: 2, Misses: 1}) {
+		t.Errorf("BillsCache = %+v, want 2 hits and 1 miss", response.BillsCache)
 	}
 }
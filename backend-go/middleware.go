@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// APIKeyAuthMiddleware requires "Authorization: Bearer <apiKey>" on /api/* routes.
+// The health check and CORS preflight requests are always allowed through.
+// When apiKey is empty, authentication is disabled and all requests pass.
+func APIKeyAuthMiddleware(apiKey string) gin.HandlerFunc {
+	expected := []byte(apiKey)
+
+	return func(c *gin.Context) {
+		if apiKey == "" || c.Request.Method == http.MethodOptions || !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), expected) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="llm-council"`)
+			respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Missing or invalid API key")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AdminAuthMiddleware requires "X-Admin-Key: <adminKey>" on the routes it guards.
+// When adminKey is empty the routes are disabled and every request is refused.
+func AdminAuthMiddleware(adminKey string) gin.HandlerFunc {
+	expected := []byte(adminKey)
+
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			respondError(c, http.StatusForbidden, ErrCodeForbidden, "Admin endpoints are disabled; set ADMIN_API_KEY to enable them")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(c.GetHeader("X-Admin-Key"))), expected) != 1 {
+			respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Missing or invalid admin key")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimiterIdleTTL is how long an idle client's bucket is kept before being dropped
+const rateLimiterIdleTTL = 10 * time.Minute
+
+// ipRateLimiter holds a token bucket per client IP
+type ipRateLimiter struct {
+	mu        sync.Mutex
+	limiters  map[string]*clientLimiter
+	rps       rate.Limit
+	burst     int
+	lastSweep time.Time
+}
+
+// clientLimiter is a single client's bucket and when it was last used
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// get returns the bucket for ip, creating it if needed and dropping idle buckets
+func (l *ipRateLimiter) get(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for key, entry := range l.limiters {
+			if now.Sub(entry.lastSeen) > rateLimiterIdleTTL {
+				delete(l.limiters, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, ok := l.limiters[ip]
+	if !ok {
+		entry = &clientLimiter{limiter: rate.NewLimiter(l.rps, l.burst)}
+		l.limiters[ip] = entry
+	}
+	entry.lastSeen = now
+
+	return entry.limiter
+}
+
+// RateLimitMiddleware limits each client IP to rps requests per second with the given burst.
+// The client IP comes from X-Forwarded-For only for the router's trusted proxies.
+// Requests over the limit get 429 with a Retry-After header. The health check and CORS
+// preflight requests are exempt. A non-positive rps disables rate limiting.
+func RateLimitMiddleware(rps float64, burst int) gin.HandlerFunc {
+	if rps <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	limiters := &ipRateLimiter{
+		limiters: make(map[string]*clientLimiter),
+		rps:      rate.Limit(rps),
+		burst:    max(burst, 1),
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.URL.Path == "/" || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		reservation := limiters.get(c.ClientIP()).Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			respondError(c, http.StatusTooManyRequests, ErrCodeRateLimited, "Rate limit exceeded, please retry later")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// trustProxies makes c.ClientIP() honour X-Forwarded-For only on requests from
+// proxies, falling back to the peer's own address for everyone else. Gin trusts
+// every peer by default, which would let any client pick its rate limit bucket.
+func trustProxies(router *gin.Engine, proxies []netip.Prefix) error {
+	cidrs := make([]string, len(proxies))
+	for i, proxy := range proxies {
+		cidrs[i] = proxy.String()
+	}
+	return router.SetTrustedProxies(cidrs)
+}
+
+// matchOrigin reports whether a request Origin is allowed by a configured origin
+// pattern. Patterns without a wildcard must match exactly. A pattern such as
+// "https://*.example.com" matches exactly one subdomain label in place of "*":
+// "https://app.example.com" matches, but "https://example.com",
+// "https://a.b.example.com" and "https://app.example.com.evil.com" do not. The
+// scheme and port must match the pattern exactly; the wildcard is only honoured
+// as the leftmost label of the host.
+func matchOrigin(pattern, origin string) bool {
+	origin = strings.ToLower(origin)
+	if !strings.Contains(pattern, "*") {
+		return origin == pattern
+	}
+
+	p, err := url.Parse(pattern)
+	if err != nil {
+		return false
+	}
+	suffix, ok := strings.CutPrefix(p.Hostname(), "*.")
+	if !ok || suffix == "" || strings.Contains(suffix, "*") {
+		return false
+	}
+
+	// Browsers never percent-encode an Origin host, so refuse anything url.Parse would unescape
+	if strings.Contains(origin, "%") {
+		return false
+	}
+	o, err := url.Parse(origin)
+	if err != nil || o.Scheme != p.Scheme || o.Port() != p.Port() || o.User != nil ||
+		o.Path != "" || o.RawQuery != "" || o.Fragment != "" {
+		return false
+	}
+	label, ok := strings.CutSuffix(o.Hostname(), "."+suffix)
+	return ok && isHostLabel(label)
+}
+
+// isHostLabel reports whether s is a single DNS label (letters, digits and inner hyphens).
+func isHostLabel(s string) bool {
+	if s == "" || len(s) > 63 || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
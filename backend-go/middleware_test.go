@@ -0,0 +1,250 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAPIKeyAuthMiddleware tests bearer-token authentication on /api routes
+func TestAPIKeyAuthMiddleware(t *testing.T) {
+	newRouter := func(apiKey string) *gin.Engine {
+		router := gin.New()
+		router.Use(APIKeyAuthMiddleware(apiKey))
+		router.GET("/", healthCheck)
+		router.GET("/api/conversations", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"ok": true})
+		})
+		router.OPTIONS("/api/conversations", func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		return router
+	}
+
+	tests := []struct {
+		name          string
+		apiKey        string
+		method        string
+		path          string
+		authorization string
+		expected      int
+	}{
+		{"valid token", "secret", "GET", "/api/conversations", "Bearer secret", http.StatusOK},
+		{"invalid token", "secret", "GET", "/api/conversations", "Bearer wrong", http.StatusUnauthorized},
+		{"token prefix of key", "secret", "GET", "/api/conversations", "Bearer secre", http.StatusUnauthorized},
+		{"missing token", "secret", "GET", "/api/conversations", "", http.StatusUnauthorized},
+		{"wrong scheme", "secret", "GET", "/api/conversations", "Basic secret", http.StatusUnauthorized},
+		{"health check exempt", "secret", "GET", "/", "", http.StatusOK},
+		{"preflight exempt", "secret", "OPTIONS", "/api/conversations", "", http.StatusNoContent},
+		{"auth disabled", "", "GET", "/api/conversations", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			newRouter(tt.apiKey).ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Status = %d, want %d", w.Code, tt.expected)
+			}
+			if tt.expected == http.StatusUnauthorized {
+				if w.Header().Get("WWW-Authenticate") == "" {
+					t.Error("Expected WWW-Authenticate header on 401")
+				}
+				AssertAPIError(t, w, http.StatusUnauthorized, ErrCodeUnauthorized)
+			}
+		})
+	}
+}
+
+// TestAdminAuthMiddleware tests the admin key check on admin routes
+func TestAdminAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		adminKey string
+		header   string
+		expected int
+		code     string
+	}{
+		{"valid key", "admin-secret", "admin-secret", http.StatusOK, ""},
+		{"invalid key", "admin-secret", "wrong", http.StatusUnauthorized, ErrCodeUnauthorized},
+		{"missing key", "admin-secret", "", http.StatusUnauthorized, ErrCodeUnauthorized},
+		{"admin disabled", "", "anything", http.StatusForbidden, ErrCodeForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/api/config/models", AdminAuthMiddleware(tt.adminKey), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"ok": true})
+			})
+
+			req := httptest.NewRequest("GET", "/api/config/models", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Admin-Key", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if tt.code == "" {
+				if w.Code != tt.expected {
+					t.Errorf("Status = %d, want %d", w.Code, tt.expected)
+				}
+				return
+			}
+			AssertAPIError(t, w, tt.expected, tt.code)
+		})
+	}
+}
+
+// TestRateLimitMiddleware tests per-IP token-bucket rate limiting
+func TestRateLimitMiddleware(t *testing.T) {
+	newRouter := func(rps float64, burst int) *gin.Engine {
+		router := gin.New()
+		if err := trustProxies(router, []netip.Prefix{netip.MustParsePrefix("10.0.0.9/32")}); err != nil {
+			t.Fatalf("trustProxies failed: %v", err)
+		}
+		router.Use(RateLimitMiddleware(rps, burst))
+		router.GET("/", healthCheck)
+		router.GET("/api/conversations", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"ok": true})
+		})
+		return router
+	}
+
+	request := func(router *gin.Engine, path, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("requests above the burst are rejected", func(t *testing.T) {
+		router := newRouter(0.5, 3)
+
+		var ok, limited int
+		for i := 0; i < 6; i++ {
+			w := request(router, "/api/conversations", "10.0.0.1:1234", "")
+			switch w.Code {
+			case http.StatusOK:
+				ok++
+			case http.StatusTooManyRequests:
+				limited++
+				if retryAfter := w.Header().Get("Retry-After"); retryAfter != "2" {
+					t.Errorf("Retry-After = %q, want '2' at 0.5 requests/second", retryAfter)
+				}
+			default:
+				t.Fatalf("Unexpected status %d", w.Code)
+			}
+		}
+
+		if ok != 3 || limited != 3 {
+			t.Errorf("Got %d allowed and %d limited, want 3 and 3", ok, limited)
+		}
+	})
+
+	t.Run("clients are limited independently", func(t *testing.T) {
+		router := newRouter(0.5, 1)
+
+		if w := request(router, "/api/conversations", "10.0.0.1:1234", ""); w.Code != http.StatusOK {
+			t.Fatalf("First client status = %d, want %d", w.Code, http.StatusOK)
+		}
+		AssertAPIError(t, request(router, "/api/conversations", "10.0.0.1:1234", ""), http.StatusTooManyRequests, ErrCodeRateLimited)
+		if w := request(router, "/api/conversations", "10.0.0.2:1234", ""); w.Code != http.StatusOK {
+			t.Errorf("Second client status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("X-Forwarded-For identifies clients behind a trusted proxy", func(t *testing.T) {
+		router := newRouter(0.5, 1)
+
+		if w := request(router, "/api/conversations", "10.0.0.9:80", "203.0.113.5, 10.0.0.9"); w.Code != http.StatusOK {
+			t.Fatalf("First forwarded client status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if w := request(router, "/api/conversations", "10.0.0.9:80", "203.0.113.6"); w.Code != http.StatusOK {
+			t.Errorf("Second forwarded client status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if w := request(router, "/api/conversations", "10.0.0.9:80", "203.0.113.5"); w.Code != http.StatusTooManyRequests {
+			t.Errorf("Repeat forwarded client status = %d, want %d", w.Code, http.StatusTooManyRequests)
+		}
+	})
+
+	t.Run("forged X-Forwarded-For from an untrusted peer is ignored", func(t *testing.T) {
+		router := newRouter(0.5, 1)
+
+		if w := request(router, "/api/conversations", "198.51.100.7:1234", "203.0.113.1"); w.Code != http.StatusOK {
+			t.Fatalf("First request status = %d, want %d", w.Code, http.StatusOK)
+		}
+		AssertAPIError(t, request(router, "/api/conversations", "198.51.100.7:1234", "203.0.113.2"), http.StatusTooManyRequests, ErrCodeRateLimited)
+	})
+
+	t.Run("health check is exempt", func(t *testing.T) {
+		router := newRouter(0.5, 1)
+
+		for i := 0; i < 5; i++ {
+			if w := request(router, "/", "10.0.0.1:1234", ""); w.Code != http.StatusOK {
+				t.Fatalf("Health check request %d status = %d, want %d", i+1, w.Code, http.StatusOK)
+			}
+		}
+	})
+
+	t.Run("disabled when rps is zero", func(t *testing.T) {
+		router := newRouter(0, 1)
+
+		for i := 0; i < 5; i++ {
+			if w := request(router, "/api/conversations", "10.0.0.1:1234", ""); w.Code != http.StatusOK {
+				t.Fatalf("Request %d status = %d, want %d", i+1, w.Code, http.StatusOK)
+			}
+		}
+	})
+}
+
+// TestMatchOrigin tests exact and wildcard subdomain origin matching
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"exact match", "https://example.com", "https://example.com", true},
+		{"exact match with port", "https://example.com:8080", "https://example.com:8080", true},
+		{"exact mismatch", "https://example.com", "https://other.com", false},
+		{"exact match is case-insensitive", "https://example.com", "https://EXAMPLE.com", true},
+		{"wildcard single label", "https://*.example.com", "https://app.example.com", true},
+		{"wildcard hyphenated label", "https://*.example.com", "https://my-app.example.com", true},
+		{"wildcard with port", "https://*.example.com:8443", "https://app.example.com:8443", true},
+		{"wildcard needs a subdomain", "https://*.example.com", "https://example.com", false},
+		{"wildcard matches one level only", "https://*.example.com", "https://a.b.example.com", false},
+		{"wildcard scheme must match", "https://*.example.com", "http://app.example.com", false},
+		{"wildcard port must match", "https://*.example.com", "https://app.example.com:8080", false},
+		{"suffix without dot boundary", "https://*.example.com", "https://evilexample.com", false},
+		{"pattern host as prefix", "https://*.example.com", "https://app.example.com.evil.com", false},
+		{"trailing dot", "https://*.example.com", "https://app.example.com.", false},
+		{"empty label", "https://*.example.com", "https://.example.com", false},
+		{"percent-encoded dot", "https://*.example.com", "https://evil%2eexample.com", false},
+		{"userinfo", "https://*.example.com", "https://app.example.com@evil.com", false},
+		{"path", "https://*.example.com", "https://app.example.com/x", false},
+		{"wildcard in scheme is not honoured", "*://app.example.com", "https://app.example.com", false},
+		{"wildcard not leftmost", "https://app.*.com", "https://app.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchOrigin(tt.pattern, tt.origin); got != tt.want {
+				t.Errorf("matchOrigin(%q, %q) = %v, want %v", tt.pattern, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
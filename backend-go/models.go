@@ -1,11 +1,15 @@
 package main
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Message represents a single message in a conversation
 type Message struct {
 	Role    string                 `json:"role"`
 	Content string                 `json:"content,omitempty"`
+	ImageURLs []string             `json:"image_urls,omitempty"`
 	Stage1  []Stage1Response       `json:"stage1,omitempty"`
 	Stage2  []Stage2Ranking        `json:"stage2,omitempty"`
 	Stage3  *Stage3Response        `json:"stage3,omitempty"`
@@ -13,24 +17,55 @@ type Message struct {
 
 // Conversation represents a full conversation with all messages
 type Conversation struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	Title     string    `json:"title"`
-	Messages  []Message `json:"messages"`
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"` // Last message added or title change
+	Title      string    `json:"title"`
+	Messages   []Message `json:"messages"`
+	ForkedFrom string    `json:"forked_from,omitempty"` // Source conversation ID for forks
+	Archived   bool      `json:"archived,omitempty"`    // Hidden from the default list
+	Tags       []string  `json:"tags,omitempty"`        // Normalized: lowercase, sorted, unique
 }
 
 // ConversationMetadata represents conversation list metadata
 type ConversationMetadata struct {
 	ID           string    `json:"id"`
 	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 	Title        string    `json:"title"`
 	MessageCount int       `json:"message_count"`
+	Archived     bool      `json:"archived"`
+	Tags         []string  `json:"tags"`
+}
+
+// ConversationFilter selects which conversations ListConversations returns
+type ConversationFilter struct {
+	IncludeArchived bool
+	Tag             string // Only conversations with this tag, if set
+	SortByUpdated   bool   // Most recently updated first instead of newest first
+}
+
+// AddTagsRequest is the body for adding tags to a conversation
+type AddTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// ConversationSearchResult is a conversation matching a search, with context for the match
+type ConversationSearchResult struct {
+	ConversationMetadata
+	MatchField string `json:"match_field"` // "title", "user", "stage1" or "stage3"
+	Snippet    string `json:"snippet"`
 }
 
 // Stage1Response represents a single model's response in Stage 1
 type Stage1Response struct {
-	Model    string `json:"model"`
-	Response string `json:"response"`
+	Model            string      `json:"model"`
+	Response         string      `json:"response"`
+	ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
+	FinishReason     string      `json:"finish_reason,omitempty"` // "length" if the answer was cut off
+
+	// Latency is how long the model took to answer; reported in Metadata, not stored
+	Latency time.Duration `json:"-"`
 }
 
 // Stage2Ranking represents a model's ranking of other responses
@@ -38,12 +73,20 @@ type Stage2Ranking struct {
 	Model          string   `json:"model"`
 	Ranking        string   `json:"ranking"`
 	ParsedRanking  []string `json:"parsed_ranking"`
+
+	// Latency is how long the model took to rank; reported in Metadata, not stored
+	Latency time.Duration `json:"-"`
 }
 
-// Stage3Response represents the chairman's final synthesis
+// Stage3Response represents the chairman's final synthesis.
+// Model is the chairman that actually produced the response; UsedFallback is
+// set when the primary chairman failed and a fallback chairman was used.
 type Stage3Response struct {
-	Model    string `json:"model"`
-	Response string `json:"response"`
+	Model            string      `json:"model"`
+	Response         string      `json:"response"`
+	UsedFallback     bool        `json:"used_fallback,omitempty"`
+	ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
+	FinishReason     string      `json:"finish_reason,omitempty"` // "length" if the answer was cut off
 }
 
 // AggregateRanking represents the aggregate ranking across all models
@@ -53,28 +96,174 @@ type AggregateRanking struct {
 	RankingsCount  int     `json:"rankings_count"`
 }
 
+// ModelFailure records why a council model produced no response
+type ModelFailure struct {
+	Model  string `json:"model"`
+	Reason string `json:"reason"`
+	Err    error  `json:"-"`
+}
+
 // Metadata contains additional information about the council process
 type Metadata struct {
 	LabelToModel       map[string]string  `json:"label_to_model"`
 	AggregateRankings  []AggregateRanking `json:"aggregate_rankings"`
+	FailedModels       []ModelFailure     `json:"failed_models,omitempty"`
+
+	// ConsensusScore is Kendall's W across the Stage 2 rankings:
+	// 0 = total disagreement, 1 = perfect agreement
+	ConsensusScore float64 `json:"consensus_score"`
+
+	// Winner is the top of AggregateRankings, nil when there are no rankings
+	Winner *CouncilWinner `json:"winner,omitempty"`
+
+	// WinnerRationale holds the sentences the rankers wrote about the winning
+	// responses, when WinnerRationaleEnabled is set
+	WinnerRationale []string `json:"winner_rationale,omitempty"`
+
+	// OmittedModels lists models whose Stage 1 responses were left out of ranking and
+	// synthesis because more than MaxCouncilResponses models responded
+	OmittedModels []string `json:"omitted_models,omitempty"`
+
+	// DuplicateGroups lists the models whose Stage 1 responses were near-identical,
+	// one group per set of duplicates
+	DuplicateGroups [][]string `json:"duplicate_groups,omitempty"`
+
+	// ModelLatencies is how long each council model took to answer in Stage 1, and
+	// RankingLatencies how long each ranker took in Stage 2, in seconds
+	ModelLatencies   map[string]float64 `json:"model_latencies,omitempty"`
+	RankingLatencies map[string]float64 `json:"ranking_latencies,omitempty"`
+
+	// TotalDuration is the wall-clock time of the whole run, and the stage durations
+	// that of each stage including its aggregation, in seconds. Unlike the latencies
+	// above they include time spent waiting on the slowest model and on retries.
+	TotalDuration  float64 `json:"total_duration,omitempty"`
+	Stage1Duration float64 `json:"stage1_duration,omitempty"`
+	Stage2Duration float64 `json:"stage2_duration,omitempty"`
+	Stage3Duration float64 `json:"stage3_duration,omitempty"`
+
+	// Mode is the council preset the run used, empty for the configured council
+	Mode string `json:"mode,omitempty"`
 }
 
-// OpenRouterMessage represents a message for OpenRouter API
+// CouncilWinner summarizes the top-ranked model(s) in the aggregate ranking.
+// Models lists every model tied for first; Margin is how far ahead of the
+// runner-up's average rank they are (0 when there is no runner-up).
+type CouncilWinner struct {
+	Models      []string `json:"models"`
+	AverageRank float64  `json:"average_rank"`
+	Margin      float64  `json:"margin"`
+}
+
+// OpenRouterMessage represents a message for OpenRouter API.
+// When Parts is set the content is sent as a multimodal content-parts array;
+// Content should still hold the message text.
 type OpenRouterMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string        `json:"role"`
+	Content string        `json:"content"`
+	Parts   []ContentPart `json:"-"`
+}
+
+// ContentPart is one part of a multimodal message: text or an image
+type ContentPart struct {
+	Type     string    `json:"type"` // "text" or "image_url"
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by URL or base64 data URL
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON sends content as a string, or as a content-parts array when Parts is set
+func (m OpenRouterMessage) MarshalJSON() ([]byte, error) {
+	if len(m.Parts) == 0 {
+		type plain OpenRouterMessage
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		Role    string        `json:"role"`
+		Content []ContentPart `json:"content"`
+	}{m.Role, m.Parts})
+}
+
+// UnmarshalJSON accepts content as either a string or a content-parts array.
+// For an array, Content is set to the concatenated text parts.
+func (m *OpenRouterMessage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = OpenRouterMessage{Role: raw.Role}
+	if len(raw.Content) == 0 || string(raw.Content) == "null" {
+		return nil
+	}
+	if raw.Content[0] != '[' {
+		return json.Unmarshal(raw.Content, &m.Content)
+	}
+	if err := json.Unmarshal(raw.Content, &m.Parts); err != nil {
+		return err
+	}
+	for _, part := range m.Parts {
+		if part.Type == "text" {
+			m.Content += part.Text
+		}
+	}
+	return nil
 }
 
 // OpenRouterRequest represents a request to OpenRouter API
 type OpenRouterRequest struct {
 	Model    string                `json:"model"`
 	Messages []OpenRouterMessage   `json:"messages"`
+	Stream   bool                  `json:"stream,omitempty"`
+
+	Reasoning      *ReasoningConfig `json:"reasoning,omitempty"`
+	ResponseFormat *ResponseFormat  `json:"response_format,omitempty"`
+}
+
+// ResponseFormat requests structured output, e.g. {"type": "json_schema", ...}
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema names the JSON schema a structured response must follow
+type JSONSchema struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// ReasoningConfig asks a reasoning model to spend more or less effort thinking
+// ("minimal", "low", "medium" or "high")
+type ReasoningConfig struct {
+	Effort string `json:"effort,omitempty"`
 }
 
 // OpenRouterResponse represents a response from OpenRouter API
 type OpenRouterResponse struct {
 	Content          string      `json:"content"`
 	ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
+
+	// FinishReason is why the model stopped, e.g. "stop", or FinishReasonLength when
+	// it ran out of output tokens; empty if the provider didn't say
+	FinishReason string `json:"finish_reason,omitempty"`
+
+	// Latency is the wall-clock duration of the query, set by QueryModelsParallel
+	Latency time.Duration `json:"-"`
+}
+
+// FinishReasonLength is the finish_reason reported when a model stopped because
+// it hit its output token limit rather than finishing its answer
+const FinishReasonLength = "length"
+
+// Truncated reports whether the model was cut off before finishing its answer
+func (r *OpenRouterResponse) Truncated() bool {
+	return r.FinishReason == FinishReasonLength
 }
 
 // OpenRouterAPIResponse represents the full API response structure
@@ -84,9 +273,79 @@ type OpenRouterAPIResponse struct {
 			Content          string      `json:"content"`
 			ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
 }
 
+// OpenRouterStreamChunk represents a single "data:" chunk of a streaming response
+type OpenRouterStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string      `json:"content"`
+			ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"` // Only set on the last chunk
+	} `json:"choices"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// CircuitStatus reports a model's circuit breaker state.
+// OpenUntil is set while the circuit is open.
+type CircuitStatus struct {
+	Model               string     `json:"model"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+// CacheStats reports how often a cache's lookups were served from it
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// MetricsResponse is the response body for GET /api/metrics
+type MetricsResponse struct {
+	ModelCircuits []CircuitStatus `json:"model_circuits"`
+	BillsCache    CacheStats      `json:"bills_cache"`
+}
+
+// CatalogModel represents a model listed in OpenRouter's model catalog
+type CatalogModel struct {
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	ContextLength int          `json:"context_length,omitempty"`
+	Pricing       ModelPricing `json:"pricing"`
+}
+
+// ModelPricing holds OpenRouter's per-token prices (USD, as decimal strings)
+type ModelPricing struct {
+	Prompt     string `json:"prompt"`
+	Completion string `json:"completion"`
+}
+
+// ModelsResponse represents the configured models plus the OpenRouter catalog.
+// CatalogError is set (and Catalog empty) when the catalog couldn't be fetched.
+type ModelsResponse struct {
+	CouncilModels     []string       `json:"council_models"`
+	RankerModels      []string       `json:"ranker_models"`
+	ChairmanModel     string         `json:"chairman_model"`
+	ChairmanFallbacks []string       `json:"chairman_fallbacks"`
+	TitleModel        string         `json:"title_model"`
+	Catalog           []CatalogModel `json:"catalog,omitempty"`
+	CatalogError      string         `json:"catalog_error,omitempty"`
+}
+
+// ModelConfig is the council roster read and updated through /api/config/models
+type ModelConfig struct {
+	CouncilModels []string `json:"council_models,omitempty"`
+	ChairmanModel string   `json:"chairman_model,omitempty"`
+}
+
 // CreateConversationRequest represents a request to create a new conversation
 type CreateConversationRequest struct {
 	// Empty for now
@@ -94,13 +353,77 @@ type CreateConversationRequest struct {
 
 // SendMessageRequest represents a request to send a message
 type SendMessageRequest struct {
+	Content   string   `json:"content"`
+	ImageURLs []string `json:"image_urls,omitempty"`
+	Mode      string   `json:"mode,omitempty"` // Council preset from CouncilModes; also accepted as ?mode=
+}
+
+// EditMessageRequest represents the request to edit a user message
+type EditMessageRequest struct {
 	Content string `json:"content"`
 }
 
+// CompareRequest asks two council rosters the same question
+type CompareRequest struct {
+	Content   string        `json:"content"`
+	ImageURLs []string      `json:"image_urls,omitempty"`
+	A         CouncilConfig `json:"a"`
+	B         CouncilConfig `json:"b"`
+}
+
+// CompareResponse holds each roster's council result, side by side
+type CompareResponse struct {
+	A SendMessageResponse `json:"a"`
+	B SendMessageResponse `json:"b"`
+}
+
+// ForkConversationRequest represents the request to fork a conversation
+type ForkConversationRequest struct {
+	UpToMessage *int `json:"up_to_message" binding:"required"` // Index of the last message to copy
+}
+
+// AnalyzeBillResponse is the council's analysis of a bill, saved as a new conversation
+type AnalyzeBillResponse struct {
+	ConversationID string `json:"conversation_id"`
+	SendMessageResponse
+}
+
+// TokenEstimate is the projected Stage 1 prompt size (and cost, if priced) for one model
+type TokenEstimate struct {
+	Model            string   `json:"model"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	EstimatedCostUSD *float64 `json:"estimated_cost_usd,omitempty"`
+}
+
+// EstimateResponse is the dry-run estimate for sending a message to the council.
+// TotalCostUSD sums the priced models only and is omitted if none are priced.
+type EstimateResponse struct {
+	Models            []TokenEstimate `json:"models"`
+	TotalPromptTokens int             `json:"total_prompt_tokens"`
+	TotalCostUSD      *float64        `json:"total_cost_usd,omitempty"`
+}
+
+// ErrorResponse is the body of every API error response
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIError describes a failed request. Code is a stable machine-readable value
+// such as "conversation_not_found"; Details carries optional context like the
+// offending field.
+type APIError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
 // SendMessageResponse represents the response after sending a message
 type SendMessageResponse struct {
 	Stage1   []Stage1Response `json:"stage1"`
 	Stage2   []Stage2Ranking  `json:"stage2"`
 	Stage3   Stage3Response   `json:"stage3"`
 	Metadata Metadata         `json:"metadata"`
+
+	// Cached is set when the result was reused from an identical earlier query
+	Cached bool `json:"cached,omitempty"`
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -215,6 +217,51 @@ func TestStage3ResponseJSONMarshaling(t *testing.T) {
 	}
 }
 
+// TestReasoningDetailsJSONMarshaling tests that reasoning details round-trip through
+// Stage 1 and Stage 3 JSON and are omitted when absent
+func TestReasoningDetailsJSONMarshaling(t *testing.T) {
+	details := []interface{}{
+		map[string]interface{}{"type": "reasoning.summary", "summary": "Compared both options"},
+	}
+
+	t.Run("stage1", func(t *testing.T) {
+		data, err := json.Marshal(Stage1Response{Model: "test/model", Response: "Answer", ReasoningDetails: details})
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		var decoded Stage1Response
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(decoded.ReasoningDetails, details) {
+			t.Errorf("ReasoningDetails = %#v, want %#v", decoded.ReasoningDetails, details)
+		}
+	})
+
+	t.Run("stage3", func(t *testing.T) {
+		data, err := json.Marshal(Stage3Response{Model: "test/chairman", Response: "Synthesis", ReasoningDetails: details})
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		var decoded Stage3Response
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(decoded.ReasoningDetails, details) {
+			t.Errorf("ReasoningDetails = %#v, want %#v", decoded.ReasoningDetails, details)
+		}
+	})
+
+	t.Run("omitted when nil", func(t *testing.T) {
+		for _, v := range []interface{}{Stage1Response{Model: "m"}, Stage3Response{Model: "m"}} {
+			data, _ := json.Marshal(v)
+			if strings.Contains(string(data), "reasoning_details") {
+				t.Errorf("Expected reasoning_details to be omitted, got %s", data)
+			}
+		}
+	})
+}
+
 // TestAggregateRankingJSONMarshaling tests JSON marshaling of AggregateRanking
 func TestAggregateRankingJSONMarshaling(t *testing.T) {
 	ranking := AggregateRanking{
@@ -300,6 +347,54 @@ func TestOpenRouterMessageJSONMarshaling(t *testing.T) {
 	}
 }
 
+// TestOpenRouterMessageContentParts tests the multimodal content-parts format
+func TestOpenRouterMessageContentParts(t *testing.T) {
+	message := OpenRouterMessage{
+		Role:    "user",
+		Content: "What does this chart show?",
+		Parts: []ContentPart{
+			{Type: "text", Text: "What does this chart show?"},
+			{Type: "image_url", ImageURL: &ImageURL{URL: "https://example.com/chart.png"}},
+		},
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	want := `{"role":"user","content":[{"type":"text","text":"What does this chart show?"},{"type":"image_url","image_url":{"url":"https://example.com/chart.png"}}]}`
+	if string(data) != want {
+		t.Errorf("JSON = %s\nwant %s", data, want)
+	}
+
+	// Plain messages still send content as a string
+	data, _ = json.Marshal(OpenRouterMessage{Role: "user", Content: "Hi"})
+	if string(data) != `{"role":"user","content":"Hi"}` {
+		t.Errorf("Plain JSON = %s", data)
+	}
+
+	// Both forms decode, with the text parts collected into Content
+	var decoded OpenRouterMessage
+	if err := json.Unmarshal([]byte(want), &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal parts: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, message) {
+		t.Errorf("Decoded = %+v, want %+v", decoded, message)
+	}
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":"Hello"}`), &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal string: %v", err)
+	}
+	if decoded.Content != "Hello" || decoded.Parts != nil {
+		t.Errorf("Decoded = %+v, want plain content", decoded)
+	}
+
+	// Parts survive inside a request payload
+	data, _ = json.Marshal(OpenRouterRequest{Model: "test/model", Messages: []OpenRouterMessage{message}})
+	if !strings.Contains(string(data), `"image_url":{"url":"https://example.com/chart.png"}`) {
+		t.Errorf("Request JSON missing image part: %s", data)
+	}
+}
+
 // TestOpenRouterRequestJSONMarshaling tests JSON marshaling of OpenRouterRequest
 func TestOpenRouterRequestJSONMarshaling(t *testing.T) {
 	request := OpenRouterRequest{
@@ -1,32 +1,262 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
-// QueryModel queries a single model via OpenRouter API with the given timeout.
-// Returns the model's response or an error if the request fails.
-func QueryModel(ctx context.Context, model string, messages []OpenRouterMessage, timeout time.Duration) (*OpenRouterResponse, error) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: timeout,
+// Sentinel errors wrapped by OpenRouterError so callers can use errors.Is
+var (
+	// ErrTimeout indicates the request did not complete within its timeout
+	ErrTimeout = errors.New("request timed out")
+
+	// ErrInvalidResponse indicates the response body could not be parsed
+	ErrInvalidResponse = errors.New("invalid response")
+
+	// ErrNoChoices indicates the response contained no choices
+	ErrNoChoices = errors.New("no choices in response")
+
+	// ErrEmptyContent indicates the model answered with no content, as reasoning
+	// models can when they spend their whole output on reasoning
+	ErrEmptyContent = errors.New("empty content in response")
+
+	// ErrCircuitOpen indicates the model was skipped because it has been failing
+	ErrCircuitOpen = errors.New("circuit open")
+)
+
+// OpenRouterError describes a failed OpenRouter query.
+// StatusCode is 0 when no HTTP response was received (network error or timeout).
+// Retryable reports whether repeating the same request could reasonably succeed.
+type OpenRouterError struct {
+	Model      string
+	StatusCode int
+	Retryable  bool
+	Body       string
+	Err        error
+}
+
+// Error implements the error interface
+func (e *OpenRouterError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
 	}
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
 
-	// Build request payload
-	payload := OpenRouterRequest{
-		Model:    model,
-		Messages: messages,
+// Unwrap returns the underlying error, if any
+func (e *OpenRouterError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthError reports whether OpenRouter rejected the request's credentials
+func (e *OpenRouterError) IsAuthError() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// isRetryableStatus reports whether an HTTP status code indicates a transient failure
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// isTimeoutError reports whether err was caused by a timeout or deadline
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// modelCircuits skips models that keep failing; nil (as in tests) disables it
+var modelCircuits *CircuitBreaker
+
+// Circuit states reported by CircuitBreaker.Snapshot
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreaker tracks consecutive failures per model. After threshold consecutive
+// failures a model's circuit opens and queries to it fail fast with ErrCircuitOpen
+// for the cooldown period. Once the cooldown has passed the circuit is half-open:
+// queries go through again, and the first success closes it while a failure reopens it.
+// A nil *CircuitBreaker allows every query.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	models    map[string]*circuitState
+}
+
+// circuitState is the failure history of one model
+type circuitState struct {
+	failures  int
+	openedAt  time.Time
+	lastError string
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// failures and stays open for cooldown. A threshold of 0 never opens.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		models:    make(map[string]*circuitState),
+	}
+}
+
+// Allow reports whether model may be queried, i.e. its circuit isn't open
+func (b *CircuitBreaker) Allow(model string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stateLocked(b.models[model]) != CircuitOpen
+}
+
+// Record updates model's circuit with the outcome of a query. Cancelled queries
+// say nothing about the model's health and are ignored.
+func (b *CircuitBreaker) Record(model string, err error) {
+	if b == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		delete(b.models, model)
+		return
+	}
+
+	state := b.models[model]
+	if state == nil {
+		state = &circuitState{}
+		b.models[model] = state
+	}
+	state.failures++
+	state.lastError = DescribeQueryError(err)
+	if b.threshold > 0 && state.failures >= b.threshold {
+		if state.openedAt.IsZero() || b.stateLocked(state) == CircuitHalfOpen {
+			slog.Warn("model circuit opened", "model", model, "failures", state.failures, "cooldown", b.cooldown)
+		}
+		state.openedAt = b.now()
+	}
+}
+
+// stateLocked returns the circuit state for a model's history; b.mu must be held
+func (b *CircuitBreaker) stateLocked(state *circuitState) string {
+	switch {
+	case state == nil || state.openedAt.IsZero():
+		return CircuitClosed
+	case b.now().Sub(state.openedAt) < b.cooldown:
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
+
+// Snapshot returns the state of every model with recent failures, sorted by model
+func (b *CircuitBreaker) Snapshot() []CircuitStatus {
+	statuses := []CircuitStatus{}
+	if b == nil {
+		return statuses
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for model, state := range b.models {
+		status := CircuitStatus{
+			Model:               model,
+			State:               b.stateLocked(state),
+			ConsecutiveFailures: state.failures,
+			LastError:           state.lastError,
+		}
+		if status.State == CircuitOpen {
+			openUntil := state.openedAt.Add(b.cooldown)
+			status.OpenUntil = &openUntil
+		}
+		statuses = append(statuses, status)
 	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Model < statuses[j].Model })
+	return statuses
+}
+
+// openRouterClient is shared by all OpenRouter requests so TLS connections are pooled
+// and reused across parallel council queries. It has no overall Client.Timeout;
+// per-request timeouts are applied with context deadlines instead.
+var openRouterClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   20, // every council query goes to the same host
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	},
+}
+
+// QueryOptions controls how a single model query is made
+type QueryOptions struct {
+	// Timeout bounds the whole request, including reading the response body
+	Timeout time.Duration
+
+	// ReasoningEffort, when set, is sent as the request's reasoning effort
+	ReasoningEffort string
+
+	// ResponseFormat, when set, asks the model for structured output
+	ResponseFormat *ResponseFormat
+}
+
+// reasoningConfig returns the reasoning request field for opts, or nil to leave
+// reasoning up to the model
+func (opts QueryOptions) reasoningConfig() *ReasoningConfig {
+	if opts.ReasoningEffort == "" {
+		return nil
+	}
+	return &ReasoningConfig{Effort: opts.ReasoningEffort}
+}
+
+// queryPayload builds the chat completion request for a non-streaming query
+func queryPayload(model string, messages []OpenRouterMessage, opts QueryOptions) OpenRouterRequest {
+	return OpenRouterRequest{
+		Model:          model,
+		Messages:       messages,
+		Reasoning:      opts.reasoningConfig(),
+		ResponseFormat: opts.ResponseFormat,
+	}
+}
+
+// sendOpenRouterRequest posts a chat completion request to OpenRouter.
+// Returns the HTTP response if OpenRouter answered with 200 OK (the caller must close
+// its body), or an *OpenRouterError describing why the request failed.
+func sendOpenRouterRequest(ctx context.Context, payload OpenRouterRequest) (*http.Response, error) {
+	model := payload.Model
 
 	// Marshal payload to JSON
 	payloadBytes, err := json.Marshal(payload)
@@ -45,73 +275,371 @@ func QueryModel(ctx context.Context, model string, messages []OpenRouterMessage,
 	req.Header.Set("Content-Type", "application/json")
 
 	// Make the request
-	resp, err := client.Do(req)
+	resp, err := openRouterClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, requestError(model, err)
 	}
-	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, &OpenRouterError{
+			Model:      model,
+			StatusCode: resp.StatusCode,
+			Retryable:  isRetryableStatus(resp.StatusCode),
+			Body:       string(bodyBytes),
+		}
+	}
+
+	return resp, nil
+}
+
+// requestError wraps a transport-level failure (no HTTP status) as an *OpenRouterError
+func requestError(model string, err error) *OpenRouterError {
+	if isTimeoutError(err) {
+		return &OpenRouterError{
+			Model:     model,
+			Retryable: true,
+			Err:       fmt.Errorf("%w: %v", ErrTimeout, err),
+		}
+	}
+	return &OpenRouterError{
+		Model:     model,
+		Retryable: !errors.Is(err, context.Canceled),
+		Err:       fmt.Errorf("failed to make request: %w", err),
+	}
+}
+
+// QueryModel queries a single model via OpenRouter API with the given timeout.
+// Returns the model's response, or an *OpenRouterError if the request fails.
+func QueryModel(ctx context.Context, model string, messages []OpenRouterMessage, timeout time.Duration) (*OpenRouterResponse, error) {
+	return QueryModelWithOptions(ctx, model, messages, QueryOptions{Timeout: timeout})
+}
+
+// QueryModelWithOptions is QueryModel with full control over the request options.
+// Models whose circuit is open fail immediately with ErrCircuitOpen. With
+// QueryCacheEnabled, an identical earlier query's response is returned from disk
+// without contacting OpenRouter.
+func QueryModelWithOptions(ctx context.Context, model string, messages []OpenRouterMessage, opts QueryOptions) (*OpenRouterResponse, error) {
+	payload := queryPayload(model, messages, opts)
+
+	var cacheKey string
+	if QueryCacheEnabled {
+		key, err := queryCacheKey(payload)
+		if err != nil {
+			return nil, err
+		}
+		if response, ok := loadCachedQuery(key); ok {
+			return response, nil
+		}
+		cacheKey = key
+	}
+
+	if !modelCircuits.Allow(model) {
+		return nil, &OpenRouterError{Model: model, Err: ErrCircuitOpen}
+	}
+	response, err := queryModel(ctx, payload, opts.Timeout)
+	modelCircuits.Record(model, err)
+
+	if err == nil && cacheKey != "" {
+		if err := storeCachedQuery(cacheKey, response); err != nil {
+			slog.WarnContext(ctx, "failed to cache model response", "model", model, "error", err)
+		}
+	}
+	return response, err
+}
+
+// queryModel sends a single non-streaming query
+func queryModel(ctx context.Context, payload OpenRouterRequest, timeout time.Duration) (*OpenRouterResponse, error) {
+	model := payload.Model
+
+	// Bound the request, including reading the body
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
+	resp, err := sendOpenRouterRequest(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
 	// Read response body
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, &OpenRouterError{
+			Model:      model,
+			StatusCode: resp.StatusCode,
+			Retryable:  true,
+			Err:        fmt.Errorf("failed to read response body: %w", err),
+		}
 	}
 
 	// Parse response
 	var apiResponse OpenRouterAPIResponse
 	if err := json.Unmarshal(bodyBytes, &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, &OpenRouterError{
+			Model:      model,
+			StatusCode: resp.StatusCode,
+			Body:       string(bodyBytes),
+			Err:        fmt.Errorf("%w: %v", ErrInvalidResponse, err),
+		}
 	}
 
 	// Extract message from response
 	if len(apiResponse.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
+		return nil, &OpenRouterError{
+			Model:      model,
+			StatusCode: resp.StatusCode,
+			Body:       string(bodyBytes),
+			Err:        ErrNoChoices,
+		}
 	}
 
-	message := apiResponse.Choices[0].Message
-	return &OpenRouterResponse{
-		Content:          message.Content,
-		ReasoningDetails: message.ReasoningDetails,
-	}, nil
+	choice := apiResponse.Choices[0]
+	return requireContent(payload.Model, resp.StatusCode, &OpenRouterResponse{
+		Content:          choice.Message.Content,
+		ReasoningDetails: choice.Message.ReasoningDetails,
+		FinishReason:     choice.FinishReason,
+	})
+}
+
+// QueryModelStream queries a single model with streaming enabled.
+// OpenRouter responds with Server-Sent Events; each "data:" chunk carries a token delta
+// which is passed to onToken as it arrives. The full content is accumulated and returned
+// once the stream ends with "data: [DONE]". Returns an *OpenRouterError if the request
+// fails or the stream is malformed. Models whose circuit is open fail immediately
+// with ErrCircuitOpen.
+func QueryModelStream(ctx context.Context, model string, messages []OpenRouterMessage, opts QueryOptions, onToken func(string)) (*OpenRouterResponse, error) {
+	if !modelCircuits.Allow(model) {
+		return nil, &OpenRouterError{Model: model, Err: ErrCircuitOpen}
+	}
+	response, err := queryModelStream(ctx, model, messages, opts, onToken)
+	modelCircuits.Record(model, err)
+	return response, err
+}
+
+// queryModelStream sends a single streaming query
+func queryModelStream(ctx context.Context, model string, messages []OpenRouterMessage, opts QueryOptions, onToken func(string)) (*OpenRouterResponse, error) {
+	// Bound the whole stream, not just the time to the first byte
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	payload := OpenRouterRequest{
+		Model:          model,
+		Messages:       messages,
+		Stream:         true,
+		Reasoning:      opts.reasoningConfig(),
+		ResponseFormat: opts.ResponseFormat,
+	}
+
+	resp, err := sendOpenRouterRequest(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var reasoningDetails interface{}
+	var finishReason string
+	receivedChoice := false
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Skip blank separators and SSE comments (e.g. ": OPENROUTER PROCESSING")
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk OpenRouterStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, &OpenRouterError{
+				Model:      model,
+				StatusCode: resp.StatusCode,
+				Body:       data,
+				Err:        fmt.Errorf("%w: %v", ErrInvalidResponse, err),
+			}
+		}
+
+		// OpenRouter reports failures after the stream has started as an error chunk
+		if chunk.Error != nil {
+			return nil, &OpenRouterError{
+				Model:      model,
+				StatusCode: chunk.Error.Code,
+				Retryable:  isRetryableStatus(chunk.Error.Code),
+				Body:       chunk.Error.Message,
+			}
+		}
+
+		for _, choice := range chunk.Choices {
+			receivedChoice = true
+			if choice.Delta.ReasoningDetails != nil {
+				reasoningDetails = choice.Delta.ReasoningDetails
+			}
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				if onToken != nil {
+					onToken(choice.Delta.Content)
+				}
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, requestError(model, err)
+	}
+
+	if !receivedChoice {
+		return nil, &OpenRouterError{
+			Model:      model,
+			StatusCode: resp.StatusCode,
+			Err:        ErrNoChoices,
+		}
+	}
+
+	return requireContent(model, resp.StatusCode, &OpenRouterResponse{
+		Content:          content.String(),
+		ReasoningDetails: reasoningDetails,
+		FinishReason:     finishReason,
+	})
+}
+
+// requireContent fails a response whose content is empty or only whitespace, so it
+// isn't counted as an answer. With ReasoningContentFallback, the model's reasoning
+// text stands in for the missing content instead, if it returned any.
+func requireContent(model string, statusCode int, response *OpenRouterResponse) (*OpenRouterResponse, error) {
+	if strings.TrimSpace(response.Content) != "" {
+		return response, nil
+	}
+	if ReasoningContentFallback {
+		if text := reasoningText(response.ReasoningDetails); text != "" {
+			response.Content = text
+			return response, nil
+		}
+	}
+	return nil, &OpenRouterError{Model: model, StatusCode: statusCode, Err: ErrEmptyContent}
+}
+
+// reasoningText joins the text of the reasoning.text and reasoning.summary entries
+// in an OpenRouter reasoning_details array, skipping encrypted or unknown entries.
+// Returns "" if there is no readable reasoning.
+func reasoningText(details interface{}) string {
+	entries, _ := details.([]interface{})
+	var parts []string
+	for _, entry := range entries {
+		fields, _ := entry.(map[string]interface{})
+		var text string
+		switch fields["type"] {
+		case "reasoning.text":
+			text, _ = fields["text"].(string)
+		case "reasoning.summary":
+			text, _ = fields["summary"].(string)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
 }
 
 // QueryModelsParallel queries multiple models in parallel using goroutines.
 // Uses errgroup for parallel execution with graceful degradation - failed models
 // return nil in the results map while successful models return their responses.
-// Returns a map of model names to responses, or an error if all models fail.
-func QueryModelsParallel(ctx context.Context, models []string, messages []OpenRouterMessage) (map[string]*OpenRouterResponse, error) {
+// Each model query is bounded by timeout, and by any deadline already set on ctx.
+// Returns a map of model names to responses, a map of failed model names to the
+// error that caused the failure, or an error if the parallel execution fails.
+func QueryModelsParallel(ctx context.Context, models []string, messages []OpenRouterMessage, timeout time.Duration) (map[string]*OpenRouterResponse, map[string]error, error) {
+	return QueryModelsParallelWithOptions(ctx, models, messages, QueryOptions{Timeout: timeout})
+}
+
+// QueryModelsParallelWithOptions is QueryModelsParallel with the same options
+// applied to every model query.
+func QueryModelsParallelWithOptions(ctx context.Context, models []string, messages []OpenRouterMessage, opts QueryOptions) (map[string]*OpenRouterResponse, map[string]error, error) {
+	return QueryModelsParallelStream(ctx, models, messages, opts, nil)
+}
+
+// launchDelay staggers the start of query index out of count across
+// ModelLaunchJitter: each query gets its own equal slot of the window and starts at
+// a random point within it. Returns 0 when jitter is disabled or there is only one
+// query.
+func launchDelay(index, count int) time.Duration {
+	if ModelLaunchJitter <= 0 || count < 2 {
+		return 0
+	}
+	slot := ModelLaunchJitter / time.Duration(count)
+	delay := slot * time.Duration(index)
+	if slot > 0 {
+		delay += rand.N(slot)
+	}
+	return delay
+}
+
+// QueryModelsParallelStream is QueryModelsParallelWithOptions that also reports each
+// model's result to onResult as soon as it arrives (response is nil when err is set).
+// Calls to onResult are serialized, so it may write to a shared stream.
+func QueryModelsParallelStream(ctx context.Context, models []string, messages []OpenRouterMessage, opts QueryOptions, onResult func(model string, response *OpenRouterResponse, err error)) (map[string]*OpenRouterResponse, map[string]error, error) {
 	// Create errgroup for parallel execution
 	g, ctx := errgroup.WithContext(ctx)
 
-	// Results map and mutex for thread-safe writes
+	// Results maps and mutex for thread-safe writes
 	results := make(map[string]*OpenRouterResponse)
+	failures := make(map[string]error)
 	var mu sync.Mutex
 
 	// Launch goroutine for each model
-	for _, model := range models {
+	for i, model := range models {
 		model := model // Capture loop variable
 		g.Go(func() error {
-			// Query the model with 120 second timeout
-			response, err := QueryModel(ctx, model, messages, 120*time.Second)
+			// Stagger launches so providers don't see one burst; a cancelled
+			// context skips the wait and fails the query below
+			if delay := launchDelay(i, len(models)); delay > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(delay):
+				}
+			}
+
+			// Query the model with the per-model timeout
+			start := time.Now()
+			response, err := QueryModelWithOptions(ctx, model, messages, opts)
 
 			// Graceful degradation: log error but don't fail entire request
 			if err != nil {
-				log.Printf("Error querying model %s: %v", model, err)
+				slog.WarnContext(ctx, "model query failed", "model", model, "error", err)
 				mu.Lock()
 				results[model] = nil
+				failures[model] = err
+				if onResult != nil {
+					onResult(model, nil, err)
+				}
 				mu.Unlock()
 				return nil // Don't propagate error, continue with other models
 			}
 
 			// Store successful response
+			response.Latency = time.Since(start)
 			mu.Lock()
 			results[model] = response
+			if onResult != nil {
+				onResult(model, response, nil)
+			}
 			mu.Unlock()
 			return nil
 		})
@@ -119,8 +647,150 @@ func QueryModelsParallel(ctx context.Context, models []string, messages []OpenRo
 
 	// Wait for all goroutines to complete
 	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return results, failures, nil
+}
+
+// DescribeQueryError returns a short, human-readable reason for a failed model query,
+// suitable for showing to users (e.g. "timeout", "http status 401 (Unauthorized)").
+func DescribeQueryError(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.Is(err, ErrInvalidResponse):
+		return "parse error: could not decode model response"
+	case errors.Is(err, ErrNoChoices):
+		return "empty response: no choices returned"
+	case errors.Is(err, ErrEmptyContent):
+		return "empty response: no content returned"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit open: skipped after repeated failures"
+	}
+
+	var orErr *OpenRouterError
+	if errors.As(err, &orErr) && orErr.StatusCode != 0 && orErr.Err == nil {
+		return fmt.Sprintf("http status %d (%s)", orErr.StatusCode, http.StatusText(orErr.StatusCode))
+	}
+
+	return fmt.Sprintf("request failed: %v", err)
+}
+
+// loadModelCatalog returns the model catalog from modelCatalogCache, fetching and
+// caching it on a miss or when refresh is set.
+func loadModelCatalog(ctx context.Context, refresh bool) ([]CatalogModel, error) {
+	if !refresh {
+		if catalog, ok := modelCatalogCache.Get(); ok {
+			return catalog, nil
+		}
+	}
+
+	catalog, err := FetchModelCatalog(ctx)
+	if err != nil {
 		return nil, err
 	}
+	modelCatalogCache.Set(catalog)
+	return catalog, nil
+}
+
+// configuredModels returns every model ID the council is configured to call:
+// council, ranker, chairman (with fallbacks) and title models, followed by those of
+// each CouncilModes preset, without duplicates.
+func configuredModels() []string {
+	roster := councilConfig(context.Background())
+	candidates := slices.Concat(roster.CouncilModels, roster.Rankers(), []string{roster.ChairmanModel}, roster.ChairmanFallbacks, []string{TitleModel})
+	for _, name := range CouncilModeNames() {
+		mode := CouncilModes[name]
+		candidates = slices.Concat(candidates, mode.CouncilModels, mode.Rankers(), []string{mode.ChairmanModel}, mode.ChairmanFallbacks)
+	}
+
+	var models []string
+	for _, model := range candidates {
+		if model != "" && !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// UnknownModels returns the configured model IDs that aren't listed in catalog.
+func UnknownModels(catalog []CatalogModel) []string {
+	return unknownModelIDs(catalog, configuredModels())
+}
+
+// unknownModelIDs returns the models that aren't listed in catalog, ignoring empty IDs
+func unknownModelIDs(catalog []CatalogModel, models []string) []string {
+	known := make(map[string]bool, len(catalog))
+	for _, model := range catalog {
+		known[model.ID] = true
+	}
+
+	var unknown []string
+	for _, model := range models {
+		if model != "" && !known[model] && !slices.Contains(unknown, model) {
+			unknown = append(unknown, model)
+		}
+	}
+	return unknown
+}
+
+// ValidateConfiguredModels checks the configured model IDs against OpenRouter's
+// model catalog so typos show up at startup rather than as failed requests. Unknown
+// IDs are logged as warnings, or returned as an error with StrictModelValidation.
+// Validation is skipped with a warning if the catalog can't be fetched.
+func ValidateConfiguredModels(ctx context.Context) error {
+	catalog, err := loadModelCatalog(ctx, false)
+	if err != nil {
+		slog.WarnContext(ctx, "skipping model validation, model catalog unavailable", "error", err)
+		return nil
+	}
+
+	unknown := UnknownModels(catalog)
+	if len(unknown) == 0 {
+		return nil
+	}
+	if StrictModelValidation {
+		return fmt.Errorf("models not in OpenRouter's catalog: %s", strings.Join(unknown, ", "))
+	}
+	for _, model := range unknown {
+		slog.WarnContext(ctx, "configured model not in OpenRouter's catalog", "model", model)
+	}
+	return nil
+}
+
+// FetchModelCatalog fetches the list of available models from OpenRouter.
+// Returns the catalog entries or an error if the request or parsing fails.
+func FetchModelCatalog(ctx context.Context) ([]CatalogModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, ModelCatalogTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", OpenRouterModelsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+OpenRouterAPIKey)
+
+	resp, err := openRouterClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch model catalog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model catalog returned status %d", resp.StatusCode)
+	}
+
+	var catalog struct {
+		Data []CatalogModel `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse model catalog: %w", err)
+	}
 
-	return results, nil
+	return catalog.Data, nil
 }
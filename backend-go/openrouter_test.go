@@ -1,9 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -43,6 +53,28 @@ func TestQueryModel(t *testing.T) {
 		}
 	})
 
+	t.Run("truncated response", func(t *testing.T) {
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"Go is a"},"finish_reason":"length"}]}`)
+		})
+		defer mockServer.Close()
+
+		OpenRouterAPIURL = mockServer.URL
+		OpenRouterAPIKey = "test-key"
+
+		response, err := QueryModel(context.Background(), "test/model", []OpenRouterMessage{{Role: "user", Content: "Test"}}, 10*time.Second)
+		if err != nil {
+			t.Fatalf("QueryModel failed: %v", err)
+		}
+		if response.FinishReason != FinishReasonLength {
+			t.Errorf("FinishReason = %q, want %q", response.FinishReason, FinishReasonLength)
+		}
+		if !response.Truncated() {
+			t.Error("Expected response to be reported as truncated")
+		}
+	})
+
 	t.Run("API error response", func(t *testing.T) {
 		mockServer := MockOpenRouterServer(t, CreateMockOpenRouterErrorHandler(500, "Internal server error"))
 		defer mockServer.Close()
@@ -118,6 +150,7 @@ func TestQueryModel(t *testing.T) {
 						Content          string      `json:"content"`
 						ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
 					} `json:"message"`
+					FinishReason string `json:"finish_reason"`
 				}{},
 			}
 			w.Header().Set("Content-Type", "application/json")
@@ -143,6 +176,343 @@ func TestQueryModel(t *testing.T) {
 	})
 }
 
+// TestQueryModelErrorTypes tests that QueryModel returns structured errors
+func TestQueryModelErrorTypes(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+	}()
+
+	emptyChoicesHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"choices": []}`))
+	}
+
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		timeout       time.Duration
+		wantStatus    int
+		wantRetryable bool
+		wantBody      string
+		wantSentinel  error
+		wantAuth      bool
+	}{
+		{
+			name:          "server error",
+			handler:       CreateMockOpenRouterErrorHandler(500, "Internal server error"),
+			timeout:       10 * time.Second,
+			wantStatus:    500,
+			wantRetryable: true,
+			wantBody:      "Internal server error",
+		},
+		{
+			name:          "rate limited",
+			handler:       CreateMockOpenRouterErrorHandler(429, "Too many requests"),
+			timeout:       10 * time.Second,
+			wantStatus:    429,
+			wantRetryable: true,
+			wantBody:      "Too many requests",
+		},
+		{
+			name:          "unauthorized",
+			handler:       CreateMockOpenRouterErrorHandler(401, "Invalid API key"),
+			timeout:       10 * time.Second,
+			wantStatus:    401,
+			wantRetryable: false,
+			wantBody:      "Invalid API key",
+			wantAuth:      true,
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(2 * time.Second)
+				w.WriteHeader(http.StatusOK)
+			},
+			timeout:       100 * time.Millisecond,
+			wantStatus:    0,
+			wantRetryable: true,
+			wantSentinel:  ErrTimeout,
+		},
+		{
+			name: "invalid JSON response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("{ invalid json }"))
+			},
+			timeout:       10 * time.Second,
+			wantStatus:    200,
+			wantRetryable: false,
+			wantBody:      "{ invalid json }",
+			wantSentinel:  ErrInvalidResponse,
+		},
+		{
+			name:          "empty choices in response",
+			handler:       emptyChoicesHandler,
+			timeout:       10 * time.Second,
+			wantStatus:    200,
+			wantRetryable: false,
+			wantBody:      `{"choices": []}`,
+			wantSentinel:  ErrNoChoices,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := MockOpenRouterServer(t, tt.handler)
+			defer mockServer.Close()
+
+			OpenRouterAPIURL = mockServer.URL
+			OpenRouterAPIKey = "test-key"
+
+			messages := []OpenRouterMessage{
+				{Role: "user", Content: "Test"},
+			}
+
+			_, err := QueryModel(context.Background(), "test/model", messages, tt.timeout)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			var orErr *OpenRouterError
+			if !errors.As(err, &orErr) {
+				t.Fatalf("Expected *OpenRouterError, got %T: %v", err, err)
+			}
+
+			if orErr.Model != "test/model" {
+				t.Errorf("Model = %q, want 'test/model'", orErr.Model)
+			}
+			if orErr.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", orErr.StatusCode, tt.wantStatus)
+			}
+			if orErr.Retryable != tt.wantRetryable {
+				t.Errorf("Retryable = %v, want %v", orErr.Retryable, tt.wantRetryable)
+			}
+			if tt.wantBody != "" && orErr.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", orErr.Body, tt.wantBody)
+			}
+			if tt.wantSentinel != nil && !errors.Is(err, tt.wantSentinel) {
+				t.Errorf("Expected errors.Is(err, %v) to be true, got %v", tt.wantSentinel, err)
+			}
+			if orErr.IsAuthError() != tt.wantAuth {
+				t.Errorf("IsAuthError = %v, want %v", orErr.IsAuthError(), tt.wantAuth)
+			}
+		})
+	}
+}
+
+// TestQueryModelEmptyContent tests that responses without content fail unless the
+// reasoning fallback is enabled and the model returned reasoning text
+func TestQueryModelEmptyContent(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldFallback := ReasoningContentFallback
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		ReasoningContentFallback = oldFallback
+	}()
+
+	reasoning := `[{"type":"reasoning.encrypted","data":"abc"},{"type":"reasoning.text","text":"Go is compiled."},{"type":"reasoning.summary","summary":"It is also fast."}]`
+	var content, details string
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q,\"reasoning_details\":%s}}]}\n\n", content, details)
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%q,"reasoning_details":%s}}]}`, content, details)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	messages := []OpenRouterMessage{{Role: "user", Content: "Test"}}
+	queries := map[string]func() (*OpenRouterResponse, error){
+		"QueryModel": func() (*OpenRouterResponse, error) {
+			return QueryModel(context.Background(), "test/model", messages, 10*time.Second)
+		},
+		"QueryModelStream": func() (*OpenRouterResponse, error) {
+			return QueryModelStream(context.Background(), "test/model", messages, QueryOptions{Timeout: 10 * time.Second}, nil)
+		},
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		details     string
+		fallback    bool
+		wantContent string
+	}{
+		{name: "empty content fails", content: "", details: reasoning},
+		{name: "whitespace content fails", content: " \n", details: "null"},
+		{name: "fallback uses reasoning text", content: "", details: reasoning, fallback: true, wantContent: "Go is compiled.\n\nIt is also fast."},
+		{name: "fallback without reasoning text fails", content: "", details: `[{"type":"reasoning.encrypted","data":"abc"}]`, fallback: true},
+		{name: "content is kept over reasoning", content: "Go is great.", details: reasoning, fallback: true, wantContent: "Go is great."},
+	}
+
+	for _, tt := range tests {
+		for name, query := range queries {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				content, details, ReasoningContentFallback = tt.content, tt.details, tt.fallback
+				response, err := query()
+				if tt.wantContent == "" {
+					if !errors.Is(err, ErrEmptyContent) {
+						t.Fatalf("Expected ErrEmptyContent, got response %v, error %v", response, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Query failed: %v", err)
+				}
+				if response.Content != tt.wantContent {
+					t.Errorf("Content = %q, want %q", response.Content, tt.wantContent)
+				}
+			})
+		}
+	}
+}
+
+// TestQueryModelStream tests streaming queries with SSE passthrough
+func TestQueryModelStream(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+	}()
+
+	messages := []OpenRouterMessage{
+		{Role: "user", Content: "Test"},
+	}
+	opts := QueryOptions{Timeout: 10 * time.Second}
+
+	t.Run("tokens delivered and accumulated", func(t *testing.T) {
+		var gotStream bool
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			var req OpenRouterRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			gotStream = req.Stream
+			WriteMockOpenRouterStream(w, []string{"Go ", "is ", "fast", ""})
+		})
+		defer mockServer.Close()
+
+		OpenRouterAPIURL = mockServer.URL
+		OpenRouterAPIKey = "test-key"
+
+		var tokens []string
+		response, err := QueryModelStream(context.Background(), "test/model", messages, opts, func(token string) {
+			tokens = append(tokens, token)
+		})
+		if err != nil {
+			t.Fatalf("QueryModelStream failed: %v", err)
+		}
+
+		if !gotStream {
+			t.Error(`Expected request payload to set "stream": true`)
+		}
+		expected := []string{"Go ", "is ", "fast"}
+		if !reflect.DeepEqual(tokens, expected) {
+			t.Errorf("Tokens = %v, want %v", tokens, expected)
+		}
+		if response.Content != "Go is fast" {
+			t.Errorf("Content = %q, want 'Go is fast'", response.Content)
+		}
+	})
+
+	t.Run("finish reason from final chunk", func(t *testing.T) {
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Go is\"},\"finish_reason\":null}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" a\"},\"finish_reason\":\"length\"}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		})
+		defer mockServer.Close()
+
+		OpenRouterAPIURL = mockServer.URL
+		OpenRouterAPIKey = "test-key"
+
+		response, err := QueryModelStream(context.Background(), "test/model", messages, opts, nil)
+		if err != nil {
+			t.Fatalf("QueryModelStream failed: %v", err)
+		}
+		if response.Content != "Go is a" {
+			t.Errorf("Content = %q, want 'Go is a'", response.Content)
+		}
+		if !response.Truncated() {
+			t.Errorf("FinishReason = %q, want %q", response.FinishReason, FinishReasonLength)
+		}
+	})
+
+	t.Run("API error response", func(t *testing.T) {
+		mockServer := MockOpenRouterServer(t, CreateMockOpenRouterErrorHandler(502, "Bad gateway"))
+		defer mockServer.Close()
+
+		OpenRouterAPIURL = mockServer.URL
+
+		_, err := QueryModelStream(context.Background(), "test/model", messages, opts, nil)
+		var orErr *OpenRouterError
+		if !errors.As(err, &orErr) || orErr.StatusCode != 502 || !orErr.Retryable {
+			t.Errorf("Expected retryable OpenRouterError with status 502, got %v", err)
+		}
+	})
+
+	t.Run("malformed chunk", func(t *testing.T) {
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("data: {not json}\n\n"))
+		})
+		defer mockServer.Close()
+
+		OpenRouterAPIURL = mockServer.URL
+
+		_, err := QueryModelStream(context.Background(), "test/model", messages, opts, nil)
+		if !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("Expected ErrInvalidResponse, got %v", err)
+		}
+	})
+
+	t.Run("mid-stream error chunk", func(t *testing.T) {
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`data: {"choices":[{"delta":{"content":"partial"}}]}` + "\n\n"))
+			w.Write([]byte(`data: {"error":{"code":503,"message":"provider overloaded"}}` + "\n\n"))
+		})
+		defer mockServer.Close()
+
+		OpenRouterAPIURL = mockServer.URL
+
+		_, err := QueryModelStream(context.Background(), "test/model", messages, opts, nil)
+		var orErr *OpenRouterError
+		if !errors.As(err, &orErr) || orErr.StatusCode != 503 || orErr.Body != "provider overloaded" {
+			t.Errorf("Expected OpenRouterError from error chunk, got %v", err)
+		}
+	})
+
+	t.Run("stream with no choices", func(t *testing.T) {
+		mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("data: [DONE]\n\n"))
+		})
+		defer mockServer.Close()
+
+		OpenRouterAPIURL = mockServer.URL
+
+		_, err := QueryModelStream(context.Background(), "test/model", messages, opts, nil)
+		if !errors.Is(err, ErrNoChoices) {
+			t.Errorf("Expected ErrNoChoices, got %v", err)
+		}
+	})
+}
+
 // TestQueryModelsParallel tests parallel model querying
 func TestQueryModelsParallel(t *testing.T) {
 	// Save original config
@@ -166,7 +536,7 @@ func TestQueryModelsParallel(t *testing.T) {
 		}
 
 		ctx := context.Background()
-		results, err := QueryModelsParallel(ctx, models, messages)
+		results, _, err := QueryModelsParallel(ctx, models, messages, 10*time.Second)
 
 		if err != nil {
 			t.Fatalf("QueryModelsParallel failed: %v", err)
@@ -202,6 +572,7 @@ func TestQueryModelsParallel(t *testing.T) {
 						Content          string      `json:"content"`
 						ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
 					} `json:"message"`
+					FinishReason string `json:"finish_reason"`
 				}{
 					{
 						Message: struct {
@@ -230,7 +601,7 @@ func TestQueryModelsParallel(t *testing.T) {
 		}
 
 		ctx := context.Background()
-		results, err := QueryModelsParallel(ctx, models, messages)
+		results, failures, err := QueryModelsParallel(ctx, models, messages, 10*time.Second)
 
 		// Should not error - graceful degradation
 		if err != nil {
@@ -246,6 +617,15 @@ func TestQueryModelsParallel(t *testing.T) {
 		if results["model/fail"] != nil {
 			t.Error("Failed model should have nil response")
 		}
+
+		// Failure should be captured alongside the nil result
+		if _, ok := failures["model/success"]; ok {
+			t.Error("Successful model should not have a failure")
+		}
+		var orErr *OpenRouterError
+		if !errors.As(failures["model/fail"], &orErr) || orErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("Failed model error = %v, want OpenRouterError with status 500", failures["model/fail"])
+		}
 	})
 
 	t.Run("empty model list", func(t *testing.T) {
@@ -261,7 +641,7 @@ func TestQueryModelsParallel(t *testing.T) {
 		}
 
 		ctx := context.Background()
-		results, err := QueryModelsParallel(ctx, models, messages)
+		results, _, err := QueryModelsParallel(ctx, models, messages, 10*time.Second)
 
 		if err != nil {
 			t.Fatalf("Should handle empty model list: %v", err)
@@ -291,7 +671,7 @@ func TestQueryModelsParallel(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 		defer cancel()
 
-		results, err := QueryModelsParallel(ctx, models, messages)
+		results, _, err := QueryModelsParallel(ctx, models, messages, 10*time.Second)
 
 		// Should handle timeout gracefully
 		if err != nil {
@@ -304,6 +684,494 @@ func TestQueryModelsParallel(t *testing.T) {
 	})
 }
 
+// TestQueryModelsParallelTimeout tests that the configured timeout is applied per model
+func TestQueryModelsParallelTimeout(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+	}()
+
+	// Every model takes longer than the configured timeout
+	slowHandler := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	mockServer := MockOpenRouterServer(t, slowHandler)
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	models := []string{"model/a", "model/b", "model/c"}
+	messages := []OpenRouterMessage{
+		{Role: "user", Content: "Test"},
+	}
+
+	start := time.Now()
+	results, _, err := QueryModelsParallel(context.Background(), models, messages, 200*time.Millisecond)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("QueryModelsParallel should not error: %v", err)
+	}
+	for _, model := range models {
+		if results[model] != nil {
+			t.Errorf("Model %s should have timed out", model)
+		}
+	}
+
+	// Models run in parallel, so the whole call should be bounded by a single timeout
+	if elapsed > time.Second {
+		t.Errorf("Elapsed = %v, expected per-model timeout of 200ms to apply", elapsed)
+	}
+}
+
+// TestQueryModelContextTimeout tests that the per-request timeout is enforced
+// through the context, since the shared client has no timeout of its own
+func TestQueryModelContextTimeout(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+	}()
+
+	if openRouterClient.Timeout != 0 {
+		t.Fatalf("openRouterClient.Timeout = %v, want 0 so callers control deadlines", openRouterClient.Timeout)
+	}
+
+	slowHandler := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	mockServer := MockOpenRouterServer(t, slowHandler)
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	messages := []OpenRouterMessage{
+		{Role: "user", Content: "Test"},
+	}
+
+	t.Run("query", func(t *testing.T) {
+		start := time.Now()
+		_, err := QueryModel(context.Background(), "test/model", messages, 100*time.Millisecond)
+		if !errors.Is(err, ErrTimeout) {
+			t.Fatalf("QueryModel error = %v, want ErrTimeout", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Elapsed = %v, expected the 100ms timeout to cut the request short", elapsed)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		start := time.Now()
+		_, err := QueryModelStream(context.Background(), "test/model", messages, QueryOptions{Timeout: 100 * time.Millisecond}, nil)
+		if !errors.Is(err, ErrTimeout) {
+			t.Fatalf("QueryModelStream error = %v, want ErrTimeout", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Elapsed = %v, expected the 100ms timeout to cut the request short", elapsed)
+		}
+	})
+}
+
+// TestQueryModelReusesConnections tests that sequential queries share a pooled connection
+func TestQueryModelReusesConnections(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+	}()
+
+	var newConns atomic.Int32
+	mockServer := httptest.NewUnstartedServer(CreateMockOpenRouterHandler(t, "ok"))
+	mockServer.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	mockServer.Start()
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	messages := []OpenRouterMessage{
+		{Role: "user", Content: "Test"},
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := QueryModel(context.Background(), "test/model", messages, 5*time.Second); err != nil {
+			t.Fatalf("QueryModel %d failed: %v", i, err)
+		}
+	}
+
+	if got := newConns.Load(); got != 1 {
+		t.Errorf("Opened %d connections for 5 sequential queries, want 1", got)
+	}
+}
+
+// TestQueryModelsParallelLaunchJitter tests that ModelLaunchJitter spreads out the
+// start of parallel queries, and that they all start together without it
+func TestQueryModelsParallelLaunchJitter(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldJitter := ModelLaunchJitter
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		ModelLaunchJitter = oldJitter
+	}()
+
+	var mu sync.Mutex
+	launches := make(map[string]time.Time)
+	respond := CreateMockOpenRouterHandler(t, "ok")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req OpenRouterRequest
+		json.Unmarshal(body, &req)
+		mu.Lock()
+		launches[req.Model] = time.Now()
+		mu.Unlock()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		respond(w, r)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	models := []string{"model/a", "model/b", "model/c", "model/d"}
+	messages := []OpenRouterMessage{{Role: "user", Content: "Test"}}
+	spread := func(t *testing.T) time.Duration {
+		t.Helper()
+		clear(launches)
+		results, _, err := QueryModelsParallel(context.Background(), models, messages, 5*time.Second)
+		if err != nil || len(results) != len(models) {
+			t.Fatalf("QueryModelsParallel returned %d results, err %v", len(results), err)
+		}
+		var first, last time.Time
+		for _, launched := range launches {
+			if first.IsZero() || launched.Before(first) {
+				first = launched
+			}
+			if launched.After(last) {
+				last = launched
+			}
+		}
+		return last.Sub(first)
+	}
+
+	t.Run("jitter spreads launches", func(t *testing.T) {
+		ModelLaunchJitter = 400 * time.Millisecond
+		// Each model starts in its own 100ms slot, so the last is at least 200ms
+		// after the first
+		if got := spread(t); got < 200*time.Millisecond {
+			t.Errorf("Launches spread over %v, want at least 200ms", got)
+		}
+		for i, model := range models[1:] {
+			if !launches[model].After(launches[models[i]]) {
+				t.Errorf("%s launched before %s", model, models[i])
+			}
+		}
+	})
+
+	t.Run("no jitter by default", func(t *testing.T) {
+		ModelLaunchJitter = 0
+		if got := spread(t); got > 100*time.Millisecond {
+			t.Errorf("Launches spread over %v without jitter, want them together", got)
+		}
+	})
+
+	t.Run("launchDelay slots", func(t *testing.T) {
+		ModelLaunchJitter = 400 * time.Millisecond
+		for i := range 4 {
+			if d := launchDelay(i, 4); d < time.Duration(i)*100*time.Millisecond || d >= time.Duration(i+1)*100*time.Millisecond {
+				t.Errorf("launchDelay(%d, 4) = %v, want within slot [%dms, %dms)", i, d, i*100, (i+1)*100)
+			}
+		}
+		if d := launchDelay(0, 1); d != 0 {
+			t.Errorf("launchDelay for a single query = %v, want 0", d)
+		}
+	})
+}
+
+// BenchmarkQueryModelsParallel measures a council-sized fan-out against a local mock server
+func BenchmarkQueryModelsParallel(b *testing.B) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+	}()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"choices": []map[string]interface{}{
+			{"message": map[string]string{"content": "Benchmark response"}},
+		},
+	})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+
+	models := []string{"model/a", "model/b", "model/c", "model/d"}
+	messages := []OpenRouterMessage{
+		{Role: "user", Content: "Benchmark"},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := QueryModelsParallel(context.Background(), models, messages, 5*time.Second); err != nil {
+			b.Fatalf("QueryModelsParallel failed: %v", err)
+		}
+	}
+}
+
+// TestCircuitBreaker tests opening a model's circuit after consecutive failures
+// and recovering once the cooldown has passed
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(3, time.Minute)
+	breaker.now = func() time.Time { return now }
+	failure := &OpenRouterError{Model: "model/a", StatusCode: http.StatusUnauthorized}
+
+	state := func() string {
+		for _, status := range breaker.Snapshot() {
+			if status.Model == "model/a" {
+				return status.State
+			}
+		}
+		return CircuitClosed
+	}
+
+	// Failures below the threshold, and cancellations, leave the circuit closed
+	breaker.Record("model/a", failure)
+	breaker.Record("model/a", failure)
+	breaker.Record("model/a", fmt.Errorf("query: %w", context.Canceled))
+	if !breaker.Allow("model/a") || state() != CircuitClosed {
+		t.Fatalf("Expected a closed circuit after 2 failures, got %s", state())
+	}
+
+	// The third consecutive failure opens it
+	breaker.Record("model/a", failure)
+	if breaker.Allow("model/a") || state() != CircuitOpen {
+		t.Fatalf("Expected an open circuit after 3 failures, got %s", state())
+	}
+	if !breaker.Allow("model/b") {
+		t.Error("Other models should be unaffected")
+	}
+	status := breaker.Snapshot()[0]
+	if status.ConsecutiveFailures != 3 || status.OpenUntil == nil || !status.OpenUntil.Equal(now.Add(time.Minute)) {
+		t.Errorf("Unexpected open status: %+v", status)
+	}
+	if status.LastError != "http status 401 (Unauthorized)" {
+		t.Errorf("LastError = %q", status.LastError)
+	}
+
+	// After the cooldown a single failure reopens it
+	now = now.Add(time.Minute)
+	if !breaker.Allow("model/a") || state() != CircuitHalfOpen {
+		t.Fatalf("Expected a half-open circuit after the cooldown, got %s", state())
+	}
+	breaker.Record("model/a", failure)
+	if breaker.Allow("model/a") {
+		t.Fatal("Expected a failure while half-open to reopen the circuit")
+	}
+
+	// A success after the next cooldown closes it again
+	now = now.Add(time.Minute)
+	breaker.Record("model/a", nil)
+	if !breaker.Allow("model/a") || len(breaker.Snapshot()) != 0 {
+		t.Errorf("Expected the circuit to close after a success, got %+v", breaker.Snapshot())
+	}
+
+	t.Run("threshold 0 never opens", func(t *testing.T) {
+		disabled := NewCircuitBreaker(0, time.Minute)
+		for i := 0; i < 10; i++ {
+			disabled.Record("model/a", failure)
+		}
+		if !disabled.Allow("model/a") {
+			t.Error("Expected a zero threshold to disable the breaker")
+		}
+	})
+
+	t.Run("nil breaker", func(t *testing.T) {
+		var nilBreaker *CircuitBreaker
+		nilBreaker.Record("model/a", failure)
+		if !nilBreaker.Allow("model/a") || len(nilBreaker.Snapshot()) != 0 {
+			t.Error("Expected a nil breaker to allow everything")
+		}
+	})
+}
+
+// TestQueryModelCircuitBreaker tests that a failing model is skipped without a request
+// while its circuit is open, reported as such, and queried again after the cooldown
+func TestQueryModelCircuitBreaker(t *testing.T) {
+	oldAPIURL := OpenRouterAPIURL
+	oldAPIKey := OpenRouterAPIKey
+	oldModels := CouncilModels
+	oldCircuits := modelCircuits
+	defer func() {
+		OpenRouterAPIURL = oldAPIURL
+		OpenRouterAPIKey = oldAPIKey
+		CouncilModels = oldModels
+		modelCircuits = oldCircuits
+	}()
+
+	var requests atomic.Int32
+	var healthy atomic.Bool
+	succeed := CreateMockOpenRouterHandler(t, "Recovered")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !healthy.Load() {
+			http.Error(w, "invalid key", http.StatusUnauthorized)
+			return
+		}
+		succeed(w, r)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	CouncilModels = []string{"model/a"}
+
+	now := time.Now()
+	modelCircuits = NewCircuitBreaker(2, time.Minute)
+	modelCircuits.now = func() time.Time { return now }
+
+	messages := []OpenRouterMessage{{Role: "user", Content: "Test"}}
+	for i := 0; i < 2; i++ {
+		if _, err := QueryModel(context.Background(), "model/a", messages, time.Second); err == nil {
+			t.Fatal("Expected the failing model to error")
+		}
+	}
+
+	// Stage 1 skips the model without a request and reports why
+	_, failures, err := Stage1CollectResponses(context.Background(), "What is Go?")
+	if err != nil {
+		t.Fatalf("Stage1CollectResponses failed: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected no request while the circuit is open, got %d requests", requests.Load())
+	}
+	if len(failures) != 1 || !errors.Is(failures[0].Err, ErrCircuitOpen) || !strings.HasPrefix(failures[0].Reason, "circuit open") {
+		t.Errorf("Failures = %+v, want model/a reported as circuit open", failures)
+	}
+
+	// After the cooldown the model is tried again and recovers
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	response, err := QueryModel(context.Background(), "model/a", messages, time.Second)
+	if err != nil || response.Content != "Recovered" {
+		t.Fatalf("QueryModel after cooldown = %v, %v", response, err)
+	}
+	if snapshot := modelCircuits.Snapshot(); len(snapshot) != 0 {
+		t.Errorf("Expected the circuit to close, got %+v", snapshot)
+	}
+}
+
+// TestFetchModelCatalogTimeout tests that catalog fetches are bounded by
+// ModelCatalogTimeout rather than any model query timeout
+func TestFetchModelCatalogTimeout(t *testing.T) {
+	oldModelsURL := OpenRouterModelsURL
+	oldCatalogTimeout := ModelCatalogTimeout
+	oldTitleTimeout := TitleGenTimeout
+	defer func() {
+		OpenRouterModelsURL = oldModelsURL
+		ModelCatalogTimeout = oldCatalogTimeout
+		TitleGenTimeout = oldTitleTimeout
+	}()
+
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	defer mockServer.Close()
+
+	OpenRouterModelsURL = mockServer.URL
+	ModelCatalogTimeout = 100 * time.Millisecond
+	TitleGenTimeout = time.Minute
+
+	start := time.Now()
+	if _, err := FetchModelCatalog(context.Background()); err == nil {
+		t.Fatal("Expected the catalog fetch to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("FetchModelCatalog took %v, want it to stop near %v", elapsed, ModelCatalogTimeout)
+	}
+}
+
+// TestDescribeQueryError tests human-readable failure reasons
+func TestDescribeQueryError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "timeout",
+			err:      &OpenRouterError{Retryable: true, Err: fmt.Errorf("%w: deadline", ErrTimeout)},
+			expected: "timeout",
+		},
+		{
+			name:     "http status",
+			err:      &OpenRouterError{StatusCode: 401, Body: "bad key"},
+			expected: "http status 401 (Unauthorized)",
+		},
+		{
+			name:     "parse error",
+			err:      &OpenRouterError{StatusCode: 200, Err: fmt.Errorf("%w: bad json", ErrInvalidResponse)},
+			expected: "parse error: could not decode model response",
+		},
+		{
+			name:     "no choices",
+			err:      &OpenRouterError{StatusCode: 200, Err: ErrNoChoices},
+			expected: "empty response: no choices returned",
+		},
+		{
+			name:     "no content",
+			err:      &OpenRouterError{StatusCode: 200, Err: ErrEmptyContent},
+			expected: "empty response: no content returned",
+		},
+		{
+			name:     "cancelled",
+			err:      &OpenRouterError{Err: fmt.Errorf("failed to make request: %w", context.Canceled)},
+			expected: "cancelled",
+		},
+		{
+			name:     "other error",
+			err:      errors.New("connection refused"),
+			expected: "request failed: connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeQueryError(tt.err); got != tt.expected {
+				t.Errorf("DescribeQueryError() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 // TestOpenRouterMessageJSON tests JSON marshaling of OpenRouterMessage
 func TestOpenRouterMessageJSON(t *testing.T) {
 	msg := OpenRouterMessage{
@@ -355,3 +1223,124 @@ func TestOpenRouterRequestJSON(t *testing.T) {
 		t.Errorf("Messages length mismatch: got %d, want %d", len(decoded.Messages), len(req.Messages))
 	}
 }
+
+// TestValidateConfiguredModels tests checking configured model IDs against a mocked
+// OpenRouter catalog
+func TestValidateConfiguredModels(t *testing.T) {
+	oldModelsURL := OpenRouterModelsURL
+	oldCache := modelCatalogCache
+	oldModels, oldRankers := CouncilModels, RankerModels
+	oldChairman, oldFallbacks, oldTitle := ChairmanModel, ChairmanFallbacks, TitleModel
+	oldStrict, oldModes := StrictModelValidation, CouncilModes
+	defer func() {
+		OpenRouterModelsURL = oldModelsURL
+		modelCatalogCache = oldCache
+		CouncilModels, RankerModels = oldModels, oldRankers
+		ChairmanModel, ChairmanFallbacks, TitleModel = oldChairman, oldFallbacks, oldTitle
+		StrictModelValidation, CouncilModes = oldStrict, oldModes
+	}()
+
+	var catalogRequests atomic.Int32
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		catalogRequests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": [{"id": "model/a"}, {"id": "model/b"}, {"id": "model/chairman"}, {"id": "model/title"}]}`))
+	})
+	defer mockServer.Close()
+
+	CouncilModels = []string{"model/a", "model/b"}
+	RankerModels = nil
+	ChairmanModel = "model/chairman"
+	ChairmanFallbacks = nil
+	TitleModel = "model/title"
+	CouncilModes = map[string]CouncilConfig{"default": {}}
+
+	reset := func(url string) {
+		OpenRouterModelsURL = url
+		modelCatalogCache = NewModelCatalogCache(time.Hour)
+		catalogRequests.Store(0)
+	}
+
+	t.Run("all models listed", func(t *testing.T) {
+		reset(mockServer.URL)
+		StrictModelValidation = true
+		if unknown := UnknownModels(mustCatalog(t)); len(unknown) != 0 {
+			t.Errorf("UnknownModels = %v, want none", unknown)
+		}
+		if err := ValidateConfiguredModels(context.Background()); err != nil {
+			t.Errorf("ValidateConfiguredModels = %v, want nil", err)
+		}
+		if n := catalogRequests.Load(); n != 1 {
+			t.Errorf("Catalog fetched %d times, want once then cached", n)
+		}
+	})
+
+	t.Run("typo in council and fallback models", func(t *testing.T) {
+		reset(mockServer.URL)
+		CouncilModels = []string{"model/a", "model/bb"}
+		ChairmanFallbacks = []string{"model/backup", "model/a"}
+		defer func() {
+			CouncilModels = []string{"model/a", "model/b"}
+			ChairmanFallbacks = nil
+		}()
+
+		want := []string{"model/bb", "model/backup"}
+		if unknown := UnknownModels(mustCatalog(t)); !reflect.DeepEqual(unknown, want) {
+			t.Errorf("UnknownModels = %v, want %v", unknown, want)
+		}
+
+		StrictModelValidation = false
+		if err := ValidateConfiguredModels(context.Background()); err != nil {
+			t.Errorf("Non-strict validation = %v, want only warnings", err)
+		}
+
+		StrictModelValidation = true
+		err := ValidateConfiguredModels(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "model/bb, model/backup") {
+			t.Errorf("Strict validation = %v, want an error naming the unknown models", err)
+		}
+	})
+
+	t.Run("typo in a council mode preset", func(t *testing.T) {
+		reset(mockServer.URL)
+		CouncilModes = map[string]CouncilConfig{
+			"default": {},
+			"fast":    {CouncilModels: []string{"model/a", "model/fast"}, ChairmanModel: "model/a", ChairmanFallbacks: []string{"model/fast-backup"}},
+		}
+		defer func() { CouncilModes = map[string]CouncilConfig{"default": {}} }()
+
+		want := []string{"model/fast", "model/fast-backup"}
+		if unknown := UnknownModels(mustCatalog(t)); !reflect.DeepEqual(unknown, want) {
+			t.Errorf("UnknownModels = %v, want %v", unknown, want)
+		}
+
+		StrictModelValidation = true
+		err := ValidateConfiguredModels(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "model/fast, model/fast-backup") {
+			t.Errorf("Strict validation = %v, want an error naming the preset's unknown models", err)
+		}
+	})
+
+	t.Run("catalog unavailable", func(t *testing.T) {
+		failing := MockOpenRouterServer(t, CreateMockOpenRouterErrorHandler(http.StatusServiceUnavailable, "down"))
+		defer failing.Close()
+		reset(failing.URL)
+		StrictModelValidation = true
+		CouncilModels = []string{"model/unchecked"}
+		defer func() { CouncilModels = []string{"model/a", "model/b"} }()
+
+		if err := ValidateConfiguredModels(context.Background()); err != nil {
+			t.Errorf("ValidateConfiguredModels = %v, want validation skipped", err)
+		}
+	})
+}
+
+// mustCatalog loads the model catalog, failing the test on error
+func mustCatalog(t *testing.T) []CatalogModel {
+	t.Helper()
+	catalog, err := loadModelCatalog(context.Background(), false)
+	if err != nil {
+		t.Fatalf("loadModelCatalog failed: %v", err)
+	}
+	return catalog
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// queryCacheKey hashes everything sent to OpenRouter for a query, so two queries
+// share a key only if they would send identical requests. The timeout is not part
+// of the request and doesn't affect the key.
+func queryCacheKey(payload OpenRouterRequest) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// queryCachePath returns the file a cached response for key is stored in
+func queryCachePath(key string) string {
+	return filepath.Join(QueryCacheDir, key+".json")
+}
+
+// loadCachedQuery returns the stored response for key.
+// A missing or unreadable entry is a miss; unreadable entries are overwritten
+// by the next successful query.
+func loadCachedQuery(key string) (*OpenRouterResponse, bool) {
+	data, err := os.ReadFile(queryCachePath(key))
+	if err != nil {
+		return nil, false
+	}
+	var response OpenRouterResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
+
+// storeCachedQuery saves a successful response under key
+func storeCachedQuery(key string, response *OpenRouterResponse) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return writeFileAtomic(queryCachePath(key), data)
+}
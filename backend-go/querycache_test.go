@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestQueryModelCache tests answering repeated queries from the on-disk response cache
+func TestQueryModelCache(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldAPIURL, oldAPIKey := OpenRouterAPIURL, OpenRouterAPIKey
+	oldEnabled, oldDir := QueryCacheEnabled, QueryCacheDir
+	defer func() {
+		OpenRouterAPIURL, OpenRouterAPIKey = oldAPIURL, oldAPIKey
+		QueryCacheEnabled, QueryCacheDir = oldEnabled, oldDir
+	}()
+
+	var requests atomic.Int32
+	var failing atomic.Bool
+	succeed := CreateMockOpenRouterHandler(t, "Cached answer")
+	mockServer := MockOpenRouterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		succeed(w, r)
+	})
+	defer mockServer.Close()
+
+	OpenRouterAPIURL = mockServer.URL
+	OpenRouterAPIKey = "test-key"
+	QueryCacheEnabled = true
+	QueryCacheDir = tempDir
+
+	ctx := context.Background()
+	messages := []OpenRouterMessage{{Role: "user", Content: "What is Go?"}}
+
+	query := func(model string, messages []OpenRouterMessage, opts QueryOptions) *OpenRouterResponse {
+		t.Helper()
+		response, err := QueryModelWithOptions(ctx, model, messages, opts)
+		if err != nil {
+			t.Fatalf("QueryModelWithOptions failed: %v", err)
+		}
+		return response
+	}
+
+	first := query("model/a", messages, QueryOptions{Timeout: time.Second})
+	second := query("model/a", messages, QueryOptions{Timeout: time.Minute})
+	if requests.Load() != 1 {
+		t.Fatalf("Expected the repeated query to be served from the cache, got %d requests", requests.Load())
+	}
+	if first.Content != "Cached answer" || second.Content != first.Content {
+		t.Errorf("Responses = %q, %q, want both %q", first.Content, second.Content, "Cached answer")
+	}
+
+	// A different model, prompt or option is a different query
+	query("model/b", messages, QueryOptions{})
+	query("model/a", []OpenRouterMessage{{Role: "user", Content: "What is Rust?"}}, QueryOptions{})
+	query("model/a", messages, QueryOptions{ReasoningEffort: "high"})
+	if requests.Load() != 4 {
+		t.Errorf("Expected each distinct query to reach OpenRouter, got %d requests", requests.Load())
+	}
+
+	// Failures are not cached
+	failing.Store(true)
+	if _, err := QueryModel(ctx, "model/c", messages, time.Second); err == nil {
+		t.Fatal("Expected the failing query to error")
+	}
+	failing.Store(false)
+	query("model/c", messages, QueryOptions{})
+	if requests.Load() != 6 {
+		t.Errorf("Expected the query to be retried after a failure, got %d requests", requests.Load())
+	}
+
+	// Nothing is read or written while the cache is disabled
+	QueryCacheEnabled = false
+	query("model/a", messages, QueryOptions{})
+	query("model/d", messages, QueryOptions{})
+	if requests.Load() != 8 {
+		t.Errorf("Expected the disabled cache to be bypassed, got %d requests", requests.Load())
+	}
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read cache directory: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Errorf("Expected 5 cached responses, got %d", len(entries))
+	}
+}
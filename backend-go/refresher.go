@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// BillsRefresher keeps a BillsCache warm by re-scraping bills in the background
+type BillsRefresher struct {
+	cache    *BillsCache
+	interval time.Duration
+	jitter   time.Duration // up to this much is added to each wait
+	fetch    func(context.Context) ([]Bill, error)
+	path     string // where refreshed bills are persisted; empty disables persistence
+
+	// With details set, each refresh also brings cached bill details up to date,
+	// fetching only new and changed bills with fetchDetail
+	details     *TTLCache[string, *BillDetail]
+	fetchDetail func(context.Context, Bill) (*BillDetail, error)
+}
+
+// NewBillsRefresher creates a refresher that calls fetch every interval (plus up to
+// 10% jitter, so restarted instances don't all scrape at once) and stores the result
+// in cache, persisting it to path if set.
+func NewBillsRefresher(cache *BillsCache, interval time.Duration, fetch func(context.Context) ([]Bill, error), path string) *BillsRefresher {
+	return &BillsRefresher{
+		cache:    cache,
+		interval: interval,
+		jitter:   interval / 10,
+		fetch:    fetch,
+		path:     path,
+	}
+}
+
+// SyncDetails makes each refresh also update details incrementally with
+// SyncBillDetails, using fetch to load a bill's detail page
+func (r *BillsRefresher) SyncDetails(details *TTLCache[string, *BillDetail], fetch func(context.Context, Bill) (*BillDetail, error)) {
+	r.details = details
+	r.fetchDetail = fetch
+}
+
+// Run refreshes the cache until ctx is cancelled. An empty or expired cache is
+// refreshed immediately. A scheduled refresh is skipped if the cache was updated
+// within the last half interval, e.g. by a manual ?refresh=true request.
+func (r *BillsRefresher) Run(ctx context.Context) {
+	if r.cache.IsExpired() {
+		r.refresh(ctx)
+	}
+
+	for {
+		timer := time.NewTimer(r.nextDelay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if age := time.Since(r.cache.GetLastUpdated()); age < r.interval/2 {
+			slog.DebugContext(ctx, "skipping scheduled bills refresh, cache was refreshed recently", "age", age)
+			continue
+		}
+		r.refresh(ctx)
+	}
+}
+
+// nextDelay returns the wait before the next scheduled refresh
+func (r *BillsRefresher) nextDelay() time.Duration {
+	if r.jitter <= 0 {
+		return r.interval
+	}
+	return r.interval + rand.N(r.jitter)
+}
+
+// refresh fetches bills once and updates the cache, keeping the old bills on failure.
+// An incomplete listing is still cached, but doesn't drop details of the bills it lacks.
+func (r *BillsRefresher) refresh(ctx context.Context) {
+	bills, err := r.fetch(ctx)
+	partial := errors.Is(err, ErrPartialBills)
+	if err != nil && !partial {
+		if ctx.Err() == nil {
+			slog.WarnContext(ctx, "scheduled bills refresh failed", "error", err)
+		}
+		return
+	}
+	if len(bills) == 0 {
+		slog.WarnContext(ctx, "scheduled bills refresh returned no bills, keeping cached bills")
+		return
+	}
+
+	previous, _, _ := r.cache.GetStale()
+	r.cache.Set(bills)
+	if r.path != "" {
+		if err := r.cache.SaveToFile(r.path); err != nil {
+			slog.WarnContext(ctx, "failed to persist bills cache", "error", err)
+		}
+	}
+	slog.InfoContext(ctx, "refreshed bills cache", "count", len(bills), "partial", partial)
+
+	if r.details != nil {
+		fetched := SyncBillDetails(ctx, r.details, previous, bills, partial, r.fetchDetail)
+		slog.InfoContext(ctx, "synced bill details", "fetched", fetched, "count", len(bills))
+	}
+}
+
+// SyncBillDetails brings cached bill details in line with a freshly scraped bills
+// list without fetching every bill again. Bills that are new, or whose content hash
+// differs from the previous list, have their detail fetched; unchanged bills keep
+// any cached detail, renewed even if it had expired; bills no longer listed are
+// dropped, unless partial says current is only a prefix of the listing. Fetches are
+// spaced by PageRequestDelay. A bill whose fetch fails loses its cached detail so it
+// is fetched again on demand. Returns the number fetched.
+func SyncBillDetails(ctx context.Context, details *TTLCache[string, *BillDetail], previous, current []Bill, partial bool, fetch func(context.Context, Bill) (*BillDetail, error)) int {
+	previousHashes := make(map[string]string, len(previous))
+	for _, bill := range previous {
+		previousHashes[bill.ID] = contentHash(bill)
+	}
+
+	// Look up unchanged bills' details before storing anything, since storing
+	// prunes expired entries
+	listed := make(map[string]bool, len(current))
+	renewed := make(map[string]*BillDetail)
+	var changed []Bill
+	for _, bill := range current {
+		listed[bill.ID] = true
+		if hash, ok := previousHashes[bill.ID]; ok && hash == contentHash(bill) {
+			if detail, _, ok := details.Peek(bill.ID); ok {
+				renewed[bill.ID] = detail
+			}
+			continue
+		}
+		changed = append(changed, bill)
+	}
+
+	for id, detail := range renewed {
+		details.Set(id, detail)
+	}
+	for id := range previousHashes {
+		if !listed[id] && !partial {
+			details.Delete(id)
+		}
+	}
+
+	limiter := rate.NewLimiter(rate.Every(PageRequestDelay), 1)
+	fetched := 0
+	for _, bill := range changed {
+		details.Delete(bill.ID)
+		if err := limiter.Wait(ctx); err != nil {
+			continue
+		}
+
+		detail, err := fetch(ctx, bill)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to fetch bill detail", "bill_id", bill.ID, "error", err)
+			continue
+		}
+		details.Set(bill.ID, detail)
+		fetched++
+	}
+	return fetched
+}
+
+// contentHash returns a bill's content hash, computing it for bills scraped
+// before hashes were recorded
+func contentHash(bill Bill) string {
+	if bill.ContentHash != "" {
+		return bill.ContentHash
+	}
+	return billContentHash(bill)
+}
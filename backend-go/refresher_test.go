@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestBillsRefresher tests periodic background refreshes of the bills cache
+func TestBillsRefresher(t *testing.T) {
+	// fakeFetch returns a new single-bill result on each call, counting calls
+	newFakeFetch := func(fail bool) (func(context.Context) ([]Bill, error), func() int) {
+		var mu sync.Mutex
+		calls := 0
+		fetch := func(ctx context.Context) ([]Bill, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if fail {
+				return nil, errors.New("scrape failed")
+			}
+			return []Bill{{ID: "r" + string(rune('0'+calls)), Title: "Bill"}}, nil
+		}
+		count := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return calls
+		}
+		return fetch, count
+	}
+
+	t.Run("refreshes periodically, persists and stops on cancel", func(t *testing.T) {
+		cache := NewBillsCache(time.Hour)
+		path := filepath.Join(t.TempDir(), "bills.json")
+		fetch, calls := newFakeFetch(false)
+		refresher := NewBillsRefresher(cache, 20*time.Millisecond, fetch, path)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			refresher.Run(ctx)
+			close(done)
+		}()
+
+		time.Sleep(150 * time.Millisecond)
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Run did not stop after cancel")
+		}
+
+		n := calls()
+		if n < 3 {
+			t.Errorf("Expected at least 3 refreshes, got %d", n)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if calls() != n {
+			t.Error("Refresher kept fetching after it was stopped")
+		}
+
+		// The latest bills were cached and persisted
+		bills, ok := cache.Get()
+		if !ok || len(bills) != 1 {
+			t.Fatalf("Expected refreshed bills in the cache, got %v", bills)
+		}
+		restored := NewBillsCache(time.Hour)
+		if err := restored.LoadFromFile(path); err != nil {
+			t.Fatalf("LoadFromFile failed: %v", err)
+		}
+		if got, _ := restored.Get(); len(got) != 1 || got[0].ID != bills[0].ID {
+			t.Errorf("Persisted bills = %v, want %v", got, bills)
+		}
+	})
+
+	t.Run("skips refresh after a recent manual refresh", func(t *testing.T) {
+		cache := NewBillsCache(time.Hour)
+		fetch, calls := newFakeFetch(false)
+		refresher := NewBillsRefresher(cache, 100*time.Millisecond, fetch, "")
+
+		// Keep the cache fresh as a user hitting ?refresh=true would
+		cache.Set([]Bill{{ID: "manual"}})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go refresher.Run(ctx)
+
+		deadline := time.Now().Add(350 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			cache.Set([]Bill{{ID: "manual"}})
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		if n := calls(); n != 0 {
+			t.Errorf("Expected scheduled refreshes to be skipped, got %d fetches", n)
+		}
+	})
+
+	t.Run("failed refresh keeps cached bills", func(t *testing.T) {
+		cache := NewBillsCache(time.Millisecond)
+		cache.Set([]Bill{{ID: "old"}})
+		time.Sleep(5 * time.Millisecond)
+		fetch, calls := newFakeFetch(true)
+		refresher := NewBillsRefresher(cache, time.Hour, fetch, "")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		refresher.Run(ctx)
+
+		if calls() != 1 {
+			t.Errorf("Expected the expired cache to be refreshed once, got %d fetches", calls())
+		}
+		if cache.GetSize() != 1 {
+			t.Error("Failed refresh should keep the previously cached bills")
+		}
+	})
+}
+
+// TestSyncBillDetails tests that only new and changed bills have their detail fetched
+func TestSyncBillDetails(t *testing.T) {
+	oldDelay := PageRequestDelay
+	defer func() { PageRequestDelay = oldDelay }()
+	PageRequestDelay = 0
+
+	bill := func(id, status string) Bill {
+		b := Bill{ID: id, Title: "Bill " + id, Status: status}
+		b.ContentHash = billContentHash(b)
+		return b
+	}
+
+	var fetchedIDs []string
+	fetch := func(ctx context.Context, b Bill) (*BillDetail, error) {
+		fetchedIDs = append(fetchedIDs, b.ID)
+		if b.ID == "broken" {
+			return nil, errors.New("detail page unavailable")
+		}
+		return &BillDetail{Bill: b, Sponsor: "fetched"}, nil
+	}
+
+	details := NewTTLCache[string, *BillDetail](time.Hour)
+	previous := []Bill{
+		bill("same", "Before Senate"),
+		bill("moved", "Before Senate"),
+		bill("gone", "Before Senate"),
+		bill("broken", "Before Senate"),
+		{ID: "legacy", Title: "Bill legacy"}, // persisted before hashes were recorded
+	}
+	for _, b := range previous {
+		details.Set(b.ID, &BillDetail{Bill: b, Sponsor: "cached"})
+	}
+
+	current := []Bill{
+		bill("same", "Before Senate"),
+		bill("moved", "Passed both Houses"),
+		bill("broken", "Passed both Houses"),
+		bill("legacy", ""),
+		bill("new", "Before House of Representatives"),
+	}
+
+	fetched := SyncBillDetails(context.Background(), details, previous, current, false, fetch)
+
+	if want := []string{"moved", "broken", "new"}; !reflect.DeepEqual(fetchedIDs, want) {
+		t.Errorf("Fetched %v, want %v", fetchedIDs, want)
+	}
+	if fetched != 2 {
+		t.Errorf("SyncBillDetails returned %d, want 2 successful fetches", fetched)
+	}
+
+	sponsor := func(id string) string {
+		detail, ok := details.Get(id)
+		if !ok {
+			return ""
+		}
+		return detail.Sponsor
+	}
+	for id, want := range map[string]string{
+		"same":   "cached",
+		"legacy": "cached",
+		"moved":  "fetched",
+		"new":    "fetched",
+		"broken": "",
+		"gone":   "",
+	} {
+		if got := sponsor(id); got != want {
+			t.Errorf("Detail for %q = %q, want %q", id, got, want)
+		}
+	}
+	if detail, _ := details.Get("moved"); detail.Status != "Passed both Houses" {
+		t.Errorf("Changed bill's detail status = %q, want the new status", detail.Status)
+	}
+
+	// Unchanged bills keep their detail through later syncs, even once it has expired
+	expiring := NewTTLCache[string, *BillDetail](10 * time.Millisecond)
+	expiring.Set("same", &BillDetail{Bill: current[0], Sponsor: "cached"})
+	time.Sleep(20 * time.Millisecond)
+	fetchedIDs = nil
+	SyncBillDetails(context.Background(), expiring, current, current, false, fetch)
+	if len(fetchedIDs) != 0 {
+		t.Errorf("Expected an unchanged listing to fetch nothing, fetched %v", fetchedIDs)
+	}
+	if detail, ok := expiring.Get("same"); !ok || detail.Sponsor != "cached" {
+		t.Errorf("Expected the unchanged bill's expired detail to be renewed, got %v, %v", detail, ok)
+	}
+
+	// A partial listing doesn't drop the details of bills past where it stopped
+	details.Set("gone", &BillDetail{Bill: bill("gone", "Before Senate"), Sponsor: "cached"})
+	SyncBillDetails(context.Background(), details, append(current, bill("gone", "Before Senate")), current[:1], true, fetch)
+	if got := sponsor("gone"); got != "cached" {
+		t.Errorf("Detail for a bill missing from a partial listing = %q, want it kept", got)
+	}
+}
+
+// TestBillsRefresherSyncDetails tests that a refresh with detail syncing skips
+// unchanged bills
+func TestBillsRefresherSyncDetails(t *testing.T) {
+	oldDelay := PageRequestDelay
+	defer func() { PageRequestDelay = oldDelay }()
+	PageRequestDelay = 0
+
+	listing := []Bill{{ID: "r1", Status: "Before Senate"}, {ID: "r2", Status: "Before Senate"}}
+	var listingErr error
+	fetch := func(ctx context.Context) ([]Bill, error) {
+		bills := slices.Clone(listing)
+		for i := range bills {
+			bills[i].ContentHash = billContentHash(bills[i])
+		}
+		return bills, listingErr
+	}
+
+	var fetchedIDs []string
+	fetchDetail := func(ctx context.Context, b Bill) (*BillDetail, error) {
+		fetchedIDs = append(fetchedIDs, b.ID)
+		return &BillDetail{Bill: b}, nil
+	}
+
+	details := NewTTLCache[string, *BillDetail](time.Hour)
+	refresher := NewBillsRefresher(NewBillsCache(time.Hour), time.Hour, fetch, "")
+	refresher.SyncDetails(details, fetchDetail)
+
+	refresher.refresh(context.Background())
+	listing[1].Status = "Passed both Houses"
+	refresher.refresh(context.Background())
+
+	if want := []string{"r1", "r2", "r2"}; !reflect.DeepEqual(fetchedIDs, want) {
+		t.Errorf("Fetched details %v, want %v", fetchedIDs, want)
+	}
+
+	// A listing cut short by a failed page keeps the details of the bills it lacks
+	listing, listingErr = listing[:1], fmt.Errorf("%w: page 2: unavailable", ErrPartialBills)
+	refresher.refresh(context.Background())
+	if _, ok := details.Get("r2"); !ok {
+		t.Error("Expected a partial refresh to keep r2's detail")
+	}
+}
@@ -1,44 +1,86 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/ledongthuc/pdf"
+	"golang.org/x/net/html"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 )
 
+// Base URL for bills before parliament (configurable via ENV BILLS_BASE_URL)
+var BillsBaseURL = "https://www.aph.gov.au/Parliamentary_Business/Bills_Legislation/Bills_before_Parliament"
+
+// BillsPageQuery is the query string for listing pages after the first, with %d for the
+// page number (configurable via ENV BILLS_PAGE_QUERY)
+var BillsPageQuery = "page=%d&drt=2&drv=7"
+
+// ScraperUserAgent identifies the scraper to APH (configurable via ENV SCRAPER_USER_AGENT)
+var ScraperUserAgent = UserAgent
+
+// ScraperContact, when set, is sent as the From header so APH can reach whoever runs
+// the scraper (configurable via ENV SCRAPER_CONTACT, an email address)
+var ScraperContact = ""
+
+// ScraperSpoofBrowser sends BrowserUserAgent instead of ScraperUserAgent, for sites
+// that turn away identified bots (configurable via ENV SCRAPER_SPOOF_BROWSER)
+var ScraperSpoofBrowser = false
+
+// BillsSelectors locates bills in listing pages (configurable via the config file's
+// scraper_selectors)
+var BillsSelectors = DefaultScraperSelectors()
+
+// pageValidatorStore holds ETag/Last-Modified validators per listing page
+// When nil, FetchBillsPage always performs unconditional requests
+var pageValidatorStore *PageValidatorStore
+
 const (
-	// Base URL for bills before parliament
-	BillsBaseURL = "https://www.aph.gov.au/Parliamentary_Business/Bills_Legislation/Bills_before_Parliament"
+	// Base URL for individual bill pages, keyed by ?bId=
+	BillDetailBaseURL = "https://www.aph.gov.au/Parliamentary_Business/Bills_Legislation/Bills_Search_Results/Result"
 
 	// HTTP timeout for each request
 	ScraperTimeout = 30 * time.Second
 
-	// Delay between page requests to be respectful
-	PageRequestDelay = 500 * time.Millisecond
-
-	// User agent for HTTP requests
+	// Default user agent for scraper requests
 	UserAgent = "LLM-Council-Bills-Scraper/1.0 (Educational Project)"
+
+	// BrowserUserAgent mimics a desktop Chrome browser
+	BrowserUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
 )
 
 // Bill represents a single parliamentary bill
 type Bill struct {
-	ID                 string    `json:"id"`                   // e.g., "r7365", "s1254"
-	Title              string    `json:"title"`
-	DateIntroduced     string    `json:"date_introduced"`      // e.g., "03 Sep 2025"
-	Chamber            string    `json:"chamber"`              // "Senate" or "House of Representatives"
-	Status             string    `json:"status"`               // e.g., "Before Senate"
-	PortfolioSponsor   string    `json:"portfolio_sponsor"`    // e.g., "Attorney-General"
-	Summary            string    `json:"summary"`
-	BillURL            string    `json:"bill_url"`             // ParlInfo link
-	ExplanatoryMemoURL string    `json:"explanatory_memo_url"` // ParlInfo link
-	ScrapedAt          time.Time `json:"scraped_at"`
+	ID                   string    `json:"id"` // e.g., "r7365", "s1254"
+	Title                string    `json:"title"`
+	DateIntroduced       string    `json:"date_introduced"`                 // e.g., "03 Sep 2025"
+	DateIntroducedParsed time.Time `json:"date_introduced_parsed,omitzero"` // zero if the date could not be parsed
+	Chamber              string    `json:"chamber"`                         // "Senate" or "House of Representatives"
+	Status               string    `json:"status"`                          // e.g., "Before Senate"
+	PortfolioSponsor     string    `json:"portfolio_sponsor"`               // e.g., "Attorney-General"
+	Summary              string    `json:"summary"`
+	BillURL              string    `json:"bill_url"`               // ParlInfo link
+	ExplanatoryMemoURL   string    `json:"explanatory_memo_url"`   // ParlInfo link
+	ContentHash          string    `json:"content_hash,omitempty"` // changes when any listed field changes
+	ScrapedAt            time.Time `json:"scraped_at"`
 }
 
 // BillsResponse represents the paginated response
@@ -46,90 +88,480 @@ type BillsResponse struct {
 	Bills       []Bill    `json:"bills"`
 	CurrentPage int       `json:"current_page"`
 	TotalPages  int       `json:"total_pages"`
+	PageSize    int       `json:"page_size"`   // 0 when every bill is on one page
+	TotalBills  int       `json:"total_bills"` // Bills matching the filter, across all pages
 	HasNextPage bool      `json:"has_next_page"`
 	LastUpdated time.Time `json:"last_updated"`
+	Stale       bool      `json:"stale,omitempty"` // Served from an old cache because a fresh fetch failed
+}
+
+// BillDetail holds the richer information found on a bill's own ParlInfo page
+type BillDetail struct {
+	Bill
+	Type             string              `json:"type"`    // e.g., "Government", "Private"
+	Sponsor          string              `json:"sponsor"` // e.g., "Chalmers, Jim, MP"
+	OriginatingHouse string              `json:"originating_house"`
+	FullSummary      string              `json:"full_summary"`
+	Progress         []BillProgressEvent `json:"progress"`
+	Stages           []BillStage         `json:"stages"`
+	Links            []BillLink          `json:"links"`
+}
+
+// BillProgressEvent is a single row of a bill's progress table
+type BillProgressEvent struct {
+	Chamber string `json:"chamber"`
+	Stage   string `json:"stage"` // e.g., "Introduced and read a first time"
+	Date    string `json:"date"`  // e.g., "03 Sep 2025"
+}
+
+// Bill stages a progress event is classified as, in the order a bill passes through them
+const (
+	BillStageIntroduced    = "introduced"
+	BillStageSecondReading = "second_reading"
+	BillStageCommittee     = "committee"
+	BillStageThirdReading  = "third_reading"
+	BillStagePassed        = "passed"
+	BillStageAssent        = "assent"
+	BillStageOther         = "other"
+)
+
+// billStagePhrases maps wording in APH progress rows to stages; the first match wins,
+// so later milestones are listed before earlier ones they might mention
+var billStagePhrases = []struct {
+	phrase string
+	stage  string
+}{
+	{"assent", BillStageAssent},
+	{"act no", BillStageAssent},
+	{"passed both houses", BillStagePassed},
+	{"third reading", BillStageThirdReading},
+	{"committee", BillStageCommittee},
+	{"consideration in detail", BillStageCommittee},
+	{"second reading", BillStageSecondReading},
+	{"introduced", BillStageIntroduced},
+	{"first time", BillStageIntroduced},
+}
+
+// BillStage is a progress event classified for charting a bill's passage
+type BillStage struct {
+	Stage      string    `json:"stage"` // one of the BillStage* constants
+	Name       string    `json:"name"`  // as shown by APH, e.g. "Second reading agreed to"
+	Chamber    string    `json:"chamber"`
+	Date       string    `json:"date"`
+	DateParsed time.Time `json:"date_parsed,omitzero"` // zero if the date could not be parsed
+}
+
+// classifyBillStage returns the stage a progress event's wording describes
+func classifyBillStage(name string) string {
+	lower := strings.ToLower(name)
+	for _, p := range billStagePhrases {
+		if strings.Contains(lower, p.phrase) {
+			return p.stage
+		}
+	}
+	return BillStageOther
+}
+
+// BillStages classifies a bill's progress events, keeping their order
+func BillStages(progress []BillProgressEvent) []BillStage {
+	stages := make([]BillStage, 0, len(progress))
+	for _, event := range progress {
+		stage := BillStage{
+			Stage:   classifyBillStage(event.Stage),
+			Name:    event.Stage,
+			Chamber: event.Chamber,
+			Date:    event.Date,
+		}
+		if date, err := ParseBillDate(event.Date); err == nil {
+			stage.DateParsed = date
+		}
+		stages = append(stages, stage)
+	}
+	return stages
+}
+
+// BillLink is a document or transcript linked from a bill's page
+type BillLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ErrBillNotFound is returned when a bill's detail page does not exist
+var ErrBillNotFound = errors.New("bill not found")
+
+// ErrUnexpectedBillsPage is returned when a listing page has no bill entries and none
+// of the markers of a genuinely empty listing, which usually means APH changed its layout
+var ErrUnexpectedBillsPage = errors.New("bills page has no bill entries or results list")
+
+// emptyListingPattern matches phrases APH shows when a listing legitimately has no bills.
+// Whole words only, so a count like "120 results" doesn't match
+var emptyListingPattern = regexp.MustCompile(`(?i)(^|\D)0 results\b|\bno (results|bills)\b`)
+
+// ErrPartialBills is returned by FetchAllBills, along with the bills it did fetch, when
+// a page after the first fails. The bills are then only a prefix of the listing.
+var ErrPartialBills = errors.New("bills listing is incomplete")
+
+// ErrUnsupportedContentType is returned when a fetched URL serves something other than
+// a page, plain text or a PDF
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// ErrContentTooLarge is returned when a fetched URL's body exceeds MaxFetchURLSize
+var ErrContentTooLarge = errors.New("content too large")
+
+// FetchURLResult is the readable content extracted from a fetched page
+type FetchURLResult struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	URL   string `json:"url"` // Final URL after redirects
+}
+
+// billsPage is a single parsed listing page
+type billsPage struct {
+	Bills      []Bill
+	HasNext    bool
+	TotalPages int // From the pagination links; may undercount if they are windowed
 }
 
 // FetchBillsPage fetches a single page of bills from the APH website
 // Returns the bills found on that page and whether there's a next page
 func FetchBillsPage(ctx context.Context, pageNum int) ([]Bill, bool, error) {
-	// Construct URL with page parameter
-	url := BillsBaseURL
-	if pageNum > 1 {
-		url = fmt.Sprintf("%s?page=%d&drt=2&drv=7", BillsBaseURL, pageNum)
-	}
-
-	// Create HTTP request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	page, err := fetchBillsPage(ctx, pageNum)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to create request: %w", err)
+		return nil, false, err
 	}
+	return page.Bills, page.HasNext, nil
+}
 
-	// Set headers to mimic a browser
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
-	req.Header.Set("Connection", "keep-alive")
+// scraperHeaders returns the headers sent with requests to APH: the scraper's
+// User-Agent, its From contact if configured, and the content it accepts
+func scraperHeaders() http.Header {
+	header := http.Header{}
+	if ScraperSpoofBrowser {
+		header.Set("User-Agent", BrowserUserAgent)
+	} else {
+		header.Set("User-Agent", ScraperUserAgent)
+	}
+	if ScraperContact != "" {
+		header.Set("From", ScraperContact)
+	}
+	header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	header.Set("Accept-Language", "en-US,en;q=0.5")
+	return header
+}
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: ScraperTimeout,
+// billsPageURL returns the listing URL for a page; the first page has no query
+func billsPageURL(pageNum int) string {
+	if pageNum <= 1 {
+		return BillsBaseURL
 	}
+	return BillsBaseURL + "?" + fmt.Sprintf(BillsPageQuery, pageNum)
+}
 
-	// Execute request with retry logic
-	var resp *http.Response
-	maxRetries := 2
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		resp, err = client.Do(req)
-		if err == nil {
-			break
-		}
+// fetchBillsPage fetches and parses a listing page, including its pagination info
+func fetchBillsPage(ctx context.Context, pageNum int) (*billsPage, error) {
+	url := billsPageURL(pageNum)
 
-		if attempt < maxRetries-1 {
-			log.Printf("Attempt %d failed, retrying in 2s: %v", attempt+1, err)
-			time.Sleep(2 * time.Second)
+	header := scraperHeaders()
+
+	// Send validators from the previous fetch so unchanged pages come back as 304
+	var previous PageValidators
+	var havePrevious bool
+	if pageValidatorStore != nil {
+		previous, havePrevious = pageValidatorStore.Get(pageNum)
+	}
+	if havePrevious {
+		if previous.ETag != "" {
+			header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			header.Set("If-Modified-Since", previous.LastModified)
 		}
 	}
 
+	// Execute request, retrying transient failures
+	opts := scraperGetOptions()
+	if havePrevious {
+		opts.Accept = []int{http.StatusNotModified}
+	}
+	resp, err := httpGetWithRetry(ctx, url, header, opts)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to fetch page %d after %d attempts: %w", pageNum, maxRetries, err)
+		return nil, fmt.Errorf("failed to fetch page %d: %w", pageNum, err)
 	}
 	defer resp.Body.Close()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, false, fmt.Errorf("unexpected status code %d for page %d", resp.StatusCode, pageNum)
+	// Page unchanged since the last fetch: reuse the bills parsed then
+	if resp.StatusCode == http.StatusNotModified {
+		slog.InfoContext(ctx, "bills page not modified, reusing cached bills", "page", pageNum, "url", url, "count", len(previous.Bills))
+		return &billsPage{Bills: previous.Bills, HasNext: previous.HasNext, TotalPages: previous.TotalPages}, nil
 	}
 
 	// Parse HTML
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to parse HTML: %w", err)
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
 	// Parse bills from HTML
-	bills, err := ParseBillsHTML(doc)
+	bills, err := ParseBillsHTML(doc, BillsSelectors)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to parse bills: %w", err)
+		return nil, fmt.Errorf("failed to parse bills: %w", err)
 	}
 
-	// Check for next page
+	// Refuse to report an empty listing unless the page looks like one, so a layout
+	// change doesn't get cached as "no bills before parliament"
+	if len(bills) == 0 && !isEmptyListing(doc) {
+		return nil, fmt.Errorf("page %d (%s): %w; the APH page layout may have changed", pageNum, url, ErrUnexpectedBillsPage)
+	}
+
+	// Check for next page and the total page count
 	hasNext := HasNextPage(doc)
+	_, totalPages, _ := ExtractPaginationInfo(doc)
+
+	// Remember validators for the next conditional request
+	if pageValidatorStore != nil {
+		etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			validators := PageValidators{
+				ETag:         etag,
+				LastModified: lastModified,
+				Bills:        bills,
+				HasNext:      hasNext,
+				TotalPages:   totalPages,
+			}
+			if err := pageValidatorStore.Set(pageNum, validators); err != nil {
+				slog.WarnContext(ctx, "failed to persist page validators", "page", pageNum, "error", err)
+			}
+		}
+	}
+
+	slog.InfoContext(ctx, "fetched bills page", "page", pageNum, "url", url, "count", len(bills), "has_next", hasNext)
+
+	return &billsPage{Bills: bills, HasNext: hasNext, TotalPages: totalPages}, nil
+}
+
+// isEmptyListing reports whether a page with no parsed bills is a genuine empty listing:
+// it still has the results list container with no items in it, or says there are no results
+func isEmptyListing(doc *goquery.Document) bool {
+	results := doc.Find("ul.search-filter-results")
+	if results.Length() > 0 && results.Children().Filter("li").Length() == 0 {
+		return true
+	}
+	return emptyListingPattern.MatchString(cleanText(doc.Find("body").Text()))
+}
+
+// ScraperHTTPError is returned when a scraped page responds with an unexpected status.
+// Retryable is true for 429 and 5xx responses that were still failing after Retries
+// retries.
+type ScraperHTTPError struct {
+	StatusCode int
+	URL        string
+	Retryable  bool
+	Retries    int
+}
+
+// Error implements the error interface
+func (e *ScraperHTTPError) Error() string {
+	if e.Retryable {
+		return fmt.Sprintf("status %d from %s (gave up after %d retries)", e.StatusCode, e.URL, e.Retries)
+	}
+	return fmt.Sprintf("unexpected status code %d from %s", e.StatusCode, e.URL)
+}
+
+// httpGetOptions controls how httpGetWithRetry fetches a URL
+type httpGetOptions struct {
+	// Timeout bounds each attempt, including reading the response body
+	Timeout time.Duration
+
+	// MaxRetries is how many times network errors, 429 and 5xx responses are retried
+	MaxRetries int
+
+	// Accept lists statuses other than 200 OK to return to the caller rather than
+	// fail with, e.g. 304 Not Modified for a conditional request
+	Accept []int
+
+	// Policy, when set, restricts which addresses the request and its redirects may
+	// reach; refused URLs fail with ErrURLNotAllowed without being retried
+	Policy *URLPolicy
+}
+
+// scraperGetOptions returns the options for a request with the scraper's
+// configured timeout and retries
+func scraperGetOptions() httpGetOptions {
+	return httpGetOptions{
+		Timeout:    ScraperTimeout,
+		MaxRetries: ScraperMaxRetries,
+	}
+}
+
+// httpGetWithRetry GETs url with header, retrying network errors, 429 and 5xx
+// responses up to opts.MaxRetries times with exponential backoff starting at
+// ScraperRetryBackoff. A Retry-After header lengthens the wait, capped at
+// ScraperMaxRetryDelay. Returns the response, which the caller must close, if the
+// status is 200 or one of opts.Accept; any other status fails with a
+// *ScraperHTTPError without further retries. Stops waiting as soon as ctx is done.
+func httpGetWithRetry(ctx context.Context, url string, header http.Header, opts httpGetOptions) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header = header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+
+	client := &http.Client{Timeout: opts.Timeout}
+	if opts.Policy != nil {
+		if err := opts.Policy.CheckURL(req.URL); err != nil {
+			return nil, err
+		}
+		client = opts.Policy.client(opts.Timeout)
+	}
+
+	delay := ScraperRetryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Do(req.Clone(ctx))
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			// Refused or looping redirects would go the same way again
+			if errors.Is(err, ErrURLNotAllowed) || errors.Is(err, ErrTooManyRedirects) || errors.Is(err, ErrRedirectLoop) {
+				return nil, err
+			}
+			if ctx.Err() != nil || attempt >= opts.MaxRetries {
+				return nil, fmt.Errorf("after %d attempts: %w", attempt+1, err)
+			}
+			slog.WarnContext(ctx, "scraper request failed, retrying", "url", url, "attempt", attempt+1, "error", err)
+		case isRetryableStatus(resp.StatusCode):
+			resp.Body.Close()
+			if attempt >= opts.MaxRetries {
+				return nil, &ScraperHTTPError{StatusCode: resp.StatusCode, URL: url, Retryable: true, Retries: attempt}
+			}
+			wait = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			slog.WarnContext(ctx, "scraper request got retryable status, retrying", "url", url, "status", resp.StatusCode, "attempt", attempt+1)
+		case resp.StatusCode == http.StatusOK || slices.Contains(opts.Accept, resp.StatusCode):
+			return resp, nil
+		default:
+			resp.Body.Close()
+			return nil, &ScraperHTTPError{StatusCode: resp.StatusCode, URL: url}
+		}
+
+		wait = min(max(wait, delay), ScraperMaxRetryDelay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+// Returns 0 if the header is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
 
-	log.Printf("Fetched page %d: found %d bills, hasNext=%v", pageNum, len(bills), hasNext)
+// Bill fields a listing label can map to in ScraperSelectors.Labels, named after
+// their JSON keys
+const (
+	BillFieldDate    = "date_introduced"
+	BillFieldChamber = "chamber"
+	BillFieldStatus  = "status"
+	BillFieldSponsor = "portfolio_sponsor"
+	BillFieldSummary = "summary"
+)
 
-	return bills, hasNext, nil
+// ScraperSelectors describes where ParseBillsHTML finds bills in a listing page.
+// Selectors are CSS selectors; Labels maps lowercased label text to a BillField.
+type ScraperSelectors struct {
+	Title     string            `json:"title"`      // Elements holding a bill's title link
+	TitleLink string            `json:"title_link"` // The title link within Title
+	Container string            `json:"container"`  // Closest ancestor of Title holding the bill's details
+	Details   string            `json:"details"`    // Label/value lists within Container
+	Label     string            `json:"label"`      // Label children of Details
+	Value     string            `json:"value"`      // Value children of Details, each following its label
+	Links     string            `json:"links"`      // Bill and Explanatory Memorandum links within Container
+	Labels    map[string]string `json:"labels"`
 }
 
-// ParseBillsHTML extracts bill information from the HTML document
-func ParseBillsHTML(doc *goquery.Document) ([]Bill, error) {
+// DefaultScraperSelectors returns the selectors for the current APH listing markup:
+// <li><div><h4><a>Title</a></h4></div><div><dl><dt>Label</dt><dd>Value</dd></dl><p><a>...</a></p></div></li>
+func DefaultScraperSelectors() ScraperSelectors {
+	return ScraperSelectors{
+		Title:     "h4",
+		TitleLink: "a",
+		Container: "li",
+		Details:   "dl",
+		Label:     "dt",
+		Value:     "dd",
+		Links:     "p a",
+		Labels: map[string]string{
+			"date":      BillFieldDate,
+			"chamber":   BillFieldChamber,
+			"status":    BillFieldStatus,
+			"portfolio": BillFieldSponsor,
+			"sponsor":   BillFieldSponsor,
+			"summary":   BillFieldSummary,
+		},
+	}
+}
+
+// WithDefaults returns sel with any blank selector, or missing Labels, taken from
+// DefaultScraperSelectors
+func (sel ScraperSelectors) WithDefaults() ScraperSelectors {
+	defaults := DefaultScraperSelectors()
+	for _, field := range []struct{ value, fallback *string }{
+		{&sel.Title, &defaults.Title},
+		{&sel.TitleLink, &defaults.TitleLink},
+		{&sel.Container, &defaults.Container},
+		{&sel.Details, &defaults.Details},
+		{&sel.Label, &defaults.Label},
+		{&sel.Value, &defaults.Value},
+		{&sel.Links, &defaults.Links},
+	} {
+		if strings.TrimSpace(*field.value) == "" {
+			*field.value = *field.fallback
+		}
+	}
+	if len(sel.Labels) == 0 {
+		sel.Labels = defaults.Labels
+	}
+	return sel
+}
+
+// Validate checks that every label maps to a known bill field
+func (sel ScraperSelectors) Validate() error {
+	var errs []error
+	for label, field := range sel.Labels {
+		switch field {
+		case BillFieldDate, BillFieldChamber, BillFieldStatus, BillFieldSponsor, BillFieldSummary:
+		default:
+			errs = append(errs, fmt.Errorf("label %q maps to unknown bill field %q", label, field))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseBillsHTML extracts bill information from the HTML document, locating each bill
+// with sel
+func ParseBillsHTML(doc *goquery.Document, sel ScraperSelectors) ([]Bill, error) {
 	var bills []Bill
 	scrapedAt := time.Now()
 
-	// Find all bill entries - they're in divs with h4 headers
-	doc.Find("h4").Each(func(i int, s *goquery.Selection) {
-		// Check if this h4 contains a bill title (has a link)
-		titleLink := s.Find("a").First()
+	// Find all bill entries - each has a title element holding a link
+	doc.Find(sel.Title).Each(func(i int, s *goquery.Selection) {
+		// Check if this title contains a bill link
+		titleLink := s.Find(sel.TitleLink).First()
 		if titleLink.Length() == 0 {
 			return // Skip if no link found
 		}
@@ -140,11 +572,6 @@ func ParseBillsHTML(doc *goquery.Document) ([]Bill, error) {
 			return
 		}
 
-		billID := extractBillID(href)
-		if billID == "" {
-			return // Skip if can't extract ID
-		}
-
 		// Extract title
 		title := strings.TrimSpace(titleLink.Text())
 		if title == "" {
@@ -154,44 +581,36 @@ func ParseBillsHTML(doc *goquery.Document) ([]Bill, error) {
 		// Store the bill URL from the title link
 		billTitleURL := normalizeURL(href)
 
-		// Navigate to parent container to find other bill details
-		// The <h4> is inside <div class="row">, which is inside <li>
+		// Navigate up to the container holding the bill's other details
+		// By default the <h4> is inside <div class="row">, which is inside <li>
 		// We need the <li> to find the sibling <div> containing <dl>
-		container := s.Parent().Parent()
+		container := s.Closest(sel.Container)
+		if container.Length() == 0 {
+			container = s.Parent()
+		}
 
 		// Extract bill details from the container
-		var dateIntroduced, chamber, status, portfolioSponsor, summary string
+		fields := make(map[string]string, len(sel.Labels))
 		var billURL, memoURL string
 
-		// Look for bill metadata in definition lists (dl/dt/dd structure)
-		// Format: <dl><dt>Label</dt><dd>Value</dd>...</dl>
-		container.Find("dl").Each(func(j int, dl *goquery.Selection) {
+		// Look for bill metadata in label/value lists
+		// Default format: <dl><dt>Label</dt><dd>Value</dd>...</dl>
+		container.Find(sel.Details).Each(func(j int, dl *goquery.Selection) {
 			var currentLabel string
 
-			// Iterate through all children of the <dl> tag
+			// Iterate through all children of the list
 			dl.Children().Each(func(k int, child *goquery.Selection) {
-				tagName := goquery.NodeName(child)
-
-				if tagName == "dt" {
-					// This is a definition term (label)
+				if child.Is(sel.Label) {
+					// This is a label
 					currentLabel = strings.ToLower(strings.TrimSpace(child.Text()))
-				} else if tagName == "dd" && currentLabel != "" {
-					// This is a definition (value) - clean up whitespace and &nbsp;
+				} else if child.Is(sel.Value) && currentLabel != "" {
+					// This is a value - clean up whitespace and &nbsp;
 					value := strings.TrimSpace(child.Text())
 					value = strings.TrimSpace(strings.ReplaceAll(value, "\u00a0", "")) // Remove &nbsp;
 
-					// Map label to appropriate field
-					switch currentLabel {
-					case "date":
-						dateIntroduced = value
-					case "chamber":
-						chamber = value
-					case "status":
-						status = value
-					case "portfolio", "sponsor":
-						portfolioSponsor = value
-					case "summary":
-						summary = value
+					// Map label to the bill field it fills
+					if field, ok := sel.Labels[currentLabel]; ok {
+						fields[field] = value
 					}
 
 					// Reset label after processing
@@ -199,10 +618,11 @@ func ParseBillsHTML(doc *goquery.Document) ([]Bill, error) {
 				}
 			})
 		})
+		dateIntroduced, chamber := fields[BillFieldDate], fields[BillFieldChamber]
 
 		// Extract links (Bill and Explanatory Memorandum)
 		// Look for links that specifically say "Bill" or "Explanatory Memorandum"
-		container.Find("p a").Each(func(j int, a *goquery.Selection) {
+		container.Find(sel.Links).Each(func(j int, a *goquery.Selection) {
 			linkText := strings.TrimSpace(a.Text())
 			linkHref, _ := a.Attr("href")
 
@@ -221,19 +641,31 @@ func ParseBillsHTML(doc *goquery.Document) ([]Bill, error) {
 			billURL = billTitleURL
 		}
 
+		// Bills whose link has no recognizable ID get one derived from their details,
+		// so the same bill keeps the same ID across scrapes
+		billID := extractBillID(href)
+		if billID == "" {
+			billID = stableBillID(title, chamber, dateIntroduced)
+		}
+
+		// Keep the display string as scraped; the parsed date stays zero if it is malformed
+		dateParsed, _ := ParseBillDate(dateIntroduced)
+
 		// Create bill object
 		bill := Bill{
-			ID:                 billID,
-			Title:              title,
-			DateIntroduced:     dateIntroduced,
-			Chamber:            chamber,
-			Status:             status,
-			PortfolioSponsor:   portfolioSponsor,
-			Summary:            summary,
-			BillURL:            billURL,
-			ExplanatoryMemoURL: memoURL,
-			ScrapedAt:          scrapedAt,
+			ID:                   billID,
+			Title:                title,
+			DateIntroduced:       dateIntroduced,
+			DateIntroducedParsed: dateParsed,
+			Chamber:              chamber,
+			Status:               fields[BillFieldStatus],
+			PortfolioSponsor:     fields[BillFieldSponsor],
+			Summary:              fields[BillFieldSummary],
+			BillURL:              billURL,
+			ExplanatoryMemoURL:   memoURL,
+			ScrapedAt:            scrapedAt,
 		}
+		bill.ContentHash = billContentHash(bill)
 
 		bills = append(bills, bill)
 	})
@@ -241,7 +673,272 @@ func ParseBillsHTML(doc *goquery.Document) ([]Bill, error) {
 	return bills, nil
 }
 
-// extractBillID extracts the bill ID from a URL
+// billDateLayouts are the date formats seen in APH bill listings, e.g. "03 Sep 2025"
+var billDateLayouts = []string{
+	"02 Jan 2006",
+	"2 Jan 2006",
+	"02 January 2006",
+	"2 January 2006",
+	"02/01/2006",
+	"2/1/2006",
+	"02-Jan-2006",
+	"2-Jan-2006",
+	"2006-01-02",
+}
+
+// ParseBillDate parses a bill date as displayed by APH, such as "03 Sep 2025", into
+// a UTC date. Surrounding and repeated whitespace is ignored.
+func ParseBillDate(s string) (time.Time, error) {
+	s = cleanText(s)
+	if s == "" {
+		return time.Time{}, errors.New("empty bill date")
+	}
+	for _, layout := range billDateLayouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized bill date %q", s)
+}
+
+// billDate returns the date a bill was introduced, and false if it is missing or
+// malformed. Bills cached before DateIntroducedParsed existed only carry the string.
+func billDate(bill Bill) (time.Time, bool) {
+	if !bill.DateIntroducedParsed.IsZero() {
+		return bill.DateIntroducedParsed, true
+	}
+	date, err := ParseBillDate(bill.DateIntroduced)
+	return date, err == nil
+}
+
+// BillFilter selects which bills FilterBills returns
+type BillFilter struct {
+	From time.Time // Only bills introduced on or after this date (zero for no lower bound)
+	To   time.Time // Only bills introduced on or before this date (zero for no upper bound)
+}
+
+// FilterBills returns the bills matching filter, keeping their order. While a date
+// bound is set, bills with a missing or malformed date are excluded.
+func FilterBills(bills []Bill, filter BillFilter) []Bill {
+	if filter.From.IsZero() && filter.To.IsZero() {
+		return bills
+	}
+
+	filtered := make([]Bill, 0, len(bills))
+	for _, bill := range bills {
+		date, ok := billDate(bill)
+		if !ok {
+			continue
+		}
+		if !filter.From.IsZero() && date.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && date.After(filter.To) {
+			continue
+		}
+		filtered = append(filtered, bill)
+	}
+	return filtered
+}
+
+// Bill sort fields accepted by SortBills
+const (
+	BillSortDate    = "date"
+	BillSortTitle   = "title"
+	BillSortChamber = "chamber"
+)
+
+// SortBills returns a copy of bills sorted by the given field (BillSortDate,
+// BillSortTitle or BillSortChamber), ascending unless descending is set. Titles and
+// chambers compare case-insensitively. Bills with a missing or malformed date sort
+// last in either direction. Ties keep their scrape order.
+func SortBills(bills []Bill, by string, descending bool) []Bill {
+	sorted := slices.Clone(bills)
+
+	var dates map[string]time.Time
+	if by == BillSortDate {
+		dates = make(map[string]time.Time, len(bills))
+		for _, bill := range bills {
+			if date, ok := billDate(bill); ok {
+				dates[bill.DateIntroduced] = date
+			}
+		}
+	}
+
+	slices.SortStableFunc(sorted, func(a, b Bill) int {
+		var cmp int
+		switch by {
+		case BillSortDate:
+			dateA, okA := dates[a.DateIntroduced]
+			dateB, okB := dates[b.DateIntroduced]
+			if !okA || !okB {
+				// Undated bills go last regardless of direction
+				return boolCompare(!okA, !okB)
+			}
+			cmp = dateA.Compare(dateB)
+		case BillSortTitle:
+			cmp = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		case BillSortChamber:
+			cmp = strings.Compare(strings.ToLower(a.Chamber), strings.ToLower(b.Chamber))
+		}
+		if descending {
+			return -cmp
+		}
+		return cmp
+	})
+
+	return sorted
+}
+
+// boolCompare orders false before true
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// BillDetailURL returns the ParlInfo page URL for a bill ID
+func BillDetailURL(billID string) string {
+	return BillDetailBaseURL + "?bId=" + url.QueryEscape(billID)
+}
+
+// FetchBillDetail fetches a bill's ParlInfo page (via BillURL) and extracts its full detail
+// Fields missing from the detail page fall back to the summary-level values in bill
+func FetchBillDetail(ctx context.Context, bill Bill) (*BillDetail, error) {
+	pageURL := bill.BillURL
+	if pageURL == "" {
+		pageURL = BillDetailURL(bill.ID)
+	}
+
+	resp, err := httpGetWithRetry(ctx, pageURL, scraperHeaders(), scraperGetOptions())
+	if err != nil {
+		var httpErr *ScraperHTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrBillNotFound, bill.ID)
+		}
+		return nil, fmt.Errorf("failed to fetch bill %s: %w", bill.ID, err)
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	return ParseBillDetailHTML(doc, bill), nil
+}
+
+// ParseBillDetailHTML extracts bill detail from a ParlInfo bill page
+// The page has a <dl> of bill metadata followed by "Summary", "Progress of bill"
+// and "Documents and transcripts" sections, each introduced by a heading
+func ParseBillDetailHTML(doc *goquery.Document, bill Bill) *BillDetail {
+	detail := &BillDetail{Bill: bill}
+	detail.ScrapedAt = time.Now()
+
+	if title := cleanText(doc.Find("h1").First().Text()); title != "" {
+		detail.Title = title
+	}
+
+	// Bill metadata in definition lists: <dl><dt>Label</dt><dd>Value</dd>...</dl>
+	doc.Find("dl").Each(func(i int, dl *goquery.Selection) {
+		dl.Find("dt").Each(func(j int, dt *goquery.Selection) {
+			value := cleanText(dt.NextFiltered("dd").Text())
+			if value == "" {
+				return
+			}
+
+			switch strings.ToLower(cleanText(dt.Text())) {
+			case "type":
+				detail.Type = value
+			case "sponsor", "sponsor(s)":
+				detail.Sponsor = value
+			case "originating house":
+				detail.OriginatingHouse = value
+			case "status":
+				detail.Status = value
+			case "portfolio":
+				detail.PortfolioSponsor = value
+			}
+		})
+	})
+
+	// Full summary: every paragraph under the "Summary" heading
+	var paragraphs []string
+	sectionAfterHeading(doc, "summary").Find("p").AddBackFiltered("p").Each(func(i int, p *goquery.Selection) {
+		if text := cleanText(p.Text()); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	})
+	detail.FullSummary = strings.Join(paragraphs, "\n\n")
+	if detail.FullSummary == "" {
+		detail.FullSummary = bill.Summary
+	}
+	if detail.Summary == "" && len(paragraphs) > 0 {
+		detail.Summary = paragraphs[0]
+	}
+
+	// Progress: header rows name the chamber, data rows give stage and date
+	detail.Progress = []BillProgressEvent{}
+	var chamber string
+	sectionAfterHeading(doc, "progress of bill").Find("tr").Each(func(i int, tr *goquery.Selection) {
+		cells := tr.Find("td")
+		if cells.Length() < 2 {
+			if header := cleanText(tr.Find("th").First().Text()); header != "" {
+				chamber = header
+			}
+			return
+		}
+
+		detail.Progress = append(detail.Progress, BillProgressEvent{
+			Chamber: chamber,
+			Stage:   cleanText(cells.Eq(0).Text()),
+			Date:    cleanText(cells.Eq(1).Text()),
+		})
+	})
+
+	detail.Stages = BillStages(detail.Progress)
+
+	// Links to the bill text, explanatory memoranda, digests and transcripts
+	detail.Links = []BillLink{}
+	sectionAfterHeading(doc, "documents and transcripts").Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		title := cleanText(a.Text())
+		if title == "" || strings.HasPrefix(href, "#") {
+			return
+		}
+
+		link := BillLink{Title: title, URL: normalizeURL(href)}
+		detail.Links = append(detail.Links, link)
+
+		if detail.ExplanatoryMemoURL == "" && strings.Contains(title, "Explanatory Memorandum") {
+			detail.ExplanatoryMemoURL = link.URL
+		}
+	})
+
+	return detail
+}
+
+// sectionAfterHeading returns the siblings following the first h2/h3 whose text is
+// title (case-insensitive), up to the next heading of either level
+func sectionAfterHeading(doc *goquery.Document, title string) *goquery.Selection {
+	heading := doc.Find("h2, h3").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return strings.EqualFold(cleanText(s.Text()), title)
+	}).First()
+
+	return heading.NextUntil("h2, h3")
+}
+
+// cleanText collapses whitespace (including &nbsp;) and trims the result
+func cleanText(text string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(text, "\u00a0", " ")), " ")
+}
+
+// extractBillID extracts the bill ID from a URL, or returns "" if it has none
 // Expected format: /Parliamentary_Business/Bills_Legislation/Bills_Search_Results/Result?bId=r7365
 func extractBillID(href string) string {
 	// Look for bId parameter in query string (e.g., "Result?bId=r7365")
@@ -258,8 +955,28 @@ func extractBillID(href string) string {
 		return matches[1]
 	}
 
-	// Last resort: use the entire URL as ID
-	return href
+	return ""
+}
+
+// stableBillID derives a short ID for a bill without a bId from its title, chamber
+// and introduction date, e.g. "h3f9a1c20b7d4". Case and whitespace are ignored so
+// cosmetic changes to the listing don't change the ID. The "h" prefix keeps these
+// apart from APH's own "r"/"s" IDs
+func stableBillID(title, chamber, dateIntroduced string) string {
+	key := strings.ToLower(strings.Join([]string{cleanText(title), cleanText(chamber), cleanText(dateIntroduced)}, "\x00"))
+	sum := sha256.Sum256([]byte(key))
+	return "h" + hex.EncodeToString(sum[:6])
+}
+
+// billContentHash hashes the fields a listing shows for a bill, so a change to any of
+// them (most often its status) can be detected without fetching its detail page
+func billContentHash(bill Bill) string {
+	key := strings.Join([]string{
+		bill.ID, bill.Title, bill.DateIntroduced, bill.Chamber, bill.Status,
+		bill.PortfolioSponsor, bill.Summary, bill.BillURL, bill.ExplanatoryMemoURL,
+	}, "\x00")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
 }
 
 // normalizeURL ensures URLs are absolute
@@ -281,6 +998,27 @@ func normalizeURL(href string) string {
 	return "https://www.aph.gov.au/" + href
 }
 
+// NormalizeContentURL returns a canonical form of rawURL for use as a cache key.
+// The scheme and host are lowercased, the fragment is dropped and a bare trailing
+// slash is removed, so trivially different spellings of a page share one entry.
+func NormalizeContentURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	if parsed.Path == "/" {
+		parsed.Path = ""
+	}
+
+	return parsed.String()
+}
+
 // HasNextPage checks if there's a next page link in the pagination
 func HasNextPage(doc *goquery.Document) bool {
 	// Look for pagination links
@@ -328,141 +1066,363 @@ func ExtractPaginationInfo(doc *goquery.Document) (currentPage int, totalPages i
 }
 
 // FetchAllBills fetches all bills across all pages
+// The first page reveals the total page count; the remaining pages are then fetched
+// concurrently by a bounded worker pool, rate limited to one request per
+// PageRequestDelay, and reassembled in page order. If a later page fails, the bills
+// before it are returned with an ErrPartialBills error.
 func FetchAllBills(ctx context.Context) ([]Bill, error) {
-	var allBills []Bill
-	pageNum := 1
+	slog.InfoContext(ctx, "starting to fetch all bills from APH website")
 
-	log.Println("Starting to fetch all bills from APH website...")
+	first, err := fetchBillsPage(ctx, 1)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch bills page", "page", 1, "error", err)
+		return nil, fmt.Errorf("failed to fetch first page: %w", err)
+	}
 
-	for {
-		// Check if context is cancelled
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
+	allBills := first.Bills
+	if !first.HasNext {
+		slog.InfoContext(ctx, "reached last bills page", "total", len(allBills))
+		return allBills, nil
+	}
 
-		// Fetch page
-		bills, hasNext, err := FetchBillsPage(ctx, pageNum)
-		if err != nil {
-			// Log error but continue with what we have
-			log.Printf("Error fetching page %d: %v", pageNum, err)
-			if pageNum == 1 {
-				// If first page fails, return error
-				return nil, fmt.Errorf("failed to fetch first page: %w", err)
+	// Token bucket: one request per PageRequestDelay, no bursts (unlimited when 0)
+	limiter := rate.NewLimiter(rate.Every(PageRequestDelay), 1)
+	limiter.Reserve() // Page 1 consumed the first token
+
+	totalPages := max(first.TotalPages, 2)
+	pages := make([]*billsPage, totalPages+1)
+	pageErrs := make([]error, totalPages+1)
+
+	var g errgroup.Group
+	g.SetLimit(BillsFetchConcurrency)
+	for pageNum := 2; pageNum <= totalPages; pageNum++ {
+		g.Go(func() error {
+			if err := limiter.Wait(ctx); err != nil {
+				pageErrs[pageNum] = err
+				return nil
 			}
-			// Otherwise, return bills we've collected so far
-			break
-		}
+			pages[pageNum], pageErrs[pageNum] = fetchBillsPage(ctx, pageNum)
+			return nil
+		})
+	}
+	g.Wait()
 
-		// Add bills to collection
-		allBills = append(allBills, bills...)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-		// Check if there are more pages
-		if !hasNext {
-			log.Printf("Reached last page. Total bills collected: %d", len(allBills))
-			break
+	// Reassemble in page order, stopping at the first failed page so the
+	// result is always a contiguous prefix of the listing
+	last := first
+	for pageNum := 2; pageNum <= totalPages; pageNum++ {
+		if pageErrs[pageNum] != nil {
+			slog.WarnContext(ctx, "failed to fetch bills page", "page", pageNum, "error", pageErrs[pageNum])
+			return allBills, fmt.Errorf("%w: page %d: %w", ErrPartialBills, pageNum, pageErrs[pageNum])
 		}
+		allBills = append(allBills, pages[pageNum].Bills...)
+		last = pages[pageNum]
+	}
 
-		// Increment page number
-		pageNum++
+	// Pagination links may be windowed; continue sequentially past the last known page
+	for pageNum := totalPages + 1; last.HasNext; pageNum++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
 
-		// Rate limiting: wait before next request
-		time.Sleep(PageRequestDelay)
+		page, err := fetchBillsPage(ctx, pageNum)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to fetch bills page", "page", pageNum, "error", err)
+			return allBills, fmt.Errorf("%w: page %d: %w", ErrPartialBills, pageNum, err)
+		}
+		allBills = append(allBills, page.Bills...)
+		last = page
 	}
 
+	slog.InfoContext(ctx, "reached last bills page", "total", len(allBills))
 	return allBills, nil
 }
 
-// CalculateTotalPages estimates total pages based on bill count
-// Assumes roughly 20 bills per page
-func CalculateTotalPages(billCount int) int {
-	if billCount == 0 {
+// CalculateTotalPages returns how many pages of pageSize bills it takes to list
+// count bills. There is always at least one page, even when empty, and a pageSize
+// of 0 or less puts every bill on a single page.
+func CalculateTotalPages(count, pageSize int) int {
+	if count == 0 || pageSize <= 0 {
 		return 1
 	}
-	pages := billCount / 20
-	if billCount%20 > 0 {
-		pages++
+	return (count + pageSize - 1) / pageSize
+}
+
+// PaginateBills returns the bills on the given 1-based page of pageSize bills, and
+// the total number of pages. Pages past the last are empty. A pageSize of 0 or less
+// puts every bill on page 1.
+func PaginateBills(bills []Bill, page, pageSize int) ([]Bill, int) {
+	totalPages := CalculateTotalPages(len(bills), pageSize)
+	if pageSize <= 0 {
+		if page != 1 {
+			return []Bill{}, totalPages
+		}
+		return bills, totalPages
+	}
+	start := (page - 1) * pageSize
+	if start >= len(bills) {
+		return []Bill{}, totalPages
 	}
-	return pages
+	return bills[start:min(start+pageSize, len(bills))], totalPages
 }
 
-// FetchURLContent fetches and extracts text content from a given URL
-// Returns the extracted text content, primarily for parliamentary documents
-func FetchURLContent(ctx context.Context, url string) (string, error) {
-	// Create HTTP request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// FetchURLContent fetches a page and extracts its readable article text
+// Boilerplate (navigation, scripts, headers, footers, sidebars) is stripped and the
+// main <article>/<main> content is returned as plain text along with the page title
+func FetchURLContent(ctx context.Context, pageURL string) (*FetchURLResult, error) {
+	// Set comprehensive headers to mimic a real browser and avoid bot detection.
+	// Accept-Encoding is left to the transport, which only then decompresses gzip
+	// bodies for us
+	header := http.Header{}
+	header.Set("User-Agent", BrowserUserAgent)
+	header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,application/pdf,image/webp,*/*;q=0.8")
+	header.Set("Accept-Language", "en-US,en;q=0.5")
+	header.Set("Upgrade-Insecure-Requests", "1")
+	header.Set("Cache-Control", "max-age=0")
+	header.Set("Referer", "https://www.aph.gov.au/")
+	header.Set("Sec-Fetch-Dest", "document")
+	header.Set("Sec-Fetch-Mode", "navigate")
+	header.Set("Sec-Fetch-Site", "same-site")
+	header.Set("Sec-Fetch-User", "?1")
+
+	// Execute request, retrying transient failures; redirects are followed, and
+	// checked against FetchURLPolicy like the URL itself
+	opts := scraperGetOptions()
+	opts.Policy = &FetchURLPolicy
+	resp, err := httpGetWithRetry(ctx, pageURL, header, opts)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set comprehensive headers to mimic a real browser and avoid bot detection
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Upgrade-Insecure-Requests", "1")
-	req.Header.Set("Cache-Control", "max-age=0")
-	req.Header.Set("Referer", "https://www.aph.gov.au/")
-	req.Header.Set("Sec-Fetch-Dest", "document")
-	req.Header.Set("Sec-Fetch-Mode", "navigate")
-	req.Header.Set("Sec-Fetch-Site", "same-site")
-	req.Header.Set("Sec-Fetch-User", "?1")
-
-	// Create HTTP client with timeout and follow redirects
-	client := &http.Client{
-		Timeout: ScraperTimeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Allow up to 10 redirects
-			if len(via) >= 10 {
-				return fmt.Errorf("stopped after 10 redirects")
-			}
-			return nil
-		},
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
 	}
+	defer resp.Body.Close()
 
-	// Execute request
-	resp, err := client.Do(req)
+	// Only pages, plain text and PDFs have text worth extracting; refuse anything
+	// else before downloading it
+	kind, err := fetchedContentKind(resp)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	data, err := readFetchedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report the final URL after any redirects
+	finalURL := resp.Request.URL
+
+	var result *FetchURLResult
+	switch kind {
+	case contentKindPDF:
+		// Explanatory memoranda and bill documents are frequently PDFs
+		result, err = ExtractPDFText(data)
+		if err != nil {
+			return nil, err
+		}
+		if result.Title == "" {
+			result.Title = path.Base(finalURL.Path)
+		}
+	case contentKindText:
+		result = &FetchURLResult{Title: path.Base(finalURL.Path), Text: strings.TrimSpace(string(data))}
+	default:
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		result = ExtractReadableContent(doc)
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("no content extracted from URL")
+	}
+
+	result.URL = finalURL.String()
+	return result, nil
+}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// Kinds of fetched content FetchURLContent extracts text from
+const (
+	contentKindHTML = "html"
+	contentKindText = "text"
+	contentKindPDF  = "pdf"
+)
+
+// fetchedContentKind classifies a response by its Content-Type, or as a PDF by its URL
+// suffix when served with a generic type. A missing Content-Type is taken to be HTML.
+// Anything else, such as images, video or archives, is ErrUnsupportedContentType.
+func fetchedContentKind(resp *http.Response) (string, error) {
+	if isPDFResponse(resp) {
+		return contentKindPDF, nil
 	}
 
-	// Parse HTML
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return contentKindHTML, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse HTML: %w", err)
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		return contentKindHTML, nil
+	case "text/plain":
+		return contentKindText, nil
 	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedContentType, mediaType)
+}
 
-	// Extract text content - focus on main content areas
-	// For ParlInfo pages, the content is typically in specific divs
-	var textContent strings.Builder
+// isPDFResponse reports whether resp carries a PDF, by content type or URL suffix
+func isPDFResponse(resp *http.Response) bool {
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == "application/pdf" {
+		return true
+	}
+	return strings.HasSuffix(strings.ToLower(resp.Request.URL.Path), ".pdf")
+}
 
-	// Try to find main content area (adjust selectors based on actual page structure)
-	doc.Find("body").Each(func(i int, s *goquery.Selection) {
-		// Remove script and style elements
-		s.Find("script, style, nav, header, footer").Remove()
+// readFetchedBody reads a fetched response body, failing with ErrContentTooLarge as
+// soon as it is known to exceed MaxFetchURLSize
+func readFetchedBody(resp *http.Response) ([]byte, error) {
+	limit := MaxFetchURLSize
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the size limit of %d bytes", ErrContentTooLarge, resp.ContentLength, limit)
+	}
 
-		// Get text content
-		text := s.Text()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: exceeds the size limit of %d bytes", ErrContentTooLarge, limit)
+	}
+	return data, nil
+}
 
-		// Clean up whitespace
-		text = strings.TrimSpace(text)
-		text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
+// ExtractPDFText extracts the document title and plain text from PDF data
+// Lines are reassembled from glyph positions and pages are separated by blank lines
+func ExtractPDFText(data []byte) (result *FetchURLResult, err error) {
+	// The PDF library panics on some malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
 
-		textContent.WriteString(text)
-	})
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
 
-	content := textContent.String()
-	if content == "" {
-		return "", fmt.Errorf("no content extracted from URL")
+	result = &FetchURLResult{
+		Title: strings.TrimSpace(reader.Trailer().Key("Info").Key("Title").Text()),
 	}
 
-	return content, nil
+	var pages []string
+	for i := 1; i <= reader.NumPage(); i++ {
+		var lines []string
+		var line strings.Builder
+		lastY := math.NaN()
+
+		flush := func() {
+			if text := strings.Join(strings.Fields(line.String()), " "); text != "" {
+				lines = append(lines, text)
+			}
+			line.Reset()
+		}
+
+		for _, text := range reader.Page(i).Content().Text {
+			if text.Y != lastY {
+				flush()
+				lastY = text.Y
+			}
+			line.WriteString(text.S)
+		}
+		flush()
+
+		if len(lines) > 0 {
+			pages = append(pages, strings.Join(lines, "\n"))
+		}
+	}
+	result.Text = strings.Join(pages, "\n\n")
+
+	return result, nil
+}
+
+// boilerplateSelector matches page chrome that is never part of the article body
+const boilerplateSelector = "script, style, noscript, template, iframe, svg, form, nav, header, footer, aside, " +
+	"[role=navigation], [role=banner], [role=contentinfo], [role=complementary], [aria-hidden=true]"
+
+// mainContentSelectors are tried in order to locate the primary content of a page
+var mainContentSelectors = []string{"article", "main", "[role=main]", "#content", "#main-content", ".content"}
+
+// blockElements start a new paragraph when rendering text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "ul": true, "ol": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "table": true, "tr": true, "br": true,
+	"figcaption": true, "address": true,
+}
+
+// ExtractReadableContent performs a readability pass over a parsed page and returns
+// its title and main text content. Paragraph breaks are preserved as blank lines.
+func ExtractReadableContent(doc *goquery.Document) *FetchURLResult {
+	result := &FetchURLResult{Title: extractPageTitle(doc)}
+
+	// Strip boilerplate before choosing the content root
+	doc.Find(boilerplateSelector).Remove()
+
+	content := doc.Find("body")
+	for _, selector := range mainContentSelectors {
+		if candidate := doc.Find(selector).First(); strings.TrimSpace(candidate.Text()) != "" {
+			content = candidate
+			break
+		}
+	}
+
+	var raw strings.Builder
+	for _, node := range content.Nodes {
+		writeNodeText(&raw, node)
+	}
+
+	var paragraphs []string
+	for _, line := range strings.Split(raw.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	result.Text = strings.Join(paragraphs, "\n\n")
+
+	return result
+}
+
+// extractPageTitle prefers the Open Graph title, then <title>, then the first <h1>
+func extractPageTitle(doc *goquery.Document) string {
+	if title, ok := doc.Find(`meta[property="og:title"]`).Attr("content"); ok && strings.TrimSpace(title) != "" {
+		return strings.TrimSpace(title)
+	}
+	if title := strings.TrimSpace(doc.Find("title").First().Text()); title != "" {
+		return title
+	}
+	return strings.Join(strings.Fields(doc.Find("h1").First().Text()), " ")
+}
+
+// writeNodeText appends the text of n to b, surrounding block elements with newlines
+func writeNodeText(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		// Source line breaks are layout, not paragraph boundaries
+		b.WriteString(strings.ReplaceAll(n.Data, "\n", " "))
+		return
+	case html.ElementNode:
+		if blockElements[n.Data] {
+			b.WriteString("\n")
+			defer b.WriteString("\n")
+		}
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		writeNodeText(b, child)
+	}
 }
@@ -0,0 +1,1446 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// TestNormalizeContentURL tests cache key normalization for fetched URLs
+func TestNormalizeContentURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://Example.COM/Path?q=1", "https://example.com/Path?q=1"},
+		{"  https://example.com/page#section  ", "https://example.com/page"},
+		{"HTTPS://example.com/", "https://example.com"},
+		{"https://example.com", "https://example.com"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := NormalizeContentURL(tt.input); got != tt.expected {
+				t.Errorf("NormalizeContentURL(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestExtractReadableContent tests that boilerplate is stripped from a sample article
+func TestExtractReadableContent(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "article.html"))
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+
+	result := ExtractReadableContent(doc)
+
+	if result.Title != "Budget Measures Explained" {
+		t.Errorf("Title = %q, want 'Budget Measures Explained'", result.Title)
+	}
+
+	expected := strings.Join([]string{
+		"Budget Measures Explained",
+		"The Treasurer has announced a package of measures aimed at easing cost-of-living pressures.",
+		"The changes include an expanded energy rebate and indexation of the low-income tax offset.",
+		"Energy rebate of $300 per household",
+		"Low-income tax offset indexed to inflation",
+	}, "\n\n")
+	if result.Text != expected {
+		t.Errorf("Text =\n%s\n\nwant\n%s", result.Text, expected)
+	}
+
+	for _, boilerplate := range []string{"Politics", "Trending now", "Subscribe", "Copyright", "analytics", "trackArticleView", "Join"} {
+		if strings.Contains(result.Text, boilerplate) {
+			t.Errorf("Text should not contain boilerplate %q", boilerplate)
+		}
+	}
+}
+
+// TestExtractReadableContentFallbacks tests title and content fallbacks without <article>
+func TestExtractReadableContentFallbacks(t *testing.T) {
+	page := `<html><body>
+		<nav>Home | About</nav>
+		<h1>Plain   Page</h1>
+		<div>First block</div><div>Second<br>line</div>
+	</body></html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Failed to parse page: %v", err)
+	}
+
+	result := ExtractReadableContent(doc)
+
+	if result.Title != "Plain Page" {
+		t.Errorf("Title = %q, want 'Plain Page'", result.Title)
+	}
+	expected := "Plain Page\n\nFirst block\n\nSecond\n\nline"
+	if result.Text != expected {
+		t.Errorf("Text = %q, want %q", result.Text, expected)
+	}
+}
+
+// TestFetchURLContent tests fetching a page and reporting its final URL
+func TestFetchURLContent(t *testing.T) {
+	AllowLoopbackFetches(t)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", "article.html"))
+	}))
+	defer mockServer.Close()
+
+	result, err := FetchURLContent(context.Background(), mockServer.URL+"/old")
+	if err != nil {
+		t.Fatalf("FetchURLContent failed: %v", err)
+	}
+
+	if result.URL != mockServer.URL+"/article" {
+		t.Errorf("URL = %q, want final redirected URL", result.URL)
+	}
+	if result.Title != "Budget Measures Explained" || !strings.HasPrefix(result.Text, "Budget Measures Explained") {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+// TestFetchURLContentGzip tests that gzip-encoded pages are decompressed before parsing
+func TestFetchURLContentGzip(t *testing.T) {
+	AllowLoopbackFetches(t)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Errorf("Accept-Encoding = %q, want the transport's gzip", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		fmt.Fprint(gz, `<html><head><title>Compressed Page</title></head><body><main><p>Served with gzip.</p></main></body></html>`)
+	}))
+	defer mockServer.Close()
+
+	result, err := FetchURLContent(context.Background(), mockServer.URL)
+	if err != nil {
+		t.Fatalf("FetchURLContent failed: %v", err)
+	}
+	if result.Title != "Compressed Page" || !strings.Contains(result.Text, "Served with gzip.") {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+// TestFetchURLContentTypes tests which content types are extracted and the download
+// size limit
+func TestFetchURLContentTypes(t *testing.T) {
+	AllowLoopbackFetches(t)
+
+	oldMaxSize := MaxFetchURLSize
+	defer func() { MaxFetchURLSize = oldMaxSize }()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, "  Plain notes on the bill.\n")
+		case "/chart.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		case "/video":
+			w.Header().Set("Content-Type", "video/mp4")
+		case "/streamed":
+			// No Content-Length: the limit must be enforced while reading
+			w.Header().Set("Content-Type", "text/html")
+			w.(http.Flusher).Flush()
+			for range 64 {
+				fmt.Fprint(w, "<p>Padding paragraph of streamed content.</p>")
+			}
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><body><p>A page with some text.</p></body></html>")
+		}
+	}))
+	defer mockServer.Close()
+
+	t.Run("plain text", func(t *testing.T) {
+		result, err := FetchURLContent(context.Background(), mockServer.URL+"/notes.txt")
+		if err != nil {
+			t.Fatalf("FetchURLContent failed: %v", err)
+		}
+		if result.Text != "Plain notes on the bill." || result.Title != "notes.txt" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	for _, path := range []string{"/chart.png", "/video"} {
+		t.Run("rejects "+path, func(t *testing.T) {
+			_, err := FetchURLContent(context.Background(), mockServer.URL+path)
+			if !errors.Is(err, ErrUnsupportedContentType) {
+				t.Errorf("Expected ErrUnsupportedContentType, got %v", err)
+			}
+		})
+	}
+
+	t.Run("rejects oversized body", func(t *testing.T) {
+		MaxFetchURLSize = 16
+		defer func() { MaxFetchURLSize = oldMaxSize }()
+
+		if _, err := FetchURLContent(context.Background(), mockServer.URL+"/page"); !errors.Is(err, ErrContentTooLarge) {
+			t.Errorf("Expected ErrContentTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("rejects oversized body without a length", func(t *testing.T) {
+		MaxFetchURLSize = 1024
+		defer func() { MaxFetchURLSize = oldMaxSize }()
+
+		if _, err := FetchURLContent(context.Background(), mockServer.URL+"/streamed"); !errors.Is(err, ErrContentTooLarge) {
+			t.Errorf("Expected ErrContentTooLarge, got %v", err)
+		}
+	})
+}
+
+// TestExtractPDFText tests text extraction from a small PDF fixture
+func TestExtractPDFText(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "memo.pdf"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	result, err := ExtractPDFText(data)
+	if err != nil {
+		t.Fatalf("ExtractPDFText failed: %v", err)
+	}
+
+	if result.Title != "Clean Energy Amendment Bill 2025 - Explanatory Memorandum" {
+		t.Errorf("Title = %q", result.Title)
+	}
+
+	expected := "Explanatory Memorandum\nClean Energy Amendment Bill 2025\nThis Bill amends the energy rebate scheme.\n\nFinancial impact: nil."
+	if result.Text != expected {
+		t.Errorf("Text = %q, want %q", result.Text, expected)
+	}
+
+	if _, err := ExtractPDFText([]byte("not a pdf")); err == nil {
+		t.Error("Expected error for invalid PDF data")
+	}
+}
+
+// TestFetchURLContentPDF tests PDF detection and the PDF size limit
+func TestFetchURLContentPDF(t *testing.T) {
+	AllowLoopbackFetches(t)
+
+	oldMaxSize := MaxFetchURLSize
+	defer func() { MaxFetchURLSize = oldMaxSize }()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := os.ReadFile(filepath.Join("testdata", "memo.pdf"))
+		if r.URL.Path == "/memo" {
+			w.Header().Set("Content-Type", "application/pdf")
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		w.Write(data)
+	}))
+	defer mockServer.Close()
+
+	for _, path := range []string{"/memo", "/files/memo.PDF"} {
+		t.Run("detects PDF at "+path, func(t *testing.T) {
+			result, err := FetchURLContent(context.Background(), mockServer.URL+path)
+			if err != nil {
+				t.Fatalf("FetchURLContent failed: %v", err)
+			}
+			if !strings.HasPrefix(result.Text, "Explanatory Memorandum\n") {
+				t.Errorf("Unexpected text: %q", result.Text)
+			}
+			if result.URL != mockServer.URL+path {
+				t.Errorf("URL = %q", result.URL)
+			}
+		})
+	}
+
+	t.Run("rejects oversized PDF", func(t *testing.T) {
+		MaxFetchURLSize = 16
+
+		_, err := FetchURLContent(context.Background(), mockServer.URL+"/memo")
+		if !errors.Is(err, ErrContentTooLarge) || !strings.Contains(err.Error(), "size limit") {
+			t.Errorf("Expected size limit error, got %v", err)
+		}
+	})
+}
+
+// TestParseBillDetailHTML tests parsing a saved bill detail page fixture
+func TestParseBillDetailHTML(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "bill_detail.html"))
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+
+	bill := Bill{
+		ID:             "r7365",
+		Title:          "Clean Energy Amendment Bill",
+		DateIntroduced: "03 Sep 2025",
+		Chamber:        "House of Representatives",
+		Status:         "Before House of Representatives",
+		Summary:        "Amends the Clean Energy Act 2011.",
+	}
+
+	detail := ParseBillDetailHTML(doc, bill)
+
+	if detail.ID != "r7365" || detail.DateIntroduced != "03 Sep 2025" {
+		t.Errorf("Expected list fields to be preserved, got %+v", detail.Bill)
+	}
+	if detail.Title != "Clean Energy Amendment Bill 2025" {
+		t.Errorf("Title = %q", detail.Title)
+	}
+	if detail.Type != "Government" || detail.Sponsor != "Bowen, Chris, MP" || detail.OriginatingHouse != "House of Representatives" {
+		t.Errorf("Unexpected metadata: type=%q sponsor=%q house=%q", detail.Type, detail.Sponsor, detail.OriginatingHouse)
+	}
+	if detail.Status != "Before Senate" {
+		t.Errorf("Status = %q, want detail page status 'Before Senate'", detail.Status)
+	}
+	if detail.PortfolioSponsor != "Climate Change, Energy, the Environment and Water" {
+		t.Errorf("PortfolioSponsor = %q", detail.PortfolioSponsor)
+	}
+	if detail.Summary != bill.Summary {
+		t.Errorf("Summary = %q, want list summary preserved", detail.Summary)
+	}
+
+	expectedSummary := "Amends the Clean Energy Act 2011 to: expand the household energy rebate.\n\nAlso makes consequential amendments to two Acts."
+	if detail.FullSummary != expectedSummary {
+		t.Errorf("FullSummary = %q, want %q", detail.FullSummary, expectedSummary)
+	}
+
+	expectedProgress := []BillProgressEvent{
+		{Chamber: "House of Representatives", Stage: "Introduced and read a first time", Date: "03 Sep 2025"},
+		{Chamber: "House of Representatives", Stage: "Third reading agreed to", Date: "10 Sep 2025"},
+		{Chamber: "Senate", Stage: "Introduced and read a first time", Date: "11 Sep 2025"},
+	}
+	if !reflect.DeepEqual(detail.Progress, expectedProgress) {
+		t.Errorf("Progress = %+v, want %+v", detail.Progress, expectedProgress)
+	}
+
+	if len(detail.Links) != 3 {
+		t.Fatalf("Expected 3 links, got %d: %+v", len(detail.Links), detail.Links)
+	}
+	if detail.Links[2].URL != "https://www.aph.gov.au/Parliamentary_Business/Bills_Legislation/Bills_Digests/r7365" {
+		t.Errorf("Expected relative link to be made absolute, got %q", detail.Links[2].URL)
+	}
+	if detail.ExplanatoryMemoURL != detail.Links[1].URL {
+		t.Errorf("ExplanatoryMemoURL = %q, want %q", detail.ExplanatoryMemoURL, detail.Links[1].URL)
+	}
+}
+
+// TestParseBillDetailHTMLStages tests classifying a passed bill's progress into stages
+func TestParseBillDetailHTMLStages(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "bill_detail_passed.html"))
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+
+	detail := ParseBillDetailHTML(doc, Bill{ID: "r7301"})
+
+	date := func(day int, month time.Month) time.Time {
+		return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	const reps, senate, both = "House of Representatives", "Senate", "Finally passed both Houses"
+	expected := []BillStage{
+		{BillStageIntroduced, "Introduced and read a first time", reps, "04 Jun 2025", date(4, time.June)},
+		{BillStageSecondReading, "Second reading moved", reps, "04 Jun 2025", date(4, time.June)},
+		{BillStageSecondReading, "Second reading agreed to", reps, "18 Jun 2025", date(18, time.June)},
+		{BillStageCommittee, "Consideration in detail", reps, "18 Jun 2025", date(18, time.June)},
+		{BillStageThirdReading, "Third reading agreed to", reps, "18 Jun 2025", date(18, time.June)},
+		{BillStageIntroduced, "Introduced and read a first time", senate, "19 Jun 2025", date(19, time.June)},
+		{BillStageCommittee, "Referred to Committee", senate, "19 Jun 2025", date(19, time.June)},
+		{BillStageThirdReading, "Third reading agreed to", senate, "2 Jul 2025", date(2, time.July)},
+		{BillStagePassed, "Passed both Houses", both, "02 Jul 2025", date(2, time.July)},
+		{BillStageAssent, "Assent", both, "09 Jul 2025", date(9, time.July)},
+		{BillStageAssent, "Act no:", both, "To be confirmed", time.Time{}},
+	}
+	if !reflect.DeepEqual(detail.Stages, expected) {
+		t.Errorf("Stages =\n%+v\nwant\n%+v", detail.Stages, expected)
+	}
+	if len(detail.Progress) != len(expected) {
+		t.Errorf("Expected the raw progress rows to be kept, got %d", len(detail.Progress))
+	}
+
+	if got := classifyBillStage("Message from Senate"); got != BillStageOther {
+		t.Errorf("classifyBillStage(unrecognized) = %q, want %q", got, BillStageOther)
+	}
+}
+
+// TestFetchBillDetail tests fetching a bill page and handling a missing bill
+func TestFetchBillDetail(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("bId") != "r7365" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", "bill_detail.html"))
+	}))
+	defer mockServer.Close()
+
+	detail, err := FetchBillDetail(context.Background(), Bill{ID: "r7365", BillURL: mockServer.URL + "/Result?bId=r7365"})
+	if err != nil {
+		t.Fatalf("FetchBillDetail failed: %v", err)
+	}
+	if detail.Sponsor != "Bowen, Chris, MP" || len(detail.Progress) != 3 {
+		t.Errorf("Unexpected detail: %+v", detail)
+	}
+
+	_, err = FetchBillDetail(context.Background(), Bill{ID: "s1", BillURL: mockServer.URL + "/Result?bId=s1"})
+	if !errors.Is(err, ErrBillNotFound) {
+		t.Errorf("Expected ErrBillNotFound, got %v", err)
+	}
+}
+
+// TestFetchBillsPageConfiguredURL tests fetching listing pages from an overridden
+// base URL and page query
+func TestFetchBillsPageConfiguredURL(t *testing.T) {
+	oldBaseURL, oldPageQuery := BillsBaseURL, BillsPageQuery
+	defer func() { BillsBaseURL, BillsPageQuery = oldBaseURL, oldPageQuery }()
+
+	var requested []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RequestURI())
+		page, _ := os.ReadFile(filepath.Join("testdata", "bills_page.html"))
+		w.Write(page)
+	}))
+	defer mockServer.Close()
+
+	BillsBaseURL = mockServer.URL + "/bills/current"
+	BillsPageQuery = "p=%d&ps=50"
+
+	for _, pageNum := range []int{1, 2} {
+		bills, _, err := FetchBillsPage(context.Background(), pageNum)
+		if err != nil {
+			t.Fatalf("FetchBillsPage(%d) failed: %v", pageNum, err)
+		}
+		if len(bills) == 0 {
+			t.Errorf("FetchBillsPage(%d) returned no bills", pageNum)
+		}
+	}
+
+	want := []string{"/bills/current", "/bills/current?p=2&ps=50"}
+	if !reflect.DeepEqual(requested, want) {
+		t.Errorf("Requested %v, want %v", requested, want)
+	}
+}
+
+// TestFetchBillsPageIdentity tests the User-Agent and From headers sent to APH
+func TestFetchBillsPageIdentity(t *testing.T) {
+	oldBaseURL, oldStore := BillsBaseURL, pageValidatorStore
+	oldAgent, oldContact, oldSpoof := ScraperUserAgent, ScraperContact, ScraperSpoofBrowser
+	defer func() {
+		BillsBaseURL, pageValidatorStore = oldBaseURL, oldStore
+		ScraperUserAgent, ScraperContact, ScraperSpoofBrowser = oldAgent, oldContact, oldSpoof
+	}()
+	pageValidatorStore = nil
+
+	var header http.Header
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		page, _ := os.ReadFile(filepath.Join("testdata", "bills_page.html"))
+		w.Write(page)
+	}))
+	defer mockServer.Close()
+	BillsBaseURL = mockServer.URL
+
+	tests := []struct {
+		name      string
+		agent     string
+		contact   string
+		spoof     bool
+		wantAgent string
+		wantFrom  string
+	}{
+		{"default", UserAgent, "", false, UserAgent, ""},
+		{"configured", "BillsWatch/2.0", "ops@example.org", false, "BillsWatch/2.0", "ops@example.org"},
+		{"spoofed browser", "BillsWatch/2.0", "ops@example.org", true, BrowserUserAgent, "ops@example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ScraperUserAgent, ScraperContact, ScraperSpoofBrowser = tt.agent, tt.contact, tt.spoof
+
+			if _, _, err := FetchBillsPage(context.Background(), 1); err != nil {
+				t.Fatalf("FetchBillsPage failed: %v", err)
+			}
+			if got := header.Get("User-Agent"); got != tt.wantAgent {
+				t.Errorf("User-Agent = %q, want %q", got, tt.wantAgent)
+			}
+			if got := header.Get("From"); got != tt.wantFrom {
+				t.Errorf("From = %q, want %q", got, tt.wantFrom)
+			}
+		})
+	}
+}
+
+// TestFetchBillsPageUnexpectedLayout tests that a page with no bills is only treated as
+// an empty listing when it still looks like one
+func TestFetchBillsPageUnexpectedLayout(t *testing.T) {
+	oldBaseURL, oldStore := BillsBaseURL, pageValidatorStore
+	defer func() { BillsBaseURL, pageValidatorStore = oldBaseURL, oldStore }()
+	pageValidatorStore = nil
+
+	tests := []struct {
+		name    string
+		html    string
+		wantErr bool
+	}{
+		{"empty results list", billsListingHTML(nil, 0, false), false},
+		{"no results message", `<html><body><main><p>Your search returned no results.</p></main></body></html>`, false},
+		{"redesigned layout", `<html><body><div class="bill-card"><h3><a href="/bills/r7365">Clean Energy Amendment Bill 2025</a></h3></div></body></html>`, true},
+		{"blank page", `<html><body></body></html>`, true},
+		{"results list without headings", `<html><body><ul class="search-filter-results"><li><div class="bill-title"><a href="/Result?bId=r7365">Clean Energy Amendment Bill 2025</a></div></li></ul></body></html>`, true},
+		{"nonzero result count", `<html><body><p>Showing 1-20 of 120 results</p><div class="bill-card">Clean Energy Amendment Bill 2025</div></body></html>`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.html)
+			}))
+			defer server.Close()
+			BillsBaseURL = server.URL
+
+			bills, hasNext, err := FetchBillsPage(context.Background(), 1)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnexpectedBillsPage) {
+					t.Fatalf("Expected ErrUnexpectedBillsPage, got %v", err)
+				}
+				if !strings.Contains(err.Error(), server.URL) {
+					t.Errorf("Error should name the fetched URL, got %v", err)
+				}
+				return
+			}
+			if err != nil || len(bills) != 0 || hasNext {
+				t.Errorf("Expected an empty listing, got %d bills (hasNext=%v, err=%v)", len(bills), hasNext, err)
+			}
+		})
+	}
+
+	// FetchAllBills fails rather than returning an empty list that would be cached
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	}))
+	defer server.Close()
+	BillsBaseURL = server.URL
+	if bills, err := FetchAllBills(context.Background()); !errors.Is(err, ErrUnexpectedBillsPage) {
+		t.Errorf("FetchAllBills() = %d bills, %v; want ErrUnexpectedBillsPage", len(bills), err)
+	}
+}
+
+// TestFetchBillsPageConditional tests ETag/Last-Modified revalidation of listing pages
+func TestFetchBillsPageConditional(t *testing.T) {
+	oldBaseURL := BillsBaseURL
+	oldStore := pageValidatorStore
+	defer func() {
+		BillsBaseURL = oldBaseURL
+		pageValidatorStore = oldStore
+	}()
+
+	const etag = `"page-1-v1"`
+	const lastModified = "Wed, 03 Sep 2025 10:00:00 GMT"
+
+	var requests, notModified int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		page, _ := os.ReadFile(filepath.Join("testdata", "bills_page.html"))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write(page)
+	}))
+	defer mockServer.Close()
+
+	BillsBaseURL = mockServer.URL
+	storePath := filepath.Join(t.TempDir(), "page_validators.json")
+	pageValidatorStore = NewPageValidatorStore(storePath)
+
+	first, hasNext, err := FetchBillsPage(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("First fetch failed: %v", err)
+	}
+	if len(first) != 2 || !hasNext {
+		t.Fatalf("Expected 2 bills with a next page, got %d (hasNext=%v)", len(first), hasNext)
+	}
+
+	second, hasNext, err := FetchBillsPage(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Second fetch failed: %v", err)
+	}
+	if notModified != 1 {
+		t.Errorf("Expected second request to be answered with 304, got %d of %d requests", notModified, requests)
+	}
+	if !reflect.DeepEqual(second, first) || !hasNext {
+		t.Errorf("Expected cached bills to be reused on 304, got %+v (hasNext=%v)", second, hasNext)
+	}
+
+	// Validators survive a restart
+	reloaded, ok := NewPageValidatorStore(storePath).Get(1)
+	if !ok || reloaded.ETag != etag || reloaded.LastModified != lastModified || len(reloaded.Bills) != 2 {
+		t.Errorf("Expected validators persisted to disk, got %+v (ok=%v)", reloaded, ok)
+	}
+}
+
+// billsListingHTML renders a minimal listing page in the APH markup ParseBillsHTML expects
+// Pagination links are shown for pages 1..linkedPages, plus Next when hasNext is set
+func billsListingHTML(ids []string, linkedPages int, hasNext bool) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><ul class="search-filter-results">`)
+	for _, id := range ids {
+		fmt.Fprintf(&b, `<li><div class="row"><h4><a href="/Result?bId=%s">Bill %s</a></h4></div>`, id, id)
+		b.WriteString(`<div class="row"><dl><dt>Status</dt><dd>Before Senate</dd></dl></div></li>`)
+	}
+	b.WriteString(`</ul><ul class="pagination">`)
+	for i := 1; i <= linkedPages; i++ {
+		fmt.Fprintf(&b, `<li><a href="?page=%d">%d</a></li>`, i, i)
+	}
+	if hasNext {
+		b.WriteString(`<li><a href="?page=next">Next</a></li>`)
+	}
+	b.WriteString("</ul></body></html>")
+	return b.String()
+}
+
+// TestFetchAllBillsConcurrent tests concurrent page fetching with ordering preserved
+func TestFetchAllBillsConcurrent(t *testing.T) {
+	oldBaseURL := BillsBaseURL
+	oldStore := pageValidatorStore
+	oldBackoff := ScraperRetryBackoff
+	defer func() {
+		BillsBaseURL = oldBaseURL
+		pageValidatorStore = oldStore
+		ScraperRetryBackoff = oldBackoff
+	}()
+	pageValidatorStore = nil
+	ScraperRetryBackoff = 10 * time.Millisecond
+
+	pageBills := map[int][]string{
+		1: {"r1", "r2"},
+		2: {"r3", "r4"},
+		3: {"r5"},
+	}
+
+	newServer := func(linkedPages int, failPage int) (*httptest.Server, *[]time.Time) {
+		var mu sync.Mutex
+		var requestTimes []time.Time
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requestTimes = append(requestTimes, time.Now())
+			mu.Unlock()
+
+			page := 1
+			if p := r.URL.Query().Get("page"); p != "" {
+				page, _ = strconv.Atoi(p)
+			}
+			if page == failPage {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			// Earlier pages respond more slowly so completion order differs from page order
+			time.Sleep(time.Duration(len(pageBills)-page) * 50 * time.Millisecond)
+			fmt.Fprint(w, billsListingHTML(pageBills[page], linkedPages, page < len(pageBills)))
+		}))
+		return server, &requestTimes
+	}
+
+	billIDs := func(bills []Bill) []string {
+		ids := make([]string, len(bills))
+		for i, bill := range bills {
+			ids[i] = bill.ID
+		}
+		return ids
+	}
+
+	t.Run("all pages collected in order", func(t *testing.T) {
+		server, requestTimes := newServer(3, 0)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		bills, err := FetchAllBills(context.Background())
+		if err != nil {
+			t.Fatalf("FetchAllBills failed: %v", err)
+		}
+
+		expected := []string{"r1", "r2", "r3", "r4", "r5"}
+		if got := billIDs(bills); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Bill IDs = %v, want %v", got, expected)
+		}
+		if len(*requestTimes) != 3 {
+			t.Fatalf("Expected 3 page requests, got %d", len(*requestTimes))
+		}
+
+		// The token bucket spaces requests out even though pages are fetched concurrently
+		for i := 1; i < len(*requestTimes); i++ {
+			if gap := (*requestTimes)[i].Sub((*requestTimes)[i-1]); gap < PageRequestDelay-100*time.Millisecond {
+				t.Errorf("Requests %d and %d were only %v apart", i, i+1, gap)
+			}
+		}
+	})
+
+	t.Run("windowed pagination continues past last linked page", func(t *testing.T) {
+		server, _ := newServer(2, 0)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		bills, err := FetchAllBills(context.Background())
+		if err != nil {
+			t.Fatalf("FetchAllBills failed: %v", err)
+		}
+
+		expected := []string{"r1", "r2", "r3", "r4", "r5"}
+		if got := billIDs(bills); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Bill IDs = %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("failed page truncates to contiguous prefix", func(t *testing.T) {
+		server, _ := newServer(3, 2)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		bills, err := FetchAllBills(context.Background())
+		if !errors.Is(err, ErrPartialBills) {
+			t.Fatalf("Expected ErrPartialBills, got %v", err)
+		}
+
+		expected := []string{"r1", "r2"}
+		if got := billIDs(bills); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Bill IDs = %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("failed page past the linked pages is reported as partial", func(t *testing.T) {
+		server, _ := newServer(2, 3)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		bills, err := FetchAllBills(context.Background())
+		if !errors.Is(err, ErrPartialBills) {
+			t.Fatalf("Expected ErrPartialBills, got %v", err)
+		}
+
+		expected := []string{"r1", "r2", "r3", "r4"}
+		if got := billIDs(bills); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Bill IDs = %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("first page failure", func(t *testing.T) {
+		server, _ := newServer(3, 1)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		if _, err := FetchAllBills(context.Background()); err == nil {
+			t.Error("Expected error when the first page fails")
+		}
+	})
+}
+
+// TestFetchAllBillsPageDelay tests that the configured delay separates listing requests
+func TestFetchAllBillsPageDelay(t *testing.T) {
+	oldBaseURL, oldStore := BillsBaseURL, pageValidatorStore
+	oldDelay, oldConcurrency := PageRequestDelay, BillsFetchConcurrency
+	defer func() {
+		BillsBaseURL, pageValidatorStore = oldBaseURL, oldStore
+		PageRequestDelay, BillsFetchConcurrency = oldDelay, oldConcurrency
+	}()
+	pageValidatorStore = nil
+	PageRequestDelay = 150 * time.Millisecond
+	BillsFetchConcurrency = 1
+
+	var mu sync.Mutex
+	var requestTimes []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestTimes = append(requestTimes, time.Now())
+		mu.Unlock()
+
+		page := 1
+		if p := r.URL.Query().Get("page"); p != "" {
+			page, _ = strconv.Atoi(p)
+		}
+		fmt.Fprint(w, billsListingHTML([]string{fmt.Sprintf("r%d", page)}, 4, page < 4))
+	}))
+	defer server.Close()
+	BillsBaseURL = server.URL
+
+	bills, err := FetchAllBills(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAllBills failed: %v", err)
+	}
+	if len(bills) != 4 || len(requestTimes) != 4 {
+		t.Fatalf("Got %d bills from %d requests, want 4 from 4", len(bills), len(requestTimes))
+	}
+	for i := 1; i < len(requestTimes); i++ {
+		if gap := requestTimes[i].Sub(requestTimes[i-1]); gap < PageRequestDelay-20*time.Millisecond {
+			t.Errorf("Requests %d and %d were only %v apart, want at least %v", i, i+1, gap, PageRequestDelay)
+		}
+	}
+}
+
+// TestFetchBillsPageRetry tests that transient failures are retried and permanent ones aren't
+func TestFetchBillsPageRetry(t *testing.T) {
+	oldBaseURL := BillsBaseURL
+	oldStore := pageValidatorStore
+	oldRetries, oldBackoff, oldMaxDelay := ScraperMaxRetries, ScraperRetryBackoff, ScraperMaxRetryDelay
+	defer func() {
+		BillsBaseURL = oldBaseURL
+		pageValidatorStore = oldStore
+		ScraperMaxRetries, ScraperRetryBackoff, ScraperMaxRetryDelay = oldRetries, oldBackoff, oldMaxDelay
+	}()
+	pageValidatorStore = nil
+	ScraperMaxRetries = 2
+	ScraperRetryBackoff = 10 * time.Millisecond
+	ScraperMaxRetryDelay = 100 * time.Millisecond
+
+	// newServer responds with statuses in order, then with a listing page
+	newServer := func(statuses ...int) (*httptest.Server, *int) {
+		var mu sync.Mutex
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			n := requests
+			requests++
+			mu.Unlock()
+			if n < len(statuses) {
+				if statuses[n] == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "120")
+				}
+				w.WriteHeader(statuses[n])
+				return
+			}
+			fmt.Fprint(w, billsListingHTML([]string{"r1"}, 1, false))
+		}))
+		return server, &requests
+	}
+
+	t.Run("503 then 200 succeeds", func(t *testing.T) {
+		server, requests := newServer(http.StatusServiceUnavailable)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		bills, _, err := FetchBillsPage(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("FetchBillsPage failed: %v", err)
+		}
+		if len(bills) != 1 || *requests != 2 {
+			t.Errorf("Got %d bills after %d requests, want 1 bill after 2", len(bills), *requests)
+		}
+	})
+
+	t.Run("Retry-After is capped", func(t *testing.T) {
+		server, requests := newServer(http.StatusTooManyRequests)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		start := time.Now()
+		if _, _, err := FetchBillsPage(context.Background(), 1); err != nil {
+			t.Fatalf("FetchBillsPage failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < ScraperMaxRetryDelay || elapsed > 2*time.Second {
+			t.Errorf("Retry took %v, want about ScraperMaxRetryDelay (%v)", elapsed, ScraperMaxRetryDelay)
+		}
+		if *requests != 2 {
+			t.Errorf("Expected 2 requests, got %d", *requests)
+		}
+	})
+
+	t.Run("permanent 404 is not retried", func(t *testing.T) {
+		server, requests := newServer(http.StatusNotFound, http.StatusNotFound, http.StatusNotFound)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		_, _, err := FetchBillsPage(context.Background(), 1)
+		var httpErr *ScraperHTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound || httpErr.Retryable {
+			t.Fatalf("Expected non-retryable 404 ScraperHTTPError, got %v", err)
+		}
+		if *requests != 1 {
+			t.Errorf("Expected 1 request, got %d", *requests)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		server, requests := newServer(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		defer server.Close()
+		BillsBaseURL = server.URL
+
+		_, _, err := FetchBillsPage(context.Background(), 1)
+		var httpErr *ScraperHTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway || !httpErr.Retryable {
+			t.Fatalf("Expected retryable 502 ScraperHTTPError, got %v", err)
+		}
+		if *requests != ScraperMaxRetries+1 {
+			t.Errorf("Expected %d requests, got %d", ScraperMaxRetries+1, *requests)
+		}
+	})
+}
+
+// TestHTTPGetWithRetry tests the scraper's shared GET helper
+func TestHTTPGetWithRetry(t *testing.T) {
+	oldBackoff, oldMaxDelay := ScraperRetryBackoff, ScraperMaxRetryDelay
+	defer func() { ScraperRetryBackoff, ScraperMaxRetryDelay = oldBackoff, oldMaxDelay }()
+	ScraperRetryBackoff = 10 * time.Millisecond
+	ScraperMaxRetryDelay = 50 * time.Millisecond
+
+	// newServer responds with statuses in order, then with 200 OK
+	newServer := func(statuses ...int) (*httptest.Server, *atomic.Int32) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := int(requests.Add(1)) - 1
+			if n < len(statuses) {
+				w.WriteHeader(statuses[n])
+				return
+			}
+			fmt.Fprint(w, r.Header.Get("User-Agent"))
+		}))
+		return server, &requests
+	}
+	opts := httpGetOptions{Timeout: time.Second, MaxRetries: 2}
+	header := http.Header{"User-Agent": {"TestAgent/1.0"}}
+
+	t.Run("success sends headers", func(t *testing.T) {
+		server, requests := newServer()
+		defer server.Close()
+
+		resp, err := httpGetWithRetry(context.Background(), server.URL, header, opts)
+		if err != nil {
+			t.Fatalf("httpGetWithRetry failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "TestAgent/1.0" || requests.Load() != 1 {
+			t.Errorf("Got body %q after %d requests, want the User-Agent after 1", body, requests.Load())
+		}
+	})
+
+	t.Run("retries then succeeds", func(t *testing.T) {
+		server, requests := newServer(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+		defer server.Close()
+
+		resp, err := httpGetWithRetry(context.Background(), server.URL, header, opts)
+		if err != nil {
+			t.Fatalf("httpGetWithRetry failed: %v", err)
+		}
+		resp.Body.Close()
+		if requests.Load() != 3 {
+			t.Errorf("Expected 3 requests, got %d", requests.Load())
+		}
+	})
+
+	t.Run("permanent failure is not retried", func(t *testing.T) {
+		server, requests := newServer(http.StatusForbidden)
+		defer server.Close()
+
+		_, err := httpGetWithRetry(context.Background(), server.URL, header, opts)
+		var httpErr *ScraperHTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden || httpErr.Retryable {
+			t.Fatalf("Expected non-retryable 403 ScraperHTTPError, got %v", err)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("Expected 1 request, got %d", requests.Load())
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		server, requests := newServer(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		defer server.Close()
+
+		_, err := httpGetWithRetry(context.Background(), server.URL, header, opts)
+		var httpErr *ScraperHTTPError
+		if !errors.As(err, &httpErr) || !httpErr.Retryable || httpErr.Retries != 2 {
+			t.Fatalf("Expected retryable 502 ScraperHTTPError after 2 retries, got %v", err)
+		}
+		if requests.Load() != 3 {
+			t.Errorf("Expected 3 requests, got %d", requests.Load())
+		}
+	})
+
+	t.Run("accepted status is returned", func(t *testing.T) {
+		server, _ := newServer(http.StatusNotModified)
+		defer server.Close()
+
+		accepting := opts
+		accepting.Accept = []int{http.StatusNotModified}
+		resp, err := httpGetWithRetry(context.Background(), server.URL, header, accepting)
+		if err != nil {
+			t.Fatalf("httpGetWithRetry failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotModified {
+			t.Errorf("Status = %d, want 304", resp.StatusCode)
+		}
+	})
+
+	t.Run("cancellation stops retrying", func(t *testing.T) {
+		ScraperRetryBackoff, ScraperMaxRetryDelay = time.Minute, time.Minute
+		defer func() { ScraperRetryBackoff, ScraperMaxRetryDelay = 10*time.Millisecond, 50*time.Millisecond }()
+		server, requests := newServer(http.StatusServiceUnavailable)
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := httpGetWithRetry(ctx, server.URL, header, opts)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context deadline error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second || requests.Load() != 1 {
+			t.Errorf("Returned after %v and %d requests, want promptly after 1", elapsed, requests.Load())
+		}
+	})
+}
+
+// TestParseRetryAfter tests parsing Retry-After in both of its formats
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 9, 3, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-3", 0},
+		{"Wed, 03 Sep 2025 12:00:30 GMT", 30 * time.Second},
+		{"Wed, 03 Sep 2025 11:59:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+// TestExtractBillID tests reading bill IDs from listing links
+func TestExtractBillID(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"/Parliamentary_Business/Bills_Legislation/Bills_Search_Results/Result?bId=r7365", "r7365"},
+		{"https://www.aph.gov.au/Result?page=2&bId=s1254", "s1254"},
+		{"https://parlinfo.aph.gov.au/parlInfo/search/display/display.w3p;query=Id:legislation/bd/bd2526a012", "2526a012"},
+		{"/Parliamentary_Business/Bills_Legislation/Bills_Search_Results/Result", ""},
+		{"https://example.com/some/bill?id=123", ""},
+	}
+
+	for _, tt := range tests {
+		if got := extractBillID(tt.href); got != tt.want {
+			t.Errorf("extractBillID(%q) = %q, want %q", tt.href, got, tt.want)
+		}
+	}
+}
+
+// TestParseBillsHTMLStableID tests that bills without a bId get a short ID derived
+// from their details that stays the same across scrapes
+func TestParseBillsHTMLStableID(t *testing.T) {
+	listing := func(title, chamber, date string) string {
+		return `<html><body><ul>` +
+			`<li><div class="row"><h4><a href="/Result?bId=r7365">Clean Energy Amendment Bill 2025</a></h4></div>` +
+			`<div class="row"><dl><dt>Date</dt><dd>03 Sep 2025</dd><dt>Chamber</dt><dd>House of Representatives</dd></dl></div></li>` +
+			`<li><div class="row"><h4><a href="/Bills_Search_Results/Result?drv=7">` + title + `</a></h4></div>` +
+			`<div class="row"><dl><dt>Date</dt><dd>` + date + `</dd><dt>Chamber</dt><dd>` + chamber + `</dd></dl></div></li>` +
+			`</ul></body></html>`
+	}
+	parse := func(html string) []Bill {
+		t.Helper()
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			t.Fatalf("Failed to parse HTML: %v", err)
+		}
+		bills, err := ParseBillsHTML(doc, DefaultScraperSelectors())
+		if err != nil || len(bills) != 2 {
+			t.Fatalf("ParseBillsHTML returned %d bills, err %v", len(bills), err)
+		}
+		return bills
+	}
+
+	bills := parse(listing("Online Safety Bill 2025", "Senate", "10 Oct 2025"))
+	if bills[0].ID != "r7365" {
+		t.Errorf("Parseable bill ID = %q, want r7365", bills[0].ID)
+	}
+	id := bills[1].ID
+	if !billIDPattern.MatchString(id) || !strings.HasPrefix(id, "h") || len(id) != 13 {
+		t.Errorf("Fallback ID = %q, want a short alphanumeric ID", id)
+	}
+
+	// The same bill always gets the same ID, ignoring cosmetic differences
+	if again := parse(listing("Online  Safety Bill 2025 ", "senate", "10 Oct 2025"))[1].ID; again != id {
+		t.Errorf("Rescraped ID = %q, want %q", again, id)
+	}
+
+	// Different bills get different IDs
+	for _, other := range [][3]string{
+		{"Online Safety Bill 2026", "Senate", "10 Oct 2025"},
+		{"Online Safety Bill 2025", "House of Representatives", "10 Oct 2025"},
+		{"Online Safety Bill 2025", "Senate", "11 Oct 2025"},
+	} {
+		if otherID := parse(listing(other[0], other[1], other[2]))[1].ID; otherID == id {
+			t.Errorf("%v got the same ID %q", other, id)
+		}
+	}
+}
+
+// TestParseBillsHTMLSelectors tests parsing listing markup that differs from APH's
+// current layout by supplying alternative selectors and labels
+func TestParseBillsHTMLSelectors(t *testing.T) {
+	html := `<html><body><section class="results">
+		<article class="bill">
+			<header><h3 class="bill-title"><a class="title" href="/Result?bId=s1254">Privacy Protections Bill 2025</a></h3></header>
+			<table class="facts">
+				<tr><th>Introduced</th><td>11 Sep 2025</td></tr>
+				<tr><th>House</th><td>Senate</td></tr>
+				<tr><th>Stage</th><td>Before Senate</td></tr>
+				<tr><th>Purpose</th><td>Introduces a statutory tort.</td></tr>
+			</table>
+			<ul class="documents"><li><a href="https://parlinfo.aph.gov.au/bill.pdf">Bill</a></li></ul>
+		</article>
+		<article class="bill">
+			<header><h3 class="bill-title"><span>Withdrawn</span></h3></header>
+		</article>
+	</section></body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	// The default selectors find nothing in this layout
+	if bills, _ := ParseBillsHTML(doc, DefaultScraperSelectors()); len(bills) != 0 {
+		t.Errorf("Default selectors found %d bills, want 0", len(bills))
+	}
+
+	sel := ScraperSelectors{
+		Title:     "h3.bill-title",
+		TitleLink: "a.title",
+		Container: "article.bill",
+		Details:   "table.facts tr",
+		Label:     "th",
+		Value:     "td",
+		Links:     "ul.documents a",
+		Labels: map[string]string{
+			"introduced": BillFieldDate,
+			"house":      BillFieldChamber,
+			"stage":      BillFieldStatus,
+			"purpose":    BillFieldSummary,
+		},
+	}
+	bills, err := ParseBillsHTML(doc, sel)
+	if err != nil || len(bills) != 1 {
+		t.Fatalf("ParseBillsHTML returned %d bills, err %v", len(bills), err)
+	}
+
+	want := Bill{
+		ID:                   "s1254",
+		Title:                "Privacy Protections Bill 2025",
+		DateIntroduced:       "11 Sep 2025",
+		DateIntroducedParsed: time.Date(2025, 9, 11, 0, 0, 0, 0, time.UTC),
+		Chamber:              "Senate",
+		Status:               "Before Senate",
+		Summary:              "Introduces a statutory tort.",
+		BillURL:              "https://parlinfo.aph.gov.au/bill.pdf",
+	}
+	want.ContentHash = billContentHash(want)
+	got := bills[0]
+	got.ScrapedAt = time.Time{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parsed bill = %+v, want %+v", got, want)
+	}
+}
+
+// TestScraperSelectorsWithDefaults tests filling unset selectors from the defaults
+func TestScraperSelectorsWithDefaults(t *testing.T) {
+	sel := ScraperSelectors{Title: "h3", Links: " "}.WithDefaults()
+	defaults := DefaultScraperSelectors()
+
+	if sel.Title != "h3" {
+		t.Errorf("Title = %q, want the configured h3", sel.Title)
+	}
+	if sel.Links != defaults.Links || sel.Details != defaults.Details || sel.Container != defaults.Container {
+		t.Errorf("Blank selectors not defaulted: %+v", sel)
+	}
+	if !reflect.DeepEqual(sel.Labels, defaults.Labels) {
+		t.Errorf("Labels = %v, want defaults", sel.Labels)
+	}
+
+	if err := sel.Validate(); err != nil {
+		t.Errorf("Default labels should validate, got %v", err)
+	}
+	sel.Labels = map[string]string{"date": "introduced_on"}
+	if err := sel.Validate(); err == nil {
+		t.Error("Expected an error for a label mapped to an unknown field")
+	}
+}
+
+// TestParseBillsHTMLParsedDate tests that scraped bills carry a parsed introduction
+// date alongside the display string, and a zero date when it cannot be parsed
+func TestParseBillsHTMLParsedDate(t *testing.T) {
+	tests := []struct {
+		date string
+		want time.Time
+	}{
+		{"03 Sep 2025", time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC)},
+		{"3 Sep 2025", time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC)},
+		{"12 February 2024", time.Date(2024, 2, 12, 0, 0, 0, 0, time.UTC)},
+		{"28/11/2024", time.Date(2024, 11, 28, 0, 0, 0, 0, time.UTC)},
+		{"01-Jul-2025", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"Not yet introduced", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		html := `<html><body><ul>` +
+			`<li><div class="row"><h4><a href="/Result?bId=r7365">Clean Energy Amendment Bill 2025</a></h4></div>` +
+			`<div class="row"><dl><dt>Date</dt><dd>` + tt.date + `</dd><dt>Chamber</dt><dd>House of Representatives</dd></dl></div></li>` +
+			`</ul></body></html>`
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			t.Fatalf("Failed to parse HTML: %v", err)
+		}
+		bills, err := ParseBillsHTML(doc, DefaultScraperSelectors())
+		if err != nil || len(bills) != 1 {
+			t.Fatalf("ParseBillsHTML returned %d bills, err %v", len(bills), err)
+		}
+
+		if bills[0].DateIntroduced != tt.date {
+			t.Errorf("DateIntroduced = %q, want %q", bills[0].DateIntroduced, tt.date)
+		}
+		if !bills[0].DateIntroducedParsed.Equal(tt.want) {
+			t.Errorf("DateIntroducedParsed for %q = %v, want %v", tt.date, bills[0].DateIntroducedParsed, tt.want)
+		}
+	}
+
+	// Unparseable dates are left out of the JSON rather than sent as year 1
+	data, err := json.Marshal(Bill{ID: "r1", DateIntroduced: "Not yet introduced"})
+	if err != nil {
+		t.Fatalf("Failed to marshal bill: %v", err)
+	}
+	if strings.Contains(string(data), "date_introduced_parsed") {
+		t.Errorf("Zero parsed date should be omitted, got %s", data)
+	}
+}
+
+// TestParseBillDate tests parsing APH display dates, including malformed ones
+func TestParseBillDate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"03 Sep 2025", time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC), false},
+		{"3 Sep 2025", time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC), false},
+		{"  28 Feb  2024 ", time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), false},
+		{"29 February 2024", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), false},
+		{"31/12/2025", time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), false},
+		{"2025-07-01", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), false},
+		{"03-Sep-2025", time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC), false},
+		{"", time.Time{}, true},
+		{"Not yet introduced", time.Time{}, true},
+		{"31 Feb 2025", time.Time{}, true},
+		{"Sep 2025", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBillDate(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBillDate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseBillDate(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestCalculateTotalPages tests page counts for various bill counts and page sizes
+func TestCalculateTotalPages(t *testing.T) {
+	tests := []struct {
+		count, pageSize, want int
+	}{
+		{0, 20, 1},
+		{0, 0, 1},
+		{1, 20, 1},
+		{20, 20, 1},
+		{21, 20, 2},
+		{40, 20, 2},
+		{41, 20, 3},
+		{7, 1, 7},
+		{7, 3, 3},
+		{100, 0, 1},
+		{100, -1, 1},
+	}
+	for _, tt := range tests {
+		if got := CalculateTotalPages(tt.count, tt.pageSize); got != tt.want {
+			t.Errorf("CalculateTotalPages(%d, %d) = %d, want %d", tt.count, tt.pageSize, got, tt.want)
+		}
+	}
+}
+
+// TestPaginateBills tests slicing bills into pages
+func TestPaginateBills(t *testing.T) {
+	bills := []Bill{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+	ids := func(bills []Bill) []string {
+		ids := []string{}
+		for _, bill := range bills {
+			ids = append(ids, bill.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		page, pageSize int
+		want           []string
+		wantPages      int
+	}{
+		{1, 2, []string{"a", "b"}, 3},
+		{3, 2, []string{"e"}, 3},
+		{4, 2, []string{}, 3},
+		{1, 10, []string{"a", "b", "c", "d", "e"}, 1},
+		{1, 0, []string{"a", "b", "c", "d", "e"}, 1},
+		{2, 0, []string{}, 1},
+	}
+	for _, tt := range tests {
+		got, pages := PaginateBills(bills, tt.page, tt.pageSize)
+		if !reflect.DeepEqual(ids(got), tt.want) || pages != tt.wantPages {
+			t.Errorf("PaginateBills(page %d, size %d) = %v, %d pages, want %v, %d pages", tt.page, tt.pageSize, ids(got), pages, tt.want, tt.wantPages)
+		}
+	}
+
+	if got, pages := PaginateBills(nil, 1, 20); len(got) != 0 || pages != 1 {
+		t.Errorf("PaginateBills(nil) = %v, %d pages, want no bills on 1 page", got, pages)
+	}
+}
+
+// TestFilterBills tests date-range filtering with inclusive bounds
+func TestFilterBills(t *testing.T) {
+	bills := []Bill{
+		{ID: "a", DateIntroduced: "31 Dec 2024"},
+		{ID: "b", DateIntroduced: "01 Jan 2025"},
+		{ID: "c", DateIntroduced: "Not yet introduced"},
+		{ID: "d", DateIntroduced: "15 Feb 2025", DateIntroducedParsed: time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: "e", DateIntroduced: "31 Mar 2025"},
+		{ID: "f", DateIntroduced: ""},
+		{ID: "g", DateIntroduced: "01 Apr 2025"},
+	}
+	date := func(s string) time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			t.Fatalf("Bad test date %q: %v", s, err)
+		}
+		return d
+	}
+	ids := func(bills []Bill) string {
+		var out []string
+		for _, bill := range bills {
+			out = append(out, bill.ID)
+		}
+		return strings.Join(out, "")
+	}
+
+	tests := []struct {
+		name   string
+		filter BillFilter
+		want   string
+	}{
+		{"no filter keeps everything", BillFilter{}, "abcdefg"},
+		{"inclusive range", BillFilter{From: date("2025-01-01"), To: date("2025-03-31")}, "bde"},
+		{"from only", BillFilter{From: date("2025-03-31")}, "eg"},
+		{"to only", BillFilter{To: date("2025-01-01")}, "ab"},
+		{"single day", BillFilter{From: date("2025-02-15"), To: date("2025-02-15")}, "d"},
+		{"empty range", BillFilter{From: date("2026-01-01")}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(FilterBills(bills, tt.filter)); got != tt.want {
+				t.Errorf("FilterBills() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSortBills tests sorting bills by date, title and chamber
+func TestSortBills(t *testing.T) {
+	bills := []Bill{
+		{ID: "a", Title: "zebra Bill", Chamber: "Senate", DateIntroduced: "03 Sep 2025"},
+		{ID: "b", Title: "Apple Bill", Chamber: "House of Representatives", DateIntroduced: ""},
+		{ID: "c", Title: "Mango Bill", Chamber: "senate", DateIntroduced: "12 Feb 2024"},
+		{ID: "d", Title: "banana Bill", Chamber: "House of Representatives", DateIntroduced: "1 Oct 2025"},
+		{ID: "e", Title: "Cherry Bill", Chamber: "Senate", DateIntroduced: "sometime"},
+	}
+	ids := func(bills []Bill) string {
+		var out []string
+		for _, bill := range bills {
+			out = append(out, bill.ID)
+		}
+		return strings.Join(out, "")
+	}
+
+	tests := []struct {
+		by         string
+		descending bool
+		want       string
+	}{
+		// Undated bills go last in both directions, in scrape order
+		{BillSortDate, false, "cadbe"},
+		{BillSortDate, true, "dacbe"},
+		{BillSortTitle, false, "bdeca"},
+		{BillSortTitle, true, "acedb"},
+		{BillSortChamber, false, "bdace"},
+		{BillSortChamber, true, "acebd"},
+	}
+	for _, tt := range tests {
+		if got := ids(SortBills(bills, tt.by, tt.descending)); got != tt.want {
+			t.Errorf("SortBills(%s, descending=%v) = %s, want %s", tt.by, tt.descending, got, tt.want)
+		}
+	}
+
+	if got := ids(bills); got != "abcde" {
+		t.Errorf("SortBills modified its input: %s", got)
+	}
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
+
+	"github.com/google/uuid"
 )
 
 // EnsureDataDir ensures the data directory exists.
@@ -31,9 +38,11 @@ func CreateConversation(conversationID string) (*Conversation, error) {
 	}
 
 	// Create new conversation
+	now := time.Now().UTC()
 	conversation := &Conversation{
 		ID:        conversationID,
-		CreatedAt: time.Now().UTC(),
+		CreatedAt: now,
+		UpdatedAt: now,
 		Title:     "New Conversation",
 		Messages:  []Message{},
 	}
@@ -73,7 +82,8 @@ func GetConversation(conversationID string) (*Conversation, error) {
 }
 
 // SaveConversation saves a conversation to storage.
-// Writes the conversation as formatted JSON to disk.
+// Writes the conversation as formatted JSON to disk atomically, so readers never see
+// a partially written file even if the process dies mid-save.
 // Returns an error if directory creation, marshaling, or writing fails.
 func SaveConversation(conversation *Conversation) error {
 	// Ensure data directory exists
@@ -89,30 +99,77 @@ func SaveConversation(conversation *Conversation) error {
 
 	// Write to file
 	path := GetConversationPath(conversation.ID)
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := writeFileAtomic(path, data); err != nil {
 		return fmt.Errorf("failed to write conversation file: %w", err)
 	}
 
 	return nil
 }
 
-// ListConversations lists all conversations with metadata only.
-// Returns a slice of conversation metadata sorted by creation time (newest first).
+// ListConversations lists conversations matching filter with metadata only.
+// Archived conversations are excluded unless filter.IncludeArchived is set.
+// Returns a slice of conversation metadata sorted by creation time (newest first), or
+// by last update when filter.SortByUpdated is set.
 // Silently skips invalid or unreadable files. Returns empty slice if no conversations exist.
-func ListConversations() ([]ConversationMetadata, error) {
+func ListConversations(filter ConversationFilter) ([]ConversationMetadata, error) {
+	convs, err := loadAllConversations()
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect metadata (initialize with empty slice to avoid null in JSON)
+	conversations := make([]ConversationMetadata, 0, len(convs))
+	for _, conv := range convs {
+		if conv.Archived && !filter.IncludeArchived {
+			continue
+		}
+		if filter.Tag != "" && !slices.Contains(conv.Tags, normalizeTag(filter.Tag)) {
+			continue
+		}
+		conversations = append(conversations, conversationMetadata(conv))
+	}
+
+	// Sort by creation time, newest first, or by most recent update
+	sort.Slice(conversations, func(i, j int) bool {
+		if filter.SortByUpdated {
+			return conversations[i].UpdatedAt.After(conversations[j].UpdatedAt)
+		}
+		return conversations[i].CreatedAt.After(conversations[j].CreatedAt)
+	})
+
+	return conversations, nil
+}
+
+// loadAllConversations reads every conversation file in the data directory.
+// Silently skips invalid or unreadable files.
+func loadAllConversations() ([]Conversation, error) {
+	var conversations []Conversation
+	err := forEachConversation(func(conv Conversation) error {
+		conversations = append(conversations, conv)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// forEachConversation calls fn with each conversation in the data directory in
+// file name order, reading one file at a time so callers can stream large data
+// sets. Silently skips invalid or unreadable files and stops at the first error
+// returned by fn.
+func forEachConversation(fn func(Conversation) error) error {
 	// Ensure data directory exists
 	if err := EnsureDataDir(); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+		return fmt.Errorf("failed to create data directory: %w", err)
 	}
 
 	// Read directory
 	entries, err := os.ReadDir(DataDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read data directory: %w", err)
+		return fmt.Errorf("failed to read data directory: %w", err)
 	}
 
-	// Collect metadata (initialize with empty slice to avoid null in JSON)
-	conversations := make([]ConversationMetadata, 0)
 	for _, entry := range entries {
 		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
 			continue
@@ -125,33 +182,190 @@ func ListConversations() ([]ConversationMetadata, error) {
 			continue // Skip files we can't read
 		}
 
-		// Parse JSON (just enough to get metadata)
+		// Parse JSON
 		var conv Conversation
 		if err := json.Unmarshal(data, &conv); err != nil {
 			continue // Skip invalid JSON
 		}
 
-		// Extract metadata
-		conversations = append(conversations, ConversationMetadata{
-			ID:           conv.ID,
-			CreatedAt:    conv.CreatedAt,
-			Title:        conv.Title,
-			MessageCount: len(conv.Messages),
-		})
+		if err := fn(conv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// conversationMetadata extracts list metadata from a conversation.
+// Conversations saved before UpdatedAt was tracked report their creation time.
+func conversationMetadata(conv Conversation) ConversationMetadata {
+	updatedAt := conv.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = conv.CreatedAt
+	}
+	return ConversationMetadata{
+		ID:           conv.ID,
+		CreatedAt:    conv.CreatedAt,
+		UpdatedAt:    updatedAt,
+		Title:        conv.Title,
+		MessageCount: len(conv.Messages),
+		Archived:     conv.Archived,
+		Tags:         append([]string{}, conv.Tags...),
+	}
+}
+
+// SearchConversations finds conversations whose title or message content contains term.
+// Matching is case-insensitive and covers user messages and Stage 1/3 responses.
+// Results are sorted newest first, capped at MaxSearchResults, and include a short
+// snippet around the first match.
+func SearchConversations(term string) ([]ConversationSearchResult, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return nil, fmt.Errorf("search term is empty")
+	}
+
+	convs, err := loadAllConversations()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ConversationSearchResult, 0)
+	for _, conv := range convs {
+		if field, snippet, ok := searchConversation(conv, term); ok {
+			results = append(results, ConversationSearchResult{
+				ConversationMetadata: conversationMetadata(conv),
+				MatchField:           field,
+				Snippet:              snippet,
+			})
+		}
 	}
 
 	// Sort by creation time, newest first
-	sort.Slice(conversations, func(i, j int) bool {
-		return conversations[i].CreatedAt.After(conversations[j].CreatedAt)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt.After(results[j].CreatedAt)
 	})
 
-	return conversations, nil
+	if len(results) > MaxSearchResults {
+		results = results[:MaxSearchResults]
+	}
+
+	return results, nil
 }
 
-// AddUserMessage adds a user message to a conversation.
-// Appends the message to the conversation's message history and saves to disk.
-// Returns an error if the conversation doesn't exist or saving fails.
-func AddUserMessage(conversationID string, content string) error {
+// searchConversation returns the field and snippet of the first match of term in conv.
+// Fields are checked in order: title, then each message's user content, Stage 1
+// responses and Stage 3 synthesis.
+func searchConversation(conv Conversation, term string) (field string, snippet string, ok bool) {
+	if snippet, ok := matchSnippet(conv.Title, term); ok {
+		return "title", snippet, true
+	}
+
+	for _, msg := range conv.Messages {
+		if snippet, ok := matchSnippet(msg.Content, term); ok {
+			return msg.Role, snippet, true
+		}
+		for _, resp := range msg.Stage1 {
+			if snippet, ok := matchSnippet(resp.Response, term); ok {
+				return "stage1", snippet, true
+			}
+		}
+		if msg.Stage3 != nil {
+			if snippet, ok := matchSnippet(msg.Stage3.Response, term); ok {
+				return "stage3", snippet, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// searchSnippetRadius is the number of characters of context on each side of a match
+const searchSnippetRadius = 60
+
+// matchSnippet finds term in text case-insensitively and returns the match with
+// surrounding context, collapsed to a single line and marked with ellipses where cut.
+func matchSnippet(text, term string) (string, bool) {
+	// Lowercase rune by rune so indexes line up with the original text
+	textRunes := []rune(text)
+	termRunes := []rune(term)
+	lowerText := make([]rune, len(textRunes))
+	for i, r := range textRunes {
+		lowerText[i] = unicode.ToLower(r)
+	}
+	for i, r := range termRunes {
+		termRunes[i] = unicode.ToLower(r)
+	}
+
+	idx := -1
+	for i := 0; i+len(termRunes) <= len(lowerText); i++ {
+		if string(lowerText[i:i+len(termRunes)]) == string(termRunes) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return "", false
+	}
+
+	start := max(idx-searchSnippetRadius, 0)
+	end := min(idx+len(termRunes)+searchSnippetRadius, len(textRunes))
+
+	snippet := strings.Join(strings.Fields(string(textRunes[start:end])), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(textRunes) {
+		snippet += "…"
+	}
+
+	return snippet, true
+}
+
+// conversationLocks serializes load-modify-save sequences on the same conversation
+var conversationLocks = &keyedMutex{locks: make(map[string]*keyedLock)}
+
+// keyedMutex hands out one mutex per key, dropping it once no one holds or waits on it.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// keyedLock is a per-key mutex with a count of holders and waiters
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock locks key and returns the function that unlocks it.
+func (k *keyedMutex) Lock(key string) (unlock func()) {
+	k.mu.Lock()
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		k.mu.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// updateConversation loads a conversation, applies fn and saves the result while
+// holding the conversation's lock, so concurrent updates (such as a new message and
+// the background title generator) don't overwrite each other. Nothing is saved if
+// fn returns an error. Returns an error if the conversation doesn't exist.
+func updateConversation(conversationID string, fn func(*Conversation) error) error {
+	unlock := conversationLocks.Lock(conversationID)
+	defer unlock()
+
 	// Load conversation
 	conversation, err := GetConversation(conversationID)
 	if err != nil {
@@ -161,57 +375,270 @@ func AddUserMessage(conversationID string, content string) error {
 		return fmt.Errorf("conversation %s not found", conversationID)
 	}
 
-	// Append user message
-	conversation.Messages = append(conversation.Messages, Message{
-		Role:    "user",
-		Content: content,
-	})
+	if err := fn(conversation); err != nil {
+		return err
+	}
 
 	// Save conversation
 	return SaveConversation(conversation)
 }
 
+// Policies for a message that would take a conversation past MaxMessagesPerConversation
+const (
+	MessageLimitReject     = "reject"      // Refuse the message with ErrConversationFull
+	MessageLimitDropOldest = "drop_oldest" // Drop the oldest user/assistant pairs to make room
+)
+
+// ErrConversationFull is returned when a conversation has no room for another message
+// under MaxMessagesPerConversation and the reject policy.
+var ErrConversationFull = errors.New("conversation has reached its message limit")
+
+// makeRoom enforces MaxMessagesPerConversation before adding a message to conversation,
+// needing room for the given number of messages: 2 for a question and its answer, 1
+// for an answer. Under the drop-oldest policy whole exchanges are dropped from the start.
+func makeRoom(conversation *Conversation, room int) error {
+	limit := MaxMessagesPerConversation
+	excess := len(conversation.Messages) + room - limit
+	if limit <= 0 || excess <= 0 {
+		return nil
+	}
+	if MessageLimitPolicy != MessageLimitDropOldest {
+		return fmt.Errorf("%w of %d", ErrConversationFull, limit)
+	}
+
+	// Drop a user message together with its answer so no answer is left orphaned
+	drop := min(excess+excess%2, len(conversation.Messages))
+	conversation.Messages = slices.Delete(conversation.Messages, 0, drop)
+	return nil
+}
+
+// AddUserMessage adds a user message to a conversation.
+// Appends the message to the conversation's message history and saves to disk.
+// Returns ErrConversationFull if MaxMessagesPerConversation leaves no room for it and
+// its answer under the reject policy.
+// Returns an error if the conversation doesn't exist or saving fails.
+func AddUserMessage(conversationID string, content string, imageURLs ...string) error {
+	return updateConversation(conversationID, func(conversation *Conversation) error {
+		if err := makeRoom(conversation, 2); err != nil {
+			return err
+		}
+		conversation.Messages = append(conversation.Messages, Message{
+			Role:      "user",
+			Content:   content,
+			ImageURLs: imageURLs,
+		})
+		conversation.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
+
 // AddAssistantMessage adds an assistant message with all 3 stages.
 // Stores the complete council results (stage1, stage2, stage3) as a single message.
+// Returns ErrConversationFull if MaxMessagesPerConversation leaves no room for it under
+// the reject policy.
 // Returns an error if the conversation doesn't exist or saving fails.
 func AddAssistantMessage(conversationID string, stage1 []Stage1Response, stage2 []Stage2Ranking, stage3 Stage3Response) error {
-	// Load conversation
-	conversation, err := GetConversation(conversationID)
+	return updateConversation(conversationID, func(conversation *Conversation) error {
+		if err := makeRoom(conversation, 1); err != nil {
+			return err
+		}
+		conversation.Messages = append(conversation.Messages, Message{
+			Role:   "assistant",
+			Stage1: stage1,
+			Stage2: stage2,
+			Stage3: &stage3,
+		})
+		conversation.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// ErrNotAssistantMessage is returned when an operation requires the last message
+// of a conversation to be an assistant response and it isn't.
+var ErrNotAssistantMessage = errors.New("last message is not an assistant message")
+
+// RemoveLastAssistantMessage removes the final assistant message from a conversation.
+// Returns ErrNotAssistantMessage if the conversation is empty or ends with a user message.
+// Returns an error if the conversation doesn't exist or saving fails.
+func RemoveLastAssistantMessage(conversationID string) error {
+	return updateConversation(conversationID, func(conversation *Conversation) error {
+		last := len(conversation.Messages) - 1
+		if last < 0 || conversation.Messages[last].Role != "assistant" {
+			return ErrNotAssistantMessage
+		}
+
+		// Drop the assistant message
+		conversation.Messages = conversation.Messages[:last]
+		return nil
+	})
+}
+
+// ErrConversationChanged is returned when a conversation no longer ends the way an
+// update expects, because another request changed it in the meantime.
+var ErrConversationChanged = errors.New("conversation changed")
+
+// ReplaceLastAssistantMessage swaps the final assistant message for a new answer in a
+// single update, provided the conversation still ends with the user message at
+// userIndex, with content userContent, followed by its assistant reply. Returns
+// ErrConversationChanged otherwise, leaving the conversation as it is.
+// Returns an error if the conversation doesn't exist or saving fails.
+func ReplaceLastAssistantMessage(conversationID string, userIndex int, userContent string, stage1 []Stage1Response, stage2 []Stage2Ranking, stage3 Stage3Response) error {
+	return updateConversation(conversationID, func(conversation *Conversation) error {
+		messages := conversation.Messages
+		if len(messages) != userIndex+2 || userIndex < 0 ||
+			messages[userIndex].Role != "user" || messages[userIndex].Content != userContent ||
+			messages[userIndex+1].Role != "assistant" {
+			return fmt.Errorf("%w: it no longer ends with the answer to message %d", ErrConversationChanged, userIndex)
+		}
+
+		messages[userIndex+1] = Message{
+			Role:   "assistant",
+			Stage1: stage1,
+			Stage2: stage2,
+			Stage3: &stage3,
+		}
+		conversation.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// ErrInvalidMessageIndex is returned when a message index is out of range.
+var ErrInvalidMessageIndex = errors.New("message index out of range")
+
+// ErrNotUserMessage is returned when an edit targets a message that isn't from the user.
+var ErrNotUserMessage = errors.New("message is not a user message")
+
+// EditUserMessage replaces the content of the user message at index and drops every
+// message after it, since the responses that followed no longer match the question.
+// Returns ErrInvalidMessageIndex if index is outside the conversation's messages, and
+// ErrNotUserMessage if the message there is an assistant response.
+// Returns an error if the conversation doesn't exist or saving fails.
+func EditUserMessage(conversationID string, index int, content string) error {
+	return updateConversation(conversationID, func(conversation *Conversation) error {
+		if index < 0 || index >= len(conversation.Messages) {
+			return fmt.Errorf("%w: %d (conversation has %d messages)", ErrInvalidMessageIndex, index, len(conversation.Messages))
+		}
+		if conversation.Messages[index].Role != "user" {
+			return fmt.Errorf("%w: message %d", ErrNotUserMessage, index)
+		}
+
+		conversation.Messages[index].Content = content
+		conversation.Messages = conversation.Messages[:index+1]
+		conversation.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// ForkConversation creates a new conversation containing messages 0..upTo of the source.
+// The fork gets a new UUID and is saved independently, so later changes to either
+// conversation don't affect the other.
+// Returns ErrInvalidMessageIndex if upTo is outside the source's messages.
+func ForkConversation(sourceID string, upTo int) (*Conversation, error) {
+	// Load source conversation
+	source, err := GetConversation(sourceID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if conversation == nil {
-		return fmt.Errorf("conversation %s not found", conversationID)
+	if source == nil {
+		return nil, fmt.Errorf("conversation %s not found", sourceID)
+	}
+
+	if upTo < 0 || upTo >= len(source.Messages) {
+		return nil, fmt.Errorf("%w: %d (conversation has %d messages)", ErrInvalidMessageIndex, upTo, len(source.Messages))
+	}
+
+	// Copy messages; the source was freshly loaded from disk so nothing is shared
+	// with any other in-memory conversation
+	messages := make([]Message, upTo+1)
+	copy(messages, source.Messages[:upTo+1])
+
+	now := time.Now().UTC()
+	fork := &Conversation{
+		ID:         uuid.New().String(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Title:      source.Title + " (fork)",
+		Messages:   messages,
+		ForkedFrom: sourceID,
 	}
 
-	// Append assistant message
-	conversation.Messages = append(conversation.Messages, Message{
-		Role:   "assistant",
-		Stage1: stage1,
-		Stage2: stage2,
-		Stage3: &stage3,
+	// Save to file
+	if err := SaveConversation(fork); err != nil {
+		return nil, err
+	}
+
+	return fork, nil
+}
+
+// SetConversationArchived archives or unarchives a conversation. Archived
+// conversations are kept on disk but hidden from the default list.
+// Returns an error if the conversation doesn't exist or saving fails.
+func SetConversationArchived(conversationID string, archived bool) error {
+	return updateConversation(conversationID, func(conversation *Conversation) error {
+		conversation.Archived = archived
+		return nil
 	})
+}
 
-	// Save conversation
-	return SaveConversation(conversation)
+// normalizeTag trims and lowercases a tag.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
 }
 
-// UpdateConversationTitle updates the title of a conversation.
-// Loads the conversation, updates its title field, and saves back to disk.
+// normalizeTags normalizes tags, dropping empty and duplicate entries, and sorts them.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized
+}
+
+// AddConversationTags adds tags to a conversation, normalizing them to lowercase and
+// ignoring any it already has. Returns the conversation's resulting tags.
 // Returns an error if the conversation doesn't exist or saving fails.
-func UpdateConversationTitle(conversationID string, title string) error {
-	// Load conversation
-	conversation, err := GetConversation(conversationID)
+func AddConversationTags(conversationID string, tags []string) ([]string, error) {
+	var result []string
+	err := updateConversation(conversationID, func(conversation *Conversation) error {
+		conversation.Tags = normalizeTags(append(conversation.Tags, tags...))
+		result = conversation.Tags
+		return nil
+	})
 	if err != nil {
-		return err
-	}
-	if conversation == nil {
-		return fmt.Errorf("conversation %s not found", conversationID)
+		return nil, err
 	}
+	return result, nil
+}
 
-	// Update title
-	conversation.Title = title
+// RemoveConversationTag removes a tag from a conversation. Removing a tag the
+// conversation doesn't have is not an error. Returns the conversation's resulting tags.
+// Returns an error if the conversation doesn't exist or saving fails.
+func RemoveConversationTag(conversationID string, tag string) ([]string, error) {
+	tag = normalizeTag(tag)
+	var result []string
+	err := updateConversation(conversationID, func(conversation *Conversation) error {
+		conversation.Tags = slices.DeleteFunc(normalizeTags(conversation.Tags), func(t string) bool {
+			return t == tag
+		})
+		result = conversation.Tags
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-	// Save conversation
-	return SaveConversation(conversation)
+// UpdateConversationTitle updates the title of a conversation.
+// Loads the conversation, updates its title field, and saves back to disk.
+// Returns an error if the conversation doesn't exist or saving fails.
+func UpdateConversationTitle(conversationID string, title string) error {
+	return updateConversation(conversationID, func(conversation *Conversation) error {
+		conversation.Title = title
+		conversation.UpdatedAt = time.Now().UTC()
+		return nil
+	})
 }
@@ -2,8 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -182,6 +189,70 @@ func TestSaveConversation(t *testing.T) {
 	}
 }
 
+// TestSaveConversationAtomic tests that readers never see a partially written
+// conversation while it is being saved, and that no temp files are left behind
+func TestSaveConversationAtomic(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	// Large enough that a non-atomic write is observable mid-way
+	conv := &Conversation{ID: "atomic", CreatedAt: time.Now(), Title: "Atomic"}
+	for i := range 200 {
+		conv.Messages = append(conv.Messages, Message{Role: "user", Content: strings.Repeat(fmt.Sprintf("message %d ", i), 50)})
+	}
+	helper.AssertNoError(SaveConversation(conv), "Initial save should succeed")
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var reads, failures atomic.Int64
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				loaded, err := GetConversation("atomic")
+				reads.Add(1)
+				if err != nil || loaded == nil || len(loaded.Messages) < 200 {
+					failures.Add(1)
+				}
+			}
+		}()
+	}
+
+	for i := range 100 {
+		conv.Title = fmt.Sprintf("Atomic %d", i)
+		conv.Messages = append(conv.Messages, Message{Role: "user", Content: "more"})
+		if err := SaveConversation(conv); err != nil {
+			t.Errorf("Save %d failed: %v", i, err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if n := failures.Load(); n > 0 {
+		t.Errorf("%d of %d concurrent reads saw a partial or missing conversation", n, reads.Load())
+	}
+
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 1 {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("Expected only the conversation file, found %v", names)
+	}
+}
+
 // TestListConversations tests listing all conversations
 func TestListConversations(t *testing.T) {
 	helper := NewTestHelper(t)
@@ -193,7 +264,7 @@ func TestListConversations(t *testing.T) {
 	defer func() { DataDir = oldDataDir }()
 
 	// Test empty directory
-	conversations, err := ListConversations()
+	conversations, err := ListConversations(ConversationFilter{})
 	helper.AssertNoError(err, "ListConversations should succeed on empty dir")
 	if len(conversations) != 0 {
 		t.Errorf("Expected 0 conversations, got %d", len(conversations))
@@ -217,7 +288,7 @@ func TestListConversations(t *testing.T) {
 	}
 
 	// List conversations
-	conversations, err = ListConversations()
+	conversations, err = ListConversations(ConversationFilter{})
 	helper.AssertNoError(err, "ListConversations should succeed")
 
 	if len(conversations) != 3 {
@@ -268,7 +339,7 @@ func TestListConversationsWithInvalidFiles(t *testing.T) {
 	os.Mkdir(filepath.Join(tempDir, "subdir"), 0755)
 
 	// List conversations - should only return valid one
-	conversations, err := ListConversations()
+	conversations, err := ListConversations(ConversationFilter{})
 	helper.AssertNoError(err, "ListConversations should succeed despite invalid files")
 
 	if len(conversations) != 1 {
@@ -380,6 +451,41 @@ func TestAddAssistantMessage(t *testing.T) {
 	}
 }
 
+// TestAddAssistantMessageReasoningDetails tests that reasoning details persist to disk
+func TestAddAssistantMessageReasoningDetails(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	CreateConversation("test-reasoning")
+
+	details := []interface{}{
+		map[string]interface{}{"type": "reasoning.text", "text": "Weighing the responses"},
+	}
+	stage1 := []Stage1Response{
+		{Model: "test/model", Response: "Test response", ReasoningDetails: details},
+	}
+	stage3 := Stage3Response{Model: "test/chairman", Response: "Final response", ReasoningDetails: details}
+
+	err := AddAssistantMessage("test-reasoning", stage1, nil, stage3)
+	helper.AssertNoError(err, "AddAssistantMessage should succeed")
+
+	conv, err := GetConversation("test-reasoning")
+	helper.AssertNoError(err, "Should load conversation")
+
+	msg := conv.Messages[0]
+	if !reflect.DeepEqual(msg.Stage1[0].ReasoningDetails, details) {
+		t.Errorf("Stage1 ReasoningDetails = %#v, want %#v", msg.Stage1[0].ReasoningDetails, details)
+	}
+	if !reflect.DeepEqual(msg.Stage3.ReasoningDetails, details) {
+		t.Errorf("Stage3 ReasoningDetails = %#v, want %#v", msg.Stage3.ReasoningDetails, details)
+	}
+}
+
 // TestAddAssistantMessageNonExistent tests adding assistant message to non-existent conversation
 func TestAddAssistantMessageNonExistent(t *testing.T) {
 	helper := NewTestHelper(t)
@@ -436,6 +542,132 @@ func TestUpdateConversationTitleNonExistent(t *testing.T) {
 	helper.AssertError(err, "Should error on non-existent conversation")
 }
 
+// TestConcurrentConversationUpdates tests that concurrent updates to the same
+// conversation serialize instead of overwriting each other
+func TestConcurrentConversationUpdates(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	t.Run("title and first message", func(t *testing.T) {
+		// Mirrors a first message racing its background title generation
+		for i := range 20 {
+			id := fmt.Sprintf("race-%d", i)
+			CreateConversation(id)
+
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				helper.AssertNoError(UpdateConversationTitle(id, "Generated Title"), "UpdateConversationTitle should succeed")
+			}()
+			go func() {
+				defer wg.Done()
+				helper.AssertNoError(AddUserMessage(id, "First question"), "AddUserMessage should succeed")
+			}()
+			wg.Wait()
+
+			conv, _ := GetConversation(id)
+			if conv.Title != "Generated Title" || len(conv.Messages) != 1 {
+				t.Fatalf("%s: title %q with %d messages, want both updates kept", id, conv.Title, len(conv.Messages))
+			}
+		}
+	})
+
+	t.Run("many messages", func(t *testing.T) {
+		CreateConversation("many")
+
+		var wg sync.WaitGroup
+		for i := range 25 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				AddUserMessage("many", fmt.Sprintf("message %d", i))
+			}()
+		}
+		wg.Wait()
+
+		conv, _ := GetConversation("many")
+		if len(conv.Messages) != 25 {
+			t.Errorf("Got %d messages, want all 25", len(conv.Messages))
+		}
+	})
+
+	conversationLocks.mu.Lock()
+	defer conversationLocks.mu.Unlock()
+	if n := len(conversationLocks.locks); n != 0 {
+		t.Errorf("Expected conversation locks to be released, %d remain", n)
+	}
+}
+
+// TestConversationUpdatedAt tests that UpdatedAt advances with each message and
+// title change, and that ListConversations can sort by it
+func TestConversationUpdatedAt(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	conv, err := CreateConversation("updated-at")
+	helper.AssertNoError(err, "CreateConversation should succeed")
+	if !conv.UpdatedAt.Equal(conv.CreatedAt) {
+		t.Errorf("UpdatedAt = %v, want CreatedAt %v for a new conversation", conv.UpdatedAt, conv.CreatedAt)
+	}
+
+	last := conv.UpdatedAt
+	mutations := []struct {
+		name   string
+		mutate func() error
+	}{
+		{"AddUserMessage", func() error { return AddUserMessage("updated-at", "Hello") }},
+		{"AddAssistantMessage", func() error {
+			return AddAssistantMessage("updated-at", nil, nil, Stage3Response{Model: "test/chairman", Response: "Hi"})
+		}},
+		{"UpdateConversationTitle", func() error { return UpdateConversationTitle("updated-at", "Greetings") }},
+	}
+	for _, m := range mutations {
+		time.Sleep(2 * time.Millisecond)
+		helper.AssertNoError(m.mutate(), m.name+" should succeed")
+		conv, _ := GetConversation("updated-at")
+		if !conv.UpdatedAt.After(last) {
+			t.Errorf("%s: UpdatedAt = %v, want after %v", m.name, conv.UpdatedAt, last)
+		}
+		last = conv.UpdatedAt
+	}
+
+	// A newer conversation sorts first by creation, but the older one was updated since
+	time.Sleep(2 * time.Millisecond)
+	CreateConversation("newer")
+	time.Sleep(2 * time.Millisecond)
+	AddUserMessage("updated-at", "Still there?")
+
+	byCreated, _ := ListConversations(ConversationFilter{})
+	byUpdated, _ := ListConversations(ConversationFilter{SortByUpdated: true})
+	if len(byCreated) != 2 || byCreated[0].ID != "newer" {
+		t.Errorf("Default order = %+v, want newer first", byCreated)
+	}
+	if len(byUpdated) != 2 || byUpdated[0].ID != "updated-at" {
+		t.Errorf("Updated order = %+v, want updated-at first", byUpdated)
+	}
+
+	// Conversations saved before UpdatedAt existed fall back to CreatedAt
+	legacy := &Conversation{ID: "legacy", CreatedAt: testTime(), Title: "Old", Messages: []Message{}}
+	SaveConversation(legacy)
+	all, _ := ListConversations(ConversationFilter{SortByUpdated: true})
+	for _, meta := range all {
+		if meta.ID == "legacy" && !meta.UpdatedAt.Equal(testTime()) {
+			t.Errorf("Legacy UpdatedAt = %v, want CreatedAt %v", meta.UpdatedAt, testTime())
+		}
+	}
+}
+
 // TestConversationWorkflow tests a complete workflow
 func TestConversationWorkflow(t *testing.T) {
 	helper := NewTestHelper(t)
@@ -479,7 +711,7 @@ func TestConversationWorkflow(t *testing.T) {
 	}
 
 	// List conversations
-	conversations, err := ListConversations()
+	conversations, err := ListConversations(ConversationFilter{})
 	helper.AssertNoError(err, "ListConversations should succeed")
 
 	if len(conversations) != 1 {
@@ -520,3 +752,583 @@ func TestCreateConversationSaveError(t *testing.T) {
 		t.Error("Expected error when creating conversation in invalid directory")
 	}
 }
+
+// TestSearchConversations tests case-insensitive search across titles and messages
+func TestSearchConversations(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	// Title match
+	titled := SampleConversation("conv-title")
+	titled.Title = "Housing Affordability Debate"
+	titled.CreatedAt = testTime().Add(1 * time.Hour)
+	SaveConversation(titled)
+
+	// Stage 1 match, with enough text to be trimmed on both sides
+	stage1 := SampleConversation("conv-stage1")
+	stage1.Messages[1].Stage1[1].Response = strings.Repeat("lead ", 20) + "negative GEARING changes" + strings.Repeat(" tail", 20)
+	stage1.CreatedAt = testTime().Add(2 * time.Hour)
+	SaveConversation(stage1)
+
+	// Stage 3 match
+	stage3 := SampleConversation("conv-stage3")
+	stage3.Messages[1].Stage3.Response = "The chairman notes negative gearing is contested."
+	SaveConversation(stage3)
+
+	// Unrelated conversation
+	SaveConversation(SampleConversation("conv-none"))
+
+	t.Run("title match", func(t *testing.T) {
+		results, err := SearchConversations("housing")
+		helper.AssertNoError(err, "SearchConversations should succeed")
+
+		if len(results) != 1 || results[0].ID != "conv-title" || results[0].MatchField != "title" {
+			t.Fatalf("Unexpected results: %+v", results)
+		}
+		if results[0].Snippet != "Housing Affordability Debate" {
+			t.Errorf("Snippet = %q", results[0].Snippet)
+		}
+	})
+
+	t.Run("message matches sorted newest first", func(t *testing.T) {
+		results, err := SearchConversations("Negative Gearing")
+		helper.AssertNoError(err, "SearchConversations should succeed")
+
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d: %+v", len(results), results)
+		}
+		if results[0].ID != "conv-stage1" || results[0].MatchField != "stage1" {
+			t.Errorf("First result = %+v, want conv-stage1 stage1 match", results[0])
+		}
+		if results[1].ID != "conv-stage3" || results[1].MatchField != "stage3" {
+			t.Errorf("Second result = %+v, want conv-stage3 stage3 match", results[1])
+		}
+		if results[0].MessageCount != 2 {
+			t.Errorf("MessageCount = %d, want 2", results[0].MessageCount)
+		}
+
+		snippet := results[0].Snippet
+		if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "negative GEARING changes") {
+			t.Errorf("Snippet = %q, want trimmed context around the match", snippet)
+		}
+	})
+
+	t.Run("user message match", func(t *testing.T) {
+		results, err := SearchConversations("what is go")
+		helper.AssertNoError(err, "SearchConversations should succeed")
+
+		if len(results) != 4 || results[0].MatchField != "user" {
+			t.Errorf("Expected all 4 conversations to match the user message, got %+v", results)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		results, err := SearchConversations("quantum")
+		helper.AssertNoError(err, "SearchConversations should succeed")
+		if results == nil || len(results) != 0 {
+			t.Errorf("Expected empty non-nil results, got %#v", results)
+		}
+	})
+
+	t.Run("results are capped", func(t *testing.T) {
+		oldMax := MaxSearchResults
+		MaxSearchResults = 2
+		defer func() { MaxSearchResults = oldMax }()
+
+		results, err := SearchConversations("go")
+		helper.AssertNoError(err, "SearchConversations should succeed")
+		if len(results) != 2 {
+			t.Errorf("Expected results capped at 2, got %d", len(results))
+		}
+	})
+
+	t.Run("empty term", func(t *testing.T) {
+		_, err := SearchConversations("  ")
+		helper.AssertError(err, "Empty search term should error")
+	})
+}
+
+// TestMatchSnippet tests snippet extraction around a match
+func TestMatchSnippet(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		term     string
+		expected string
+		found    bool
+	}{
+		{"short text", "Go is fast", "FAST", "Go is fast", true},
+		{"newlines collapsed", "line one\n\nline two", "two", "line one line two", true},
+		{"multibyte text", "Ünïcödé Çafé résumé", "ÇAFÉ", "Ünïcödé Çafé résumé", true},
+		{"no match", "Go is fast", "slow", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet, found := matchSnippet(tt.text, tt.term)
+			if found != tt.found || snippet != tt.expected {
+				t.Errorf("matchSnippet(%q, %q) = %q, %v; want %q, %v", tt.text, tt.term, snippet, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
+
+// TestMaxMessagesPerConversation tests the reject and drop-oldest message limit policies
+func TestMaxMessagesPerConversation(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir, oldMax, oldPolicy := DataDir, MaxMessagesPerConversation, MessageLimitPolicy
+	DataDir = tempDir
+	defer func() {
+		DataDir, MaxMessagesPerConversation, MessageLimitPolicy = oldDataDir, oldMax, oldPolicy
+	}()
+	MaxMessagesPerConversation = 4
+
+	answer := Stage3Response{Model: "test/chairman", Response: "Answer"}
+	contents := func(id string) []string {
+		conversation, _ := GetConversation(id)
+		var out []string
+		for _, message := range conversation.Messages {
+			if message.Role == "user" {
+				out = append(out, message.Content)
+			} else {
+				out = append(out, "answer")
+			}
+		}
+		return out
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		MessageLimitPolicy = MessageLimitReject
+		CreateConversation("limit-reject")
+		for _, question := range []string{"q1", "q2"} {
+			helper.AssertNoError(AddUserMessage("limit-reject", question), "AddUserMessage within the limit")
+			helper.AssertNoError(AddAssistantMessage("limit-reject", nil, nil, answer), "AddAssistantMessage within the limit")
+		}
+
+		err := AddUserMessage("limit-reject", "q3")
+		if !errors.Is(err, ErrConversationFull) {
+			t.Errorf("Expected ErrConversationFull, got %v", err)
+		}
+		if got := contents("limit-reject"); !reflect.DeepEqual(got, []string{"q1", "answer", "q2", "answer"}) {
+			t.Errorf("Messages after rejection = %v", got)
+		}
+	})
+
+	t.Run("reject leaves room for the answer", func(t *testing.T) {
+		MessageLimitPolicy = MessageLimitReject
+		CreateConversation("limit-room")
+		AddUserMessage("limit-room", "q1")
+		AddAssistantMessage("limit-room", nil, nil, answer)
+		AddUserMessage("limit-room", "q2")
+
+		// An unanswered question can't be followed by another one...
+		if err := AddUserMessage("limit-room", "q3"); !errors.Is(err, ErrConversationFull) {
+			t.Errorf("Expected ErrConversationFull, got %v", err)
+		}
+		// ...but can still be answered
+		helper.AssertNoError(AddAssistantMessage("limit-room", nil, nil, answer), "AddAssistantMessage filling the last slot")
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		MessageLimitPolicy = MessageLimitDropOldest
+		CreateConversation("limit-drop")
+		for _, question := range []string{"q1", "q2", "q3"} {
+			helper.AssertNoError(AddUserMessage("limit-drop", question), "AddUserMessage")
+			helper.AssertNoError(AddAssistantMessage("limit-drop", nil, nil, answer), "AddAssistantMessage")
+		}
+
+		if got := contents("limit-drop"); !reflect.DeepEqual(got, []string{"q2", "answer", "q3", "answer"}) {
+			t.Errorf("Messages after dropping = %v", got)
+		}
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		MaxMessagesPerConversation = 0
+		MessageLimitPolicy = MessageLimitReject
+		CreateConversation("limit-none")
+		for range 5 {
+			helper.AssertNoError(AddUserMessage("limit-none", "q"), "AddUserMessage without a limit")
+		}
+		if got := contents("limit-none"); len(got) != 5 {
+			t.Errorf("Expected 5 messages, got %d", len(got))
+		}
+	})
+}
+
+// TestRemoveLastAssistantMessage tests removing the final assistant message
+func TestRemoveLastAssistantMessage(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	SaveConversation(SampleConversation("remove-last"))
+
+	err := RemoveLastAssistantMessage("remove-last")
+	helper.AssertNoError(err, "RemoveLastAssistantMessage should succeed")
+
+	conv, _ := GetConversation("remove-last")
+	if len(conv.Messages) != 1 || conv.Messages[0].Role != "user" {
+		t.Fatalf("Expected only the user message to remain, got %+v", conv.Messages)
+	}
+
+	// The conversation now ends with a user message
+	err = RemoveLastAssistantMessage("remove-last")
+	if !errors.Is(err, ErrNotAssistantMessage) {
+		t.Errorf("Expected ErrNotAssistantMessage, got %v", err)
+	}
+
+	// Empty conversation
+	CreateConversation("remove-empty")
+	err = RemoveLastAssistantMessage("remove-empty")
+	if !errors.Is(err, ErrNotAssistantMessage) {
+		t.Errorf("Expected ErrNotAssistantMessage for empty conversation, got %v", err)
+	}
+
+	err = RemoveLastAssistantMessage("non-existent")
+	helper.AssertError(err, "Should error for non-existent conversation")
+}
+
+// TestReplaceLastAssistantMessage tests swapping the final answer only while the
+// conversation still ends with it
+func TestReplaceLastAssistantMessage(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	SaveConversation(SampleConversation("replace-last"))
+	question := SampleConversation("replace-last").Messages[0].Content
+
+	err := ReplaceLastAssistantMessage("replace-last", 0, question, nil, nil, Stage3Response{Model: "model/new", Response: "New answer"})
+	helper.AssertNoError(err, "ReplaceLastAssistantMessage should succeed")
+
+	conv, _ := GetConversation("replace-last")
+	if len(conv.Messages) != 2 || conv.Messages[1].Role != "assistant" || conv.Messages[1].Stage3.Model != "model/new" {
+		t.Fatalf("Expected the answer to be replaced, got %+v", conv.Messages)
+	}
+
+	tests := []struct {
+		name      string
+		setup     func()
+		userIndex int
+		content   string
+	}{
+		{"new exchange added", func() {
+			AddUserMessage("replace-last", "Another question")
+			AddAssistantMessage("replace-last", nil, nil, Stage3Response{Model: "model/other"})
+		}, 0, question},
+		{"question edited", func() {
+			EditUserMessage("replace-last", 2, "Edited question")
+			AddAssistantMessage("replace-last", nil, nil, Stage3Response{Model: "model/other"})
+		}, 2, "Another question"},
+		{"answer removed", func() {
+			RemoveLastAssistantMessage("replace-last")
+		}, 2, "Edited question"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			before, _ := GetConversation("replace-last")
+
+			err := ReplaceLastAssistantMessage("replace-last", tt.userIndex, tt.content, nil, nil, Stage3Response{Model: "model/stale"})
+			if !errors.Is(err, ErrConversationChanged) {
+				t.Errorf("Expected ErrConversationChanged, got %v", err)
+			}
+			if after, _ := GetConversation("replace-last"); !reflect.DeepEqual(after.Messages, before.Messages) {
+				t.Errorf("Conversation changed by a rejected replace: %+v", after.Messages)
+			}
+		})
+	}
+
+	err = ReplaceLastAssistantMessage("non-existent", 0, question, nil, nil, Stage3Response{})
+	helper.AssertError(err, "Should error for non-existent conversation")
+}
+
+// TestEditUserMessage tests correcting a user message and dropping what followed it
+func TestEditUserMessage(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	conversation := SampleConversation("edit-me")
+	conversation.Messages = append(conversation.Messages,
+		Message{Role: "user", Content: "And its concurency model?", ImageURLs: []string{"https://example.com/diagram.png"}},
+		Message{Role: "assistant", Stage3: &Stage3Response{Model: "test/chairman", Response: "Goroutines and channels."}},
+		Message{Role: "user", Content: "Thanks"},
+	)
+	SaveConversation(conversation)
+
+	t.Run("index out of range", func(t *testing.T) {
+		for _, index := range []int{-1, 5} {
+			err := EditUserMessage("edit-me", index, "Edited")
+			if !errors.Is(err, ErrInvalidMessageIndex) {
+				t.Errorf("index=%d: expected ErrInvalidMessageIndex, got %v", index, err)
+			}
+		}
+	})
+
+	t.Run("assistant message", func(t *testing.T) {
+		err := EditUserMessage("edit-me", 3, "Edited")
+		if !errors.Is(err, ErrNotUserMessage) {
+			t.Errorf("Expected ErrNotUserMessage, got %v", err)
+		}
+		unchanged, _ := GetConversation("edit-me")
+		if len(unchanged.Messages) != 5 {
+			t.Errorf("Rejected edit changed the conversation: %d messages", len(unchanged.Messages))
+		}
+	})
+
+	t.Run("edits and truncates", func(t *testing.T) {
+		err := EditUserMessage("edit-me", 2, "And its concurrency model?")
+		helper.AssertNoError(err, "EditUserMessage should succeed")
+
+		edited, _ := GetConversation("edit-me")
+		if len(edited.Messages) != 3 {
+			t.Fatalf("Expected 3 messages after edit, got %d", len(edited.Messages))
+		}
+		last := edited.Messages[2]
+		if last.Role != "user" || last.Content != "And its concurrency model?" || len(last.ImageURLs) != 1 {
+			t.Errorf("Edited message = %+v", last)
+		}
+		if edited.Messages[0].Content != "What is Go?" {
+			t.Errorf("Earlier message changed: %+v", edited.Messages[0])
+		}
+		if edited.UpdatedAt.IsZero() {
+			t.Error("Expected UpdatedAt to be set")
+		}
+	})
+
+	t.Run("last message", func(t *testing.T) {
+		err := EditUserMessage("edit-me", 2, "Last edit")
+		helper.AssertNoError(err, "Editing the last message should succeed")
+		edited, _ := GetConversation("edit-me")
+		if len(edited.Messages) != 3 || edited.Messages[2].Content != "Last edit" {
+			t.Errorf("Messages after editing the last one = %+v", edited.Messages)
+		}
+	})
+
+	t.Run("conversation not found", func(t *testing.T) {
+		helper.AssertError(EditUserMessage("missing", 0, "Edited"), "Should error for non-existent conversation")
+	})
+}
+
+// TestForkConversation tests forking a prefix of a conversation
+func TestForkConversation(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	source := SampleConversation("fork-source")
+	source.Messages = append(source.Messages,
+		Message{Role: "user", Content: "And its concurrency model?"},
+		Message{Role: "assistant", Stage3: &Stage3Response{Model: "test/chairman", Response: "Goroutines and channels."}},
+	)
+	SaveConversation(source)
+
+	t.Run("copies messages up to index", func(t *testing.T) {
+		fork, err := ForkConversation("fork-source", 1)
+		helper.AssertNoError(err, "ForkConversation should succeed")
+
+		if fork.ID == "" || fork.ID == "fork-source" {
+			t.Errorf("Expected a new conversation ID, got %q", fork.ID)
+		}
+		if fork.ForkedFrom != "fork-source" {
+			t.Errorf("ForkedFrom = %q, want 'fork-source'", fork.ForkedFrom)
+		}
+		if len(fork.Messages) != 2 {
+			t.Fatalf("Expected 2 messages in fork, got %d", len(fork.Messages))
+		}
+
+		saved, _ := GetConversation(fork.ID)
+		helper.AssertNotNil(saved, "Fork should be saved")
+		if len(saved.Messages) != 2 || saved.Messages[1].Stage3.Response != source.Messages[1].Stage3.Response {
+			t.Errorf("Saved fork messages = %+v", saved.Messages)
+		}
+
+		// Changes to the fork don't affect the source
+		AddUserMessage(fork.ID, "A different follow-up")
+		UpdateConversationTitle(fork.ID, "Forked title")
+
+		original, _ := GetConversation("fork-source")
+		if len(original.Messages) != 4 || original.Title != source.Title {
+			t.Errorf("Source changed after editing fork: %d messages, title %q", len(original.Messages), original.Title)
+		}
+		forked, _ := GetConversation(fork.ID)
+		if len(forked.Messages) != 3 {
+			t.Errorf("Expected 3 messages in fork after follow-up, got %d", len(forked.Messages))
+		}
+	})
+
+	t.Run("last index copies everything", func(t *testing.T) {
+		fork, err := ForkConversation("fork-source", 3)
+		helper.AssertNoError(err, "ForkConversation should succeed")
+		if len(fork.Messages) != 4 {
+			t.Errorf("Expected 4 messages, got %d", len(fork.Messages))
+		}
+	})
+
+	t.Run("index out of range", func(t *testing.T) {
+		for _, upTo := range []int{-1, 4} {
+			_, err := ForkConversation("fork-source", upTo)
+			if !errors.Is(err, ErrInvalidMessageIndex) {
+				t.Errorf("upTo=%d: expected ErrInvalidMessageIndex, got %v", upTo, err)
+			}
+		}
+	})
+
+	t.Run("source not found", func(t *testing.T) {
+		_, err := ForkConversation("missing", 0)
+		helper.AssertError(err, "Should error for non-existent source")
+	})
+}
+
+// TestSetConversationArchived tests archiving hides conversations from the default list
+func TestSetConversationArchived(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	CreateConversation("keep")
+	CreateConversation("archive-me")
+
+	listIDs := func(filter ConversationFilter) []string {
+		conversations, err := ListConversations(filter)
+		helper.AssertNoError(err, "ListConversations should succeed")
+		ids := []string{}
+		for _, conv := range conversations {
+			ids = append(ids, conv.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	t.Run("archive hides from default list", func(t *testing.T) {
+		err := SetConversationArchived("archive-me", true)
+		helper.AssertNoError(err, "SetConversationArchived should succeed")
+
+		conv, _ := GetConversation("archive-me")
+		if !conv.Archived {
+			t.Error("Expected conversation to be archived on disk")
+		}
+		if ids := listIDs(ConversationFilter{}); !reflect.DeepEqual(ids, []string{"keep"}) {
+			t.Errorf("Default list = %v, want [keep]", ids)
+		}
+		if ids := listIDs(ConversationFilter{IncludeArchived: true}); !reflect.DeepEqual(ids, []string{"archive-me", "keep"}) {
+			t.Errorf("List with archived = %v, want [archive-me keep]", ids)
+		}
+	})
+
+	t.Run("unarchive restores", func(t *testing.T) {
+		err := SetConversationArchived("archive-me", false)
+		helper.AssertNoError(err, "SetConversationArchived should succeed")
+
+		if ids := listIDs(ConversationFilter{}); !reflect.DeepEqual(ids, []string{"archive-me", "keep"}) {
+			t.Errorf("Default list = %v, want [archive-me keep]", ids)
+		}
+	})
+
+	t.Run("non-existent conversation", func(t *testing.T) {
+		err := SetConversationArchived("missing", true)
+		helper.AssertError(err, "SetConversationArchived should fail for a missing conversation")
+	})
+}
+
+// TestConversationTags tests adding, removing and filtering by tags
+func TestConversationTags(t *testing.T) {
+	helper := NewTestHelper(t)
+	tempDir := helper.CreateTempDir()
+	defer helper.Cleanup()
+
+	oldDataDir := DataDir
+	DataDir = tempDir
+	defer func() { DataDir = oldDataDir }()
+
+	CreateConversation("tagged")
+	CreateConversation("untagged")
+
+	t.Run("add normalizes and deduplicates", func(t *testing.T) {
+		tags, err := AddConversationTags("tagged", []string{" Budget ", "housing", "BUDGET", ""})
+		helper.AssertNoError(err, "AddConversationTags should succeed")
+		if !reflect.DeepEqual(tags, []string{"budget", "housing"}) {
+			t.Errorf("Tags = %v, want [budget housing]", tags)
+		}
+
+		tags, err = AddConversationTags("tagged", []string{"Housing", "climate"})
+		helper.AssertNoError(err, "AddConversationTags should succeed")
+		if !reflect.DeepEqual(tags, []string{"budget", "climate", "housing"}) {
+			t.Errorf("Tags = %v, want [budget climate housing]", tags)
+		}
+
+		conv, _ := GetConversation("tagged")
+		if !reflect.DeepEqual(conv.Tags, tags) {
+			t.Errorf("Saved tags = %v, want %v", conv.Tags, tags)
+		}
+	})
+
+	t.Run("filter by tag", func(t *testing.T) {
+		conversations, err := ListConversations(ConversationFilter{Tag: "Climate"})
+		helper.AssertNoError(err, "ListConversations should succeed")
+		if len(conversations) != 1 || conversations[0].ID != "tagged" {
+			t.Errorf("Filtered list = %+v, want only 'tagged'", conversations)
+		}
+
+		conversations, _ = ListConversations(ConversationFilter{Tag: "missing"})
+		if len(conversations) != 0 {
+			t.Errorf("Expected no conversations for an unused tag, got %d", len(conversations))
+		}
+
+		conversations, _ = ListConversations(ConversationFilter{})
+		if len(conversations) != 2 {
+			t.Errorf("Unfiltered list has %d conversations, want 2", len(conversations))
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		tags, err := RemoveConversationTag("tagged", "CLIMATE")
+		helper.AssertNoError(err, "RemoveConversationTag should succeed")
+		if !reflect.DeepEqual(tags, []string{"budget", "housing"}) {
+			t.Errorf("Tags = %v, want [budget housing]", tags)
+		}
+
+		// Removing an absent tag is a no-op
+		tags, err = RemoveConversationTag("tagged", "climate")
+		helper.AssertNoError(err, "RemoveConversationTag should succeed for an absent tag")
+		if len(tags) != 2 {
+			t.Errorf("Tags = %v, want 2 tags", tags)
+		}
+	})
+
+	t.Run("non-existent conversation", func(t *testing.T) {
+		_, err := AddConversationTags("missing", []string{"x"})
+		helper.AssertError(err, "AddConversationTags should fail for a missing conversation")
+		_, err = RemoveConversationTag("missing", "x")
+		helper.AssertError(err, "RemoveConversationTag should fail for a missing conversation")
+	})
+}
@@ -57,7 +57,7 @@ func main() {
 	}
 
 	start = time.Now()
-	responses, err := QueryModelsParallel(ctx, testModels, messages)
+	responses, _, err := QueryModelsParallel(ctx, testModels, messages, 30*time.Second)
 	elapsed = time.Since(start)
 
 	if err != nil {
@@ -2,10 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -118,6 +121,34 @@ func (h *TestHelper) AssertError(err error, message string) {
 	}
 }
 
+// AssertAPIError checks that w is an error envelope with the given status and code,
+// and returns the decoded error for further checks
+func AssertAPIError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) APIError {
+	t.Helper()
+	if w.Code != status {
+		t.Errorf("Status = %d, want %d", w.Code, status)
+	}
+	var response ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse error response %q: %v", w.Body.String(), err)
+	}
+	if response.Error.Code != code {
+		t.Errorf("Error code = %q, want %q (body: %s)", response.Error.Code, code, w.Body.String())
+	}
+	if response.Error.Message == "" {
+		t.Errorf("Error message is empty (body: %s)", w.Body.String())
+	}
+	return response.Error
+}
+
+// AllowLoopbackFetches lets FetchURLContent reach httptest servers, which listen on
+// loopback, for the rest of the test
+func AllowLoopbackFetches(t *testing.T) {
+	oldPolicy := FetchURLPolicy
+	t.Cleanup(func() { FetchURLPolicy = oldPolicy })
+	FetchURLPolicy.Allow = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+}
+
 // MockOpenRouterServer creates a mock HTTP server for OpenRouter API
 func MockOpenRouterServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
 	return httptest.NewServer(handler)
@@ -135,6 +166,14 @@ func CreateMockOpenRouterHandler(t *testing.T, response string) http.HandlerFunc
 			t.Errorf("Missing Authorization header")
 		}
 
+		// Stream the response as SSE chunks if streaming was requested
+		var req OpenRouterRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			WriteMockOpenRouterStream(w, strings.SplitAfter(response, " "))
+			return
+		}
+
 		// Return mock response
 		apiResponse := OpenRouterAPIResponse{
 			Choices: []struct {
@@ -142,6 +181,7 @@ func CreateMockOpenRouterHandler(t *testing.T, response string) http.HandlerFunc
 					Content          string      `json:"content"`
 					ReasoningDetails interface{} `json:"reasoning_details,omitempty"`
 				} `json:"message"`
+				FinishReason string `json:"finish_reason"`
 			}{
 				{
 					Message: struct {
@@ -160,6 +200,27 @@ func CreateMockOpenRouterHandler(t *testing.T, response string) http.HandlerFunc
 	}
 }
 
+// WriteMockOpenRouterStream writes tokens as OpenRouter-style SSE chunks, flushing each one
+func WriteMockOpenRouterStream(w http.ResponseWriter, tokens []string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	fmt.Fprint(w, ": OPENROUTER PROCESSING\n\n")
+	for _, token := range tokens {
+		chunk, _ := json.Marshal(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"delta": map[string]string{"content": token}},
+			},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
 // CreateMockOpenRouterErrorHandler creates a handler that returns errors
 func CreateMockOpenRouterErrorHandler(statusCode int, errorMsg string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrURLNotAllowed is returned when a URL's scheme or the address its host resolves
+// to is refused by a URLPolicy
+var ErrURLNotAllowed = errors.New("URL not allowed")
+
+// ErrTooManyRedirects is returned when a fetch is redirected more times than its
+// URLPolicy allows
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// ErrRedirectLoop is returned when a fetch is redirected back to a URL it already
+// visited
+var ErrRedirectLoop = errors.New("redirect loop")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), not covered by
+// netip.Addr.IsPrivate but just as unreachable from the internet
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// URLPolicy decides which addresses server-side fetches of user-supplied URLs may
+// connect to. By default only public addresses are allowed, so a URL can't be used to
+// reach loopback, private networks or cloud metadata endpoints (SSRF).
+type URLPolicy struct {
+	// AllowPrivate allows loopback, private, link-local and other non-public addresses
+	AllowPrivate bool
+
+	// Allow lists ranges that are always allowed, e.g. an intranet documents server
+	Allow []netip.Prefix
+
+	// Deny lists ranges that are always refused, even if public or in Allow
+	Deny []netip.Prefix
+
+	// MaxRedirects is the most redirects a fetch may follow; 0 follows none
+	MaxRedirects int
+}
+
+// AllowAddr reports whether the policy allows connecting to addr
+func (p URLPolicy) AllowAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p.Deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	for _, prefix := range p.Allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return p.AllowPrivate || isPublicAddr(addr)
+}
+
+// CheckURL refuses URLs that aren't http(s) or whose host is a literal IP the policy
+// doesn't allow. Hostnames are checked once resolved, when connecting.
+func (p URLPolicy) CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q is not http or https", ErrURLNotAllowed, u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: no host", ErrURLNotAllowed)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !p.AllowAddr(addr) {
+		return fmt.Errorf("%w: %s is not a public address", ErrURLNotAllowed, addr)
+	}
+	return nil
+}
+
+// dialControl refuses connections to addresses the policy doesn't allow. It runs
+// after DNS resolution for every address dialed, so hostnames that resolve to
+// internal addresses, and redirects to them, are caught too.
+func (p URLPolicy) dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unrecognized address %q", ErrURLNotAllowed, address)
+	}
+	if !p.AllowAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s is not a public address", ErrURLNotAllowed, addrPort.Addr())
+	}
+	return nil
+}
+
+// checkRedirect applies the policy to each redirect hop, and stops at
+// p.MaxRedirects hops or when a hop leads back to a URL already visited
+func (p URLPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > p.MaxRedirects {
+		return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, p.MaxRedirects)
+	}
+	target := NormalizeContentURL(req.URL.String())
+	for _, previous := range via {
+		if NormalizeContentURL(previous.URL.String()) == target {
+			return fmt.Errorf("%w: %s redirects back to itself", ErrRedirectLoop, req.URL.Redacted())
+		}
+	}
+	return p.CheckURL(req.URL)
+}
+
+// client returns an HTTP client that enforces the policy on every connection and
+// redirect. It never uses a proxy, since the proxy's address is all it could check.
+func (p URLPolicy) client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   p.dialControl,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true, // Each client is used for a single fetch
+		},
+		CheckRedirect: p.checkRedirect,
+	}
+}
+
+// isPublicAddr reports whether addr is routable on the public internet
+func isPublicAddr(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// parsePrefixList parses a comma-separated list of CIDR ranges; a bare IP address
+// is taken as a range holding just that address
+func parsePrefixList(raw string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(field); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q", field)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestURLPolicyAllowAddr tests which addresses the default and configured policies allow
+func TestURLPolicyAllowAddr(t *testing.T) {
+	configured := URLPolicy{
+		Allow: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")},
+		Deny:  []netip.Prefix{netip.MustParsePrefix("8.8.8.0/24"), netip.MustParsePrefix("10.20.30.0/24")},
+	}
+
+	tests := []struct {
+		addr           string
+		wantDefault    bool
+		wantConfigured bool
+	}{
+		{"93.184.216.34", true, true},
+		{"2606:2800:220:1::1", true, true},
+		{"127.0.0.1", false, false},
+		{"::1", false, false},
+		{"10.0.0.1", false, false},
+		{"172.16.5.4", false, false},
+		{"192.168.1.1", false, false},
+		{"169.254.169.254", false, false},
+		{"100.64.0.1", false, false},
+		{"0.0.0.0", false, false},
+		{"fd00::1", false, false},
+		{"fe80::1", false, false},
+		{"::ffff:127.0.0.1", false, false},
+		{"224.0.0.1", false, false},
+		{"10.20.1.1", false, true},
+		{"10.20.30.1", false, false},
+		{"8.8.8.8", true, false},
+	}
+
+	for _, tt := range tests {
+		addr := netip.MustParseAddr(tt.addr)
+		if got := (URLPolicy{}).AllowAddr(addr); got != tt.wantDefault {
+			t.Errorf("default AllowAddr(%s) = %v, want %v", tt.addr, got, tt.wantDefault)
+		}
+		if got := configured.AllowAddr(addr); got != tt.wantConfigured {
+			t.Errorf("configured AllowAddr(%s) = %v, want %v", tt.addr, got, tt.wantConfigured)
+		}
+	}
+
+	if !(URLPolicy{AllowPrivate: true}).AllowAddr(netip.MustParseAddr("192.168.1.1")) {
+		t.Error("Expected AllowPrivate to allow private addresses")
+	}
+}
+
+// TestURLPolicyCheckURL tests scheme and literal IP checks on URLs
+func TestURLPolicyCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://www.aph.gov.au/bills", false},
+		{"http://example.com:8080/page", false},
+		{"http://93.184.216.34/", false},
+		{"file:///etc/passwd", true},
+		{"gopher://example.com/", true},
+		{"ftp://example.com/file.pdf", true},
+		{"http:///path", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://127.0.0.1:8001/api/conversations", true},
+		{"http://[::1]/", true},
+		{"http://10.1.2.3/", true},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) failed: %v", tt.url, err)
+		}
+		err = URLPolicy{}.CheckURL(u)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrURLNotAllowed) {
+			t.Errorf("CheckURL(%q) error = %v, want ErrURLNotAllowed", tt.url, err)
+		}
+	}
+}
+
+// TestFetchURLContentBlocksInternalAddresses tests that fetches of internal addresses
+// are refused before any request reaches them
+func TestFetchURLContentBlocksInternalAddresses(t *testing.T) {
+	var requests atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, "<html><body><p>Internal admin page</p></body></html>")
+	}))
+	defer internal.Close()
+	port := internal.URL[strings.LastIndex(internal.URL, ":")+1:]
+
+	urls := []string{
+		internal.URL,
+		"http://localhost:" + port + "/",
+		"http://[::1]:" + port + "/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+		"http://192.168.0.1/",
+		"file:///etc/passwd",
+	}
+	for _, target := range urls {
+		_, err := FetchURLContent(context.Background(), target)
+		if !errors.Is(err, ErrURLNotAllowed) {
+			t.Errorf("FetchURLContent(%q) error = %v, want ErrURLNotAllowed", target, err)
+		}
+	}
+	if requests.Load() != 0 {
+		t.Errorf("Expected no requests to reach the internal server, got %d", requests.Load())
+	}
+}
+
+// TestFetchURLContentBlocksRedirects tests that redirects are checked like the
+// original URL
+func TestFetchURLContentBlocksRedirects(t *testing.T) {
+	oldPolicy := FetchURLPolicy
+	defer func() { FetchURLPolicy = oldPolicy }()
+
+	var internalRequests atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalRequests.Add(1)
+		fmt.Fprint(w, "<html><body><p>Internal admin page</p></body></html>")
+	}))
+	defer internal.Close()
+	internalPort := internal.URL[strings.LastIndex(internal.URL, ":")+1:]
+
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := map[string]string{
+			"/metadata": "http://169.254.169.254/latest/meta-data/",
+			"/internal": "http://[::1]:" + internalPort + "/",
+			"/file":     "file:///etc/passwd",
+		}[r.URL.Path]
+		http.Redirect(w, r, target, http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	// Only the redirecting server itself is allowed, standing in for a public site
+	redirectorAddr := netip.MustParseAddrPort(strings.TrimPrefix(redirector.URL, "http://")).Addr()
+	FetchURLPolicy = URLPolicy{
+		Allow:        []netip.Prefix{netip.PrefixFrom(redirectorAddr, redirectorAddr.BitLen())},
+		MaxRedirects: 5,
+	}
+
+	for _, path := range []string{"/metadata", "/internal", "/file"} {
+		_, err := FetchURLContent(context.Background(), redirector.URL+path)
+		if !errors.Is(err, ErrURLNotAllowed) {
+			t.Errorf("Redirect via %s: error = %v, want ErrURLNotAllowed", path, err)
+		}
+	}
+	if internalRequests.Load() != 0 {
+		t.Errorf("Expected no requests to reach the internal server, got %d", internalRequests.Load())
+	}
+}
+
+// TestFetchURLContentRedirectLimits tests stopping redirect loops and chains longer
+// than the policy allows, without retrying them
+func TestFetchURLContentRedirectLimits(t *testing.T) {
+	AllowLoopbackFetches(t)
+	FetchURLPolicy.MaxRedirects = 3
+
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch {
+		case r.URL.Path == "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case r.URL.Path == "/b":
+			http.Redirect(w, r, "/a#top", http.StatusMovedPermanently)
+		case r.URL.Path == "/self":
+			http.Redirect(w, r, "/self", http.StatusTemporaryRedirect)
+		case strings.HasPrefix(r.URL.Path, "/hop/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+			if n > 0 {
+				http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+				return
+			}
+			fmt.Fprint(w, "<html><head><title>Destination</title></head><body><p>Arrived</p></body></html>")
+		}
+	}))
+	defer mockServer.Close()
+
+	for path, want := range map[string]error{
+		"/a":     ErrRedirectLoop,
+		"/self":  ErrRedirectLoop,
+		"/hop/4": ErrTooManyRedirects,
+	} {
+		requests.Store(0)
+		_, err := FetchURLContent(context.Background(), mockServer.URL+path)
+		if !errors.Is(err, want) {
+			t.Errorf("FetchURLContent(%s) error = %v, want %v", path, err, want)
+		}
+		if requests.Load() > 5 {
+			t.Errorf("FetchURLContent(%s) made %d requests, expected it not to retry", path, requests.Load())
+		}
+	}
+
+	result, err := FetchURLContent(context.Background(), mockServer.URL+"/hop/3")
+	if err != nil {
+		t.Fatalf("Expected 3 redirects to be followed, got error: %v", err)
+	}
+	if result.Title != "Destination" {
+		t.Errorf("Title = %q, want %q", result.Title, "Destination")
+	}
+
+	FetchURLPolicy.MaxRedirects = 0
+	if _, err := FetchURLContent(context.Background(), mockServer.URL+"/hop/1"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("Expected no redirects to be followed with MaxRedirects 0, got %v", err)
+	}
+}
+
+// TestParsePrefixList tests parsing configured CIDR lists
+func TestParsePrefixList(t *testing.T) {
+	prefixes, err := parsePrefixList(" 10.20.0.0/16, 192.168.1.7 ,fd00::/8,,")
+	if err != nil {
+		t.Fatalf("parsePrefixList failed: %v", err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.20.0.0/16"),
+		netip.MustParsePrefix("192.168.1.7/32"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+	if !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("parsePrefixList = %v, want %v", prefixes, want)
+	}
+
+	if _, err := parsePrefixList("10.0.0.0/33"); err == nil {
+		t.Error("Expected error for an invalid range")
+	}
+	if _, err := parsePrefixList("intranet.local"); err == nil {
+		t.Error("Expected error for a hostname")
+	}
+}
@@ -132,6 +132,15 @@ function App() {
             });
             break;
 
+          case 'stage2_model_complete':
+            setCurrentConversation((prev) => {
+              const messages = [...prev.messages];
+              const lastMsg = messages[messages.length - 1];
+              lastMsg.stage2 = [...(lastMsg.stage2 || []), event.data];
+              return { ...prev, messages };
+            });
+            break;
+
           case 'stage2_complete':
             setCurrentConversation((prev) => {
               const messages = [...prev.messages];
@@ -152,6 +161,18 @@ function App() {
             });
             break;
 
+          case 'stage3_token':
+            setCurrentConversation((prev) => {
+              const messages = [...prev.messages];
+              const lastMsg = messages[messages.length - 1];
+              lastMsg.stage3 = {
+                ...lastMsg.stage3,
+                response: (lastMsg.stage3?.response || '') + event.data,
+              };
+              return { ...prev, messages };
+            });
+            break;
+
           case 'stage3_complete':
             setCurrentConversation((prev) => {
               const messages = [...prev.messages];
@@ -3,13 +3,23 @@
  */
 
 const API_BASE = 'http://localhost:8001';
+const API_KEY = import.meta.env.VITE_API_KEY;
+
+/**
+ * Build request headers, adding the bearer token when VITE_API_KEY is set.
+ */
+function buildHeaders(headers = {}) {
+  return API_KEY ? { ...headers, Authorization: `Bearer ${API_KEY}` } : headers;
+}
 
 export const api = {
   /**
    * List all conversations.
    */
   async listConversations() {
-    const response = await fetch(`${API_BASE}/api/conversations`);
+    const response = await fetch(`${API_BASE}/api/conversations`, {
+      headers: buildHeaders(),
+    });
     if (!response.ok) {
       throw new Error('Failed to list conversations');
     }
@@ -22,9 +32,7 @@ export const api = {
   async createConversation() {
     const response = await fetch(`${API_BASE}/api/conversations`, {
       method: 'POST',
-      headers: {
-        'Content-Type': 'application/json',
-      },
+      headers: buildHeaders({ 'Content-Type': 'application/json' }),
       body: JSON.stringify({}),
     });
     if (!response.ok) {
@@ -38,7 +46,8 @@ export const api = {
    */
   async getConversation(conversationId) {
     const response = await fetch(
-      `${API_BASE}/api/conversations/${conversationId}`
+      `${API_BASE}/api/conversations/${conversationId}`,
+      { headers: buildHeaders() }
     );
     if (!response.ok) {
       throw new Error('Failed to get conversation');
@@ -54,9 +63,7 @@ export const api = {
       `${API_BASE}/api/conversations/${conversationId}/message`,
       {
         method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-        },
+        headers: buildHeaders({ 'Content-Type': 'application/json' }),
         body: JSON.stringify({ content }),
       }
     );
@@ -78,9 +85,7 @@ export const api = {
       `${API_BASE}/api/conversations/${conversationId}/message/stream`,
       {
         method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-        },
+        headers: buildHeaders({ 'Content-Type': 'application/json' }),
         body: JSON.stringify({ content }),
       }
     );
@@ -122,7 +127,7 @@ export const api = {
     if (options.refresh) params.append('refresh', 'true');
 
     const url = `${API_BASE}/api/bills${params.toString() ? '?' + params.toString() : ''}`;
-    const response = await fetch(url);
+    const response = await fetch(url, { headers: buildHeaders() });
     if (!response.ok) {
       throw new Error('Failed to fetch bills');
     }
@@ -132,14 +137,12 @@ export const api = {
   /**
    * Fetch content from a given URL.
    * @param {string} url - The URL to fetch content from
-   * @returns {Promise<{content: string}>}
+   * @returns {Promise<{title: string, text: string, url: string, cached: boolean}>}
    */
   async fetchURLContent(url) {
     const response = await fetch(`${API_BASE}/api/fetch-url`, {
       method: 'POST',
-      headers: {
-        'Content-Type': 'application/json',
-      },
+      headers: buildHeaders({ 'Content-Type': 'application/json' }),
       body: JSON.stringify({ url }),
     });
     if (!response.ok) {
@@ -106,7 +106,7 @@ function BillCard({ bill, onSelectContent }) {
     setLoadingMemo(true);
     try {
       const result = await api.fetchURLContent(bill.explanatory_memo_url);
-      const prompt = `Please analyze this Explanatory Memorandum for the bill "${bill.title}":\n\n${result.content}`;
+      const prompt = `Please analyze this Explanatory Memorandum for the bill "${bill.title}":\n\n${result.text}`;
       onSelectContent(prompt);
     } catch (error) {
       console.error('Failed to fetch explanatory memo:', error);